@@ -221,6 +221,7 @@ var _ = Describe("Bake", func() {
 				"--variables-file", "some-variables-file",
 				"--sha256",
 				"--download-threads", "5",
+				"--timeout", "15m",
 			})
 			Expect(err).NotTo(HaveOccurred())
 
@@ -378,6 +379,8 @@ var _ = Describe("Bake", func() {
 				"--variable", "some-variable=some-variable-value",
 				"--download-threads", "5",
 				"--no-confirm",
+				"--timeout", "15m0s",
+				"--parallel", "1",
 				"--releases-directory",
 				otherReleasesDirectory,
 			}))
@@ -390,6 +393,8 @@ var _ = Describe("Bake", func() {
 				"--variable", "some-variable=some-variable-value",
 				"--download-threads", "5",
 				"--no-confirm",
+				"--timeout", "15m0s",
+				"--parallel", "1",
 				"--releases-directory",
 				someReleasesDirectory,
 			}))
@@ -613,6 +618,8 @@ var _ = Describe("Bake", func() {
 					"/home/.kiln/credentials.yml",
 					"--download-threads", "0",
 					"--no-confirm",
+					"--timeout", "0s",
+					"--parallel", "1",
 					"--releases-directory",
 					someReleasesDirectory,
 				}))
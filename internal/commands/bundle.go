@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// Bundle groups the commands that move the build inputs of a tile across an air gap.
+// The first argument selects the action.
+type Bundle struct {
+	actions map[string]jhanda.Command
+}
+
+var _ jhanda.Command = Bundle{}
+
+func NewBundle(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, localReleaseDirectory LocalReleaseDirectory) Bundle {
+	return Bundle{
+		actions: map[string]jhanda.Command{
+			"export": NewBundleExport(ctx, outLogger, fs, localReleaseDirectory),
+			"import": NewBundleImport(ctx, outLogger, fs),
+		},
+	}
+}
+
+func (cmd Bundle) Execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing bundle action: expected one of %s", strings.Join(cmd.actionNames(), ", "))
+	}
+	action, ok := cmd.actions[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown bundle action %q: expected one of %s", args[0], strings.Join(cmd.actionNames(), ", "))
+	}
+	return action.Execute(args[1:])
+}
+
+func (cmd Bundle) actionNames() []string {
+	names := make([]string, 0, len(cmd.actions))
+	for name := range cmd.actions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (cmd Bundle) Usage() jhanda.Usage {
+	var description strings.Builder
+	description.WriteString("Packages and unpacks the Kilnfile, Kilnfile.lock and release tarballs of a tile. Run \"kiln bundle ACTION --help\" for the flags of an action.\n\nActions:\n")
+	for _, name := range cmd.actionNames() {
+		_, _ = fmt.Fprintf(&description, "  %-20s %s\n", name, cmd.actions[name].Usage().ShortDescription)
+	}
+	return jhanda.Usage{
+		Description:      description.String(),
+		ShortDescription: "moves tile build inputs across an air gap",
+	}
+}
+
+// BundleExport writes the Kilnfile, Kilnfile.lock and every locked release tarball into one archive.
+type BundleExport struct {
+	ctx                   context.Context
+	outLogger             *log.Logger
+	fs                    billy.Filesystem
+	localReleaseDirectory LocalReleaseDirectory
+
+	Options struct {
+		flags.Standard
+
+		ReleasesDir string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory with the release tarballs in Kilnfile.lock (run kiln fetch first)"`
+		OutputFile  string `short:"o"  long:"output-file"        required:"true"   description:"path to write the bundle to"`
+	}
+}
+
+func NewBundleExport(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, localReleaseDirectory LocalReleaseDirectory) BundleExport {
+	return BundleExport{
+		ctx:                   ctx,
+		outLogger:             outLogger,
+		fs:                    fs,
+		localReleaseDirectory: localReleaseDirectory,
+	}
+}
+
+func (cmd BundleExport) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+
+	_, kilnfileLock, err := cmd.Options.Standard.LoadKilnfiles(cmd.fs, nil)
+	if err != nil {
+		return fmt.Errorf("error loading Kilnfiles: %w", err)
+	}
+	// the Kilnfiles it extends are not bundled, so the Kilnfile is bundled merged with them
+	kilnfileBuf, err := cmd.Options.Standard.RenderKilnfile(cmd.fs, nil)
+	if err != nil {
+		return err
+	}
+	kilnfileLockBuf, err := util.ReadFile(cmd.fs, cmd.Options.KilnfileLockPath())
+	if err != nil {
+		return err
+	}
+
+	localReleases, err := cmd.localReleaseDirectory.GetLocalReleases(cmd.Options.ReleasesDir)
+	if err != nil {
+		return fmt.Errorf("failed reading releases directory: %w", err)
+	}
+	releases, missing, _ := partition(kilnfileLock.Releases, localReleases)
+	if len(missing) > 0 {
+		var list strings.Builder
+		for _, rl := range missing {
+			_, _ = fmt.Fprintf(&list, "\n- %s %s (sha1: %s)", rl.Name, rl.Version, rl.SHA1)
+		}
+		return fmt.Errorf("%d release(s) in Kilnfile.lock are not in %q (run kiln fetch first):%s", len(missing), cmd.Options.ReleasesDir, list.String())
+	}
+	// sort by name so bundles of the same inputs are the same
+	slices.SortFunc(releases, func(a, b component.Local) int {
+		return strings.Compare(a.Lock.Name, b.Lock.Name)
+	})
+
+	out, err := cmd.fs.Create(cmd.Options.OutputFile)
+	if err != nil {
+		return err
+	}
+	err = component.WriteBundle(cmd.ctx, out, kilnfileBuf, kilnfileLockBuf, releases)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = cmd.fs.Remove(cmd.Options.OutputFile)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	cmd.outLogger.Printf("Wrote %d release(s), the Kilnfile and Kilnfile.lock to %s", len(releases), cmd.Options.OutputFile)
+	return nil
+}
+
+func (cmd BundleExport) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Writes the Kilnfile, Kilnfile.lock, every release tarball in Kilnfile.lock and a manifest of their checksums into a single archive. The Kilnfile is bundled as rendered by \"kiln kilnfile render\": variables are interpolated and the Kilnfiles it extends are merged into it.",
+		ShortDescription: "packages the build inputs of a tile into a bundle",
+		Flags:            cmd.Options,
+	}
+}
+
+// BundleImport writes the release tarballs in a bundle into a releases directory.
+type BundleImport struct {
+	ctx       context.Context
+	outLogger *log.Logger
+	fs        billy.Filesystem
+
+	Options struct {
+		BundlePath        string `short:"b"  long:"bundle"             required:"true"   description:"path to a bundle written by kiln bundle export"`
+		ReleasesDir       string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to write the release tarballs to"`
+		KilnfileDirectory string `           long:"kilnfile-directory"                   description:"path to a directory to write the bundled Kilnfile and Kilnfile.lock to; they are not written when this is not set"`
+	}
+}
+
+func NewBundleImport(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem) BundleImport {
+	return BundleImport{
+		ctx:       ctx,
+		outLogger: outLogger,
+		fs:        fs,
+	}
+}
+
+func (cmd BundleImport) Execute(args []string) error {
+	if _, err := jhanda.Parse(&cmd.Options, args); err != nil {
+		return err
+	}
+
+	bundle, err := component.OpenBundle(cmd.Options.BundlePath)
+	if err != nil {
+		return err
+	}
+	kilnfileLockBuf, err := bundle.ReadFile(cmd.ctx, component.BundleKilnfileLockFileName)
+	if err != nil {
+		return err
+	}
+	var kilnfileLock cargo.KilnfileLock
+	if err := yaml.Unmarshal(kilnfileLockBuf, &kilnfileLock); err != nil {
+		return fmt.Errorf("failed to parse Kilnfile.lock in bundle: %w", err)
+	}
+
+	if err := os.MkdirAll(cmd.Options.ReleasesDir, 0o777); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, rl := range kilnfileLock.Releases {
+		if err := cmd.importRelease(bundle, rl); err != nil {
+			errs = append(errs, fmt.Errorf("failed to import %s %s: %w", rl.Name, rl.Version, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if cmd.Options.KilnfileDirectory != "" {
+		kilnfileBuf, err := bundle.ReadFile(cmd.ctx, component.BundleKilnfileFileName)
+		if err != nil {
+			return err
+		}
+		if err := cmd.fs.MkdirAll(cmd.Options.KilnfileDirectory, 0o777); err != nil {
+			return err
+		}
+		if err := util.WriteFile(cmd.fs, filepath.Join(cmd.Options.KilnfileDirectory, component.BundleKilnfileFileName), kilnfileBuf, 0o644); err != nil {
+			return err
+		}
+		if err := util.WriteFile(cmd.fs, filepath.Join(cmd.Options.KilnfileDirectory, component.BundleKilnfileLockFileName), kilnfileLockBuf, 0o644); err != nil {
+			return err
+		}
+	}
+
+	cmd.outLogger.Printf("Imported %d release(s) into %s", len(kilnfileLock.Releases), cmd.Options.ReleasesDir)
+	return nil
+}
+
+// importRelease extracts a release and removes it again when it does not match the lock.
+func (cmd BundleImport) importRelease(bundle *component.Bundle, rl cargo.BOSHReleaseTarballLock) error {
+	release, found := bundle.Release(rl.Name, rl.Version)
+	if !found {
+		return errors.New("the release is not in the bundle")
+	}
+	local, err := bundle.ExtractRelease(cmd.ctx, cmd.Options.ReleasesDir, release)
+	if err != nil {
+		return err
+	}
+	if local.Lock.SHA1 != rl.SHA1 {
+		_ = os.Remove(local.LocalPath)
+		return fmt.Errorf("release %q had an incorrect SHA1 - expected %q, got %q", local.LocalPath, rl.SHA1, local.Lock.SHA1)
+	}
+	if rl.SHA256 != "" && local.Lock.SHA256 != rl.SHA256 {
+		_ = os.Remove(local.LocalPath)
+		return fmt.Errorf("release %q had an incorrect SHA256 - expected %q, got %q", local.LocalPath, rl.SHA256, local.Lock.SHA256)
+	}
+	cmd.outLogger.Printf("Imported %s %s", rl.Name, rl.Version)
+	return nil
+}
+
+func (cmd BundleImport) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Writes the release tarballs in a bundle into a releases directory. Every tarball is checked against the SHA1 (and SHA256 when set) in the bundled Kilnfile.lock.",
+		ShortDescription: "unpacks the releases in a bundle",
+		Flags:            cmd.Options,
+	}
+}
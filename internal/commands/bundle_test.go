@@ -0,0 +1,183 @@
+package commands_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	fetcherFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("bundle", func() {
+	var (
+		fs                    billy.Filesystem
+		tmpDir                string
+		kilnfilePath          string
+		bundlePath            string
+		releasesDir           string
+		kilnfileLock          cargo.KilnfileLock
+		localReleaseDirectory *commandsFakes.LocalReleaseDirectory
+		logger                *log.Logger
+	)
+
+	sha1Of := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "bundle-test")
+		Expect(err).NotTo(HaveOccurred())
+		fs = osfs.New("")
+		logger = log.New(GinkgoWriter, "", 0)
+		kilnfilePath = filepath.Join(tmpDir, "Kilnfile")
+		bundlePath = filepath.Join(tmpDir, "tile.bundle")
+		releasesDir = filepath.Join(tmpDir, "releases")
+		Expect(os.Mkdir(releasesDir, 0o755)).To(Succeed())
+
+		kilnfileLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "1.0.0", SHA1: sha1Of("bpm contents"), RemoteSource: "bosh.io", RemotePath: "https://bosh.io/bpm"},
+				{Name: "routing", Version: "2.0.0", SHA1: sha1Of("routing contents"), RemoteSource: "bosh.io", RemotePath: "https://bosh.io/routing"},
+			},
+		}
+		var locals []component.Local
+		for _, rl := range kilnfileLock.Releases {
+			p := filepath.Join(releasesDir, rl.Name+"-"+rl.Version+".tgz")
+			Expect(os.WriteFile(p, []byte(rl.Name+" contents"), 0o644)).To(Succeed())
+			locals = append(locals, component.Local{Lock: rl, LocalPath: p})
+		}
+		localReleaseDirectory = new(commandsFakes.LocalReleaseDirectory)
+		localReleaseDirectory.GetLocalReleasesReturns(locals, nil)
+
+		Expect(fsWriteYAML(fs, kilnfilePath, cargo.Kilnfile{Slug: "some-tile"})).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		Expect(fsWriteYAML(fs, kilnfilePath+".lock", kilnfileLock)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	exportBundle := func() error {
+		return commands.NewBundle(context.Background(), logger, fs, localReleaseDirectory).Execute([]string{
+			"export", "--kilnfile", kilnfilePath, "--releases-directory", releasesDir, "--output-file", bundlePath,
+		})
+	}
+
+	Describe("export", func() {
+		When("a locked release is not in the releases directory", func() {
+			BeforeEach(func() {
+				locals, _ := localReleaseDirectory.GetLocalReleases(releasesDir)
+				localReleaseDirectory.GetLocalReleasesReturns(locals[:1], nil)
+			})
+
+			It("lists the missing releases", func() {
+				err := exportBundle()
+				Expect(err).To(MatchError(And(ContainSubstring("run kiln fetch first"), ContainSubstring("- routing 2.0.0"))))
+				Expect(bundlePath).NotTo(BeAnExistingFile())
+			})
+		})
+
+		When("the Kilnfile extends another Kilnfile", func() {
+			BeforeEach(func() {
+				Expect(os.Mkdir(filepath.Join(tmpDir, "shared"), 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "shared", "Kilnfile"), []byte("release_sources:\n  - type: bosh.io\n    id: shared-source\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(kilnfilePath, []byte("extends:\n  - shared/Kilnfile\nslug: some-tile\n"), 0o644)).To(Succeed())
+			})
+
+			It("bundles the merged Kilnfile", func() {
+				Expect(exportBundle()).To(Succeed())
+
+				importDir := filepath.Join(tmpDir, "imported")
+				Expect(commands.NewBundle(context.Background(), logger, fs, nil).Execute([]string{
+					"import", "--bundle", bundlePath, "--releases-directory", filepath.Join(importDir, "releases"), "--kilnfile-directory", importDir,
+				})).To(Succeed())
+
+				var imported cargo.Kilnfile
+				Expect(fsReadYAML(fs, filepath.Join(importDir, "Kilnfile"), &imported)).To(Succeed())
+				Expect(imported.Slug).To(Equal("some-tile"))
+				Expect(imported.Extends).To(BeEmpty())
+				Expect(imported.ReleaseSources).To(ConsistOf(cargo.ReleaseSourceConfig{Type: "bosh.io", ID: "shared-source"}))
+			})
+		})
+	})
+
+	Describe("import", func() {
+		var importDir string
+
+		BeforeEach(func() {
+			importDir = filepath.Join(tmpDir, "imported")
+		})
+
+		It("writes the releases and Kilnfiles", func() {
+			Expect(exportBundle()).To(Succeed())
+
+			err := commands.NewBundle(context.Background(), logger, fs, nil).Execute([]string{
+				"import", "--bundle", bundlePath, "--releases-directory", filepath.Join(importDir, "releases"), "--kilnfile-directory", importDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(importDir, "releases", "bpm-1.0.0.tgz"))).To(Equal([]byte("bpm contents")))
+			Expect(os.ReadFile(filepath.Join(importDir, "releases", "routing-2.0.0.tgz"))).To(Equal([]byte("routing contents")))
+
+			var imported cargo.KilnfileLock
+			Expect(fsReadYAML(fs, filepath.Join(importDir, "Kilnfile.lock"), &imported)).To(Succeed())
+			Expect(imported).To(Equal(kilnfileLock))
+			Expect(os.ReadFile(filepath.Join(importDir, "Kilnfile"))).To(ContainSubstring("some-tile"))
+		})
+
+		When("a release does not match the bundled Kilnfile.lock", func() {
+			BeforeEach(func() {
+				kilnfileLock.Releases[1].SHA1 = "some-other-sha1"
+				locals, _ := localReleaseDirectory.GetLocalReleases(releasesDir)
+				locals[1].Lock.SHA1 = "some-other-sha1"
+				localReleaseDirectory.GetLocalReleasesReturns(locals, nil)
+			})
+
+			It("removes it and returns an error", func() {
+				Expect(exportBundle()).To(Succeed())
+
+				err := commands.NewBundle(context.Background(), logger, fs, nil).Execute([]string{
+					"import", "--bundle", bundlePath, "--releases-directory", importDir,
+				})
+				Expect(err).To(MatchError(And(ContainSubstring("routing 2.0.0"), ContainSubstring("incorrect SHA1"))))
+				Expect(filepath.Join(importDir, "routing-2.0.0.tgz")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(importDir, "bpm-1.0.0.tgz")).To(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("fetch --from-bundle", func() {
+		It("takes releases from the bundle", func() {
+			Expect(exportBundle()).To(Succeed())
+
+			releaseSource := new(fetcherFakes.MultiReleaseSource)
+			provider := new(commandsFakes.MultiReleaseSourceProvider)
+			provider.Returns(releaseSource)
+			fetchDir := filepath.Join(tmpDir, "fetched")
+
+			fetch := commands.NewFetch(component.ContextWithOffline(context.Background()), logger, provider.Spy, new(commandsFakes.LocalReleaseDirectory), nil)
+			err := fetch.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", fetchDir, "--from-bundle", bundlePath})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+			Expect(os.ReadFile(filepath.Join(fetchDir, "routing-2.0.0.tgz"))).To(Equal([]byte("routing contents")))
+		})
+	})
+})
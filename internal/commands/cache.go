@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// Cache manages the shared release cache populated by fetch.
+type Cache struct {
+	ctx       context.Context
+	outLogger *log.Logger
+
+	Options struct {
+		Directory     string        `long:"cache-directory" description:"path to the release cache (defaults to $KILN_CACHE_DIR or ~/.kiln/cache)"`
+		OlderThan     time.Duration `long:"older-than"      description:"prune entries that have not been used for this long (for example 720h)"`
+		Unreferenced  bool          `long:"unreferenced"    description:"prune entries that are not referenced by any Kilnfile.lock that used the cache"`
+		KilnfileLocks []string      `long:"kilnfile-lock"   description:"path to an additional Kilnfile.lock whose releases are kept when pruning unreferenced entries"`
+		DryRun        bool          `long:"dry-run"         description:"print the entries prune would remove without removing them"`
+	}
+}
+
+var _ jhanda.Command = (*Cache)(nil)
+
+func NewCache(ctx context.Context, outLogger *log.Logger) *Cache {
+	return &Cache{
+		ctx:       ctx,
+		outLogger: outLogger,
+	}
+}
+
+func (cmd *Cache) Execute(args []string) error {
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	rest, err := jhanda.Parse(&cmd.Options, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+	}
+	if cmd.Options.Directory == "" {
+		cmd.Options.Directory, err = component.DefaultReleaseCacheDirectory()
+		if err != nil {
+			return err
+		}
+	}
+	cache := component.NewReleaseCache(cmd.Options.Directory)
+
+	switch action {
+	case "list":
+		return cmd.list(cache)
+	case "size":
+		return cmd.size(cache)
+	case "verify":
+		return cmd.verify(cache)
+	case "prune":
+		return cmd.prune(cache)
+	default:
+		return fmt.Errorf("unknown cache action %q: expected one of list, size, verify, or prune", action)
+	}
+}
+
+func (cmd *Cache) list(cache component.ReleaseCache) error {
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(cmd.outLogger.Writer(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tVERSION\tSHA1\tSIZE\tLAST USED")
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Name, entry.Version, entry.SHA1, formatByteCount(entry.Size), entry.LastUsedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func (cmd *Cache) size(cache component.ReleaseCache) error {
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	cmd.outLogger.Printf("%d releases using %s in %s", len(entries), formatByteCount(total), cache.Directory)
+	return nil
+}
+
+func (cmd *Cache) verify(cache component.ReleaseCache) error {
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if err := cache.Verify(cmd.ctx, entry); err != nil {
+			cmd.outLogger.Printf("FAIL %s %s: %s", entry.Name, entry.Version, err)
+			errs = append(errs, err)
+			continue
+		}
+		cmd.outLogger.Printf("OK   %s %s", entry.Name, entry.Version)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d cached releases failed verification", len(errs), len(entries))
+	}
+	return nil
+}
+
+func (cmd *Cache) prune(cache component.ReleaseCache) error {
+	if cmd.Options.OlderThan <= 0 && !cmd.Options.Unreferenced {
+		return errors.New("prune requires --older-than or --unreferenced")
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	var referenced map[string]struct{}
+	if cmd.Options.Unreferenced {
+		referenced, err = cmd.referencedSHA1s(cache)
+		if err != nil {
+			return err
+		}
+	}
+
+	var removedCount int
+	var removedSize int64
+	for _, entry := range entries {
+		var reasons []string
+		if cmd.Options.OlderThan > 0 && time.Since(entry.LastUsedAt) > cmd.Options.OlderThan {
+			reasons = append(reasons, "last used "+entry.LastUsedAt.Format(time.RFC3339))
+		}
+		if _, found := referenced[entry.SHA1]; cmd.Options.Unreferenced && !found {
+			reasons = append(reasons, "not referenced by a Kilnfile.lock")
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		cmd.outLogger.Printf("removing %s %s (%s)", entry.Name, entry.Version, strings.Join(reasons, "; "))
+		if !cmd.Options.DryRun {
+			if err := cache.Remove(entry); err != nil {
+				return err
+			}
+		}
+		removedCount++
+		removedSize += entry.Size
+	}
+	cmd.outLogger.Printf("removed %d releases freeing %s", removedCount, formatByteCount(removedSize))
+	return nil
+}
+
+func (cmd *Cache) referencedSHA1s(cache component.ReleaseCache) (map[string]struct{}, error) {
+	paths, err := cache.TrackedKilnfileLocks()
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, cmd.Options.KilnfileLocks...)
+
+	referenced := make(map[string]struct{})
+	for _, p := range paths {
+		// ReadKilnfileLock takes the path of the Kilnfile
+		lock, err := cargo.ReadKilnfileLock(strings.TrimSuffix(p, ".lock"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		for _, release := range lock.Releases {
+			referenced[release.SHA1] = struct{}{}
+		}
+	}
+	return referenced, nil
+}
+
+func (cmd *Cache) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description: "Manages the release tarball cache shared by all tiles on this machine. " +
+			"Fetch (and bake) take releases from the cache before downloading them and add downloaded releases to it. " +
+			"Actions: \"list\" (default) shows the cached releases, \"size\" reports the disk usage, " +
+			"\"verify\" checks the SHA1 of every cached release, and \"prune\" removes entries by age (--older-than) " +
+			"or that no Kilnfile.lock references (--unreferenced).",
+		ShortDescription: "manages the shared release cache",
+		Flags:            cmd.Options,
+	}
+}
+
+func formatByteCount(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,124 @@
+package commands_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		output            strings.Builder
+		cacheDirectory    string
+		releasesDirectory string
+		cache             component.ReleaseCache
+		mango, banana     component.Local
+	)
+
+	putRelease := func(name, version string) component.Local {
+		content := []byte(name + " " + version)
+		sum := sha1.Sum(content)
+		local := component.Local{
+			Lock:      cargo.BOSHReleaseTarballLock{Name: name, Version: version, SHA1: hex.EncodeToString(sum[:])},
+			LocalPath: filepath.Join(releasesDirectory, name+"-"+version+".tgz"),
+		}
+		Expect(os.WriteFile(local.LocalPath, content, 0o644)).To(Succeed())
+		Expect(cache.Put(context.Background(), local)).To(Succeed())
+		return local
+	}
+
+	execute := func(args ...string) error {
+		output.Reset()
+		cmd := commands.NewCache(context.Background(), log.New(&output, "", 0))
+		return cmd.Execute(append(args, "--cache-directory", cacheDirectory))
+	}
+
+	BeforeEach(func() {
+		var err error
+		cacheDirectory, err = os.MkdirTemp("", "release-cache")
+		Expect(err).NotTo(HaveOccurred())
+		releasesDirectory, err = os.MkdirTemp("", "releases")
+		Expect(err).NotTo(HaveOccurred())
+		cache = component.NewReleaseCache(cacheDirectory)
+
+		mango = putRelease("mango", "2.3.4")
+		banana = putRelease("banana", "1.0.0")
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(cacheDirectory)
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("lists the cached releases", func() {
+		Expect(execute()).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("NAME"))
+		Expect(output.String()).To(ContainSubstring(mango.Lock.SHA1))
+		Expect(output.String()).To(ContainSubstring(banana.Lock.SHA1))
+	})
+
+	It("reports the size", func() {
+		Expect(execute("size")).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("2 releases using 23 B"))
+	})
+
+	It("verifies the cached releases", func() {
+		Expect(execute("verify")).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("OK   mango 2.3.4"))
+	})
+
+	It("rejects unknown actions", func() {
+		Expect(execute("burn")).To(MatchError(ContainSubstring(`unknown cache action "burn"`)))
+	})
+
+	Describe("prune", func() {
+		It("requires a filter", func() {
+			Expect(execute("prune")).To(MatchError(ContainSubstring("--older-than or --unreferenced")))
+		})
+
+		When("a Kilnfile.lock references one of the releases", func() {
+			var lockPath string
+			BeforeEach(func() {
+				lockPath = filepath.Join(releasesDirectory, "Kilnfile.lock")
+				buf, err := yaml.Marshal(cargo.KilnfileLock{
+					Releases: []cargo.BOSHReleaseTarballLock{mango.Lock},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(lockPath, buf, 0o644)).To(Succeed())
+				Expect(cache.TrackKilnfileLock(lockPath)).To(Succeed())
+			})
+
+			It("removes unreferenced releases", func() {
+				Expect(execute("prune", "--unreferenced")).To(Succeed())
+				entries, err := cache.Entries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Name).To(Equal("mango"))
+				Expect(output.String()).To(ContainSubstring("removed 1 releases"))
+			})
+
+			It("does not remove anything on a dry run", func() {
+				Expect(execute("prune", "--unreferenced", "--dry-run")).To(Succeed())
+				Expect(cache.Entries()).To(HaveLen(2))
+				Expect(output.String()).To(ContainSubstring("removing banana 1.0.0"))
+			})
+		})
+
+		It("keeps recently used releases", func() {
+			Expect(execute("prune", "--older-than", "1h")).To(Succeed())
+			Expect(cache.Entries()).To(HaveLen(2))
+		})
+	})
+})
@@ -1,5 +1,21 @@
 package commands
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 func closeAndIgnoreError(c io.Closer) { _ = c.Close() }
+
+// releaseSourceContext returns the context for a single release source operation.
+// It is cancelled with parent or, when timeout is positive, once the timeout elapses.
+func releaseSourceContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
@@ -0,0 +1,278 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type ReleaseCache struct {
+	GetStub        func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, bool, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
+	}
+	getReturns struct {
+		result1 component.Local
+		result2 bool
+		result3 error
+	}
+	getReturnsOnCall map[int]struct {
+		result1 component.Local
+		result2 bool
+		result3 error
+	}
+	PutStub        func(context.Context, component.Local) error
+	putMutex       sync.RWMutex
+	putArgsForCall []struct {
+		arg1 context.Context
+		arg2 component.Local
+	}
+	putReturns struct {
+		result1 error
+	}
+	putReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TrackKilnfileLockStub        func(string) error
+	trackKilnfileLockMutex       sync.RWMutex
+	trackKilnfileLockArgsForCall []struct {
+		arg1 string
+	}
+	trackKilnfileLockReturns struct {
+		result1 error
+	}
+	trackKilnfileLockReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *ReleaseCache) Get(arg1 context.Context, arg2 string, arg3 cargo.BOSHReleaseTarballLock) (component.Local, bool, error) {
+	fake.getMutex.Lock()
+	ret, specificReturn := fake.getReturnsOnCall[len(fake.getArgsForCall)]
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
+	}{arg1, arg2, arg3})
+	stub := fake.GetStub
+	fakeReturns := fake.getReturns
+	fake.recordInvocation("Get", []interface{}{arg1, arg2, arg3})
+	fake.getMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *ReleaseCache) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *ReleaseCache) GetCalls(stub func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, bool, error)) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = stub
+}
+
+func (fake *ReleaseCache) GetArgsForCall(i int) (context.Context, string, cargo.BOSHReleaseTarballLock) {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	argsForCall := fake.getArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *ReleaseCache) GetReturns(result1 component.Local, result2 bool, result3 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 component.Local
+		result2 bool
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *ReleaseCache) GetReturnsOnCall(i int, result1 component.Local, result2 bool, result3 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	if fake.getReturnsOnCall == nil {
+		fake.getReturnsOnCall = make(map[int]struct {
+			result1 component.Local
+			result2 bool
+			result3 error
+		})
+	}
+	fake.getReturnsOnCall[i] = struct {
+		result1 component.Local
+		result2 bool
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *ReleaseCache) Put(arg1 context.Context, arg2 component.Local) error {
+	fake.putMutex.Lock()
+	ret, specificReturn := fake.putReturnsOnCall[len(fake.putArgsForCall)]
+	fake.putArgsForCall = append(fake.putArgsForCall, struct {
+		arg1 context.Context
+		arg2 component.Local
+	}{arg1, arg2})
+	stub := fake.PutStub
+	fakeReturns := fake.putReturns
+	fake.recordInvocation("Put", []interface{}{arg1, arg2})
+	fake.putMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *ReleaseCache) PutCallCount() int {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	return len(fake.putArgsForCall)
+}
+
+func (fake *ReleaseCache) PutCalls(stub func(context.Context, component.Local) error) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = stub
+}
+
+func (fake *ReleaseCache) PutArgsForCall(i int) (context.Context, component.Local) {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	argsForCall := fake.putArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *ReleaseCache) PutReturns(result1 error) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = nil
+	fake.putReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ReleaseCache) PutReturnsOnCall(i int, result1 error) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = nil
+	if fake.putReturnsOnCall == nil {
+		fake.putReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.putReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ReleaseCache) TrackKilnfileLock(arg1 string) error {
+	fake.trackKilnfileLockMutex.Lock()
+	ret, specificReturn := fake.trackKilnfileLockReturnsOnCall[len(fake.trackKilnfileLockArgsForCall)]
+	fake.trackKilnfileLockArgsForCall = append(fake.trackKilnfileLockArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.TrackKilnfileLockStub
+	fakeReturns := fake.trackKilnfileLockReturns
+	fake.recordInvocation("TrackKilnfileLock", []interface{}{arg1})
+	fake.trackKilnfileLockMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *ReleaseCache) TrackKilnfileLockCallCount() int {
+	fake.trackKilnfileLockMutex.RLock()
+	defer fake.trackKilnfileLockMutex.RUnlock()
+	return len(fake.trackKilnfileLockArgsForCall)
+}
+
+func (fake *ReleaseCache) TrackKilnfileLockCalls(stub func(string) error) {
+	fake.trackKilnfileLockMutex.Lock()
+	defer fake.trackKilnfileLockMutex.Unlock()
+	fake.TrackKilnfileLockStub = stub
+}
+
+func (fake *ReleaseCache) TrackKilnfileLockArgsForCall(i int) string {
+	fake.trackKilnfileLockMutex.RLock()
+	defer fake.trackKilnfileLockMutex.RUnlock()
+	argsForCall := fake.trackKilnfileLockArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *ReleaseCache) TrackKilnfileLockReturns(result1 error) {
+	fake.trackKilnfileLockMutex.Lock()
+	defer fake.trackKilnfileLockMutex.Unlock()
+	fake.TrackKilnfileLockStub = nil
+	fake.trackKilnfileLockReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ReleaseCache) TrackKilnfileLockReturnsOnCall(i int, result1 error) {
+	fake.trackKilnfileLockMutex.Lock()
+	defer fake.trackKilnfileLockMutex.Unlock()
+	fake.TrackKilnfileLockStub = nil
+	if fake.trackKilnfileLockReturnsOnCall == nil {
+		fake.trackKilnfileLockReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.trackKilnfileLockReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ReleaseCache) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	fake.trackKilnfileLockMutex.RLock()
+	defer fake.trackKilnfileLockMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *ReleaseCache) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ commands.ReleaseCache = new(ReleaseCache)
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -21,11 +22,12 @@ type ReleaseStorage struct {
 	configurationReturnsOnCall map[int]struct {
 		result1 cargo.ReleaseSourceConfig
 	}
-	DownloadReleaseStub        func(string, cargo.BOSHReleaseTarballLock) (component.Local, error)
+	DownloadReleaseStub        func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, error)
 	downloadReleaseMutex       sync.RWMutex
 	downloadReleaseArgsForCall []struct {
-		arg1 string
-		arg2 cargo.BOSHReleaseTarballLock
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
 	}
 	downloadReleaseReturns struct {
 		result1 component.Local
@@ -35,11 +37,12 @@ type ReleaseStorage struct {
 		result1 component.Local
 		result2 error
 	}
-	FindReleaseVersionStub        func(cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)
+	FindReleaseVersionStub        func(context.Context, cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)
 	findReleaseVersionMutex       sync.RWMutex
 	findReleaseVersionArgsForCall []struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 bool
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 bool
 	}
 	findReleaseVersionReturns struct {
 		result1 cargo.BOSHReleaseTarballLock
@@ -49,10 +52,11 @@ type ReleaseStorage struct {
 		result1 cargo.BOSHReleaseTarballLock
 		result2 error
 	}
-	GetMatchedReleaseStub        func(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
+	GetMatchedReleaseStub        func(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
 	getMatchedReleaseMutex       sync.RWMutex
 	getMatchedReleaseArgsForCall []struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
 	}
 	getMatchedReleaseReturns struct {
 		result1 cargo.BOSHReleaseTarballLock
@@ -62,11 +66,12 @@ type ReleaseStorage struct {
 		result1 cargo.BOSHReleaseTarballLock
 		result2 error
 	}
-	UploadReleaseStub        func(cargo.BOSHReleaseTarballSpecification, io.Reader) (cargo.BOSHReleaseTarballLock, error)
+	UploadReleaseStub        func(context.Context, cargo.BOSHReleaseTarballSpecification, io.Reader) (cargo.BOSHReleaseTarballLock, error)
 	uploadReleaseMutex       sync.RWMutex
 	uploadReleaseArgsForCall []struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 io.Reader
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 io.Reader
 	}
 	uploadReleaseReturns struct {
 		result1 cargo.BOSHReleaseTarballLock
@@ -133,19 +138,20 @@ func (fake *ReleaseStorage) ConfigurationReturnsOnCall(i int, result1 cargo.Rele
 	}{result1}
 }
 
-func (fake *ReleaseStorage) DownloadRelease(arg1 string, arg2 cargo.BOSHReleaseTarballLock) (component.Local, error) {
+func (fake *ReleaseStorage) DownloadRelease(arg1 context.Context, arg2 string, arg3 cargo.BOSHReleaseTarballLock) (component.Local, error) {
 	fake.downloadReleaseMutex.Lock()
 	ret, specificReturn := fake.downloadReleaseReturnsOnCall[len(fake.downloadReleaseArgsForCall)]
 	fake.downloadReleaseArgsForCall = append(fake.downloadReleaseArgsForCall, struct {
-		arg1 string
-		arg2 cargo.BOSHReleaseTarballLock
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
+	}{arg1, arg2, arg3})
 	stub := fake.DownloadReleaseStub
 	fakeReturns := fake.downloadReleaseReturns
-	fake.recordInvocation("DownloadRelease", []interface{}{arg1, arg2})
+	fake.recordInvocation("DownloadRelease", []interface{}{arg1, arg2, arg3})
 	fake.downloadReleaseMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -159,17 +165,17 @@ func (fake *ReleaseStorage) DownloadReleaseCallCount() int {
 	return len(fake.downloadReleaseArgsForCall)
 }
 
-func (fake *ReleaseStorage) DownloadReleaseCalls(stub func(string, cargo.BOSHReleaseTarballLock) (component.Local, error)) {
+func (fake *ReleaseStorage) DownloadReleaseCalls(stub func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, error)) {
 	fake.downloadReleaseMutex.Lock()
 	defer fake.downloadReleaseMutex.Unlock()
 	fake.DownloadReleaseStub = stub
 }
 
-func (fake *ReleaseStorage) DownloadReleaseArgsForCall(i int) (string, cargo.BOSHReleaseTarballLock) {
+func (fake *ReleaseStorage) DownloadReleaseArgsForCall(i int) (context.Context, string, cargo.BOSHReleaseTarballLock) {
 	fake.downloadReleaseMutex.RLock()
 	defer fake.downloadReleaseMutex.RUnlock()
 	argsForCall := fake.downloadReleaseArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *ReleaseStorage) DownloadReleaseReturns(result1 component.Local, result2 error) {
@@ -198,19 +204,20 @@ func (fake *ReleaseStorage) DownloadReleaseReturnsOnCall(i int, result1 componen
 	}{result1, result2}
 }
 
-func (fake *ReleaseStorage) FindReleaseVersion(arg1 cargo.BOSHReleaseTarballSpecification, arg2 bool) (cargo.BOSHReleaseTarballLock, error) {
+func (fake *ReleaseStorage) FindReleaseVersion(arg1 context.Context, arg2 cargo.BOSHReleaseTarballSpecification, arg3 bool) (cargo.BOSHReleaseTarballLock, error) {
 	fake.findReleaseVersionMutex.Lock()
 	ret, specificReturn := fake.findReleaseVersionReturnsOnCall[len(fake.findReleaseVersionArgsForCall)]
 	fake.findReleaseVersionArgsForCall = append(fake.findReleaseVersionArgsForCall, struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 bool
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 bool
+	}{arg1, arg2, arg3})
 	stub := fake.FindReleaseVersionStub
 	fakeReturns := fake.findReleaseVersionReturns
-	fake.recordInvocation("FindReleaseVersion", []interface{}{arg1, arg2})
+	fake.recordInvocation("FindReleaseVersion", []interface{}{arg1, arg2, arg3})
 	fake.findReleaseVersionMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -224,17 +231,17 @@ func (fake *ReleaseStorage) FindReleaseVersionCallCount() int {
 	return len(fake.findReleaseVersionArgsForCall)
 }
 
-func (fake *ReleaseStorage) FindReleaseVersionCalls(stub func(cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)) {
+func (fake *ReleaseStorage) FindReleaseVersionCalls(stub func(context.Context, cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)) {
 	fake.findReleaseVersionMutex.Lock()
 	defer fake.findReleaseVersionMutex.Unlock()
 	fake.FindReleaseVersionStub = stub
 }
 
-func (fake *ReleaseStorage) FindReleaseVersionArgsForCall(i int) (cargo.BOSHReleaseTarballSpecification, bool) {
+func (fake *ReleaseStorage) FindReleaseVersionArgsForCall(i int) (context.Context, cargo.BOSHReleaseTarballSpecification, bool) {
 	fake.findReleaseVersionMutex.RLock()
 	defer fake.findReleaseVersionMutex.RUnlock()
 	argsForCall := fake.findReleaseVersionArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *ReleaseStorage) FindReleaseVersionReturns(result1 cargo.BOSHReleaseTarballLock, result2 error) {
@@ -263,18 +270,19 @@ func (fake *ReleaseStorage) FindReleaseVersionReturnsOnCall(i int, result1 cargo
 	}{result1, result2}
 }
 
-func (fake *ReleaseStorage) GetMatchedRelease(arg1 cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (fake *ReleaseStorage) GetMatchedRelease(arg1 context.Context, arg2 cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	fake.getMatchedReleaseMutex.Lock()
 	ret, specificReturn := fake.getMatchedReleaseReturnsOnCall[len(fake.getMatchedReleaseArgsForCall)]
 	fake.getMatchedReleaseArgsForCall = append(fake.getMatchedReleaseArgsForCall, struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-	}{arg1})
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+	}{arg1, arg2})
 	stub := fake.GetMatchedReleaseStub
 	fakeReturns := fake.getMatchedReleaseReturns
-	fake.recordInvocation("GetMatchedRelease", []interface{}{arg1})
+	fake.recordInvocation("GetMatchedRelease", []interface{}{arg1, arg2})
 	fake.getMatchedReleaseMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -288,17 +296,17 @@ func (fake *ReleaseStorage) GetMatchedReleaseCallCount() int {
 	return len(fake.getMatchedReleaseArgsForCall)
 }
 
-func (fake *ReleaseStorage) GetMatchedReleaseCalls(stub func(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)) {
+func (fake *ReleaseStorage) GetMatchedReleaseCalls(stub func(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)) {
 	fake.getMatchedReleaseMutex.Lock()
 	defer fake.getMatchedReleaseMutex.Unlock()
 	fake.GetMatchedReleaseStub = stub
 }
 
-func (fake *ReleaseStorage) GetMatchedReleaseArgsForCall(i int) cargo.BOSHReleaseTarballSpecification {
+func (fake *ReleaseStorage) GetMatchedReleaseArgsForCall(i int) (context.Context, cargo.BOSHReleaseTarballSpecification) {
 	fake.getMatchedReleaseMutex.RLock()
 	defer fake.getMatchedReleaseMutex.RUnlock()
 	argsForCall := fake.getMatchedReleaseArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *ReleaseStorage) GetMatchedReleaseReturns(result1 cargo.BOSHReleaseTarballLock, result2 error) {
@@ -327,19 +335,20 @@ func (fake *ReleaseStorage) GetMatchedReleaseReturnsOnCall(i int, result1 cargo.
 	}{result1, result2}
 }
 
-func (fake *ReleaseStorage) UploadRelease(arg1 cargo.BOSHReleaseTarballSpecification, arg2 io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+func (fake *ReleaseStorage) UploadRelease(arg1 context.Context, arg2 cargo.BOSHReleaseTarballSpecification, arg3 io.Reader) (cargo.BOSHReleaseTarballLock, error) {
 	fake.uploadReleaseMutex.Lock()
 	ret, specificReturn := fake.uploadReleaseReturnsOnCall[len(fake.uploadReleaseArgsForCall)]
 	fake.uploadReleaseArgsForCall = append(fake.uploadReleaseArgsForCall, struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 io.Reader
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 io.Reader
+	}{arg1, arg2, arg3})
 	stub := fake.UploadReleaseStub
 	fakeReturns := fake.uploadReleaseReturns
-	fake.recordInvocation("UploadRelease", []interface{}{arg1, arg2})
+	fake.recordInvocation("UploadRelease", []interface{}{arg1, arg2, arg3})
 	fake.uploadReleaseMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -353,17 +362,17 @@ func (fake *ReleaseStorage) UploadReleaseCallCount() int {
 	return len(fake.uploadReleaseArgsForCall)
 }
 
-func (fake *ReleaseStorage) UploadReleaseCalls(stub func(cargo.BOSHReleaseTarballSpecification, io.Reader) (cargo.BOSHReleaseTarballLock, error)) {
+func (fake *ReleaseStorage) UploadReleaseCalls(stub func(context.Context, cargo.BOSHReleaseTarballSpecification, io.Reader) (cargo.BOSHReleaseTarballLock, error)) {
 	fake.uploadReleaseMutex.Lock()
 	defer fake.uploadReleaseMutex.Unlock()
 	fake.UploadReleaseStub = stub
 }
 
-func (fake *ReleaseStorage) UploadReleaseArgsForCall(i int) (cargo.BOSHReleaseTarballSpecification, io.Reader) {
+func (fake *ReleaseStorage) UploadReleaseArgsForCall(i int) (context.Context, cargo.BOSHReleaseTarballSpecification, io.Reader) {
 	fake.uploadReleaseMutex.RLock()
 	defer fake.uploadReleaseMutex.RUnlock()
 	argsForCall := fake.uploadReleaseArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *ReleaseStorage) UploadReleaseReturns(result1 cargo.BOSHReleaseTarballLock, result2 error) {
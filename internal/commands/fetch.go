@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync"
 
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/pivotal-cf/jhanda"
 
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
@@ -20,24 +25,32 @@ type FetchOptions struct {
 	flags.Standard
 	flags.FetchBakeOptions
 	FetchReleaseDir
+
+	FromBundle string `long:"from-bundle" description:"path to a bundle written by kiln bundle export to take releases from before using the release sources in the Kilnfile"`
 }
 
 type Fetch struct {
+	ctx    context.Context
 	logger *log.Logger
 
 	multiReleaseSourceProvider MultiReleaseSourceProvider
 	localReleaseDirectory      LocalReleaseDirectory
+	releaseCache               ReleaseCache
 	Options                    FetchOptions
 }
 
 //counterfeiter:generate -o ./fakes/multi_release_source_provider.go --fake-name MultiReleaseSourceProvider . MultiReleaseSourceProvider
 type MultiReleaseSourceProvider func(cargo.Kilnfile, bool) component.MultiReleaseSource
 
-func NewFetch(logger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceProvider, localReleaseDirectory LocalReleaseDirectory) Fetch {
+// NewFetch returns a Fetch command. The releaseCache may be nil; when it is set releases
+// are taken from the cache before they are downloaded and are added to it afterwards.
+func NewFetch(ctx context.Context, logger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceProvider, localReleaseDirectory LocalReleaseDirectory, releaseCache ReleaseCache) Fetch {
 	return Fetch{
+		ctx:                        ctx,
 		logger:                     logger,
 		localReleaseDirectory:      localReleaseDirectory,
 		multiReleaseSourceProvider: multiReleaseSourceProvider,
+		releaseCache:               releaseCache,
 	}
 }
 
@@ -47,6 +60,15 @@ type LocalReleaseDirectory interface {
 	DeleteExtraReleases(extraReleases []component.Local, noConfirm bool) error
 }
 
+//counterfeiter:generate -o ./fakes/release_cache.go --fake-name ReleaseCache . ReleaseCache
+
+// ReleaseCache is implemented by component.ReleaseCache.
+type ReleaseCache interface {
+	Get(ctx context.Context, releasesDir string, lock cargo.BOSHReleaseTarballLock) (component.Local, bool, error)
+	Put(ctx context.Context, local component.Local) error
+	TrackKilnfileLock(kilnfileLockPath string) error
+}
+
 func (f Fetch) Execute(args []string) error {
 	kilnfile, kilnfileLock, availableLocalReleaseSet, err := f.setup(args)
 	if err != nil {
@@ -113,35 +135,202 @@ func (f *Fetch) setup(args []string) (cargo.Kilnfile, cargo.KilnfileLock, []comp
 
 func (f Fetch) downloadMissingReleases(kilnfile cargo.Kilnfile, releaseLocks []cargo.BOSHReleaseTarballLock) ([]component.Local, error) {
 	releaseSource := f.multiReleaseSourceProvider(kilnfile, f.Options.AllowOnlyPublishableReleases)
+	if f.Options.FromBundle != "" {
+		bundle, err := component.OpenBundle(f.Options.FromBundle)
+		if err != nil {
+			return nil, err
+		}
+		releaseSource = bundleFirstReleaseSource{MultiReleaseSource: releaseSource, bundle: component.NewBundleReleaseSource(bundle)}
+	}
+
+	useCache := f.releaseCache != nil && !f.Options.NoCache
+	if useCache {
+		if err := f.releaseCache.TrackKilnfileLock(f.Options.KilnfileLockPath()); err != nil {
+			f.logger.Printf("Warning: failed to record Kilnfile.lock in the release cache: %s", err)
+		}
+	}
+
+	// log lines written by release sources sharing the logger are printed above the progress
+	logOutput := f.logger.Writer()
+	progress := newFetchProgress(logOutput, len(releaseLocks))
+	f.logger.SetOutput(progress)
+	defer func() {
+		progress.close()
+		f.logger.SetOutput(logOutput)
+	}()
+
+	ctx, cancel := context.WithCancel(f.ctx)
+	defer cancel()
+
+	var (
+		results  = make([]component.Local, len(releaseLocks))
+		errs     = make([]error, len(releaseLocks))
+		offline  = make([]bool, len(releaseLocks))
+		firstErr error
+		errMutex sync.Mutex
+		indexes  = make(chan int)
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < min(max(f.Options.Parallel, 1), len(releaseLocks)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				local, err := f.fetchRelease(ctx, releaseSource, useCache, progress, releaseLocks[index])
+				if component.IsErrOffline(err) {
+					// in offline mode every missing release is reported, not only the first one
+					offline[index] = true
+					continue
+				}
+				if err != nil {
+					errMutex.Lock()
+					errs[index] = err
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMutex.Unlock()
+					if !f.Options.CollectErrors {
+						// stop the other downloads
+						cancel()
+					}
+					continue
+				}
+				results[index] = local
+			}
+		}()
+	}
+queueReleases:
+	for index := range releaseLocks {
+		select {
+		case indexes <- index:
+		case <-ctx.Done():
+			break queueReleases
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if !f.Options.CollectErrors && firstErr != nil {
+		return nil, firstErr
+	}
+	if err := errors.Join(append([]error{missingOfflineReleases(releaseLocks, offline)}, errs...)...); err != nil {
+		return nil, err
+	}
+	if err := f.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// missingOfflineReleases lists the releases that could not be fetched because their release
+// sources need network access. It returns nil when there are none.
+func missingOfflineReleases(releaseLocks []cargo.BOSHReleaseTarballLock, offline []bool) error {
+	var missing strings.Builder
+	count := 0
+	for index, rl := range releaseLocks {
+		if !offline[index] {
+			continue
+		}
+		count++
+		fmt.Fprintf(&missing, "\n- %s %s (sha1: %s) from release source %q", rl.Name, rl.Version, rl.SHA1, rl.RemoteSource)
+	}
+	if count == 0 {
+		return nil
+	}
+	return fmt.Errorf("offline mode: %d release(s) are not in the releases directory or the release cache:%s", count, missing.String())
+}
+
+func (f Fetch) fetchRelease(ctx context.Context, releaseSource component.MultiReleaseSource, useCache bool, progress *fetchProgress, rl cargo.BOSHReleaseTarballLock) (component.Local, error) {
+	if useCache {
+		local, found, err := f.releaseCache.Get(ctx, f.Options.ReleasesDir, rl)
+		if err != nil {
+			f.logger.Printf("Warning: failed to read %s %s from the release cache: %s", rl.Name, rl.Version, err)
+		}
+		if found {
+			f.logger.Printf("Using cached release %s %s", rl.Name, rl.Version)
+			progress.skip()
+			return local, nil
+		}
+	}
 
-	var downloaded []component.Local
+	remoteRelease := cargo.BOSHReleaseTarballLock{
+		Name:         rl.Name,
+		Version:      rl.Version,
+		RemotePath:   rl.RemotePath,
+		RemoteSource: rl.RemoteSource,
+	}
+
+	releaseProgress := progress.start(rl.Name, rl.Version)
+	local, err := f.downloadRelease(component.ContextWithDownloadProgress(ctx, releaseProgress), releaseSource, remoteRelease, rl)
+	progress.finish(releaseProgress, err)
+	if err != nil {
+		return component.Local{}, err
+	}
 
-	for _, rl := range releaseLocks {
-		remoteRelease := cargo.BOSHReleaseTarballLock{
-			Name:         rl.Name,
-			Version:      rl.Version,
-			RemotePath:   rl.RemotePath,
-			RemoteSource: rl.RemoteSource,
+	if useCache {
+		if err := f.releaseCache.Put(ctx, local); err != nil {
+			f.logger.Printf("Warning: failed to add %s %s to the release cache: %s", rl.Name, rl.Version, err)
 		}
+	}
+
+	return local, nil
+}
 
-		local, err := releaseSource.DownloadRelease(f.Options.ReleasesDir, remoteRelease)
+func (f Fetch) downloadRelease(ctx context.Context, releaseSource component.MultiReleaseSource, remoteRelease, expected cargo.BOSHReleaseTarballLock) (component.Local, error) {
+	ctx, cancel := releaseSourceContext(ctx, f.Options.Timeout)
+	defer cancel()
+
+	local, err := releaseSource.DownloadRelease(ctx, f.Options.ReleasesDir, remoteRelease)
+	if err != nil {
+		return component.Local{}, fmt.Errorf("download failed: %w", err)
+	}
+
+	if local.Lock.SHA1 != expected.SHA1 {
+		err = os.Remove(local.LocalPath)
 		if err != nil {
-			return nil, fmt.Errorf("download failed: %w", err)
+			return component.Local{}, fmt.Errorf("error deleting bad release file %q: %w", local.LocalPath, err) // untested
 		}
 
-		if local.Lock.SHA1 != rl.SHA1 {
+		return component.Local{}, fmt.Errorf("downloaded release %q had an incorrect SHA1 - expected %q, got %q", local.LocalPath, expected.SHA1, local.Lock.SHA1)
+	}
+
+	// locks written before SHA256 was added are only verified with SHA1
+	if expected.SHA256 != "" {
+		sum, err := component.CalculateSHA256Sum(local.LocalPath, osfs.New(""))
+		if err != nil {
+			return component.Local{}, fmt.Errorf("failed to calculate SHA256 of %q: %w", local.LocalPath, err)
+		}
+		if sum != expected.SHA256 {
 			err = os.Remove(local.LocalPath)
 			if err != nil {
-				return nil, fmt.Errorf("error deleting bad release file %q: %w", local.LocalPath, err) // untested
+				return component.Local{}, fmt.Errorf("error deleting bad release file %q: %w", local.LocalPath, err) // untested
 			}
 
-			return nil, fmt.Errorf("downloaded release %q had an incorrect SHA1 - expected %q, got %q", local.LocalPath, rl.SHA1, local.Lock.SHA1)
+			return component.Local{}, fmt.Errorf("downloaded release %q had an incorrect SHA256 - expected %q, got %q", local.LocalPath, expected.SHA256, sum)
 		}
-
-		downloaded = append(downloaded, local)
+		local.Lock.SHA256 = sum
 	}
 
-	return downloaded, nil
+	return local, nil
+}
+
+// bundleFirstReleaseSource downloads the releases in a bundle from the bundle and the
+// others from the release sources in the Kilnfile.
+type bundleFirstReleaseSource struct {
+	component.MultiReleaseSource
+	bundle component.BundleReleaseSource
+}
+
+func (src bundleFirstReleaseSource) DownloadRelease(ctx context.Context, releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (component.Local, error) {
+	_, err := src.bundle.GetMatchedRelease(ctx, cargo.BOSHReleaseTarballSpecification{Name: remoteRelease.Name, Version: remoteRelease.Version})
+	if component.IsErrNotFound(err) {
+		return src.MultiReleaseSource.DownloadRelease(ctx, releasesDir, remoteRelease)
+	}
+	if err != nil {
+		return component.Local{}, err
+	}
+	return src.bundle.DownloadRelease(ctx, releasesDir, remoteRelease)
 }
 
 func (f Fetch) Usage() jhanda.Usage {
@@ -159,10 +348,12 @@ func partition(releaseLocks []cargo.BOSHReleaseTarballLock, localReleases []comp
 nextRelease:
 	for _, rel := range localReleases {
 		for j, lock := range missing {
-			if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version && rel.Lock.SHA1 == lock.SHA1 {
+			if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version && rel.Lock.SHA1 == lock.SHA1 && (lock.SHA256 == "" || rel.Lock.SHA256 == lock.SHA256) {
 				intersection = append(intersection, rel)
 				missing = append(missing[:j], missing[j+1:]...)
 				continue nextRelease
+			} else if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version && rel.Lock.SHA1 == lock.SHA1 {
+				fmt.Printf("Local release: [ %s ] sha256 mismatch: [ %s ]\n", lock.Name, rel.Lock.SHA256)
 			} else if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version {
 				fmt.Printf("Local release: [ %s ] sha mismatch: [ %s ]\n", lock.Name, rel.Lock.SHA1)
 			} else if rel.Lock.Name == lock.Name && rel.Lock.SHA1 == lock.SHA1 {
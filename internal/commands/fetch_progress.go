@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+)
+
+const fetchProgressRedrawInterval = 200 * time.Millisecond
+
+// fetchProgress reports release downloads. On a terminal it redraws a line for each active
+// download and a summary line below the log output. Otherwise it logs a line when each
+// release finishes so the output stays readable in CI logs.
+//
+// It is an io.Writer so log lines written during a fetch are printed above the progress lines.
+type fetchProgress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	terminal bool
+
+	started  time.Time
+	total    int
+	finished int
+	failed   int
+	bytes    int64
+	active   []*releaseDownloadProgress
+
+	drawnLines int
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+func newFetchProgress(out io.Writer, total int) *fetchProgress {
+	p := &fetchProgress{
+		out:      out,
+		terminal: isTerminal(out),
+		started:  time.Now(),
+		total:    total,
+	}
+	if p.terminal {
+		p.stop = make(chan struct{})
+		p.stopped = make(chan struct{})
+		go p.redraw()
+	}
+	return p
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func (p *fetchProgress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.terminal {
+		return p.out.Write(b)
+	}
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+	return n, err
+}
+
+// start begins tracking the download of a release. The result implements
+// component.DownloadProgress.
+func (p *fetchProgress) start(name, version string) *releaseDownloadProgress {
+	r := &releaseDownloadProgress{
+		progress: p,
+		name:     name,
+		version:  version,
+		started:  time.Now(),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active = append(p.active, r)
+	return r
+}
+
+// skip counts a release that did not need to be downloaded.
+func (p *fetchProgress) skip() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished++
+}
+
+func (p *fetchProgress) finish(r *releaseDownloadProgress, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active = slices.DeleteFunc(p.active, func(a *releaseDownloadProgress) bool { return a == r })
+	p.finished++
+	if err != nil {
+		p.failed++
+	}
+	if p.terminal {
+		// errors are returned to the caller and completed downloads are shown in the summary
+		return
+	}
+	status := "downloaded"
+	if err != nil {
+		status = "failed to download"
+	}
+	_, _ = fmt.Fprintf(p.out, "%s %s %s (%s in %s) [%d/%d]\n", status, r.name, r.version,
+		formatByteCount(r.written), time.Since(r.started).Round(time.Second), p.finished, p.total)
+}
+
+// close stops redrawing and prints the summary.
+func (p *fetchProgress) close() {
+	if p.terminal {
+		close(p.stop)
+		<-p.stopped
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	_, _ = fmt.Fprintf(p.out, "%s\n", p.summary())
+}
+
+func (p *fetchProgress) redraw() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(fetchProgressRedrawInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.clear()
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// clear removes the lines written by draw. The caller must hold mu.
+func (p *fetchProgress) clear() {
+	if p.drawnLines == 0 {
+		return
+	}
+	// move the cursor to the first progress line and erase to the end of the screen
+	_, _ = fmt.Fprintf(p.out, "\033[%dA\r\033[J", p.drawnLines)
+	p.drawnLines = 0
+}
+
+// draw writes a line for each active download and the summary. The caller must hold mu.
+func (p *fetchProgress) draw() {
+	for _, r := range p.active {
+		_, _ = fmt.Fprintf(p.out, "  %s\n", r.status())
+		p.drawnLines++
+	}
+	_, _ = fmt.Fprintf(p.out, "%s\n", p.summary())
+	p.drawnLines++
+}
+
+// summary describes all downloads. The caller must hold mu.
+func (p *fetchProgress) summary() string {
+	elapsed := time.Since(p.started)
+	line := fmt.Sprintf("%d/%d releases, %s downloaded in %s", p.finished, p.total, formatByteCount(p.bytes), elapsed.Round(time.Second))
+	if seconds := elapsed.Seconds(); seconds > 0 && p.bytes > 0 {
+		line += fmt.Sprintf(" (%s/s)", formatByteCount(int64(float64(p.bytes)/seconds)))
+	}
+	if p.failed > 0 {
+		line += fmt.Sprintf(", %d failed", p.failed)
+	}
+	if remaining := p.total - p.finished; remaining > 0 && p.finished > 0 {
+		// assume the remaining releases take as long as the finished ones did on average
+		eta := time.Duration(float64(elapsed) / float64(p.finished) * float64(remaining))
+		line += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+	return line
+}
+
+type releaseDownloadProgress struct {
+	progress *fetchProgress
+
+	name, version string
+	started       time.Time
+	size, written int64
+}
+
+var _ component.DownloadProgress = (*releaseDownloadProgress)(nil)
+
+func (r *releaseDownloadProgress) SetSize(n int64) {
+	r.progress.mu.Lock()
+	defer r.progress.mu.Unlock()
+	r.size = n
+}
+
+func (r *releaseDownloadProgress) Add(n int64) {
+	r.progress.mu.Lock()
+	defer r.progress.mu.Unlock()
+	r.written += n
+	r.progress.bytes += n
+}
+
+// Reset removes the bytes reported by an earlier attempt that are written again on a retry
+// from the totals, so they are not counted twice.
+func (r *releaseDownloadProgress) Reset(n int64) {
+	r.progress.mu.Lock()
+	defer r.progress.mu.Unlock()
+	r.progress.bytes += n - r.written
+	r.written = n
+}
+
+// status describes the download. The caller must hold progress.mu.
+func (r *releaseDownloadProgress) status() string {
+	line := fmt.Sprintf("%s %s %s", r.name, r.version, formatByteCount(r.written))
+	if r.size <= 0 {
+		return line
+	}
+	line += fmt.Sprintf(" / %s %3d%%", formatByteCount(r.size), r.written*100/r.size)
+	if elapsed := time.Since(r.started); r.written > 0 && r.written < r.size {
+		eta := time.Duration(float64(elapsed) / float64(r.written) * float64(r.size-r.written))
+		line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+	}
+	return line
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFetchProgress_retry(t *testing.T) {
+	please := NewWithT(t)
+	var out bytes.Buffer
+	progress := newFetchProgress(&out, 1)
+
+	release := progress.start("bpm", "1.2.3")
+	release.SetSize(100)
+	release.Add(60)
+	// the server sent the file again from the start
+	release.Reset(0)
+	release.Add(40)
+	// the download resumed after the first 40 bytes
+	release.Reset(40)
+	release.Add(60)
+	progress.finish(release, nil)
+	progress.close()
+
+	please.Expect(release.written).To(BeEquivalentTo(100))
+	please.Expect(progress.bytes).To(BeEquivalentTo(100))
+	please.Expect(out.String()).To(ContainSubstring("downloaded bpm 1.2.3 (100 B in"))
+}
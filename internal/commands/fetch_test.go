@@ -1,17 +1,22 @@
 package commands_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pivotal-cf/jhanda"
 	"gopkg.in/yaml.v2"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	"github.com/pivotal-cf/kiln/internal/commands"
 	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
@@ -36,7 +41,9 @@ var _ = Describe("Fetch", func() {
 		releaseSourceList           component.ReleaseSourceList
 		fakeLocalReleaseDirectory   *commandsFakes.LocalReleaseDirectory
 		multiReleaseSourceProvider  commands.MultiReleaseSourceProvider
+		releaseCache                commands.ReleaseCache
 
+		fetchContext     context.Context
 		fetchExecuteArgs []string
 		fetchExecuteErr  error
 	)
@@ -77,6 +84,8 @@ stemcell_criteria:
 `
 
 			fakeLocalReleaseDirectory = new(commandsFakes.LocalReleaseDirectory)
+			releaseCache = nil
+			fetchContext = context.Background()
 
 			fakeS3CompiledReleaseSource = new(componentFakes.ReleaseSource)
 			fakeS3CompiledReleaseSource.ConfigurationReturns(cargo.ReleaseSourceConfig{
@@ -102,14 +111,14 @@ stemcell_criteria:
 			fakeReleaseSources.FindByIDStub = func(s string) (component.ReleaseSource, error) {
 				return releaseSourceList.FindByID(s)
 			}
-			fakeReleaseSources.DownloadReleaseStub = func(s string, lock cargo.BOSHReleaseTarballLock) (component.Local, error) {
-				return releaseSourceList.DownloadRelease(s, lock)
+			fakeReleaseSources.DownloadReleaseStub = func(ctx context.Context, s string, lock cargo.BOSHReleaseTarballLock) (component.Local, error) {
+				return releaseSourceList.DownloadRelease(ctx, s, lock)
 			}
-			fakeReleaseSources.FindReleaseVersionStub = func(requirement cargo.BOSHReleaseTarballSpecification, withSHA bool) (cargo.BOSHReleaseTarballLock, error) {
-				return releaseSourceList.FindReleaseVersion(requirement, false)
+			fakeReleaseSources.FindReleaseVersionStub = func(ctx context.Context, requirement cargo.BOSHReleaseTarballSpecification, withSHA bool) (cargo.BOSHReleaseTarballLock, error) {
+				return releaseSourceList.FindReleaseVersion(ctx, requirement, false)
 			}
-			fakeReleaseSources.GetMatchedReleaseStub = func(requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
-				return releaseSourceList.GetMatchedRelease(requirement)
+			fakeReleaseSources.GetMatchedReleaseStub = func(ctx context.Context, requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+				return releaseSourceList.GetMatchedRelease(ctx, requirement)
 			}
 			multiReleaseSourceProvider = func(kilnfile cargo.Kilnfile, allowOnlyPublishable bool) component.MultiReleaseSource {
 				return fakeReleaseSources
@@ -117,7 +126,7 @@ stemcell_criteria:
 
 			err := os.WriteFile(someKilnfileLockPath, []byte(lockContents), 0o644)
 			Expect(err).NotTo(HaveOccurred())
-			fetch = commands.NewFetch(logger, multiReleaseSourceProvider, fakeLocalReleaseDirectory)
+			fetch = commands.NewFetch(fetchContext, logger, multiReleaseSourceProvider, fakeLocalReleaseDirectory, releaseCache)
 
 			fetchExecuteErr = fetch.Execute(fetchExecuteArgs)
 		})
@@ -245,7 +254,7 @@ stemcell_criteria:
 			It("fetches compiled release from s3 compiled release source", func() {
 				Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
 
-				releasesDir, object := fakeS3CompiledReleaseSource.DownloadReleaseArgsForCall(0)
+				_, releasesDir, object := fakeS3CompiledReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(releasesDir).To(Equal(someReleasesDirectory))
 				Expect(object).To(Equal(
 					s3CompiledReleaseID.Lock().WithRemote(s3CompiledReleaseSourceID, "some-s3-key"),
@@ -254,7 +263,7 @@ stemcell_criteria:
 
 			It("fetches built release from s3 built release source", func() {
 				Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				releasesDir, object := fakeS3BuiltReleaseSource.DownloadReleaseArgsForCall(0)
+				_, releasesDir, object := fakeS3BuiltReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(releasesDir).To(Equal(someReleasesDirectory))
 				Expect(object).To(Equal(
 					s3BuiltReleaseID.Lock().WithRemote(s3BuiltReleaseSourceID, "some-other-s3-key"),
@@ -263,12 +272,182 @@ stemcell_criteria:
 
 			It("fetches bosh.io release from bosh.io release source", func() {
 				Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				releasesDir, object := fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
+				_, releasesDir, object := fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(releasesDir).To(Equal(someReleasesDirectory))
 				Expect(object).To(Equal(
 					boshIOReleaseID.Lock().WithRemote(boshIOReleaseSourceID, "some-bosh-io-url"),
 				))
 			})
+
+			It("does not set a deadline on the downloads", func() {
+				ctx, _, _ := fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
+				_, hasDeadline := ctx.Deadline()
+				Expect(hasDeadline).To(BeFalse())
+			})
+
+			When("a timeout is set", func() {
+				BeforeEach(func() {
+					fetchExecuteArgs = append(fetchExecuteArgs, "--timeout", "10m")
+				})
+
+				It("sets a deadline on each download", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+
+					ctx, _, _ := fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
+					deadline, hasDeadline := ctx.Deadline()
+					Expect(hasDeadline).To(BeTrue())
+					Expect(deadline).To(BeTemporally("~", time.Now().Add(10*time.Minute), time.Minute))
+				})
+			})
+
+			When("downloading releases in parallel", func() {
+				var output *gbytes.Buffer
+				BeforeEach(func() {
+					output = gbytes.NewBuffer()
+					logger = log.New(io.MultiWriter(output, GinkgoWriter), "", 0)
+					fetchExecuteArgs = append(fetchExecuteArgs, "--parallel", "3")
+
+					// each download waits for the others to start so the test hangs if they run one at a time
+					var started sync.WaitGroup
+					started.Add(3)
+					for _, source := range []*componentFakes.ReleaseSource{fakeS3CompiledReleaseSource, fakeS3BuiltReleaseSource, fakeBoshIOReleaseSource} {
+						source.DownloadReleaseStub = func(ctx context.Context, _ string, lock cargo.BOSHReleaseTarballLock) (component.Local, error) {
+							started.Done()
+							started.Wait()
+							return component.Local{Lock: lock.WithSHA1("correct-sha"), LocalPath: lock.Name}, nil
+						}
+					}
+				})
+
+				It("downloads the releases at the same time", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+					Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+				})
+
+				It("logs a line for each release", func() {
+					Expect(output).To(gbytes.Say(`downloaded \S+ \S+ \(0 B in 0s\) \[1/3\]`))
+					Expect(output).To(gbytes.Say(`\[2/3\]`))
+					Expect(output).To(gbytes.Say(`\[3/3\]`))
+					Expect(output).To(gbytes.Say(`3/3 releases, 0 B downloaded`))
+				})
+			})
+
+			When("a download fails", func() {
+				BeforeEach(func() {
+					fetchExecuteArgs = append(fetchExecuteArgs, "--parallel", "3")
+					fakeS3CompiledReleaseSource.DownloadReleaseStub = func(ctx context.Context, _ string, _ cargo.BOSHReleaseTarballLock) (component.Local, error) {
+						<-ctx.Done()
+						return component.Local{}, ctx.Err()
+					}
+					fakeS3BuiltReleaseSource.DownloadReleaseReturns(component.Local{}, errors.New("lemon"))
+					fakeBoshIOReleaseSource.DownloadReleaseReturns(component.Local{}, errors.New("lime"))
+				})
+
+				It("stops the other downloads and returns the first error", func() {
+					Expect(fetchExecuteErr).To(MatchError(Or(ContainSubstring("lemon"), ContainSubstring("lime"))))
+					Expect(fetchExecuteErr).NotTo(MatchError(ContainSubstring("canceled")))
+				})
+
+				When("--collect-errors is passed", func() {
+					BeforeEach(func() {
+						fetchExecuteArgs = append(fetchExecuteArgs, "--collect-errors")
+						fakeS3CompiledReleaseSource.DownloadReleaseStub = nil
+					})
+
+					It("returns every error", func() {
+						Expect(fetchExecuteErr).To(MatchError(And(ContainSubstring("lemon"), ContainSubstring("lime"))))
+						Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			When("kiln is offline", func() {
+				BeforeEach(func() {
+					fetchContext = component.ContextWithOffline(context.Background())
+					fakeS3CompiledReleaseSource.ConfigurationReturns(cargo.ReleaseSourceConfig{
+						ID:   s3CompiledReleaseSourceID,
+						Type: component.ReleaseSourceTypeDirectory,
+					})
+				})
+
+				It("only fetches from directory release sources", func() {
+					Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(0))
+					Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(0))
+				})
+
+				It("lists every missing release", func() {
+					Expect(fetchExecuteErr).To(MatchError(And(
+						ContainSubstring("offline mode: 2 release(s) are not in the releases directory or the release cache"),
+						ContainSubstring(`- lts-built-release 1.3.9 (sha1: correct-sha) from release source "s3-built"`),
+						ContainSubstring(`- boshio-release 1.4.16 (sha1: correct-sha) from release source "bosh.io"`),
+					)))
+					Expect(fetchExecuteErr).NotTo(MatchError(ContainSubstring("lts-compiled-release")))
+				})
+			})
+
+			When("a release cache is configured", func() {
+				var fakeReleaseCache *commandsFakes.ReleaseCache
+				BeforeEach(func() {
+					fakeReleaseCache = new(commandsFakes.ReleaseCache)
+					fakeReleaseCache.GetStub = func(_ context.Context, releasesDir string, lock cargo.BOSHReleaseTarballLock) (component.Local, bool, error) {
+						if lock.Name != boshIOReleaseID.Name {
+							return component.Local{}, false, nil
+						}
+						return component.Local{Lock: lock, LocalPath: filepath.Join(releasesDir, "cached.tgz")}, true, nil
+					}
+					releaseCache = fakeReleaseCache
+				})
+
+				It("records the Kilnfile.lock", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+					Expect(fakeReleaseCache.TrackKilnfileLockCallCount()).To(Equal(1))
+					Expect(fakeReleaseCache.TrackKilnfileLockArgsForCall(0)).To(Equal(someKilnfileLockPath))
+				})
+
+				It("does not download cached releases", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+					Expect(fakeReleaseCache.GetCallCount()).To(Equal(3))
+					Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(0))
+				})
+
+				It("adds downloaded releases to the cache", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+					Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeReleaseCache.PutCallCount()).To(Equal(2))
+					var names []string
+					for i := 0; i < fakeReleaseCache.PutCallCount(); i++ {
+						_, local := fakeReleaseCache.PutArgsForCall(i)
+						names = append(names, local.Lock.Name)
+					}
+					Expect(names).To(ConsistOf(s3CompiledReleaseID.Name, s3BuiltReleaseID.Name))
+				})
+
+				When("adding a release to the cache fails", func() {
+					BeforeEach(func() {
+						fakeReleaseCache.PutReturns(errors.New("disk full"))
+					})
+					It("still succeeds", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+					})
+				})
+
+				When("--no-cache is passed", func() {
+					BeforeEach(func() {
+						fetchExecuteArgs = append(fetchExecuteArgs, "--no-cache")
+					})
+					It("does not use the cache", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+						Expect(fakeReleaseCache.GetCallCount()).To(Equal(0))
+						Expect(fakeReleaseCache.PutCallCount()).To(Equal(0))
+						Expect(fakeReleaseCache.TrackKilnfileLockCallCount()).To(Equal(0))
+						Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					})
+				})
+			})
 		})
 
 		Context("when all releases are already present in releases directory", func() {
@@ -384,15 +563,15 @@ stemcell_criteria:
 				Expect(fetchExecuteErr).NotTo(HaveOccurred())
 
 				Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				_, object := fakeS3CompiledReleaseSource.DownloadReleaseArgsForCall(0)
+				_, _, object := fakeS3CompiledReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(object).To(Equal(missingReleaseS3Compiled))
 
 				Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				_, object = fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
+				_, _, object = fakeBoshIOReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(object).To(Equal(missingReleaseBoshIO))
 
 				Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				_, object = fakeS3BuiltReleaseSource.DownloadReleaseArgsForCall(0)
+				_, _, object = fakeS3BuiltReleaseSource.DownloadReleaseArgsForCall(0)
 				Expect(object).To(Equal(missingReleaseS3Built))
 			})
 
@@ -420,7 +599,7 @@ stemcell_criteria:
 				BeforeEach(func() {
 					badReleasePath = filepath.Join(someReleasesDirectory, "local-path-3")
 
-					fakeS3BuiltReleaseSource.DownloadReleaseCalls(func(string, cargo.BOSHReleaseTarballLock) (component.Local, error) {
+					fakeS3BuiltReleaseSource.DownloadReleaseCalls(func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, error) {
 						f, err := os.Create(badReleasePath)
 						Expect(err).NotTo(HaveOccurred())
 						defer closeAndIgnoreError(f)
@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/pivotal-cf/jhanda"
 
@@ -13,6 +15,7 @@ import (
 )
 
 type FindReleaseVersion struct {
+	ctx         context.Context
 	outLogger   *log.Logger
 	mrsProvider MultiReleaseSourceProvider
 
@@ -20,6 +23,8 @@ type FindReleaseVersion struct {
 		flags.Standard
 		Release    string `short:"r" long:"release" description:"release name"`
 		NoDownload bool   `long:"no-download" description:"do not download any files"`
+
+		Timeout time.Duration `long:"timeout" description:"maximum duration of the release source search (for example 10m); unlimited when not set"`
 	}
 }
 
@@ -30,8 +35,9 @@ type releaseVersionOutput struct {
 	SHA        string `json:"sha"`
 }
 
-func NewFindReleaseVersion(outLogger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceProvider) *FindReleaseVersion {
+func NewFindReleaseVersion(ctx context.Context, outLogger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceProvider) *FindReleaseVersion {
 	return &FindReleaseVersion{
+		ctx:         ctx,
 		outLogger:   outLogger,
 		mrsProvider: multiReleaseSourceProvider,
 	}
@@ -52,7 +58,9 @@ func (cmd *FindReleaseVersion) Execute(args []string) error {
 	spec.StemcellOS = kilnfileLock.Stemcell.OS
 	spec.StemcellVersion = kilnfileLock.Stemcell.Version
 
-	releaseRemote, err := releaseSource.FindReleaseVersion(spec, cmd.Options.NoDownload)
+	ctx, cancel := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancel()
+	releaseRemote, err := releaseSource.FindReleaseVersion(ctx, spec, cmd.Options.NoDownload)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
@@ -69,7 +70,7 @@ releases:
 			multiReleaseSourceProvider := func(kilnfile cargo.Kilnfile, allowOnlyPublishable bool) component.MultiReleaseSource {
 				return fakeReleasesSource
 			}
-			findReleaseVersion = commands.NewFindReleaseVersion(logger, multiReleaseSourceProvider)
+			findReleaseVersion = commands.NewFindReleaseVersion(context.Background(), logger, multiReleaseSourceProvider)
 
 			logger.Printf("releaseName is: %s", releaseName)
 			executeErr = findReleaseVersion.Execute(fetchExecuteArgs)
@@ -104,7 +105,7 @@ releases:
 				When("uaac has releases on bosh.io", func() {
 					It("returns the latest release version", func() {
 						Expect(executeErr).NotTo(HaveOccurred())
-						args, _ := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
+						_, args, _ := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
 						Expect(args.StemcellVersion).To(Equal("4.5.6"))
 						Expect(args.StemcellOS).To(Equal("some-os"))
 						Expect(args.Version).To(Equal(""))
@@ -137,7 +138,7 @@ releases:
 				When("uaa has releases on bosh.io", func() {
 					It("returns the latest release version", func() {
 						Expect(executeErr).NotTo(HaveOccurred())
-						args, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
+						_, args, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
 						Expect(noDownload).To(BeFalse())
 						Expect(args.Version).To(Equal("~74.16.0"))
 						Expect(args.StemcellVersion).To(Equal("4.5.6"))
@@ -161,7 +162,7 @@ releases:
 
 			It("calls source with correct args", func() {
 				Expect(executeErr).NotTo(HaveOccurred())
-				_, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
+				_, _, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
 				Expect(noDownload).To(BeTrue())
 			})
 		})
@@ -177,7 +178,7 @@ releases:
 
 			It("calls source with correct args", func() {
 				Expect(executeErr).NotTo(HaveOccurred())
-				_, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
+				_, _, noDownload := fakeReleasesSource.FindReleaseVersionArgsForCall(0)
 				Expect(noDownload).To(BeFalse())
 			})
 		})
@@ -8,9 +8,11 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
 	"github.com/pivotal-cf/jhanda"
 	"gopkg.in/yaml.v3"
 
@@ -43,57 +45,91 @@ type Standard struct {
 }
 
 type FetchBakeOptions struct {
-	DownloadThreads              int  `short:"dt" long:"download-threads" description:"number of parallel threads to download parts from S3"`
-	NoConfirm                    bool `short:"n" long:"no-confirm" default:"true" description:"non-interactive mode, will delete extra releases in releases dir without prompting"`
-	AllowOnlyPublishableReleases bool `long:"allow-only-publishable-releases" default:"false" description:"include releases that would not be shipped with the tile (development builds)"`
+	DownloadThreads              int           `short:"dt" long:"download-threads" description:"number of parallel threads to download parts from S3"`
+	NoConfirm                    bool          `short:"n" long:"no-confirm" default:"true" description:"non-interactive mode, will delete extra releases in releases dir without prompting"`
+	AllowOnlyPublishableReleases bool          `long:"allow-only-publishable-releases" default:"false" description:"include releases that would not be shipped with the tile (development builds)"`
+	Timeout                      time.Duration `long:"timeout" description:"maximum duration of each release download (for example 10m); unlimited when not set"`
+	Parallel                     int           `long:"parallel" default:"1" description:"number of releases to download at the same time"`
+	CollectErrors                bool          `long:"collect-errors" description:"keep downloading after a release fails and report every failure instead of stopping at the first one"`
+	NoCache                      bool          `long:"no-cache" description:"do not read or populate the shared release cache (see kiln cache)"`
 }
 
 // LoadKilnfiles parses and interpolates the Kilnfile and parsed the Kilnfile.lock.
 // The function parameters are for overriding default services. These parameters are
 // helpful for testing, in most cases nil can be passed for both.
 func (options *Standard) LoadKilnfiles(fsOverride billy.Basic, variablesServiceOverride VariablesService) (_ cargo.Kilnfile, _ cargo.KilnfileLock, err error) {
-	fs := fsOverride
-	if fs == nil {
-		fs = osfs.New("")
-	}
-	variablesService := variablesServiceOverride
-	if variablesService == nil {
-		variablesService = baking.NewTemplateVariablesService(fs)
+	kilnfileYAML, lockBuf, err := options.ReadKilnfiles(fsOverride, variablesServiceOverride)
+	if err != nil {
+		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
 	}
 
-	templateVariables, err := variablesService.FromPathsAndPairs(options.VariableFiles, options.Variables)
+	var kilnfile cargo.Kilnfile
+	err = yaml.Unmarshal(kilnfileYAML, &kilnfile)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, fmt.Errorf("failed to parse template variables: %s", err)
+		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
 	}
 
-	kilnfileFP, err := fs.Open(options.Kilnfile)
+	var lock cargo.KilnfileLock
+	err = yaml.Unmarshal(lockBuf, &lock)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, fmt.Errorf("failed to open Kilnfile: %w", err)
+		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
+	}
+
+	return kilnfile, lock, nil
+}
+
+// ReadKilnfiles is like LoadKilnfiles but returns the interpolated Kilnfile and the
+// Kilnfile.lock without parsing them.
+func (options *Standard) ReadKilnfiles(fsOverride billy.Basic, variablesServiceOverride VariablesService) (kilnfileYAML, lockYAML []byte, err error) {
+	fs := fsOverride
+	if fs == nil {
+		fs = osfs.New("")
 	}
-	defer closeAndIgnoreError(kilnfileFP)
 
-	kilnfile, err := cargo.InterpolateAndParseKilnfile(kilnfileFP, templateVariables)
+	kilnfileYAML, err = options.RenderKilnfile(fs, variablesServiceOverride)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
+		return nil, nil, err
 	}
 
 	lockFP, err := fs.Open(options.KilnfileLockPath())
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, fmt.Errorf("failed to open Kilnfile.lock: %w", err)
+		return nil, nil, fmt.Errorf("failed to open Kilnfile.lock: %w", err)
 	}
 	defer closeAndIgnoreError(lockFP)
-	lockBuf, err := io.ReadAll(lockFP)
+	lockYAML, err = io.ReadAll(lockFP)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
+		return nil, nil, err
 	}
 
-	var lock cargo.KilnfileLock
-	err = yaml.Unmarshal(lockBuf, &lock)
+	return kilnfileYAML, lockYAML, nil
+}
+
+// RenderKilnfile returns the interpolated Kilnfile merged with the Kilnfiles listed in
+// extends (see cargo.RenderKilnfile).
+func (options *Standard) RenderKilnfile(fsOverride billy.Basic, variablesServiceOverride VariablesService) ([]byte, error) {
+	fs := fsOverride
+	if fs == nil {
+		fs = osfs.New("")
+	}
+	variablesService := variablesServiceOverride
+	if variablesService == nil {
+		variablesService = baking.NewTemplateVariablesService(fs)
+	}
+
+	templateVariables, err := variablesService.FromPathsAndPairs(options.VariableFiles, options.Variables)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
+		return nil, fmt.Errorf("failed to parse template variables: %s", err)
 	}
 
-	return kilnfile, lock, nil
+	return cargo.RenderKilnfile(options.Kilnfile, func(name string) ([]byte, error) {
+		// the os filesystem does not open relative paths outside the working directory, like a shared Kilnfile in ../shared
+		if name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			if abs, err := filepath.Abs(name); err == nil {
+				name = abs
+			}
+		}
+		return util.ReadFile(fs, name)
+	}, templateVariables)
 }
 
 func (options Standard) SaveKilnfileLock(fsOverride billy.Basic, kilnfileLock cargo.KilnfileLock) error {
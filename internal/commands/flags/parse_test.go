@@ -360,6 +360,8 @@ func TestArgs(t *testing.T) {
 			"--variable", "variables-2",
 			"--download-threads", "0",
 			"--no-confirm",
+			"--timeout", "0s",
+			"--parallel", "0",
 			"--releases-directory", "releases-dir",
 		}, "it encodes an options struct into a string slice with jhanda formatting")
 	})
@@ -388,6 +390,8 @@ func TestArgs(t *testing.T) {
 			"--variable", "variables-1",
 			"--variable", "variables-2",
 			"--download-threads", "0",
+			"--timeout", "0s",
+			"--parallel", "0",
 			"--releases-directory", "releases-dir",
 		}, "it encodes an options struct into a string slice with jhanda formatting")
 	})
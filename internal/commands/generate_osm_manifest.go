@@ -11,13 +11,13 @@ import (
 	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	"golang.org/x/oauth2"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/google/go-github/v50/github"
 	"github.com/pivotal-cf/jhanda"
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
 	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/internal/gh"
 	"github.com/pivotal-cf/kiln/pkg/cargo"
 	"gopkg.in/yaml.v2"
 )
@@ -28,18 +28,19 @@ type OSM struct {
 	gc      *github.Client
 	Options struct {
 		flags.Standard
-		NoDownload  bool   `short:"nd" long:"no-download" default:"false" description:"Do not download & zip the packages"`
-		GithubToken string `short:"g" long:"github-token" description:"Auth token for fetching specified Github packages" env:"GITHUB_TOKEN"`
-		Only        string `short:"o" long:"only" default:"" description:"Only download the specified package name, must be used with --url to specify package Github URL"`
-		Url         string `short:"u" long:"url" default:"" description:"Github URL for package specified by --only"`
+		GitHubOptions
+		NoDownload bool   `short:"nd" long:"no-download" default:"false" description:"Do not download & zip the packages"`
+		Only       string `short:"o" long:"only" default:"" description:"Only download the specified package name, must be used with --url to specify package Github URL"`
+		Url        string `short:"u" long:"url" default:"" description:"Github URL for package specified by --only"`
 	}
+	githubToken func() (string, error)
 }
 
 //counterfeiter:generate -o ./fakes/release_storage.go --fake-name ReleaseStorage . ReleaseStorage
 
 type ReleaseStorage interface {
 	component.ReleaseSource
-	UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error)
+	UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error)
 }
 
 func NewOSM(outLogger *log.Logger, rs component.ReleaseSource) *OSM {
@@ -64,14 +65,16 @@ func NewOSMWithGHClient(outLogger *log.Logger, rs component.ReleaseSource, githu
 	}
 }
 
-func getClient(token string, ctx context.Context) *github.Client {
+func getClient(ctx context.Context, config gh.Config) (*github.Client, error) {
 	// go-github client needed for singlePackage() to reach out to Github
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-	return client
+	tc, err := config.HTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.IsEnterprise() {
+		return github.NewClient(tc), nil
+	}
+	return github.NewEnterpriseClient(config.APIURL(), config.UploadURL(), tc)
 }
 
 func (cmd *OSM) Execute(args []string) error {
@@ -81,6 +84,15 @@ func (cmd *OSM) Execute(args []string) error {
 	}
 	ctx := context.Background()
 
+	githubConfig, err := cmd.Options.GitHubOptions.config()
+	if err != nil {
+		return err
+	}
+	cmd.githubToken, err = cmd.Options.GitHubOptions.githubTokenFunc(ctx)
+	if err != nil {
+		return err
+	}
+
 	out := map[string]osmEntry{}
 
 	if cmd.Options.Only == "" && cmd.Options.Url == "" {
@@ -95,7 +107,7 @@ func (cmd *OSM) Execute(args []string) error {
 		}
 
 		for _, r := range kilnfile.Releases {
-			lock, err := cmd.getReleaseLockFromBOSHIO(r)
+			lock, err := cmd.getReleaseLockFromBOSHIO(ctx, r)
 			if err != nil {
 				continue
 			}
@@ -114,7 +126,7 @@ func (cmd *OSM) Execute(args []string) error {
 				continue
 			}
 
-			err = downloadAndZip(r.Name, url, cmd.Options.GithubToken, lockVersion)
+			err = cmd.downloadAndZip(r.Name, url, lockVersion)
 			if err != nil {
 				return fmt.Errorf("download and zip failed %s", err)
 			}
@@ -127,11 +139,14 @@ func (cmd *OSM) Execute(args []string) error {
 		if cmd.Options.Only == "" {
 			return fmt.Errorf("missing --only, must provide a --only for the specified package of the Github repository")
 		}
-		if !strings.Contains(cmd.Options.Url, "github.com") {
+		if !strings.Contains(cmd.Options.Url, githubConfig.HostName()) {
 			return fmt.Errorf("invalid --url, must provide a valid Github --url for specified package")
 		}
 		if cmd.gc == nil {
-			cmd.gc = getClient(cmd.Options.GithubToken, ctx)
+			cmd.gc, err = getClient(ctx, githubConfig)
+			if err != nil {
+				return err
+			}
 		}
 		entry, s, err := cmd.singlePackage(cmd.Options.Only, cmd.Options.Url, ctx)
 		out[s] = entry
@@ -175,7 +190,7 @@ func (cmd *OSM) singlePackage(name string, url string, ctx context.Context) (osm
 		return entry, s, nil
 	}
 
-	err = downloadAndZip(name, url, cmd.Options.GithubToken, release.GetName())
+	err = cmd.downloadAndZip(name, url, release.GetName())
 	if err != nil {
 		return osmEntry{}, "nil", fmt.Errorf("download and zip failed %s", err)
 	}
@@ -243,10 +258,10 @@ func zipWalker(repoPath string, zw *zip.Writer) filepath.WalkFunc {
 	}
 }
 
-func (cmd *OSM) getReleaseLockFromBOSHIO(cs cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
-	lock, err := cmd.FindReleaseVersion(cs, true)
+func (cmd *OSM) getReleaseLockFromBOSHIO(ctx context.Context, cs cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	lock, err := cmd.FindReleaseVersion(ctx, cs, true)
 	if err != nil {
-		return cmd.FindReleaseVersion(specWithoutOffline(cs), true)
+		return cmd.FindReleaseVersion(ctx, specWithoutOffline(cs), true)
 	}
 	return lock, nil
 }
@@ -287,9 +302,14 @@ func formatOSMEntry(name, lockVersion, url string) (string, osmEntry) {
 	return s, e
 }
 
-func downloadAndZip(name, url, githubToken, lockVersion string) error {
+func (cmd *OSM) downloadAndZip(name, url, lockVersion string) error {
+	// the token is requested for each clone because GitHub App tokens expire
+	githubToken, err := cmd.githubToken()
+	if err != nil {
+		return err
+	}
 	repoPath := fmt.Sprintf("/tmp/osm/%s", name)
-	err := cloneGitRepo(url, repoPath, githubToken)
+	err = cloneGitRepo(url, repoPath, githubToken)
 	if err != nil {
 		return fmt.Errorf("could not clone repo %s, %s", url, err)
 	}
@@ -109,7 +109,7 @@ func TestOSM_Execute(t *testing.T) {
 		})
 
 		rs := new(fakes.ReleaseStorage)
-		rs.FindReleaseVersionCalls(func(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+		rs.FindReleaseVersionCalls(func(_ context.Context, spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
 			switch spec.Name {
 			case "banana":
 				return cargo.BOSHReleaseTarballLock{}, nil
@@ -162,7 +162,7 @@ func TestOSM_Execute(t *testing.T) {
 		})
 
 		rs := new(fakes.ReleaseStorage)
-		rs.FindReleaseVersionCalls(func(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+		rs.FindReleaseVersionCalls(func(_ context.Context, spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
 			switch spec.Name {
 			case "lemon-buildpack":
 				return cargo.BOSHReleaseTarballLock{
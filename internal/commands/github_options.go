@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pivotal-cf/kiln/internal/gh"
+)
+
+// GitHubOptions are the flags of commands that call the GitHub API. They match the
+// github_host, github_token and github_app_* fields of github release sources.
+type GitHubOptions struct {
+	GithubToken             string `long:"github-token"               short:"g" description:"auth token for the GitHub API"                                         env:"GITHUB_TOKEN"`
+	GithubHost              string `long:"github-host"                          description:"host of a GitHub Enterprise Server instance (default: github.com)"    env:"GITHUB_HOST"`
+	GithubAppID             int64  `long:"github-app-id"                        description:"ID of a GitHub App to authenticate as instead of using a token"       env:"GITHUB_APP_ID"`
+	GithubAppInstallationID int64  `long:"github-app-installation-id"           description:"ID of the installation of the GitHub App"                             env:"GITHUB_APP_INSTALLATION_ID"`
+	GithubAppPrivateKeyPath string `long:"github-app-private-key"               description:"path to the PEM encoded private key of the GitHub App"                env:"GITHUB_APP_PRIVATE_KEY_PATH"`
+}
+
+// hasCredentials reports whether a token or a GitHub App is configured.
+func (options GitHubOptions) hasCredentials() bool {
+	return options.GithubToken != "" || options.usesApp()
+}
+
+func (options GitHubOptions) usesApp() bool {
+	return options.GithubAppID != 0 || options.GithubAppInstallationID != 0 || options.GithubAppPrivateKeyPath != ""
+}
+
+func (options GitHubOptions) config() (gh.Config, error) {
+	config := gh.Config{
+		Host:              options.GithubHost,
+		Token:             options.GithubToken,
+		AppID:             options.GithubAppID,
+		AppInstallationID: options.GithubAppInstallationID,
+	}
+	if options.GithubAppPrivateKeyPath != "" {
+		key, err := os.ReadFile(options.GithubAppPrivateKeyPath)
+		if err != nil {
+			return gh.Config{}, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+		config.AppPrivateKey = string(key)
+	}
+	return config, nil
+}
+
+// githubTokenFunc returns a function that returns a current token for git operations over
+// HTTPS. GitHub App installation tokens are requested again when they expire.
+func (options GitHubOptions) githubTokenFunc(ctx context.Context) (func() (string, error), error) {
+	config, err := options.config()
+	if err != nil {
+		return nil, err
+	}
+	source, err := config.TokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func() (string, error) {
+		if source == nil {
+			return "", nil
+		}
+		token, err := source.Token()
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	}, nil
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+)
+
+// Kilnfile groups the commands that operate on the Kilnfile. The first argument selects
+// the action.
+type Kilnfile struct {
+	actions map[string]jhanda.Command
+}
+
+var _ jhanda.Command = Kilnfile{}
+
+func NewKilnfile(outLogger *log.Logger, fs billy.Filesystem) Kilnfile {
+	return Kilnfile{
+		actions: map[string]jhanda.Command{
+			"render": NewKilnfileRender(outLogger, fs),
+		},
+	}
+}
+
+func (cmd Kilnfile) Execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing kilnfile action: expected one of %s", strings.Join(cmd.actionNames(), ", "))
+	}
+	action, ok := cmd.actions[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown kilnfile action %q: expected one of %s", args[0], strings.Join(cmd.actionNames(), ", "))
+	}
+	return action.Execute(args[1:])
+}
+
+func (cmd Kilnfile) actionNames() []string {
+	names := make([]string, 0, len(cmd.actions))
+	for name := range cmd.actions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (cmd Kilnfile) Usage() jhanda.Usage {
+	var description strings.Builder
+	description.WriteString("Operates on the Kilnfile. Run \"kiln kilnfile ACTION --help\" for the flags of an action.\n\nActions:\n")
+	for _, name := range cmd.actionNames() {
+		_, _ = fmt.Fprintf(&description, "  %-20s %s\n", name, cmd.actions[name].Usage().ShortDescription)
+	}
+	return jhanda.Usage{
+		Description:      description.String(),
+		ShortDescription: "operates on the Kilnfile",
+	}
+}
+
+// KilnfileRender prints the Kilnfile after interpolating variables and merging the
+// Kilnfiles it extends.
+type KilnfileRender struct {
+	outLogger *log.Logger
+	fs        billy.Filesystem
+
+	Options struct {
+		flags.Standard
+	}
+}
+
+func NewKilnfileRender(outLogger *log.Logger, fs billy.Filesystem) *KilnfileRender {
+	return &KilnfileRender{
+		outLogger: outLogger,
+		fs:        fs,
+	}
+}
+
+func (cmd *KilnfileRender) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+	kilnfileYAML, err := cmd.Options.Standard.RenderKilnfile(cmd.fs, nil)
+	if err != nil {
+		return err
+	}
+	cmd.outLogger.Print(string(kilnfileYAML))
+	return nil
+}
+
+func (cmd *KilnfileRender) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prints the Kilnfile with variables interpolated and the Kilnfiles listed in extends merged into it.",
+		ShortDescription: "prints the merged Kilnfile",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,74 @@
+package commands_test
+
+import (
+	"log"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+)
+
+var _ = Describe("kilnfile", func() {
+	var (
+		fs     billy.Filesystem
+		output strings.Builder
+
+		kilnfile commands.Kilnfile
+	)
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		output.Reset()
+		kilnfile = commands.NewKilnfile(log.New(&output, "", 0), fs)
+	})
+
+	It("requires an action", func() {
+		Expect(kilnfile.Execute(nil)).To(MatchError("missing kilnfile action: expected one of render"))
+		Expect(kilnfile.Execute([]string{"merge"})).To(MatchError(`unknown kilnfile action "merge": expected one of render`))
+	})
+
+	Describe("render", func() {
+		BeforeEach(func() {
+			Expect(util.WriteFile(fs, "shared/Kilnfile", []byte(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+    version: ~1
+  - name: capi
+    version: ~2
+`), 0o644)).To(Succeed())
+			Expect(util.WriteFile(fs, "Kilnfile", []byte(`---
+extends: [shared/Kilnfile]
+slug: $(variable "slug")
+releases:
+  - name: capi
+    version: ~2.1
+`), 0o644)).To(Succeed())
+		})
+
+		It("prints the merged Kilnfile", func() {
+			Expect(kilnfile.Execute([]string{"render", "--variable", "slug=my-tile"})).To(Succeed())
+			Expect(output.String()).To(Equal(`release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+    version: ~1
+  - name: capi
+    version: ~2.1
+slug: my-tile
+`))
+		})
+
+		It("does not need a Kilnfile.lock", func() {
+			_, err := fs.Stat("Kilnfile.lock")
+			Expect(err).To(HaveOccurred())
+			Expect(kilnfile.Execute([]string{"render", "--variable", "slug=my-tile"})).To(Succeed())
+		})
+	})
+})
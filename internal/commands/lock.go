@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// Lock groups commands that operate on Kilnfile.lock. The first argument selects the action.
+type Lock struct {
+	actions map[string]jhanda.Command
+}
+
+var _ jhanda.Command = Lock{}
+
+func NewLock(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, localReleaseDirectory LocalReleaseDirectory, mrsProvider MultiReleaseSourceProvider) Lock {
+	return Lock{
+		actions: map[string]jhanda.Command{
+			"upgrade-checksums": NewLockUpgradeChecksums(ctx, outLogger, fs, localReleaseDirectory, mrsProvider),
+			"diff":              NewLockDiff(outLogger, fs),
+		},
+	}
+}
+
+func (cmd Lock) Execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing lock action: expected one of %s", strings.Join(cmd.actionNames(), ", "))
+	}
+	action, ok := cmd.actions[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown lock action %q: expected one of %s", args[0], strings.Join(cmd.actionNames(), ", "))
+	}
+	return action.Execute(args[1:])
+}
+
+func (cmd Lock) actionNames() []string {
+	names := make([]string, 0, len(cmd.actions))
+	for name := range cmd.actions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (cmd Lock) Usage() jhanda.Usage {
+	var description strings.Builder
+	description.WriteString("Operates on Kilnfile.lock. Run \"kiln lock ACTION --help\" for the flags of an action.\n\nActions:\n")
+	for _, name := range cmd.actionNames() {
+		_, _ = fmt.Fprintf(&description, "  %-20s %s\n", name, cmd.actions[name].Usage().ShortDescription)
+	}
+	return jhanda.Usage{
+		Description:      description.String(),
+		ShortDescription: "operates on Kilnfile.lock",
+	}
+}
+
+// LockUpgradeChecksums adds SHA256 checksums to the releases in Kilnfile.lock that only have a SHA1.
+type LockUpgradeChecksums struct {
+	ctx                   context.Context
+	outLogger             *log.Logger
+	fs                    billy.Filesystem
+	localReleaseDirectory LocalReleaseDirectory
+	mrsProvider           MultiReleaseSourceProvider
+
+	Options struct {
+		flags.Standard
+
+		ReleasesDir string        `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory with release tarballs to checksum before downloading from release sources"`
+		NoDownload  bool          `           long:"no-download"                           description:"only checksum release tarballs found in the releases directory"`
+		Timeout     time.Duration `           long:"timeout"                               description:"maximum duration of each release download (for example 10m); unlimited when not set"`
+	}
+}
+
+func NewLockUpgradeChecksums(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, localReleaseDirectory LocalReleaseDirectory, mrsProvider MultiReleaseSourceProvider) LockUpgradeChecksums {
+	return LockUpgradeChecksums{
+		ctx:                   ctx,
+		outLogger:             outLogger,
+		fs:                    fs,
+		localReleaseDirectory: localReleaseDirectory,
+		mrsProvider:           mrsProvider,
+	}
+}
+
+func (cmd LockUpgradeChecksums) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+
+	kilnfile, kilnfileLock, err := cmd.Options.Standard.LoadKilnfiles(cmd.fs, nil)
+	if err != nil {
+		return fmt.Errorf("error loading Kilnfiles: %w", err)
+	}
+
+	var localReleases []component.Local
+	if _, err := os.Stat(cmd.Options.ReleasesDir); err == nil {
+		localReleases, err = cmd.localReleaseDirectory.GetLocalReleases(cmd.Options.ReleasesDir)
+		if err != nil {
+			return fmt.Errorf("failed reading releases directory: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	var (
+		releaseSource component.MultiReleaseSource
+		upgraded      int
+		errs          []error
+	)
+	for i := range kilnfileLock.Releases {
+		lock := &kilnfileLock.Releases[i]
+		if lock.SHA256 != "" {
+			continue
+		}
+
+		if index := slices.IndexFunc(localReleases, func(local component.Local) bool {
+			return local.Lock.Name == lock.Name && local.Lock.Version == lock.Version && local.Lock.SHA1 == lock.SHA1 && local.Lock.SHA256 != ""
+		}); index >= 0 {
+			lock.SHA256 = localReleases[index].Lock.SHA256
+			upgraded++
+			cmd.outLogger.Printf("%s %s: sha256 %s (from %s)", lock.Name, lock.Version, lock.SHA256, localReleases[index].LocalPath)
+			continue
+		}
+
+		if cmd.Options.NoDownload {
+			cmd.outLogger.Printf("%s %s: skipped (no matching tarball in %s)", lock.Name, lock.Version, cmd.Options.ReleasesDir)
+			continue
+		}
+
+		if releaseSource == nil {
+			releaseSource = cmd.mrsProvider(kilnfile, false)
+		}
+		sum, err := cmd.downloadSHA256Sum(releaseSource, *lock)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", lock.Name, lock.Version, err))
+			continue
+		}
+		lock.SHA256 = sum
+		upgraded++
+		cmd.outLogger.Printf("%s %s: sha256 %s (from %s)", lock.Name, lock.Version, lock.SHA256, lock.RemoteSource)
+	}
+
+	if upgraded > 0 {
+		if err := cmd.Options.Standard.SaveKilnfileLock(cmd.fs, kilnfileLock); err != nil {
+			return err
+		}
+	}
+	cmd.outLogger.Printf("Added SHA256 checksums for %d releases", upgraded)
+
+	return errors.Join(errs...)
+}
+
+// downloadSHA256Sum downloads the release into a temporary directory, checks it matches the
+// SHA1 in the lock, and returns its SHA256.
+func (cmd LockUpgradeChecksums) downloadSHA256Sum(releaseSource component.MultiReleaseSource, lock cargo.BOSHReleaseTarballLock) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "kiln-lock-upgrade-checksums-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	ctx, cancel := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancel()
+	local, err := releaseSource.DownloadRelease(ctx, tmpDir, lock)
+	if err != nil {
+		return "", fmt.Errorf("failed to download release: %w", err)
+	}
+	if lock.SHA1 != "" && local.Lock.SHA1 != lock.SHA1 {
+		return "", fmt.Errorf("downloaded release had an incorrect SHA1 - expected %q, got %q", lock.SHA1, local.Lock.SHA1)
+	}
+	return component.CalculateSHA256Sum(local.LocalPath, osfs.New(""))
+}
+
+func (cmd LockUpgradeChecksums) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Adds SHA256 checksums to Kilnfile.lock for releases that only have a SHA1. Release tarballs in the releases directory are used when their SHA1 matches; the others are downloaded from their release source.",
+		ShortDescription: "adds SHA256 checksums to Kilnfile.lock",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+	"github.com/pivotal-cf/kiln/pkg/history"
+	"github.com/pivotal-cf/kiln/pkg/tile"
+)
+
+const (
+	lockDiffFormatText     = "text"
+	lockDiffFormatMarkdown = "markdown"
+	lockDiffFormatJSON     = "json"
+)
+
+// LockDiff prints the release and stemcell changes between two Kilnfile.lock files. Each side
+// may be a git revision, a Kilnfile.lock, or a baked tile.
+type LockDiff struct {
+	outLogger *log.Logger
+	fs        billy.Filesystem
+
+	Options struct {
+		flags.Standard
+
+		// From, To, and Format do not have default tags because LoadWithDefaultFilePaths treats string defaults as paths
+		From   string `long:"from"   description:"git revision, path to a Kilnfile.lock, or path to a tile (.pivotal) to compare from (default: HEAD)"`
+		To     string `long:"to"     description:"git revision, path to a Kilnfile.lock, or path to a tile (.pivotal) to compare to (default: the Kilnfile.lock next to the Kilnfile)"`
+		Format string `long:"format" description:"output format: text (the default), markdown, or json"`
+	}
+}
+
+func NewLockDiff(outLogger *log.Logger, fs billy.Filesystem) LockDiff {
+	return LockDiff{
+		outLogger: outLogger,
+		fs:        fs,
+	}
+}
+
+func (cmd LockDiff) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+	switch cmd.Options.Format {
+	case "":
+		cmd.Options.Format = lockDiffFormatText
+	case lockDiffFormatText, lockDiffFormatMarkdown, lockDiffFormatJSON:
+	default:
+		return fmt.Errorf("unknown format %q (expected %s, %s, or %s)", cmd.Options.Format, lockDiffFormatText, lockDiffFormatMarkdown, lockDiffFormatJSON)
+	}
+	if cmd.Options.From == "" {
+		cmd.Options.From = "HEAD"
+	}
+	if cmd.Options.To == "" {
+		cmd.Options.To = cmd.Options.KilnfileLockPath()
+	}
+
+	from, err := cmd.loadLock(cmd.Options.From)
+	if err != nil {
+		return err
+	}
+	to, err := cmd.loadLock(cmd.Options.To)
+	if err != nil {
+		return err
+	}
+	diff := cargo.DiffKilnfileLocks(from, to)
+
+	switch cmd.Options.Format {
+	case lockDiffFormatJSON:
+		buf, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.outLogger.Println(string(buf))
+	case lockDiffFormatMarkdown:
+		cmd.outLogger.Print(lockDiffMarkdown(cmd.Options.From, cmd.Options.To, diff))
+	default:
+		cmd.outLogger.Print(lockDiffText(diff))
+	}
+	return nil
+}
+
+// loadLock reads the Kilnfile.lock of a tile (a file with the .pivotal extension), a
+// Kilnfile.lock file, or the Kilnfile.lock at a git revision.
+func (cmd LockDiff) loadLock(name string) (cargo.KilnfileLock, error) {
+	if info, err := cmd.fs.Stat(name); err == nil && !info.IsDir() {
+		if filepath.Ext(name) == ".pivotal" {
+			f, err := cmd.fs.Open(name)
+			if err != nil {
+				return cargo.KilnfileLock{}, err
+			}
+			defer closeAndIgnoreError(f)
+			buf, err := tile.ReadMetadataFromZip(f, info.Size())
+			if err != nil {
+				return cargo.KilnfileLock{}, fmt.Errorf("failed to read tile metadata from %s: %w", name, err)
+			}
+			return lockFromTileMetadata(buf)
+		}
+		buf, err := util.ReadFile(cmd.fs, name)
+		if err != nil {
+			return cargo.KilnfileLock{}, err
+		}
+		var lock cargo.KilnfileLock
+		if err := yaml.Unmarshal(buf, &lock); err != nil {
+			return cargo.KilnfileLock{}, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return lock, nil
+	}
+	return cmd.loadLockAtRevision(name)
+}
+
+func (cmd LockDiff) loadLockAtRevision(revision string) (cargo.KilnfileLock, error) {
+	kilnfilePath, err := filepath.Abs(cmd.Options.Kilnfile)
+	if err != nil {
+		return cargo.KilnfileLock{}, err
+	}
+	repo, err := git.PlainOpenWithOptions(filepath.Dir(kilnfilePath), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return cargo.KilnfileLock{}, fmt.Errorf("%q is not a file and the Kilnfile is not in a git repository: %w", revision, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return cargo.KilnfileLock{}, err
+	}
+	kilnfilePath, err = filepath.Rel(worktree.Filesystem.Root(), kilnfilePath)
+	if err != nil {
+		return cargo.KilnfileLock{}, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return cargo.KilnfileLock{}, fmt.Errorf("%q is not a file or a git revision: %w", revision, err)
+	}
+	_, lock, err := history.Kilnfile(repo.Storer, *hash, filepath.ToSlash(kilnfilePath))
+	if err != nil {
+		return cargo.KilnfileLock{}, fmt.Errorf("failed to read Kilnfile.lock at %s: %w", revision, err)
+	}
+	return lock, nil
+}
+
+func lockFromTileMetadata(metadata []byte) (cargo.KilnfileLock, error) {
+	var product struct {
+		Releases []struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+			SHA1    string `yaml:"sha1"`
+		} `yaml:"releases"`
+		StemcellCriteria cargo.Stemcell `yaml:"stemcell_criteria"`
+	}
+	if err := yaml.Unmarshal(metadata, &product); err != nil {
+		return cargo.KilnfileLock{}, fmt.Errorf("failed to parse tile metadata: %w", err)
+	}
+	lock := cargo.KilnfileLock{
+		Stemcell: cargo.Stemcell{OS: product.StemcellCriteria.OS, Version: product.StemcellCriteria.Version},
+	}
+	for _, release := range product.Releases {
+		lock.Releases = append(lock.Releases, cargo.BOSHReleaseTarballLock{Name: release.Name, Version: release.Version, SHA1: release.SHA1})
+	}
+	return lock, nil
+}
+
+func lockDiffText(diff cargo.KilnfileLockDiff) string {
+	if diff.Empty() {
+		return "no changes\n"
+	}
+	var out strings.Builder
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	for _, release := range diff.Releases {
+		switch release.Change {
+		case cargo.ReleaseAdded:
+			_, _ = fmt.Fprintf(w, "added\t%s\t%s\n", release.Name, release.ToVersion)
+		case cargo.ReleaseRemoved:
+			_, _ = fmt.Fprintf(w, "removed\t%s\t%s\n", release.Name, release.FromVersion)
+		case cargo.ReleaseUnchanged:
+		default:
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s -> %s\n", release.Change, release.Name, release.FromVersion, release.ToVersion)
+		}
+		if release.SourceChanged() {
+			_, _ = fmt.Fprintf(w, "source\t%s\t%s -> %s\n", release.Name, release.FromSource, release.ToSource)
+		}
+		if release.ChecksumChanged {
+			_, _ = fmt.Fprintf(w, "WARNING\t%s\tchecksum changed but version %s did not\n", release.Name, release.ToVersion)
+		}
+	}
+	if diff.Stemcell != nil {
+		_, _ = fmt.Fprintf(w, "stemcell\t%s\t%s -> %s\n", stemcellName(diff.Stemcell.To, diff.Stemcell.From), stemcellVersion(diff.Stemcell.From), stemcellVersion(diff.Stemcell.To))
+	}
+	_ = w.Flush()
+	return out.String()
+}
+
+func lockDiffMarkdown(from, to string, diff cargo.KilnfileLockDiff) string {
+	var out strings.Builder
+	_, _ = fmt.Fprintf(&out, "### Kilnfile.lock changes from `%s` to `%s`\n\n", from, to)
+	if diff.Empty() {
+		out.WriteString("No changes.\n")
+		return out.String()
+	}
+	if len(diff.Releases) > 0 {
+		out.WriteString("| Release | From | To | Change |\n")
+		out.WriteString("|---------|------|----|--------|\n")
+		for _, release := range diff.Releases {
+			var changes []string
+			if release.Change != cargo.ReleaseUnchanged {
+				changes = append(changes, release.Change)
+			}
+			if release.SourceChanged() {
+				changes = append(changes, fmt.Sprintf("source `%s` → `%s`", release.FromSource, release.ToSource))
+			}
+			if release.ChecksumChanged {
+				changes = append(changes, "⚠️ checksum changed")
+			}
+			_, _ = fmt.Fprintf(&out, "| %s | %s | %s | %s |\n", release.Name, release.FromVersion, release.ToVersion, strings.Join(changes, ", "))
+		}
+	}
+	for _, release := range diff.Releases {
+		if release.ChecksumChanged {
+			_, _ = fmt.Fprintf(&out, "\n> [!WARNING]\n> The checksum of %s %s changed but its version did not.\n", release.Name, release.ToVersion)
+		}
+	}
+	if diff.Stemcell != nil {
+		_, _ = fmt.Fprintf(&out, "\n**Stemcell:** %s %s → %s\n", stemcellName(diff.Stemcell.To, diff.Stemcell.From), stemcellVersion(diff.Stemcell.From), stemcellVersion(diff.Stemcell.To))
+	}
+	return out.String()
+}
+
+// stemcellName returns the OS of the stemcell, or both when it changed.
+func stemcellName(to, from cargo.Stemcell) string {
+	if from.OS != to.OS && from.OS != "" {
+		return from.OS + " → " + to.OS
+	}
+	return to.OS
+}
+
+func stemcellVersion(stemcell cargo.Stemcell) string {
+	if stemcell.Version == "" {
+		return "(none)"
+	}
+	return stemcell.Version
+}
+
+func (cmd LockDiff) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prints the releases added, removed, and bumped (with the major, minor, or patch classification), release source changes, checksum changes of releases with the same version, and stemcell changes between two Kilnfile.lock files. Each side may be a git revision, a Kilnfile.lock, or a baked tile.",
+		ShortDescription: "prints the changes between two Kilnfile.lock files",
+		Flags:            cmd.Options,
+	}
+}
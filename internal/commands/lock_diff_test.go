@@ -0,0 +1,205 @@
+package commands_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("lock diff", func() {
+	var (
+		fs     billy.Filesystem
+		output strings.Builder
+
+		lockDiff commands.LockDiff
+
+		fromLock, toLock cargo.KilnfileLock
+	)
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		output.Reset()
+		lockDiff = commands.NewLockDiff(log.New(&output, "", 0), fs)
+
+		fromLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "1.2.3", SHA1: "bpm-sha1", RemoteSource: "bosh.io"},
+				{Name: "capi", Version: "1.2.3", SHA1: "capi-sha1", RemoteSource: "bosh.io"},
+				{Name: "removed", Version: "1.0.0", SHA1: "removed-sha1"},
+			},
+			Stemcell: cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.100"},
+		}
+		toLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "added", Version: "0.1.0", SHA1: "added-sha1"},
+				{Name: "bpm", Version: "1.2.3", SHA1: "replaced-sha1", RemoteSource: "artifactory"},
+				{Name: "capi", Version: "2.0.0", SHA1: "capi-sha1", RemoteSource: "bosh.io"},
+			},
+			Stemcell: cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.123"},
+		}
+	})
+
+	Context("with Kilnfile.lock files", func() {
+		BeforeEach(func() {
+			Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{})).To(Succeed())
+			Expect(fsWriteYAML(fs, "Kilnfile.lock", toLock)).To(Succeed())
+			Expect(fsWriteYAML(fs, "old.lock", fromLock)).To(Succeed())
+		})
+
+		It("prints the changes as text", func() {
+			Expect(lockDiff.Execute([]string{"--from", "old.lock"})).To(Succeed())
+			Expect(output.String()).To(Equal(`added     added         0.1.0
+source    bpm           bosh.io -> artifactory
+WARNING   bpm           checksum changed but version 1.2.3 did not
+major     capi          1.2.3 -> 2.0.0
+removed   removed       1.0.0
+stemcell  ubuntu-jammy  1.100 -> 1.123
+`))
+		})
+
+		It("prints the changes as Markdown", func() {
+			Expect(lockDiff.Execute([]string{"--from", "old.lock", "--to", "Kilnfile.lock", "--format", "markdown"})).To(Succeed())
+			Expect(output.String()).To(Equal("### Kilnfile.lock changes from `old.lock` to `Kilnfile.lock`\n" + `
+| Release | From | To | Change |
+|---------|------|----|--------|
+| added |  | 0.1.0 | added |
+| bpm | 1.2.3 | 1.2.3 | source ` + "`bosh.io` → `artifactory`" + `, ⚠️ checksum changed |
+| capi | 1.2.3 | 2.0.0 | major |
+| removed | 1.0.0 |  | removed |
+
+> [!WARNING]
+> The checksum of bpm 1.2.3 changed but its version did not.
+
+**Stemcell:** ubuntu-jammy 1.100 → 1.123
+`))
+		})
+
+		It("prints the changes as JSON", func() {
+			Expect(lockDiff.Execute([]string{"--from", "old.lock", "--format", "json"})).To(Succeed())
+			var diff cargo.KilnfileLockDiff
+			Expect(json.Unmarshal([]byte(output.String()), &diff)).To(Succeed())
+			Expect(diff).To(Equal(cargo.DiffKilnfileLocks(fromLock, toLock)))
+		})
+
+		It("reports when there are no changes", func() {
+			Expect(lockDiff.Execute([]string{"--from", "Kilnfile.lock"})).To(Succeed())
+			Expect(output.String()).To(Equal("no changes\n"))
+		})
+
+		It("rejects unknown formats", func() {
+			Expect(lockDiff.Execute([]string{"--from", "old.lock", "--format", "html"})).To(MatchError(ContainSubstring(`unknown format "html"`)))
+		})
+	})
+
+	Context("with a tile", func() {
+		BeforeEach(func() {
+			Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{})).To(Succeed())
+			Expect(fsWriteYAML(fs, "Kilnfile.lock", toLock)).To(Succeed())
+
+			var tile bytes.Buffer
+			zw := zip.NewWriter(&tile)
+			metadata, err := zw.Create("metadata/metadata.yml")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = metadata.Write([]byte(`---
+name: some-tile
+releases:
+  - name: added
+    version: 0.1.0
+    file: added-0.1.0.tgz
+    sha1: added-sha1
+  - name: bpm
+    version: 1.2.3
+    file: bpm-1.2.3.tgz
+    sha1: bpm-sha1
+  - name: capi
+    version: 1.3.0
+    file: capi-1.3.0.tgz
+    sha1: capi-sha1
+stemcell_criteria:
+  os: ubuntu-jammy
+  version: "1.123"
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(zw.Close()).To(Succeed())
+			Expect(util.WriteFile(fs, "tile-1.0.0.pivotal", tile.Bytes(), 0o644)).To(Succeed())
+		})
+
+		It("compares the releases in the tile metadata", func() {
+			Expect(lockDiff.Execute([]string{"--from", "tile-1.0.0.pivotal"})).To(Succeed())
+			Expect(output.String()).To(Equal(`WARNING  bpm   checksum changed but version 1.2.3 did not
+major    capi  1.3.0 -> 2.0.0
+`))
+		})
+	})
+
+	Context("with git revisions", func() {
+		var repoDirectory string
+
+		BeforeEach(func() {
+			var err error
+			repoDirectory, err = os.MkdirTemp("", "lock-diff")
+			Expect(err).NotTo(HaveOccurred())
+
+			fs = osfs.New("")
+			lockDiff = commands.NewLockDiff(log.New(&output, "", 0), fs)
+
+			repo, err := git.PlainInit(repoDirectory, false)
+			Expect(err).NotTo(HaveOccurred())
+			worktree, err := repo.Worktree()
+			Expect(err).NotTo(HaveOccurred())
+
+			tileDirectory := filepath.Join(repoDirectory, "tile")
+			Expect(os.MkdirAll(tileDirectory, 0o755)).To(Succeed())
+			Expect(fsWriteYAML(fs, filepath.Join(tileDirectory, "Kilnfile"), cargo.Kilnfile{})).To(Succeed())
+			for _, lock := range []cargo.KilnfileLock{fromLock, toLock} {
+				Expect(fsWriteYAML(fs, filepath.Join(tileDirectory, "Kilnfile.lock"), lock)).To(Succeed())
+				_, err = worktree.Add(".")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = worktree.Commit("update lock", &git.CommitOptions{
+					Author: &object.Signature{Name: "Kiln", Email: "kiln@example.com", When: time.Now()},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(repoDirectory)
+		})
+
+		It("compares Kilnfile.lock at the revisions", func() {
+			kilnfile := filepath.Join(repoDirectory, "tile", "Kilnfile")
+			Expect(lockDiff.Execute([]string{"--kilnfile", kilnfile, "--from", "HEAD~1", "--to", "HEAD", "--format", "json"})).To(Succeed())
+			var diff cargo.KilnfileLockDiff
+			Expect(json.Unmarshal([]byte(output.String()), &diff)).To(Succeed())
+			Expect(diff).To(Equal(cargo.DiffKilnfileLocks(fromLock, toLock)))
+		})
+
+		It("compares HEAD with the working tree by default", func() {
+			kilnfile := filepath.Join(repoDirectory, "tile", "Kilnfile")
+			Expect(lockDiff.Execute([]string{"--kilnfile", kilnfile})).To(Succeed())
+			Expect(output.String()).To(Equal("no changes\n"))
+		})
+
+		It("fails for unknown revisions", func() {
+			kilnfile := filepath.Join(repoDirectory, "tile", "Kilnfile")
+			Expect(lockDiff.Execute([]string{"--kilnfile", kilnfile, "--from", "no-such-branch"})).To(MatchError(ContainSubstring(`"no-such-branch" is not a file or a git revision`)))
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	fetcherFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("lock", func() {
+	const (
+		kilnfilePath     = "Kilnfile"
+		kilnfileLockPath = kilnfilePath + ".lock"
+
+		// sha256sum of "some release contents"
+		downloadedSHA256 = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	var (
+		fs                         billy.Filesystem
+		localReleaseDirectory      *commandsFakes.LocalReleaseDirectory
+		multiReleaseSourceProvider *commandsFakes.MultiReleaseSourceProvider
+		releaseSource              *fetcherFakes.MultiReleaseSource
+		releasesDirectory          string
+		kilnfileLock               cargo.KilnfileLock
+
+		lock commands.Lock
+	)
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		var err error
+		releasesDirectory, err = os.MkdirTemp("", "releases")
+		Expect(err).NotTo(HaveOccurred())
+
+		localReleaseDirectory = new(commandsFakes.LocalReleaseDirectory)
+		releaseSource = new(fetcherFakes.MultiReleaseSource)
+		multiReleaseSourceProvider = new(commandsFakes.MultiReleaseSourceProvider)
+		multiReleaseSourceProvider.Returns(releaseSource)
+
+		kilnfileLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "local", Version: "1.0.0", SHA1: "local-sha1", RemoteSource: "bosh.io", RemotePath: "local-path"},
+				{Name: "remote", Version: "2.0.0", SHA1: "remote-sha1", RemoteSource: "bosh.io", RemotePath: "remote-path"},
+				{Name: "upgraded", Version: "3.0.0", SHA1: "upgraded-sha1", SHA256: "upgraded-sha256", RemoteSource: "bosh.io", RemotePath: "upgraded-path"},
+			},
+		}
+
+		localReleaseDirectory.GetLocalReleasesReturns([]component.Local{
+			{Lock: cargo.BOSHReleaseTarballLock{Name: "local", Version: "1.0.0", SHA1: "local-sha1", SHA256: "local-sha256"}, LocalPath: "releases/local-1.0.0.tgz"},
+		}, nil)
+		releaseSource.DownloadReleaseCalls(func(_ context.Context, dir string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+			p := filepath.Join(dir, remote.Name+".tgz")
+			if err := os.WriteFile(p, []byte("some release contents"), 0o644); err != nil {
+				return component.Local{}, err
+			}
+			return component.Local{Lock: remote, LocalPath: p}, nil
+		})
+
+		lock = commands.NewLock(context.Background(), log.New(GinkgoWriter, "", 0), fs, localReleaseDirectory, multiReleaseSourceProvider.Spy)
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	JustBeforeEach(func() {
+		Expect(fsWriteYAML(fs, kilnfilePath, cargo.Kilnfile{})).To(Succeed())
+		Expect(fsWriteYAML(fs, kilnfileLockPath, kilnfileLock)).To(Succeed())
+	})
+
+	It("requires an action", func() {
+		Expect(lock.Execute(nil)).To(MatchError(ContainSubstring("missing lock action")))
+		Expect(lock.Execute([]string{"banana"})).To(MatchError(ContainSubstring(`unknown lock action "banana"`)))
+	})
+
+	Describe("upgrade-checksums", func() {
+		It("adds SHA256 checksums from local tarballs and downloads the rest", func() {
+			Expect(lock.Execute([]string{"upgrade-checksums", "--kilnfile", kilnfilePath, "--releases-directory", releasesDirectory})).To(Succeed())
+
+			var updated cargo.KilnfileLock
+			Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+			Expect(updated.Releases[0].SHA256).To(Equal("local-sha256"))
+			Expect(updated.Releases[1].SHA256).To(Equal(downloadedSHA256))
+			Expect(updated.Releases[2].SHA256).To(Equal("upgraded-sha256"))
+
+			Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(1))
+			_, dir, remote := releaseSource.DownloadReleaseArgsForCall(0)
+			Expect(remote).To(Equal(kilnfileLock.Releases[1]))
+			Expect(dir).NotTo(BeADirectory(), "it removes the temporary download directory")
+		})
+
+		When("--no-download is passed", func() {
+			It("only uses local tarballs", func() {
+				Expect(lock.Execute([]string{"upgrade-checksums", "--kilnfile", kilnfilePath, "--releases-directory", releasesDirectory, "--no-download"})).To(Succeed())
+
+				var updated cargo.KilnfileLock
+				Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+				Expect(updated.Releases[0].SHA256).To(Equal("local-sha256"))
+				Expect(updated.Releases[1].SHA256).To(BeEmpty())
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+			})
+		})
+
+		When("the downloaded release has a different SHA1", func() {
+			BeforeEach(func() {
+				releaseSource.DownloadReleaseCalls(func(_ context.Context, dir string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+					remote.SHA1 = "some-other-sha1"
+					return component.Local{Lock: remote, LocalPath: filepath.Join(dir, remote.Name+".tgz")}, nil
+				})
+			})
+
+			It("returns an error and keeps the other checksums", func() {
+				err := lock.Execute([]string{"upgrade-checksums", "--kilnfile", kilnfilePath, "--releases-directory", releasesDirectory})
+				Expect(err).To(MatchError(ContainSubstring("incorrect SHA1")))
+
+				var updated cargo.KilnfileLock
+				Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+				Expect(updated.Releases[0].SHA256).To(Equal("local-sha256"))
+				Expect(updated.Releases[1].SHA256).To(BeEmpty())
+			})
+		})
+
+		When("the download fails", func() {
+			BeforeEach(func() {
+				releaseSource.DownloadReleaseCalls(nil)
+				releaseSource.DownloadReleaseReturns(component.Local{}, errors.New("lemon"))
+			})
+
+			It("returns the error", func() {
+				err := lock.Execute([]string{"upgrade-checksums", "--kilnfile", kilnfilePath, "--releases-directory", releasesDirectory})
+				Expect(err).To(MatchError(ContainSubstring("lemon")))
+			})
+		})
+	})
+})
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// Mirror copies the releases in Kilnfile.lock to another release source and points the lock at the copies.
+type Mirror struct {
+	ctx                   context.Context
+	outLogger             *log.Logger
+	fs                    billy.Filesystem
+	mrsProvider           MultiReleaseSourceProvider
+	releaseUploaderFinder ReleaseUploaderFinder
+
+	Options struct {
+		flags.Standard
+
+		TargetID string        `long:"target"  required:"true" description:"the ID of the release source in the Kilnfile to copy the releases to"`
+		DryRun   bool          `long:"dry-run"                 description:"print the releases that would be copied without copying them or changing Kilnfile.lock"`
+		Timeout  time.Duration `long:"timeout"                 description:"maximum duration of each release source operation (for example 10m); unlimited when not set"`
+	}
+}
+
+func NewMirror(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, mrsProvider MultiReleaseSourceProvider, releaseUploaderFinder ReleaseUploaderFinder) Mirror {
+	return Mirror{
+		ctx:                   ctx,
+		outLogger:             outLogger,
+		fs:                    fs,
+		mrsProvider:           mrsProvider,
+		releaseUploaderFinder: releaseUploaderFinder,
+	}
+}
+
+func (cmd Mirror) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+
+	kilnfile, kilnfileLock, err := cmd.Options.Standard.LoadKilnfiles(cmd.fs, nil)
+	if err != nil {
+		return fmt.Errorf("error loading Kilnfiles: %w", err)
+	}
+
+	releaseUploader, err := cmd.releaseUploaderFinder(kilnfile, cmd.Options.TargetID)
+	if err != nil {
+		return fmt.Errorf("error finding release source: %w", err)
+	}
+
+	var (
+		releaseSource component.MultiReleaseSource
+		changed       int
+	)
+	for i := range kilnfileLock.Releases {
+		lock := &kilnfileLock.Releases[i]
+		if lock.RemoteSource == cmd.Options.TargetID {
+			cmd.outLogger.Printf("%s %s: already in %s", lock.Name, lock.Version, cmd.Options.TargetID)
+			continue
+		}
+
+		existing, found, err := cmd.findExisting(releaseUploader, *lock)
+		if err != nil {
+			err = fmt.Errorf("%s %s: %w", lock.Name, lock.Version, err)
+			return cmd.saveAfterError(kilnfileLock, changed, err)
+		}
+		if found {
+			cmd.outLogger.Printf("%s %s: found in %s at %s", lock.Name, lock.Version, cmd.Options.TargetID, existing.RemotePath)
+			if !cmd.Options.DryRun {
+				lock.RemoteSource, lock.RemotePath = cmd.Options.TargetID, existing.RemotePath
+				changed++
+			}
+			continue
+		}
+
+		if cmd.Options.DryRun {
+			cmd.outLogger.Printf("%s %s: would copy from %s to %s", lock.Name, lock.Version, lock.RemoteSource, cmd.Options.TargetID)
+			continue
+		}
+
+		if releaseSource == nil {
+			releaseSource = cmd.mrsProvider(kilnfile, false)
+		}
+		remotePath, err := cmd.copyRelease(releaseSource, releaseUploader, *lock)
+		if err != nil {
+			err = fmt.Errorf("%s %s: %w", lock.Name, lock.Version, err)
+			return cmd.saveAfterError(kilnfileLock, changed, err)
+		}
+		cmd.outLogger.Printf("%s %s: copied from %s to %s at %s", lock.Name, lock.Version, lock.RemoteSource, cmd.Options.TargetID, remotePath)
+		lock.RemoteSource, lock.RemotePath = cmd.Options.TargetID, remotePath
+		changed++
+	}
+
+	if changed == 0 {
+		return nil
+	}
+	return cmd.Options.Standard.SaveKilnfileLock(cmd.fs, kilnfileLock)
+}
+
+// saveAfterError keeps the lock changes for the releases mirrored before err so a later run
+// does not copy them again.
+func (cmd Mirror) saveAfterError(kilnfileLock cargo.KilnfileLock, changed int, err error) error {
+	if changed > 0 {
+		if saveErr := cmd.Options.Standard.SaveKilnfileLock(cmd.fs, kilnfileLock); saveErr != nil {
+			return fmt.Errorf("%w (failed to save Kilnfile.lock: %s)", err, saveErr)
+		}
+	}
+	return err
+}
+
+func (cmd Mirror) findExisting(releaseUploader component.ReleaseUploader, lock cargo.BOSHReleaseTarballLock) (cargo.BOSHReleaseTarballLock, bool, error) {
+	ctx, cancel := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancel()
+	existing, err := releaseUploader.GetMatchedRelease(ctx, cargo.BOSHReleaseTarballSpecification{
+		Name:            lock.Name,
+		Version:         lock.Version,
+		StemcellOS:      lock.StemcellOS,
+		StemcellVersion: lock.StemcellVersion,
+	})
+	if err != nil {
+		if component.IsErrNotFound(err) {
+			return cargo.BOSHReleaseTarballLock{}, false, nil
+		}
+		return cargo.BOSHReleaseTarballLock{}, false, fmt.Errorf("error checking %s for the release: %w", cmd.Options.TargetID, err)
+	}
+	if existing.SHA1 != "" && lock.SHA1 != "" && existing.SHA1 != lock.SHA1 {
+		return cargo.BOSHReleaseTarballLock{}, false, fmt.Errorf("release in %s has an incorrect SHA1 - expected %q, got %q", cmd.Options.TargetID, lock.SHA1, existing.SHA1)
+	}
+	return existing, true, nil
+}
+
+// copyRelease downloads the release into a temporary directory, verifies its checksums, and
+// uploads it. It returns the remote path of the uploaded release.
+func (cmd Mirror) copyRelease(releaseSource component.MultiReleaseSource, releaseUploader component.ReleaseUploader, lock cargo.BOSHReleaseTarballLock) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "kiln-mirror-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	downloadCtx, cancelDownload := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancelDownload()
+	local, err := releaseSource.DownloadRelease(downloadCtx, tmpDir, lock)
+	if err != nil {
+		return "", fmt.Errorf("failed to download release: %w", err)
+	}
+	if lock.SHA1 != "" && local.Lock.SHA1 != lock.SHA1 {
+		return "", fmt.Errorf("downloaded release had an incorrect SHA1 - expected %q, got %q", lock.SHA1, local.Lock.SHA1)
+	}
+	if lock.SHA256 != "" {
+		sum, err := component.CalculateSHA256Sum(local.LocalPath, osfs.New(""))
+		if err != nil {
+			return "", err
+		}
+		if sum != lock.SHA256 {
+			return "", fmt.Errorf("downloaded release had an incorrect SHA256 - expected %q, got %q", lock.SHA256, sum)
+		}
+	}
+
+	file, err := os.Open(local.LocalPath)
+	if err != nil {
+		return "", err
+	}
+	defer closeAndIgnoreError(file)
+
+	uploadCtx, cancelUpload := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancelUpload()
+	uploaded, err := releaseUploader.UploadRelease(uploadCtx, cargo.BOSHReleaseTarballSpecification{
+		Name:            lock.Name,
+		Version:         lock.Version,
+		StemcellOS:      lock.StemcellOS,
+		StemcellVersion: lock.StemcellVersion,
+	}, file)
+	if err != nil {
+		return "", fmt.Errorf("error uploading the release: %w", err)
+	}
+	if uploaded.SHA1 != "" && lock.SHA1 != "" && uploaded.SHA1 != lock.SHA1 {
+		return "", fmt.Errorf("uploaded release has an incorrect SHA1 - expected %q, got %q", lock.SHA1, uploaded.SHA1)
+	}
+	return uploaded.RemotePath, nil
+}
+
+func (cmd Mirror) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Copies every release in Kilnfile.lock from its release source to the target release source and updates remote_source and remote_path in Kilnfile.lock. Releases the target already has are not copied again.",
+		ShortDescription: "copies locked releases to another release source",
+		Flags:            cmd.Options,
+	}
+}
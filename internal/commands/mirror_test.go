@@ -0,0 +1,167 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	fetcherFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("mirror", func() {
+	const (
+		kilnfilePath     = "Kilnfile"
+		kilnfileLockPath = kilnfilePath + ".lock"
+		targetID         = "private-bucket"
+	)
+
+	var (
+		fs                         billy.Filesystem
+		multiReleaseSourceProvider *commandsFakes.MultiReleaseSourceProvider
+		releaseSource              *fetcherFakes.MultiReleaseSource
+		releaseUploaderFinder      *commandsFakes.ReleaseUploaderFinder
+		releaseUploader            *fetcherFakes.ReleaseUploader
+		kilnfileLock               cargo.KilnfileLock
+		uploadedContents           []string
+
+		mirror commands.Mirror
+	)
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		uploadedContents = nil
+
+		releaseSource = new(fetcherFakes.MultiReleaseSource)
+		multiReleaseSourceProvider = new(commandsFakes.MultiReleaseSourceProvider)
+		multiReleaseSourceProvider.Returns(releaseSource)
+		releaseUploader = new(fetcherFakes.ReleaseUploader)
+		releaseUploaderFinder = new(commandsFakes.ReleaseUploaderFinder)
+		releaseUploaderFinder.Returns(releaseUploader, nil)
+
+		kilnfileLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "1.0.0", SHA1: "bpm-sha1", RemoteSource: "bosh.io", RemotePath: "https://bosh.io/bpm"},
+				{Name: "routing", Version: "2.0.0", SHA1: "routing-sha1", RemoteSource: "github", RemotePath: "https://github.com/routing.tgz"},
+				{Name: "uaa", Version: "3.0.0", SHA1: "uaa-sha1", RemoteSource: targetID, RemotePath: "uaa-3.0.0.tgz"},
+			},
+		}
+
+		releaseUploader.GetMatchedReleaseCalls(func(_ context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+			if spec.Name == "routing" {
+				return cargo.BOSHReleaseTarballLock{Name: spec.Name, Version: spec.Version, RemoteSource: targetID, RemotePath: "routing-2.0.0.tgz"}, nil
+			}
+			return cargo.BOSHReleaseTarballLock{}, component.ErrNotFound
+		})
+		releaseSource.DownloadReleaseCalls(func(_ context.Context, dir string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+			p := filepath.Join(dir, remote.Name+".tgz")
+			if err := os.WriteFile(p, []byte(remote.Name+" contents"), 0o644); err != nil {
+				return component.Local{}, err
+			}
+			return component.Local{Lock: remote, LocalPath: p}, nil
+		})
+		releaseUploader.UploadReleaseCalls(func(_ context.Context, spec cargo.BOSHReleaseTarballSpecification, r io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+			buf, err := io.ReadAll(r)
+			if err != nil {
+				return cargo.BOSHReleaseTarballLock{}, err
+			}
+			uploadedContents = append(uploadedContents, string(buf))
+			return cargo.BOSHReleaseTarballLock{Name: spec.Name, Version: spec.Version, RemoteSource: targetID, RemotePath: spec.Name + "-" + spec.Version + ".tgz"}, nil
+		})
+
+		mirror = commands.NewMirror(context.Background(), log.New(GinkgoWriter, "", 0), fs, multiReleaseSourceProvider.Spy, releaseUploaderFinder.Spy)
+	})
+
+	JustBeforeEach(func() {
+		Expect(fsWriteYAML(fs, kilnfilePath, cargo.Kilnfile{})).To(Succeed())
+		Expect(fsWriteYAML(fs, kilnfileLockPath, kilnfileLock)).To(Succeed())
+	})
+
+	It("copies releases the target does not have and updates the lock", func() {
+		Expect(mirror.Execute([]string{"--kilnfile", kilnfilePath, "--target", targetID})).To(Succeed())
+
+		_, sourceID := releaseUploaderFinder.ArgsForCall(0)
+		Expect(sourceID).To(Equal(targetID))
+
+		Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(1))
+		_, _, downloaded := releaseSource.DownloadReleaseArgsForCall(0)
+		Expect(downloaded).To(Equal(kilnfileLock.Releases[0]))
+		Expect(uploadedContents).To(Equal([]string{"bpm contents"}))
+
+		var updated cargo.KilnfileLock
+		Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+		Expect(updated.Releases).To(Equal([]cargo.BOSHReleaseTarballLock{
+			{Name: "bpm", Version: "1.0.0", SHA1: "bpm-sha1", RemoteSource: targetID, RemotePath: "bpm-1.0.0.tgz"},
+			{Name: "routing", Version: "2.0.0", SHA1: "routing-sha1", RemoteSource: targetID, RemotePath: "routing-2.0.0.tgz"},
+			{Name: "uaa", Version: "3.0.0", SHA1: "uaa-sha1", RemoteSource: targetID, RemotePath: "uaa-3.0.0.tgz"},
+		}))
+	})
+
+	When("--dry-run is passed", func() {
+		It("does not copy releases or change the lock", func() {
+			Expect(mirror.Execute([]string{"--kilnfile", kilnfilePath, "--target", targetID, "--dry-run"})).To(Succeed())
+
+			Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+			Expect(releaseUploader.UploadReleaseCallCount()).To(Equal(0))
+
+			var updated cargo.KilnfileLock
+			Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+			Expect(updated).To(Equal(kilnfileLock))
+		})
+	})
+
+	When("the downloaded release has a different SHA1", func() {
+		BeforeEach(func() {
+			releaseSource.DownloadReleaseCalls(func(_ context.Context, dir string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+				return component.Local{Lock: remote.WithSHA1("some-other-sha1"), LocalPath: filepath.Join(dir, "bpm.tgz")}, nil
+			})
+		})
+
+		It("does not upload it", func() {
+			err := mirror.Execute([]string{"--kilnfile", kilnfilePath, "--target", targetID})
+			Expect(err).To(MatchError(ContainSubstring("incorrect SHA1")))
+			Expect(releaseUploader.UploadReleaseCallCount()).To(Equal(0))
+		})
+	})
+
+	When("the release in the target has a different SHA1", func() {
+		BeforeEach(func() {
+			releaseUploader.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{SHA1: "some-other-sha1"}, nil)
+			releaseUploader.GetMatchedReleaseCalls(nil)
+		})
+
+		It("returns an error", func() {
+			err := mirror.Execute([]string{"--kilnfile", kilnfilePath, "--target", targetID})
+			Expect(err).To(MatchError(ContainSubstring("incorrect SHA1")))
+		})
+	})
+
+	When("an upload fails", func() {
+		BeforeEach(func() {
+			kilnfileLock.Releases[0], kilnfileLock.Releases[1] = kilnfileLock.Releases[1], kilnfileLock.Releases[0]
+			releaseUploader.UploadReleaseCalls(nil)
+			releaseUploader.UploadReleaseReturns(cargo.BOSHReleaseTarballLock{}, errors.New("lemon"))
+		})
+
+		It("keeps the lock changes for the releases before it", func() {
+			err := mirror.Execute([]string{"--kilnfile", kilnfilePath, "--target", targetID})
+			Expect(err).To(MatchError(ContainSubstring("lemon")))
+
+			var updated cargo.KilnfileLock
+			Expect(fsReadYAML(fs, kilnfileLockPath, &updated)).To(Succeed())
+			Expect(updated.Releases[0].RemoteSource).To(Equal(targetID))
+			Expect(updated.Releases[1].RemoteSource).To(Equal("bosh.io"))
+		})
+	})
+})
@@ -29,10 +29,10 @@ type ReleaseNotes struct {
 	Options struct {
 		ReleaseDate  string `long:"release-date" short:"d" description:"release date of the tile"`
 		TemplateName string `long:"template"     short:"t" description:"path to template"`
-		GithubToken  string `long:"github-token" short:"g" description:"auth token for fetching issues merged between releases" env:"GITHUB_TOKEN"`
 		Kilnfile     string `long:"kilnfile"     short:"k" description:"path to Kilnfile"`
 		DocsFile     string `long:"update-docs"  short:"u" description:"path to docs file to update"`
 		Window       string `long:"window"       short:"w" description:"GA window for release notes" default:"ga"`
+		GitHubOptions
 		notes.IssuesQuery
 		notes.TrainstatQuery
 	}
@@ -84,8 +84,15 @@ func (r ReleaseNotes) Execute(args []string) error {
 	}
 
 	var client *github.Client
-	if r.Options.GithubToken != "" {
-		client = gh.Client(ctx, r.Options.GithubToken)
+	if r.Options.hasCredentials() {
+		config, err := r.Options.GitHubOptions.config()
+		if err != nil {
+			return err
+		}
+		client, err = config.Client(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
 	trainstatClient := notes.NewTrainstatClient(r.Options.TrainstatQuery.TrainstatURL)
@@ -170,7 +177,7 @@ func (r *ReleaseNotes) initRepo() error {
 		return fmt.Errorf("release-notes must be run from the root of the repository (use --kilnfile flag to specify which tile to build)")
 	}
 
-	repoOwner, repoName, err := getGithubRemoteRepoOwnerAndName(repo)
+	repoOwner, repoName, err := getGithubRemoteRepoOwnerAndName(repo, gh.Config{Host: r.Options.GithubHost}.HostName())
 	if err != nil {
 		return err
 	}
@@ -217,11 +224,11 @@ func (r ReleaseNotes) checkInputs(nonFlagArgs []string) error {
 		}
 	}
 
-	if r.Options.GithubToken == "" &&
+	if !r.Options.hasCredentials() &&
 		(r.Options.IssueMilestone != "" ||
 			len(r.Options.IssueIDs) > 0 ||
 			len(r.Options.IssueLabels) > 0) {
-		return errors.New("github-token (env: GITHUB_TOKEN) must be set to interact with the github api")
+		return errors.New("github-token (env: GITHUB_TOKEN) or the github-app flags must be set to interact with the github api")
 	}
 
 	if r.Options.DocsFile != "" {
@@ -253,7 +260,7 @@ func (r ReleaseNotes) parseReleaseDate() (time.Time, error) {
 	return releaseDate, nil
 }
 
-func getGithubRemoteRepoOwnerAndName(repo *git.Repository) (string, string, error) {
+func getGithubRemoteRepoOwnerAndName(repo *git.Repository, githubHost string) (string, string, error) {
 	var remoteURL string
 	remote, err := repo.Remote("origin")
 	if err != nil {
@@ -261,7 +268,7 @@ func getGithubRemoteRepoOwnerAndName(repo *git.Repository) (string, string, erro
 	}
 	config := remote.Config()
 	for _, u := range config.URLs {
-		if !strings.Contains(u, "github.com") {
+		if !strings.Contains(u, githubHost) {
 			continue
 		}
 		remoteURL = u
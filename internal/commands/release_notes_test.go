@@ -243,7 +243,7 @@ func Test_getGithubRemoteRepoOwnerAndName(t *testing.T) {
 				"https://github.com/pivotal-cf/kiln",
 			},
 		})
-		o, r, err := getGithubRemoteRepoOwnerAndName(repo)
+		o, r, err := getGithubRemoteRepoOwnerAndName(repo, "github.com")
 		please.Expect(err).NotTo(HaveOccurred())
 		please.Expect(o).To(Equal("pivotal-cf"))
 		please.Expect(r).To(Equal("kiln"))
@@ -259,17 +259,36 @@ func Test_getGithubRemoteRepoOwnerAndName(t *testing.T) {
 				"git@github.com:pivotal-cf/kiln.git",
 			},
 		})
-		o, r, err := getGithubRemoteRepoOwnerAndName(repo)
+		o, r, err := getGithubRemoteRepoOwnerAndName(repo, "github.com")
 		please.Expect(err).NotTo(HaveOccurred())
 		please.Expect(o).To(Equal("pivotal-cf"))
 		please.Expect(r).To(Equal("kiln"))
 	})
 
+	t.Run("when there is a github enterprise remote", func(t *testing.T) {
+		please := NewWithT(t)
+
+		repo, _ := git.Init(memory.NewStorage(), memfs.New())
+		_, _ = repo.CreateRemote(&config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{
+				"git@github.example.com:platform/some-tile.git",
+			},
+		})
+		o, r, err := getGithubRemoteRepoOwnerAndName(repo, "github.example.com")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(o).To(Equal("platform"))
+		please.Expect(r).To(Equal("some-tile"))
+
+		_, _, err = getGithubRemoteRepoOwnerAndName(repo, "github.com")
+		please.Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+
 	t.Run("when there are no remotes", func(t *testing.T) {
 		please := NewWithT(t)
 
 		repo, _ := git.Init(memory.NewStorage(), memfs.New())
-		_, _, err := getGithubRemoteRepoOwnerAndName(repo)
+		_, _, err := getGithubRemoteRepoOwnerAndName(repo, "github.com")
 		please.Expect(err).To(MatchError(ContainSubstring("not found")))
 	})
 
@@ -289,7 +308,7 @@ func Test_getGithubRemoteRepoOwnerAndName(t *testing.T) {
 				"git@github.com:pivotal-cf/kiln.git",
 			},
 		})
-		o, _, err := getGithubRemoteRepoOwnerAndName(repo)
+		o, _, err := getGithubRemoteRepoOwnerAndName(repo, "github.com")
 		please.Expect(err).NotTo(HaveOccurred())
 		please.Expect(o).To(Equal("pivotal-cf"), "it uses the remote with name 'origin'")
 	})
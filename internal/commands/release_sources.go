@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// ReleaseSourceListProvider returns every release source configured in a Kilnfile.
+type ReleaseSourceListProvider func(cargo.Kilnfile) component.ReleaseSourceList
+
+// ReleaseSources groups the commands that operate on the release sources of a Kilnfile.
+// The first argument selects the action.
+type ReleaseSources struct {
+	actions map[string]jhanda.Command
+}
+
+var _ jhanda.Command = ReleaseSources{}
+
+func NewReleaseSources(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, releaseSources ReleaseSourceListProvider) ReleaseSources {
+	return ReleaseSources{
+		actions: map[string]jhanda.Command{
+			"check": NewReleaseSourcesCheck(ctx, outLogger, fs, releaseSources),
+		},
+	}
+}
+
+func (cmd ReleaseSources) Execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing release-sources action: expected one of %s", strings.Join(cmd.actionNames(), ", "))
+	}
+	action, ok := cmd.actions[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown release-sources action %q: expected one of %s", args[0], strings.Join(cmd.actionNames(), ", "))
+	}
+	return action.Execute(args[1:])
+}
+
+func (cmd ReleaseSources) actionNames() []string {
+	names := make([]string, 0, len(cmd.actions))
+	for name := range cmd.actions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (cmd ReleaseSources) Usage() jhanda.Usage {
+	var description strings.Builder
+	description.WriteString("Operates on the release sources configured in the Kilnfile. Run \"kiln release-sources ACTION --help\" for the flags of an action.\n\nActions:\n")
+	for _, name := range cmd.actionNames() {
+		_, _ = fmt.Fprintf(&description, "  %-20s %s\n", name, cmd.actions[name].Usage().ShortDescription)
+	}
+	return jhanda.Usage{
+		Description:      description.String(),
+		ShortDescription: "operates on the release sources in the Kilnfile",
+	}
+}
+
+// ReleaseSourcesCheck checks that each release source in the Kilnfile can be reached with
+// the configured credentials and that kiln may read, list and (for release sources that
+// support it) upload releases.
+type ReleaseSourcesCheck struct {
+	ctx            context.Context
+	outLogger      *log.Logger
+	fs             billy.Filesystem
+	releaseSources ReleaseSourceListProvider
+
+	Options struct {
+		flags.Standard
+
+		JSON    bool          `long:"json"    description:"print the results as JSON"`
+		Timeout time.Duration `long:"timeout" description:"maximum duration of each check" default:"1m"`
+	}
+}
+
+func NewReleaseSourcesCheck(ctx context.Context, outLogger *log.Logger, fs billy.Filesystem, releaseSources ReleaseSourceListProvider) *ReleaseSourcesCheck {
+	return &ReleaseSourcesCheck{
+		ctx:            ctx,
+		outLogger:      outLogger,
+		fs:             fs,
+		releaseSources: releaseSources,
+	}
+}
+
+const (
+	releaseSourceCheckRead   = "read"
+	releaseSourceCheckList   = "list"
+	releaseSourceCheckUpload = "upload"
+
+	releaseSourceCheckStatusOK      = "ok"
+	releaseSourceCheckStatusFailed  = "failed"
+	releaseSourceCheckStatusSkipped = "skipped"
+)
+
+type releaseSourceCheckResult struct {
+	ID     string                    `json:"id"`
+	Type   string                    `json:"type"`
+	Checks []releaseSourceCheckEntry `json:"checks"`
+}
+
+type releaseSourceCheckEntry struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+func (cmd *ReleaseSourcesCheck) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&cmd.Options, args, cmd.fs.Stat)
+	if err != nil {
+		return err
+	}
+	kilnfile, kilnfileLock, err := cmd.Options.Standard.LoadKilnfiles(cmd.fs, nil)
+	if err != nil {
+		return fmt.Errorf("error loading Kilnfiles: %w", err)
+	}
+
+	sources := cmd.releaseSources(kilnfile)
+	results := make([]releaseSourceCheckResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = cmd.check(source, kilnfile, kilnfileLock)
+		}()
+	}
+	wg.Wait()
+
+	if err := cmd.print(results); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, result := range results {
+		for _, check := range result.Checks {
+			if check.Status == releaseSourceCheckStatusFailed {
+				failed = append(failed, result.ID+" ("+check.Name+")")
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d release source checks failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (cmd *ReleaseSourcesCheck) check(source component.ReleaseSource, kilnfile cargo.Kilnfile, kilnfileLock cargo.KilnfileLock) releaseSourceCheckResult {
+	config := source.Configuration()
+	result := releaseSourceCheckResult{ID: config.ID, Type: config.Type}
+
+	if component.IsOffline(cmd.ctx) && component.NeedsNetwork(config) {
+		for _, name := range []string{releaseSourceCheckRead, releaseSourceCheckList, releaseSourceCheckUpload} {
+			result.Checks = append(result.Checks, releaseSourceCheckEntry{Name: name, Status: releaseSourceCheckStatusSkipped, Detail: "kiln is offline"})
+		}
+		return result
+	}
+
+	spec, locked := releaseSourceCheckSpec(config.ID, kilnfile, kilnfileLock)
+
+	result.Checks = append(result.Checks, cmd.run(releaseSourceCheckRead, func(ctx context.Context) (string, error) {
+		_, err := source.GetMatchedRelease(ctx, spec)
+		switch {
+		case err == nil:
+			return fmt.Sprintf("found %s %s", spec.Name, spec.Version), nil
+		case component.IsErrNotFound(err) && locked:
+			return "", fmt.Errorf("locked release %s %s was not found", spec.Name, spec.Version)
+		case component.IsErrNotFound(err):
+			return "no locked releases; looked up a release that does not exist", nil
+		default:
+			return "", err
+		}
+	}))
+
+	listSpec := spec
+	listSpec.Version = ""
+	if locked {
+		if s, err := kilnfile.BOSHReleaseTarballSpecification(spec.Name); err == nil {
+			listSpec.Version = s.Version
+		}
+	}
+	result.Checks = append(result.Checks, cmd.run(releaseSourceCheckList, func(ctx context.Context) (string, error) {
+		lock, err := source.FindReleaseVersion(ctx, listSpec, true)
+		switch {
+		case err == nil:
+			return fmt.Sprintf("highest matching version of %s is %s", listSpec.Name, lock.Version), nil
+		case component.IsErrNotFound(err):
+			return fmt.Sprintf("no versions of %s found", listSpec.Name), nil
+		default:
+			return "", err
+		}
+	}))
+
+	uploadCheck := releaseSourceCheckEntry{Name: releaseSourceCheckUpload, Status: releaseSourceCheckStatusSkipped}
+	switch uploader := source.(type) {
+	case component.UploadChecker:
+		uploadCheck = cmd.run(releaseSourceCheckUpload, func(ctx context.Context) (string, error) {
+			return "dry run", uploader.CheckUpload(ctx)
+		})
+	case component.ReleaseUploader:
+		uploadCheck.Detail = "can not check without uploading a release"
+	default:
+		uploadCheck.Detail = "release source does not support uploads"
+	}
+	result.Checks = append(result.Checks, uploadCheck)
+
+	return result
+}
+
+func (cmd *ReleaseSourcesCheck) run(name string, fn func(ctx context.Context) (string, error)) releaseSourceCheckEntry {
+	ctx, cancel := releaseSourceContext(cmd.ctx, cmd.Options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	entry := releaseSourceCheckEntry{
+		Name:      name,
+		Status:    releaseSourceCheckStatusOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Detail:    detail,
+	}
+	if err != nil {
+		entry.Status = releaseSourceCheckStatusFailed
+		entry.Detail = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			entry.Detail = fmt.Sprintf("timed out after %s", cmd.Options.Timeout)
+		}
+	}
+	return entry
+}
+
+// releaseSourceCheckSpec returns the specification of the first release locked to the
+// release source. When no release is locked to it, a release that does not exist is used
+// so the checks still make requests.
+func releaseSourceCheckSpec(sourceID string, kilnfile cargo.Kilnfile, kilnfileLock cargo.KilnfileLock) (cargo.BOSHReleaseTarballSpecification, bool) {
+	for _, lock := range kilnfileLock.Releases {
+		if lock.RemoteSource != sourceID {
+			continue
+		}
+		spec, err := kilnfile.BOSHReleaseTarballSpecification(lock.Name)
+		if err != nil {
+			spec = cargo.BOSHReleaseTarballSpecification{Name: lock.Name}
+		}
+		spec.Version = lock.Version
+		spec.StemcellOS = lock.StemcellOS
+		spec.StemcellVersion = lock.StemcellVersion
+		return spec, true
+	}
+	return cargo.BOSHReleaseTarballSpecification{
+		Name:            "kiln-release-sources-check",
+		Version:         "0.0.0",
+		StemcellOS:      kilnfileLock.Stemcell.OS,
+		StemcellVersion: kilnfileLock.Stemcell.Version,
+	}, false
+}
+
+func (cmd *ReleaseSourcesCheck) print(results []releaseSourceCheckResult) error {
+	if cmd.Options.JSON {
+		buf, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.outLogger.Println(string(buf))
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.outLogger.Writer(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTYPE\tCHECK\tSTATUS\tLATENCY\tDETAIL")
+	for _, result := range results {
+		for _, check := range result.Checks {
+			latency := "-"
+			if check.Status != releaseSourceCheckStatusSkipped {
+				latency = (time.Duration(check.LatencyMS) * time.Millisecond).String()
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.ID, result.Type, check.Name, check.Status, latency, check.Detail)
+		}
+	}
+	return w.Flush()
+}
+
+func (cmd *ReleaseSourcesCheck) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Checks that each release source in the Kilnfile is reachable with the configured credentials and that kiln may read and list releases. Release sources that support uploads are checked with a dry run that does not upload a release. Exits with an error when any check fails.",
+		ShortDescription: "checks access to the release sources in the Kilnfile",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,182 @@
+package commands_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/component"
+	fetcherFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("release-sources check", func() {
+	var (
+		fs                   billy.Filesystem
+		tmpDir, kilnfilePath string
+		rootDir              string
+		kilnfileLock         cargo.KilnfileLock
+		output               *bytes.Buffer
+		remoteSource         *fetcherFakes.ReleaseSource
+		ctx                  context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "release-sources-check")
+		Expect(err).NotTo(HaveOccurred())
+		fs = osfs.New("")
+		kilnfilePath = filepath.Join(tmpDir, "Kilnfile")
+		rootDir = filepath.Join(tmpDir, "share")
+		Expect(os.MkdirAll(filepath.Join(rootDir, "bpm"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(rootDir, "bpm", "bpm-1.2.0.tgz"), []byte("bpm"), 0o644)).To(Succeed())
+		output = new(bytes.Buffer)
+		ctx = context.Background()
+
+		remoteSource = new(fetcherFakes.ReleaseSource)
+		remoteSource.ConfigurationReturns(cargo.ReleaseSourceConfig{ID: "bosh.io", Type: component.ReleaseSourceTypeBOSHIO})
+		remoteSource.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
+		remoteSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{Name: "kiln-release-sources-check", Version: "1.0.0"}, nil)
+
+		kilnfileLock = cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "1.2.0", RemoteSource: "share", RemotePath: "bpm/bpm-1.2.0.tgz"},
+			},
+		}
+		Expect(fsWriteYAML(fs, kilnfilePath, cargo.Kilnfile{
+			ReleaseSources: []cargo.ReleaseSourceConfig{
+				{ID: "share", Type: component.ReleaseSourceTypeDirectory, Root: rootDir, PathTemplate: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"},
+				{ID: "bosh.io", Type: component.ReleaseSourceTypeBOSHIO},
+			},
+			Releases: []cargo.BOSHReleaseTarballSpecification{{Name: "bpm", Version: "~1"}},
+		})).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		Expect(fsWriteYAML(fs, kilnfilePath+".lock", kilnfileLock)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	check := func(args ...string) error {
+		releaseSources := func(kilnfile cargo.Kilnfile) component.ReleaseSourceList {
+			return component.ReleaseSourceList{
+				component.NewDirectoryReleaseSource(kilnfile.ReleaseSources[0], log.New(GinkgoWriter, "", 0)),
+				remoteSource,
+			}
+		}
+		cmd := commands.NewReleaseSources(ctx, log.New(output, "", 0), fs, releaseSources)
+		return cmd.Execute(append([]string{"check", "--kilnfile", kilnfilePath}, args...))
+	}
+
+	checkJSON := func() ([]map[string]any, error) {
+		err := check("--json")
+		var results []map[string]any
+		Expect(json.Unmarshal(output.Bytes(), &results)).To(Succeed())
+		return results, err
+	}
+
+	It("checks each release source", func() {
+		results, err := checkJSON()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(results).To(HaveLen(2))
+		Expect(results[0]).To(HaveKeyWithValue("id", "share"))
+		Expect(results[0]["checks"]).To(ConsistOf(
+			And(HaveKeyWithValue("name", "read"), HaveKeyWithValue("status", "ok"), HaveKeyWithValue("detail", "found bpm 1.2.0")),
+			And(HaveKeyWithValue("name", "list"), HaveKeyWithValue("status", "ok"), HaveKeyWithValue("detail", "highest matching version of bpm is 1.2.0")),
+			And(HaveKeyWithValue("name", "upload"), HaveKeyWithValue("status", "ok"), HaveKey("latency_ms")),
+		))
+		Expect(results[1]).To(HaveKeyWithValue("id", "bosh.io"))
+		Expect(results[1]["checks"]).To(ContainElement(And(HaveKeyWithValue("name", "upload"), HaveKeyWithValue("status", "skipped"))))
+
+		_, spec := remoteSource.GetMatchedReleaseArgsForCall(0)
+		Expect(spec.Name).To(Equal("kiln-release-sources-check"))
+		_, _, noDownload := remoteSource.FindReleaseVersionArgsForCall(0)
+		Expect(noDownload).To(BeTrue())
+	})
+
+	It("prints a table", func() {
+		Expect(check()).To(Succeed())
+		Expect(output.String()).To(MatchRegexp(`ID\s+TYPE\s+CHECK\s+STATUS\s+LATENCY\s+DETAIL`))
+		Expect(output.String()).To(MatchRegexp(`share\s+directory\s+upload\s+ok\s+\S+\s+dry run`))
+	})
+
+	When("a locked release is missing", func() {
+		BeforeEach(func() {
+			kilnfileLock.Releases[0].Version = "1.1.0"
+		})
+
+		It("fails the read check", func() {
+			results, err := checkJSON()
+			Expect(err).To(MatchError(ContainSubstring("share (read)")))
+			Expect(results[0]["checks"]).To(ContainElement(And(
+				HaveKeyWithValue("name", "read"),
+				HaveKeyWithValue("status", "failed"),
+				HaveKeyWithValue("detail", "locked release bpm 1.1.0 was not found"),
+			)))
+		})
+	})
+
+	When("a release source can not be reached", func() {
+		BeforeEach(func() {
+			remoteSource.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{}, errors.New("dial tcp: connection refused"))
+			remoteSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{}, errors.New("dial tcp: connection refused"))
+		})
+
+		It("reports the error and fails", func() {
+			results, err := checkJSON()
+			Expect(err).To(MatchError(ContainSubstring("2 release source checks failed: bosh.io (read), bosh.io (list)")))
+			Expect(results[1]["checks"]).To(ContainElement(And(HaveKeyWithValue("name", "read"), HaveKeyWithValue("detail", "dial tcp: connection refused"))))
+		})
+	})
+
+	When("uploads are not allowed", func() {
+		BeforeEach(func() {
+			Expect(os.Chmod(rootDir, 0o555)).To(Succeed())
+			if f, err := os.CreateTemp(rootDir, ""); err == nil {
+				_ = f.Close()
+				Skip("the tests are running with permissions that ignore the directory mode")
+			}
+		})
+
+		AfterEach(func() {
+			Expect(os.Chmod(rootDir, 0o755)).To(Succeed())
+		})
+
+		It("fails the upload check", func() {
+			err := check()
+			Expect(err).To(MatchError(ContainSubstring("share (upload)")))
+		})
+	})
+
+	When("kiln is offline", func() {
+		BeforeEach(func() {
+			ctx = component.ContextWithOffline(context.Background())
+		})
+
+		It("skips release sources that need network access", func() {
+			results, err := checkJSON()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[1]["checks"]).To(HaveEach(And(HaveKeyWithValue("status", "skipped"), HaveKeyWithValue("detail", "kiln is offline"))))
+			Expect(remoteSource.GetMatchedReleaseCallCount()).To(Equal(0))
+		})
+	})
+
+	It("requires an action", func() {
+		err := commands.NewReleaseSources(ctx, log.New(output, "", 0), fs, nil).Execute(nil)
+		Expect(err).To(MatchError("missing release-sources action: expected one of check"))
+	})
+})
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var schemaDocuments = map[string]func() *cargo.Schema{
+	"kilnfile":           cargo.KilnfileSchema,
+	"kilnfile-lock":      cargo.KilnfileLockSchema,
+	"release-source":     cargo.ReleaseSourceConfigSchema,
+	"bake-configuration": cargo.BakeConfigurationSchema,
+}
+
+type Schema struct {
+	outLogger *log.Logger
+
+	Options struct {
+		Document string `short:"d" long:"document" default:"kilnfile" description:"document to describe: kilnfile, kilnfile-lock, release-source, or bake-configuration"`
+	}
+}
+
+func NewSchema(outLogger *log.Logger) Schema {
+	return Schema{outLogger: outLogger}
+}
+
+func (cmd Schema) Execute(args []string) error {
+	if _, err := jhanda.Parse(&cmd.Options, args); err != nil {
+		return err
+	}
+	schema, ok := schemaDocuments[cmd.Options.Document]
+	if !ok {
+		names := make([]string, 0, len(schemaDocuments))
+		for name := range schemaDocuments {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown document %q (expected one of %s)", cmd.Options.Document, strings.Join(names, ", "))
+	}
+	buf, err := json.MarshalIndent(schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	cmd.outLogger.Println(string(buf))
+	return nil
+}
+
+func (cmd Schema) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prints the JSON Schema of a Kilnfile, Kilnfile.lock, release source, or bake configuration. Editors with a YAML language server can use it to complete and check Kilnfiles.",
+		ShortDescription: "prints the JSON Schema of Kilnfile documents",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,42 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+)
+
+var _ = Describe("schema", func() {
+	var output strings.Builder
+
+	BeforeEach(func() {
+		output.Reset()
+	})
+
+	It("prints the Kilnfile schema by default", func() {
+		Expect(commands.NewSchema(log.New(&output, "", 0)).Execute(nil)).To(Succeed())
+
+		var schema map[string]any
+		Expect(json.Unmarshal([]byte(output.String()), &schema)).To(Succeed())
+		Expect(schema).To(HaveKeyWithValue("title", "Kilnfile"))
+		Expect(schema).To(HaveKey("$defs"))
+	})
+
+	It("prints the schema of the requested document", func() {
+		Expect(commands.NewSchema(log.New(&output, "", 0)).Execute([]string{"--document", "kilnfile-lock"})).To(Succeed())
+
+		Expect(output.String()).To(ContainSubstring(`"title": "Kilnfile.lock"`))
+	})
+
+	When("the document is not known", func() {
+		It("returns an error", func() {
+			err := commands.NewSchema(log.New(&output, "", 0)).Execute([]string{"--document", "tile"})
+			Expect(err).To(MatchError(ContainSubstring(`unknown document "tile"`)))
+		})
+	})
+})
@@ -92,6 +92,7 @@ func (command SyncWithLocal) Execute(args []string) error {
 
 		matchingRelease.Version = rel.Lock.Version
 		matchingRelease.SHA1 = rel.Lock.SHA1
+		matchingRelease.SHA256 = rel.Lock.SHA256
 		matchingRelease.RemoteSource = command.Options.ReleaseSourceID
 		matchingRelease.RemotePath = remotePath
 
@@ -28,6 +28,7 @@ var _ = Describe("sync-with-local", func() {
 			release1NewVersion    = "2"
 			release1OldSha        = "old-sha"
 			release1NewSha        = "new-sha"
+			release1NewSha256     = "new-sha256"
 			release1OldSourceID   = "old-source"
 			release1OldRemotePath = "old-path"
 			release1NewRemotePath = "new-path"
@@ -77,7 +78,7 @@ var _ = Describe("sync-with-local", func() {
 			localReleaseDirectory = new(commandsFakes.LocalReleaseDirectory)
 			localReleaseDirectory.GetLocalReleasesReturns([]component.Local{
 				{
-					Lock:      cargo.BOSHReleaseTarballLock{Name: release1Name, Version: release1NewVersion, SHA1: release1NewSha},
+					Lock:      cargo.BOSHReleaseTarballLock{Name: release1Name, Version: release1NewVersion, SHA1: release1NewSha, SHA256: release1NewSha256},
 					LocalPath: "local-path",
 				},
 				{
@@ -128,6 +129,7 @@ var _ = Describe("sync-with-local", func() {
 					RemoteSource: releaseSourceID,
 					RemotePath:   release1NewRemotePath,
 					SHA1:         release1NewSha,
+					SHA256:       release1NewSha256,
 				},
 				{
 					Name:         releaseName,
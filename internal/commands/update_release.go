@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/go-git/go-billy/v5"
 	"github.com/pivotal-cf/jhanda"
 
@@ -21,14 +24,18 @@ type UpdateRelease struct {
 		ReleasesDir                  string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
 		AllowOnlyPublishableReleases bool   `long:"allow-only-publishable-releases" description:"include releases that would not be shipped with the tile (development builds)"`
 		WithoutDownload              bool   `long:"without-download" description:"updates releases without downloading them"`
+
+		Timeout time.Duration `long:"timeout" description:"maximum duration of each release source operation (for example 10m); unlimited when not set"`
 	}
+	ctx                        context.Context
 	multiReleaseSourceProvider MultiReleaseSourceProvider
 	filesystem                 billy.Filesystem
 	logger                     *log.Logger
 }
 
-func NewUpdateRelease(logger *log.Logger, filesystem billy.Filesystem, multiReleaseSourceProvider MultiReleaseSourceProvider) UpdateRelease {
+func NewUpdateRelease(ctx context.Context, logger *log.Logger, filesystem billy.Filesystem, multiReleaseSourceProvider MultiReleaseSourceProvider) UpdateRelease {
 	return UpdateRelease{
+		ctx:                        ctx,
 		logger:                     logger,
 		multiReleaseSourceProvider: multiReleaseSourceProvider,
 		filesystem:                 filesystem,
@@ -71,12 +78,17 @@ func (u UpdateRelease) Execute(args []string) error {
 	var remoteRelease cargo.BOSHReleaseTarballLock
 	var newVersion, newSHA1, newSourceID, newRemotePath string
 	if u.Options.WithoutDownload {
-		remoteRelease, err = releaseSource.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{
+		ctx, cancel := releaseSourceContext(u.ctx, u.Options.Timeout)
+		defer cancel()
+		remoteRelease, err = releaseSource.FindReleaseVersion(ctx, cargo.BOSHReleaseTarballSpecification{
 			Name:             u.Options.Name,
 			Version:          releaseVersionConstraint,
+			Channel:          releaseSpec.Channel,
 			StemcellVersion:  kilnfileLock.Stemcell.Version,
 			StemcellOS:       kilnfileLock.Stemcell.OS,
 			GitHubRepository: releaseSpec.GitHubRepository,
+			ReleaseSource:    releaseSpec.ReleaseSource,
+			ReleaseSources:   releaseSpec.ReleaseSources,
 		}, false)
 
 		if err != nil {
@@ -85,6 +97,9 @@ func (u UpdateRelease) Execute(args []string) error {
 			}
 			return fmt.Errorf("couldn't find %q %s in any release source", u.Options.Name, u.Options.Version)
 		}
+		if err := checkReleaseChannel(releaseSpec, remoteRelease.Version); err != nil {
+			return err
+		}
 
 		newVersion = remoteRelease.Version
 		newSHA1 = remoteRelease.SHA1
@@ -92,12 +107,17 @@ func (u UpdateRelease) Execute(args []string) error {
 		newRemotePath = remoteRelease.RemotePath
 
 	} else {
-		remoteRelease, err = releaseSource.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
+		ctx, cancel := releaseSourceContext(u.ctx, u.Options.Timeout)
+		defer cancel()
+		remoteRelease, err = releaseSource.GetMatchedRelease(ctx, cargo.BOSHReleaseTarballSpecification{
 			Name:             u.Options.Name,
 			Version:          u.Options.Version,
+			Channel:          releaseSpec.Channel,
 			StemcellOS:       kilnfileLock.Stemcell.OS,
 			StemcellVersion:  kilnfileLock.Stemcell.Version,
 			GitHubRepository: releaseSpec.GitHubRepository,
+			ReleaseSource:    releaseSpec.ReleaseSource,
+			ReleaseSources:   releaseSpec.ReleaseSources,
 		})
 
 		if err != nil {
@@ -106,8 +126,13 @@ func (u UpdateRelease) Execute(args []string) error {
 			}
 			return fmt.Errorf("couldn't find %q %s in any release source", u.Options.Name, u.Options.Version)
 		}
+		if err := checkReleaseChannel(releaseSpec, remoteRelease.Version); err != nil {
+			return err
+		}
 
-		localRelease, err = releaseSource.DownloadRelease(u.Options.ReleasesDir, remoteRelease)
+		downloadCtx, cancelDownload := releaseSourceContext(u.ctx, u.Options.Timeout)
+		defer cancelDownload()
+		localRelease, err = releaseSource.DownloadRelease(downloadCtx, u.Options.ReleasesDir, remoteRelease)
 		if err != nil {
 			return fmt.Errorf("error downloading the release: %w", err)
 		}
@@ -122,8 +147,17 @@ func (u UpdateRelease) Execute(args []string) error {
 		return nil
 	}
 
+	newSHA256 := remoteRelease.SHA256
+	if !u.Options.WithoutDownload {
+		newSHA256, err = component.CalculateSHA256Sum(localRelease.LocalPath, u.filesystem)
+		if err != nil {
+			return fmt.Errorf("error calculating the SHA256 of the release: %w", err)
+		}
+	}
+
 	releaseLock.Version = newVersion
 	releaseLock.SHA1 = newSHA1
+	releaseLock.SHA256 = newSHA256
 	releaseLock.RemoteSource = newSourceID
 	releaseLock.RemotePath = newRemotePath
 
@@ -134,10 +168,23 @@ func (u UpdateRelease) Execute(args []string) error {
 		return err
 	}
 
+	if newSHA256 == "" {
+		u.logger.Printf("The SHA256 of %s was not recorded because it was not downloaded; run \"kiln lock upgrade-checksums\" to add it.\n", u.Options.Name)
+	}
 	u.logger.Printf("Updated %s to %s. DON'T FORGET TO MAKE A COMMIT AND PR\n", u.Options.Name, u.Options.Version)
 	return nil
 }
 
+// checkReleaseChannel returns an error when the channel of the release does not allow
+// version. Versions that are not semantic versions are not checked.
+func checkReleaseChannel(spec cargo.BOSHReleaseTarballSpecification, version string) error {
+	v, err := semver.NewVersion(version)
+	if err != nil || spec.Channel.Allows(v) {
+		return nil
+	}
+	return fmt.Errorf("%s %s is a prerelease which the %q channel of the release does not allow", spec.Name, version, spec.Channel)
+}
+
 func (u UpdateRelease) Usage() jhanda.Usage {
 	return jhanda.Usage{
 		Description:      "Bumps a release to a new version in Kilnfile.lock",
@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -35,6 +37,7 @@ var _ = Describe("UpdateRelease", func() {
 		notDownloadedReleaseSourceName = "compiled-releases"
 		oldReleaseSha1                 = "old-sha1"
 		newReleaseSha1                 = "new-sha1"
+		newReleaseSha256               = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
 		notDownloadedReleaseSha1       = "some-other-new-sha1"
 		githubRepo                     = "https://example.com/org/repo"
 
@@ -108,6 +111,7 @@ var _ = Describe("UpdateRelease", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			downloadedReleasePath = filepath.Join(releasesDir, fmt.Sprintf("%s-%s.tgz", releaseName, newReleaseVersion))
+			Expect(util.WriteFile(filesystem, downloadedReleasePath, []byte("some release contents"), 0o644)).To(Succeed())
 			expectedDownloadedRelease = component.Local{
 				Lock:      cargo.BOSHReleaseTarballLock{Name: releaseName, Version: newReleaseVersion, SHA1: newReleaseSha1},
 				LocalPath: downloadedReleasePath,
@@ -127,7 +131,7 @@ var _ = Describe("UpdateRelease", func() {
 		})
 
 		JustBeforeEach(func() {
-			updateReleaseCommand = commands.NewUpdateRelease(logger, filesystem, multiReleaseSourceProvider.Spy)
+			updateReleaseCommand = commands.NewUpdateRelease(context.Background(), logger, filesystem, multiReleaseSourceProvider.Spy)
 		})
 
 		When("updating to a version that exists in the remote", func() {
@@ -142,7 +146,7 @@ var _ = Describe("UpdateRelease", func() {
 
 				Expect(releaseSource.GetMatchedReleaseCallCount()).To(Equal(1))
 
-				receivedReleaseRequirement := releaseSource.GetMatchedReleaseArgsForCall(0)
+				_, receivedReleaseRequirement := releaseSource.GetMatchedReleaseArgsForCall(0)
 				releaseRequirement := cargo.BOSHReleaseTarballSpecification{
 					Name:             releaseName,
 					Version:          newReleaseVersion,
@@ -154,7 +158,7 @@ var _ = Describe("UpdateRelease", func() {
 
 				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(1))
 
-				receivedReleasesDir, receivedRemoteRelease := releaseSource.DownloadReleaseArgsForCall(0)
+				_, receivedReleasesDir, receivedRemoteRelease := releaseSource.DownloadReleaseArgsForCall(0)
 				Expect(receivedReleasesDir).To(Equal(releasesDir))
 				Expect(receivedRemoteRelease).To(Equal(expectedRemoteRelease))
 			})
@@ -178,6 +182,7 @@ var _ = Describe("UpdateRelease", func() {
 						Version: newReleaseVersion,
 
 						SHA1:         newReleaseSha1,
+						SHA256:       newReleaseSha256,
 						RemoteSource: newReleaseSourceName,
 						RemotePath:   newRemotePath,
 					},
@@ -373,6 +378,60 @@ var _ = Describe("UpdateRelease", func() {
 			})
 		})
 
+		When("the release is on the stable channel and the source offers a prerelease", func() {
+			const prereleaseVersion = "1.9.0-rc.1"
+
+			BeforeEach(func() {
+				Expect(fsWriteYAML(filesystem, kilnfilePath, cargo.Kilnfile{
+					Releases: []cargo.BOSHReleaseTarballSpecification{
+						{Name: "minecraft"},
+						{Name: releaseName, GitHubRepository: githubRepo, Channel: cargo.ReleaseChannelStable},
+					},
+				})).To(Succeed())
+
+				prerelease := expectedRemoteRelease
+				prerelease.Version = prereleaseVersion
+				releaseSource.GetMatchedReleaseReturns(prerelease, nil)
+				releaseSource.FindReleaseVersionReturns(prerelease, nil)
+			})
+
+			It("rejects the prerelease without downloading it", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", prereleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`capi 1.9.0-rc.1 is a prerelease which the "stable" channel of the release does not allow`)))
+
+				_, spec := releaseSource.GetMatchedReleaseArgsForCall(0)
+				Expect(spec.Channel).To(Equal(cargo.ReleaseChannelStable))
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+			})
+
+			It("rejects the prerelease when updating without downloading", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", "~1.9.0-rc.1",
+					"--releases-directory", releasesDir,
+					"--without-download",
+				})
+				Expect(err).To(MatchError(ContainSubstring("is a prerelease")))
+
+				_, spec, _ := releaseSource.FindReleaseVersionArgsForCall(0)
+				Expect(spec.Channel).To(Equal(cargo.ReleaseChannelStable))
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+			})
+		})
+
 		When("invalid arguments are given", func() {
 			It("errors", func() {
 				err := updateReleaseCommand.Execute([]string{"--no-such-flag"})
@@ -391,7 +450,7 @@ var _ = Describe("UpdateRelease", func() {
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				receivedReleaseRequirement, _ := releaseSource.FindReleaseVersionArgsForCall(0)
+				_, receivedReleaseRequirement, _ := releaseSource.FindReleaseVersionArgsForCall(0)
 				releaseRequirement := cargo.BOSHReleaseTarballSpecification{
 					Name:             releaseName,
 					Version:          newReleaseVersion,
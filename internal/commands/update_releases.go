@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-billy/v5"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// UpdateReleases bumps every release in the Kilnfile (or the releases selected with --only
+// and --except) to the newest version that satisfies its version constraint.
+type UpdateReleases struct {
+	Options struct {
+		flags.Standard
+
+		Only   []string `long:"only"   description:"name of a release to update (may be repeated); all releases are updated when not set"`
+		Except []string `long:"except" description:"name of a release not to update (may be repeated)"`
+
+		PatchOnly bool `long:"patch-only" description:"only update releases to versions with the same major and minor version as the locked version"`
+		Minor     bool `long:"minor"      description:"only update releases to versions with the same major version as the locked version"`
+
+		ReleasesDir                  string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
+		AllowOnlyPublishableReleases bool   `long:"allow-only-publishable-releases" description:"include releases that would not be shipped with the tile (development builds)"`
+		WithoutDownload              bool   `long:"without-download" description:"updates releases without downloading them"`
+		DryRun                       bool   `long:"dry-run" description:"print the changes to Kilnfile.lock without downloading releases or writing Kilnfile.lock"`
+
+		Timeout time.Duration `long:"timeout" description:"maximum duration of each release source operation (for example 10m); unlimited when not set"`
+	}
+	ctx                        context.Context
+	multiReleaseSourceProvider MultiReleaseSourceProvider
+	filesystem                 billy.Filesystem
+	logger                     *log.Logger
+}
+
+func NewUpdateReleases(ctx context.Context, logger *log.Logger, filesystem billy.Filesystem, multiReleaseSourceProvider MultiReleaseSourceProvider) UpdateReleases {
+	return UpdateReleases{
+		ctx:                        ctx,
+		logger:                     logger,
+		multiReleaseSourceProvider: multiReleaseSourceProvider,
+		filesystem:                 filesystem,
+	}
+}
+
+func (u UpdateReleases) Execute(args []string) error {
+	_, err := flags.LoadWithDefaultFilePaths(&u.Options, args, u.filesystem.Stat)
+	if err != nil {
+		return err
+	}
+	if u.Options.PatchOnly && u.Options.Minor {
+		return errors.New("--patch-only and --minor can not be used together")
+	}
+
+	kilnfile, kilnfileLock, err := u.Options.Standard.LoadKilnfiles(u.filesystem, nil)
+	if err != nil {
+		return fmt.Errorf("error loading Kilnfiles: %w", err)
+	}
+
+	specs, err := u.selectReleases(kilnfile)
+	if err != nil {
+		return err
+	}
+
+	previousLock := kilnfileLock
+	previousLock.Releases = slices.Clone(kilnfileLock.Releases)
+
+	releaseSource := u.multiReleaseSourceProvider(kilnfile, u.Options.AllowOnlyPublishableReleases)
+
+	var errs []error
+	for _, spec := range specs {
+		if err := u.updateRelease(releaseSource, spec, &kilnfileLock); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", spec.Name, err))
+		}
+	}
+
+	diff := cargo.DiffKilnfileLocks(previousLock, kilnfileLock)
+	if u.Options.DryRun {
+		u.logger.Println("Dry run: Kilnfile.lock was not changed. It would change like this:")
+		u.logger.Print(lockDiffText(diff))
+		return errors.Join(errs...)
+	}
+	if !diff.Empty() {
+		if err := u.Options.Standard.SaveKilnfileLock(u.filesystem, kilnfileLock); err != nil {
+			return err
+		}
+	}
+	u.logger.Print(lockDiffText(diff))
+	if slices.ContainsFunc(kilnfileLock.Releases, func(lock cargo.BOSHReleaseTarballLock) bool { return lock.SHA256 == "" }) {
+		u.logger.Println("Some releases in Kilnfile.lock do not have a SHA256; run \"kiln lock upgrade-checksums\" to add it.")
+	}
+
+	return errors.Join(errs...)
+}
+
+// selectReleases returns the release specifications in the Kilnfile filtered by --only and --except.
+func (u UpdateReleases) selectReleases(kilnfile cargo.Kilnfile) ([]cargo.BOSHReleaseTarballSpecification, error) {
+	for _, name := range append(slices.Clone(u.Options.Only), u.Options.Except...) {
+		if _, err := kilnfile.BOSHReleaseTarballSpecification(name); err != nil {
+			return nil, err
+		}
+	}
+	var specs []cargo.BOSHReleaseTarballSpecification
+	for _, spec := range kilnfile.Releases {
+		if len(u.Options.Only) > 0 && !slices.Contains(u.Options.Only, spec.Name) {
+			continue
+		}
+		if slices.Contains(u.Options.Except, spec.Name) {
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// updateRelease finds the newest version of the release allowed by its constraint and the
+// --patch-only or --minor limit and records it in the lock.
+func (u UpdateReleases) updateRelease(releaseSource component.MultiReleaseSource, spec cargo.BOSHReleaseTarballSpecification, kilnfileLock *cargo.KilnfileLock) error {
+	releaseLock, err := kilnfileLock.FindBOSHReleaseWithName(spec.Name)
+	if err != nil {
+		return errors.New("release is not in Kilnfile.lock")
+	}
+
+	spec.Version, err = u.limitVersionConstraint(spec.Version, releaseLock.Version)
+	if err != nil {
+		return err
+	}
+	spec.StemcellOS = kilnfileLock.Stemcell.OS
+	spec.StemcellVersion = kilnfileLock.Stemcell.Version
+
+	// the source only downloads the release to calculate its SHA1 when it is not downloaded below
+	noDownload := u.Options.DryRun || !u.Options.WithoutDownload
+	ctx, cancel := releaseSourceContext(u.ctx, u.Options.Timeout)
+	defer cancel()
+	remoteRelease, err := releaseSource.FindReleaseVersion(ctx, spec, noDownload)
+	if err != nil {
+		if component.IsErrNotFound(err) {
+			return fmt.Errorf("no version matching %q found in any release source: %w", spec.Version, err)
+		}
+		return err
+	}
+	if remoteRelease.Version == releaseLock.Version {
+		return nil
+	}
+
+	releaseLock.Version = remoteRelease.Version
+	releaseLock.SHA1 = remoteRelease.SHA1
+	releaseLock.SHA256 = remoteRelease.SHA256
+	releaseLock.RemoteSource = remoteRelease.RemoteSource
+	releaseLock.RemotePath = remoteRelease.RemotePath
+	if releaseLock.SHA1 == sha1NotCalculated {
+		// the SHA1 is not known until the release is downloaded, so it is left out of the diff
+		releaseLock.SHA1 = ""
+	}
+
+	if !u.Options.WithoutDownload && !u.Options.DryRun {
+		u.logger.Printf("Downloading %s %s...", spec.Name, remoteRelease.Version)
+		downloadCtx, cancelDownload := releaseSourceContext(u.ctx, u.Options.Timeout)
+		defer cancelDownload()
+		localRelease, err := releaseSource.DownloadRelease(downloadCtx, u.Options.ReleasesDir, remoteRelease)
+		if err != nil {
+			return fmt.Errorf("error downloading the release: %w", err)
+		}
+		releaseLock.SHA1 = localRelease.Lock.SHA1
+		releaseLock.SHA256, err = component.CalculateSHA256Sum(localRelease.LocalPath, u.filesystem)
+		if err != nil {
+			return fmt.Errorf("error calculating the SHA256 of the release: %w", err)
+		}
+	}
+
+	return kilnfileLock.UpdateBOSHReleaseTarballLockWithName(spec.Name, releaseLock)
+}
+
+// sha1NotCalculated is the SHA1 release sources return from FindReleaseVersion when noDownload
+// is set and they would have to download the release to calculate it.
+const sha1NotCalculated = "not-calculated"
+
+// limitVersionConstraint adds the --patch-only or --minor limit, relative to the locked
+// version, to each alternative of the Kilnfile version constraint.
+func (u UpdateReleases) limitVersionConstraint(constraint, lockedVersion string) (string, error) {
+	if !u.Options.PatchOnly && !u.Options.Minor {
+		return constraint, nil
+	}
+	locked, err := semver.NewVersion(lockedVersion)
+	if err != nil {
+		return "", fmt.Errorf("locked version %q is not a semantic version so it can not be limited to patch or minor updates: %w", lockedVersion, err)
+	}
+	upper := locked.IncMajor()
+	if u.Options.PatchOnly {
+		upper = *semver.New(locked.Major(), locked.Minor()+1, 0, "", "")
+	}
+	limit := fmt.Sprintf(">=%s, <%s", locked.Original(), upper.String())
+	if strings.TrimSpace(constraint) == "" {
+		return limit, nil
+	}
+	alternatives := strings.Split(constraint, "||")
+	for i, alternative := range alternatives {
+		alternatives[i] = strings.TrimSpace(alternative) + ", " + limit
+	}
+	return strings.Join(alternatives, " || "), nil
+}
+
+func (u UpdateReleases) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Bumps every release in Kilnfile.lock to the newest version that satisfies its version constraint in the Kilnfile and prints the changes",
+		ShortDescription: "bumps all releases to the newest versions allowed by the Kilnfile",
+		Flags:            u.Options,
+	}
+}
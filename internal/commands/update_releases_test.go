@@ -0,0 +1,255 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	fetcherFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("UpdateReleases", func() {
+	const (
+		kilnfilePath     = "Kilnfile"
+		kilnfileLockPath = kilnfilePath + ".lock"
+		releasesDir      = "releases"
+	)
+
+	var (
+		fs                         billy.Filesystem
+		output                     strings.Builder
+		releaseSource              *fetcherFakes.MultiReleaseSource
+		multiReleaseSourceProvider *commandsFakes.MultiReleaseSourceProvider
+
+		// availableVersions are the versions the fake release source has for each release
+		availableVersions map[string][]string
+
+		updateReleases commands.UpdateReleases
+	)
+
+	readLock := func() cargo.KilnfileLock {
+		buf, err := util.ReadFile(fs, kilnfileLockPath)
+		Expect(err).NotTo(HaveOccurred())
+		var lock cargo.KilnfileLock
+		Expect(yaml.Unmarshal(buf, &lock)).To(Succeed())
+		return lock
+	}
+
+	lockedVersions := func(lock cargo.KilnfileLock) map[string]string {
+		versions := make(map[string]string)
+		for _, release := range lock.Releases {
+			versions[release.Name] = release.Version
+		}
+		return versions
+	}
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		output.Reset()
+
+		availableVersions = map[string][]string{
+			"bpm":     {"1.2.3", "1.2.4", "1.3.0", "2.0.0"},
+			"capi":    {"1.8.0", "1.8.1", "1.9.0"},
+			"routing": {"0.200.0", "0.201.0"},
+		}
+
+		releaseSource = new(fetcherFakes.MultiReleaseSource)
+		releaseSource.FindReleaseVersionStub = func(_ context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+			constraints, err := spec.VersionConstraints()
+			if err != nil {
+				return cargo.BOSHReleaseTarballLock{}, err
+			}
+			var newest *semver.Version
+			for _, v := range availableVersions[spec.Name] {
+				version := semver.MustParse(v)
+				if constraints.Check(version) && (newest == nil || version.GreaterThan(newest)) {
+					newest = version
+				}
+			}
+			if newest == nil {
+				return cargo.BOSHReleaseTarballLock{}, component.ErrNotFound
+			}
+			sha1 := "remote-sha1-" + newest.Original()
+			if noDownload {
+				// like the sources that download the release to calculate its SHA1
+				sha1 = "not-calculated"
+			}
+			return cargo.BOSHReleaseTarballLock{
+				Name:         spec.Name,
+				Version:      newest.Original(),
+				SHA1:         sha1,
+				RemoteSource: "bosh.io",
+				RemotePath:   fmt.Sprintf("%s/%s", spec.Name, newest.Original()),
+			}, nil
+		}
+		releaseSource.DownloadReleaseStub = func(_ context.Context, dir string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+			localPath := filepath.Join(dir, remote.Name+"-"+remote.Version+".tgz")
+			if err := util.WriteFile(fs, localPath, []byte("some release contents"), 0o644); err != nil {
+				return component.Local{}, err
+			}
+			lock := remote
+			lock.SHA1 = "downloaded-sha1-" + remote.Version
+			return component.Local{Lock: lock, LocalPath: localPath}, nil
+		}
+		multiReleaseSourceProvider = new(commandsFakes.MultiReleaseSourceProvider)
+		multiReleaseSourceProvider.Returns(releaseSource)
+
+		Expect(fsWriteYAML(fs, kilnfilePath, cargo.Kilnfile{
+			Releases: []cargo.BOSHReleaseTarballSpecification{
+				{Name: "bpm"},
+				{Name: "capi", Version: "~1.8"},
+				{Name: "routing"},
+			},
+		})).To(Succeed())
+		Expect(fsWriteYAML(fs, kilnfileLockPath, cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "1.2.3", SHA1: "bpm-sha1", RemoteSource: "bosh.io", RemotePath: "bpm/1.2.3"},
+				{Name: "capi", Version: "1.8.0", SHA1: "capi-sha1", RemoteSource: "bosh.io", RemotePath: "capi/1.8.0"},
+				{Name: "routing", Version: "0.201.0", SHA1: "routing-sha1", SHA256: "routing-sha256", RemoteSource: "bosh.io", RemotePath: "routing/0.201.0"},
+			},
+			Stemcell: cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.123"},
+		})).To(Succeed())
+
+		updateReleases = commands.NewUpdateReleases(context.Background(), log.New(&output, "", 0), fs, multiReleaseSourceProvider.Spy)
+	})
+
+	It("updates every release to the newest version satisfying its constraint", func() {
+		Expect(updateReleases.Execute([]string{"--releases-directory", releasesDir})).To(Succeed())
+
+		lock := readLock()
+		Expect(lockedVersions(lock)).To(Equal(map[string]string{"bpm": "2.0.0", "capi": "1.8.1", "routing": "0.201.0"}))
+		Expect(lock.Releases[0]).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name:         "bpm",
+			Version:      "2.0.0",
+			SHA1:         "downloaded-sha1-2.0.0",
+			SHA256:       "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc", // sha256sum of "some release contents"
+			RemoteSource: "bosh.io",
+			RemotePath:   "bpm/2.0.0",
+		}))
+		Expect(lock.Stemcell).To(Equal(cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.123"}))
+
+		_, spec, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+		Expect(spec.StemcellOS).To(Equal("ubuntu-jammy"))
+		Expect(spec.StemcellVersion).To(Equal("1.123"))
+		Expect(noDownload).To(BeTrue(), "the release is only downloaded once, by DownloadRelease")
+		Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(2))
+		_, _, downloaded := releaseSource.DownloadReleaseArgsForCall(0)
+		Expect(downloaded.Version).To(Equal("2.0.0"))
+
+		Expect(output.String()).To(ContainSubstring("Downloading bpm 2.0.0..."))
+		Expect(output.String()).To(ContainSubstring("major  bpm   1.2.3 -> 2.0.0"))
+		Expect(output.String()).To(ContainSubstring("patch  capi  1.8.0 -> 1.8.1"))
+		Expect(output.String()).NotTo(ContainSubstring("routing"))
+	})
+
+	It("limits updates to patch versions", func() {
+		Expect(updateReleases.Execute([]string{"--patch-only", "--without-download"})).To(Succeed())
+
+		lock := readLock()
+		Expect(lockedVersions(lock)).To(Equal(map[string]string{"bpm": "1.2.4", "capi": "1.8.1", "routing": "0.201.0"}))
+		Expect(lock.Releases[0].SHA1).To(Equal("remote-sha1-1.2.4"))
+		Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+		_, _, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+		Expect(noDownload).To(BeFalse(), "the source calculates the SHA1 when the release is not downloaded")
+		Expect(output.String()).To(ContainSubstring(`run "kiln lock upgrade-checksums"`))
+
+		_, spec, _ := releaseSource.FindReleaseVersionArgsForCall(1)
+		Expect(spec.Version).To(Equal("~1.8, >=1.8.0, <1.9.0"))
+	})
+
+	It("limits updates to minor versions", func() {
+		Expect(updateReleases.Execute([]string{"--minor", "--without-download"})).To(Succeed())
+
+		Expect(lockedVersions(readLock())).To(Equal(map[string]string{"bpm": "1.3.0", "capi": "1.8.1", "routing": "0.201.0"}))
+	})
+
+	It("does not allow both limits", func() {
+		Expect(updateReleases.Execute([]string{"--minor", "--patch-only"})).To(MatchError(ContainSubstring("can not be used together")))
+	})
+
+	It("only updates the releases passed to --only", func() {
+		Expect(updateReleases.Execute([]string{"--only", "capi", "--without-download"})).To(Succeed())
+
+		Expect(lockedVersions(readLock())).To(Equal(map[string]string{"bpm": "1.2.3", "capi": "1.8.1", "routing": "0.201.0"}))
+		Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(1))
+	})
+
+	It("does not update the releases passed to --except", func() {
+		Expect(updateReleases.Execute([]string{"--except", "bpm", "--except", "routing", "--without-download"})).To(Succeed())
+
+		Expect(lockedVersions(readLock())).To(Equal(map[string]string{"bpm": "1.2.3", "capi": "1.8.1", "routing": "0.201.0"}))
+		Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(1))
+	})
+
+	It("fails for filters naming releases that are not in the Kilnfile", func() {
+		Expect(updateReleases.Execute([]string{"--only", "banana"})).To(MatchError(ContainSubstring("banana")))
+		Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(0))
+	})
+
+	It("prints the changes without writing Kilnfile.lock on a dry run", func() {
+		before, err := util.ReadFile(fs, kilnfileLockPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(updateReleases.Execute([]string{"--dry-run"})).To(Succeed())
+
+		after, err := util.ReadFile(fs, kilnfileLockPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).To(Equal(before))
+		Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+		_, _, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+		Expect(noDownload).To(BeTrue())
+		Expect(output.String()).To(Equal(`Dry run: Kilnfile.lock was not changed. It would change like this:
+major  bpm   1.2.3 -> 2.0.0
+patch  capi  1.8.0 -> 1.8.1
+`))
+	})
+
+	It("leaves checksums that were not calculated out of the dry run diff", func() {
+		// the checksum of a release is only compared when its version does not change
+		availableVersions["routing"] = []string{"0.201.0"}
+		Expect(fsWriteYAML(fs, kilnfileLockPath, cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "bpm", Version: "2.0.0", SHA1: "bpm-sha1", RemoteSource: "bosh.io", RemotePath: "bpm/2.0.0"},
+				{Name: "capi", Version: "1.8.1", SHA1: "capi-sha1", RemoteSource: "bosh.io", RemotePath: "capi/1.8.1"},
+				{Name: "routing", Version: "v0.201.0", SHA1: "routing-sha1", RemoteSource: "bosh.io", RemotePath: "routing/0.201.0"},
+			},
+		})).To(Succeed())
+
+		Expect(updateReleases.Execute([]string{"--dry-run"})).To(Succeed())
+
+		Expect(output.String()).NotTo(ContainSubstring("checksum"))
+		Expect(output.String()).NotTo(ContainSubstring("not-calculated"))
+	})
+
+	It("updates the other releases when one can not be resolved", func() {
+		availableVersions["capi"] = nil
+
+		err := updateReleases.Execute([]string{"--without-download"})
+		Expect(err).To(MatchError(ContainSubstring(`capi: no version matching "~1.8" found in any release source`)))
+
+		Expect(lockedVersions(readLock())).To(Equal(map[string]string{"bpm": "2.0.0", "capi": "1.8.0", "routing": "0.201.0"}))
+	})
+
+	It("reports download failures", func() {
+		releaseSource.DownloadReleaseStub = nil
+		releaseSource.DownloadReleaseReturns(component.Local{}, errors.New("lemon"))
+
+		Expect(updateReleases.Execute(nil)).To(MatchError(ContainSubstring("error downloading the release: lemon")))
+		Expect(lockedVersions(readLock())).To(Equal(map[string]string{"bpm": "1.2.3", "capi": "1.8.0", "routing": "0.201.0"}))
+	})
+})
@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 
@@ -20,7 +22,10 @@ type UpdateStemcell struct {
 
 		Version     string `short:"v"  long:"version"            required:"true"    description:"desired version of stemcell"`
 		ReleasesDir string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
+
+		Timeout time.Duration `long:"timeout" description:"maximum duration of each release source operation (for example 10m); unlimited when not set"`
 	}
+	Context                    context.Context
 	FS                         billy.Filesystem
 	MultiReleaseSourceProvider MultiReleaseSourceProvider
 	Logger                     *log.Logger
@@ -78,7 +83,9 @@ func (update UpdateStemcell) Execute(args []string) error {
 		spec.StemcellVersion = trimmedInputVersion
 		spec.Version = rel.Version
 
-		remote, err := releaseSource.GetMatchedRelease(spec)
+		matchCtx, cancelMatch := releaseSourceContext(update.Context, update.Options.Timeout)
+		remote, err := releaseSource.GetMatchedRelease(matchCtx, spec)
+		cancelMatch()
 		if err != nil {
 			return fmt.Errorf("while finding release %q, encountered error: %w", rel.Name, err)
 		}
@@ -91,7 +98,9 @@ func (update UpdateStemcell) Execute(args []string) error {
 			continue
 		}
 
-		local, err := releaseSource.DownloadRelease(update.Options.ReleasesDir, remote)
+		downloadCtx, cancelDownload := releaseSourceContext(update.Context, update.Options.Timeout)
+		local, err := releaseSource.DownloadRelease(downloadCtx, update.Options.ReleasesDir, remote)
+		cancelDownload()
 		if err != nil {
 			return fmt.Errorf("while downloading release %q, encountered error: %w", rel.Name, err)
 		}
@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
@@ -94,7 +95,7 @@ var _ = Describe("UpdateStemcell", func() {
 			}
 
 			releaseSource = new(fetcherFakes.MultiReleaseSource)
-			releaseSource.GetMatchedReleaseCalls(func(requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+			releaseSource.GetMatchedReleaseCalls(func(_ context.Context, requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 				switch requirement.Name {
 				case release1Name:
 					remote := cargo.BOSHReleaseTarballLock{
@@ -115,7 +116,7 @@ var _ = Describe("UpdateStemcell", func() {
 				}
 			})
 
-			releaseSource.DownloadReleaseCalls(func(_ string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
+			releaseSource.DownloadReleaseCalls(func(_ context.Context, _ string, remote cargo.BOSHReleaseTarballLock) (component.Local, error) {
 				switch remote.Name {
 				case release1Name:
 					local := component.Local{
@@ -200,14 +201,14 @@ var _ = Describe("UpdateStemcell", func() {
 
 			Expect(releaseSource.GetMatchedReleaseCallCount()).To(Equal(2))
 
-			req1 := releaseSource.GetMatchedReleaseArgsForCall(0)
+			_, req1 := releaseSource.GetMatchedReleaseArgsForCall(0)
 			Expect(req1).To(Equal(cargo.BOSHReleaseTarballSpecification{
 				Name: release1Name, Version: release1Version,
 				StemcellOS: newStemcellOS, StemcellVersion: newStemcellVersion,
 				GitHubRepository: "https://example.com/lemon",
 			}))
 
-			req2 := releaseSource.GetMatchedReleaseArgsForCall(1)
+			_, req2 := releaseSource.GetMatchedReleaseArgsForCall(1)
 			Expect(req2).To(Equal(cargo.BOSHReleaseTarballSpecification{
 				Name: release2Name, Version: release2Version,
 				StemcellOS: newStemcellOS, StemcellVersion: newStemcellVersion,
@@ -223,7 +224,7 @@ var _ = Describe("UpdateStemcell", func() {
 
 			Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(2))
 
-			actualDir, remote1 := releaseSource.DownloadReleaseArgsForCall(0)
+			_, actualDir, remote1 := releaseSource.DownloadReleaseArgsForCall(0)
 			Expect(actualDir).To(Equal(releasesDirPath))
 			Expect(remote1).To(Equal(
 				cargo.BOSHReleaseTarballLock{
@@ -233,7 +234,7 @@ var _ = Describe("UpdateStemcell", func() {
 				},
 			))
 
-			actualDir, remote2 := releaseSource.DownloadReleaseArgsForCall(1)
+			_, actualDir, remote2 := releaseSource.DownloadReleaseArgsForCall(1)
 			Expect(actualDir).To(Equal(releasesDirPath))
 			Expect(remote2).To(Equal(
 				cargo.BOSHReleaseTarballLock{
@@ -377,7 +378,7 @@ var _ = Describe("UpdateStemcell", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(1))
-				_, remote := releaseSource.DownloadReleaseArgsForCall(0)
+				_, _, remote := releaseSource.DownloadReleaseArgsForCall(0)
 				Expect(remote.Name).To(Equal(release1Name))
 
 				Expect(string(outputBuffer.Contents())).To(ContainSubstring("No change"))
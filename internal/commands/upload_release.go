@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 
@@ -16,6 +18,7 @@ import (
 )
 
 type UploadRelease struct {
+	Context               context.Context
 	FS                    billy.Filesystem
 	ReleaseUploaderFinder ReleaseUploaderFinder
 	Logger                *log.Logger
@@ -25,6 +28,9 @@ type UploadRelease struct {
 
 		UploadTargetID string `           long:"upload-target-id" required:"true" description:"the ID of the release source where the built release will be uploaded"`
 		LocalPath      string `short:"lp" long:"local-path"       required:"true" description:"path to BOSH release tarball"`
+
+		Timeout time.Duration `long:"timeout" description:"maximum duration of each release source operation (for example 10m); unlimited when not set"`
+		Force   bool          `long:"force"   description:"replace the release if it already exists on the release source"`
 	}
 }
 
@@ -61,13 +67,17 @@ func (command UploadRelease) Execute(args []string) error {
 	}
 
 	requirement := cargo.BOSHReleaseTarballSpecification{Name: releaseTarball.Manifest.Name, Version: releaseTarball.Manifest.Version}
-	_, err = releaseUploader.GetMatchedRelease(requirement)
+	matchCtx, cancelMatch := releaseSourceContext(command.Context, command.Options.Timeout)
+	defer cancelMatch()
+	_, err = releaseUploader.GetMatchedRelease(matchCtx, requirement)
 	if err != nil {
 		if !component.IsErrNotFound(err) {
 			return fmt.Errorf("couldn't query release source: %w", err)
 		}
+	} else if command.Options.Force {
+		command.Logger.Printf("replacing release %s %s on %s\n", releaseTarball.Manifest.Name, releaseTarball.Manifest.Version, command.Options.UploadTargetID)
 	} else {
-		return fmt.Errorf("a release with name %q and version %q already exists on %s",
+		return fmt.Errorf("a release with name %q and version %q already exists on %s (use --force to replace it)",
 			releaseTarball.Manifest.Name, releaseTarball.Manifest.Version, command.Options.UploadTargetID)
 	}
 
@@ -76,15 +86,29 @@ func (command UploadRelease) Execute(args []string) error {
 		return err
 	}
 	defer closeAndIgnoreError(file)
-	_, err = releaseUploader.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+	uploadCtx, cancelUpload := releaseSourceContext(command.Context, command.Options.Timeout)
+	defer cancelUpload()
+	if command.Options.Force {
+		uploadCtx = component.ContextWithOverwrite(uploadCtx)
+	}
+	uploaded, err := releaseUploader.UploadRelease(uploadCtx, cargo.BOSHReleaseTarballSpecification{
 		Name:    releaseTarball.Manifest.Name,
 		Version: releaseTarball.Manifest.Version,
 	}, file)
 	if err != nil {
+		if component.IsErrAlreadyExists(err) {
+			return fmt.Errorf("error uploading the release: %w (use --force to replace it)", err)
+		}
 		return fmt.Errorf("error uploading the release: %w", err)
 	}
+	if uploaded.SHA1 != "" && uploaded.SHA1 != releaseTarball.SHA1 {
+		return fmt.Errorf("uploaded release has an incorrect SHA1 - expected %q, got %q", releaseTarball.SHA1, uploaded.SHA1)
+	}
+	if uploaded.SHA256 != "" && uploaded.SHA256 != releaseTarball.SHA256 {
+		return fmt.Errorf("uploaded release has an incorrect SHA256 - expected %q, got %q", releaseTarball.SHA256, uploaded.SHA256)
+	}
 
-	command.Logger.Println("Upload succeeded")
+	command.Logger.Printf("Upload succeeded (sha1: %s, sha256: %s)\n", releaseTarball.SHA1, releaseTarball.SHA256)
 
 	return nil
 }
@@ -2,6 +2,7 @@ package commands_test
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -60,7 +61,7 @@ var _ = Describe("UploadRelease", func() {
 
 				Expect(releaseUploader.UploadReleaseCallCount()).To(Equal(1))
 
-				spec, f := releaseUploader.UploadReleaseArgsForCall(0)
+				_, spec, f := releaseUploader.UploadReleaseArgsForCall(0)
 				Expect(spec.Name).To(Equal("bpm"))
 				Expect(spec.Version).To(Equal("1.1.21"))
 
@@ -89,11 +90,54 @@ var _ = Describe("UploadRelease", func() {
 
 					Expect(releaseUploader.GetMatchedReleaseCallCount()).To(Equal(1))
 
-					requirement := releaseUploader.GetMatchedReleaseArgsForCall(0)
+					_, requirement := releaseUploader.GetMatchedReleaseArgsForCall(0)
 					Expect(requirement).To(Equal(cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.1.21"}))
 
 					Expect(releaseUploader.UploadReleaseCallCount()).To(Equal(0))
 				})
+
+				When("--force is given", func() {
+					It("replaces the release", func() {
+						err := uploadRelease.Execute([]string{
+							"--kilnfile", filepath.Join(tileDirectory, "Kilnfile"),
+							"--local-path", filepath.Join("testdata", "bpm-1.1.21.tgz"),
+							"--upload-target-id", "orange-bucket",
+							"--force",
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(releaseUploader.UploadReleaseCallCount()).To(Equal(1))
+						ctx, _, _ := releaseUploader.UploadReleaseArgsForCall(0)
+						Expect(component.AllowsOverwrite(ctx)).To(BeTrue())
+					})
+				})
+			})
+
+			It("does not allow the release source to overwrite releases", func() {
+				err := uploadRelease.Execute([]string{
+					"--kilnfile", filepath.Join(tileDirectory, "Kilnfile"),
+					"--local-path", filepath.Join("testdata", "bpm-1.1.21.tgz"),
+					"--upload-target-id", "orange-bucket",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx, _, _ := releaseUploader.UploadReleaseArgsForCall(0)
+				Expect(component.AllowsOverwrite(ctx)).To(BeFalse())
+			})
+
+			When("the release source refuses to overwrite the release", func() {
+				BeforeEach(func() {
+					releaseUploader.UploadReleaseReturns(cargo.BOSHReleaseTarballLock{}, fmt.Errorf("s3://orange-bucket/bpm-1.1.21.tgz %w", component.ErrAlreadyExists))
+				})
+
+				It("suggests --force", func() {
+					err := uploadRelease.Execute([]string{
+						"--kilnfile", filepath.Join(tileDirectory, "Kilnfile"),
+						"--local-path", filepath.Join("testdata", "bpm-1.1.21.tgz"),
+						"--upload-target-id", "orange-bucket",
+					})
+					Expect(err).To(MatchError(ContainSubstring("already exists (use --force to replace it)")))
+				})
 			})
 		})
 
@@ -158,6 +202,24 @@ var _ = Describe("UploadRelease", func() {
 			})
 		})
 
+		When("the release source reports a different checksum for the upload", func() {
+			BeforeEach(func() {
+				releaseUploader.UploadReleaseReturns(cargo.BOSHReleaseTarballLock{
+					Name: "bpm", Version: "1.1.21",
+					SHA1: "not-the-sha1-of-the-tarball",
+				}, nil)
+			})
+
+			It("returns an error", func() {
+				err := uploadRelease.Execute([]string{
+					"--kilnfile", filepath.Join(tileDirectory, "Kilnfile"),
+					"--local-path", filepath.Join("testdata", "bpm-1.1.21.tgz"),
+					"--upload-target-id", "orange-bucket",
+				})
+				Expect(err).To(MatchError(ContainSubstring("incorrect SHA1")))
+			})
+		})
+
 		When("the upload fails", func() {
 			BeforeEach(func() {
 				releaseUploader.UploadReleaseReturns(cargo.BOSHReleaseTarballLock{}, errors.New("boom"))
@@ -1,29 +1,47 @@
 package commands
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v3"
 
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
 	"github.com/pivotal-cf/kiln/pkg/cargo"
 )
 
+const (
+	validateFormatHuman = "human"
+	validateFormatJSON  = "json"
+	validateFormatSARIF = "sarif"
+)
+
 type Validate struct {
 	Options struct {
 		flags.Standard
+
+		// Format does not have a default tag because LoadWithDefaultFilePaths treats string defaults as paths
+		Format string `long:"format" description:"output format: human (the default), json, or sarif"`
 	}
 
-	FS billy.Filesystem
+	FS        billy.Filesystem
+	outLogger *log.Logger
 }
 
 var _ jhanda.Command = (*Validate)(nil)
 
-func NewValidate(fs billy.Filesystem) Validate {
+func NewValidate(outLogger *log.Logger, fs billy.Filesystem) Validate {
 	return Validate{
-		FS: fs,
+		FS:        fs,
+		outLogger: outLogger,
 	}
 }
 
@@ -32,17 +50,152 @@ func (v Validate) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	switch v.Options.Format {
+	case "":
+		v.Options.Format = validateFormatHuman
+	case validateFormatHuman, validateFormatJSON, validateFormatSARIF:
+	default:
+		return fmt.Errorf("unknown format %q (expected %s, %s, or %s)", v.Options.Format, validateFormatHuman, validateFormatJSON, validateFormatSARIF)
+	}
 
-	kf, lock, err := v.Options.Standard.LoadKilnfiles(v.FS, nil)
+	kilnfileYAML, lockYAML, err := v.Options.Standard.ReadKilnfiles(v.FS, nil)
 	if err != nil {
 		return fmt.Errorf("failed to load kilnfiles: %w", err)
 	}
 
-	errs := cargo.Validate(kf, lock)
-	if len(errs) > 0 {
-		return errorList(errs)
+	problems := v.problems(validateKilnfiles(kilnfileYAML, lockYAML, filepath.Dir(v.Options.Kilnfile), v.FS.Stat))
+
+	switch v.Options.Format {
+	case validateFormatJSON:
+		if err := v.printJSON(struct {
+			Problems []validationProblem `json:"problems"`
+		}{Problems: problems}); err != nil {
+			return err
+		}
+	case validateFormatSARIF:
+		if err := v.printJSON(newSARIFLog(problems)); err != nil {
+			return err
+		}
+	default:
+		var list errorList
+		for _, problem := range problems {
+			if problem.Severity == cargo.SeverityWarning {
+				v.outLogger.Println(problem.Error())
+				continue
+			}
+			list = append(list, problem)
+		}
+		if len(list) > 0 {
+			return list
+		}
 	}
 
+	errorCount := 0
+	for _, problem := range problems {
+		if problem.Severity != cargo.SeverityWarning {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("found %d errors in the Kilnfile and Kilnfile.lock", errorCount)
+	}
+	return nil
+}
+
+// validateKilnfiles checks the structure of the Kilnfile and Kilnfile.lock against their
+// schemas and, when they are well-formed, checks them with cargo.Validate. Problems the
+// Kilnfile validation configuration or kiln:ignore comments suppress are removed.
+func validateKilnfiles(kilnfileYAML, lockYAML []byte, kilnfileDirectory string, stat flags.StatFunc) []error {
+	var (
+		kilnfile cargo.Kilnfile
+		lock     cargo.KilnfileLock
+	)
+	kilnfileNode, kilnfileErrs := decodeValidatedYAML(cargo.KilnfileDocument, kilnfileYAML, cargo.KilnfileSchema(), &kilnfile)
+	lockNode, lockErrs := decodeValidatedYAML(cargo.KilnfileLockDocument, lockYAML, cargo.KilnfileLockSchema(), &lock)
+	errs := append(kilnfileErrs, lockErrs...)
+	if len(errs) == 0 {
+		errs = cargo.Validate(kilnfile, lock)
+		errs = append(errs, cargo.ValidateBakeConfigurationPaths(kilnfile, kilnfileDirectory, stat)...)
+		errs = kilnfile.Validation.Apply(errs)
+		cargo.SetFieldErrorPositions(errs, cargo.KilnfileDocument, kilnfileNode)
+		cargo.SetFieldErrorPositions(errs, cargo.KilnfileLockDocument, lockNode)
+	}
+	errs = cargo.RemoveIgnoredFieldErrors(errs, cargo.KilnfileDocument, kilnfileYAML, kilnfileNode)
+	return cargo.RemoveIgnoredFieldErrors(errs, cargo.KilnfileLockDocument, lockYAML, lockNode)
+}
+
+var yamlErrorLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func decodeValidatedYAML(document string, buf []byte, schema *cargo.Schema, out any) (*yaml.Node, []error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf, &node); err != nil {
+		fieldErr := cargo.NewFieldError(document, cargo.RuleYAMLSyntax, err)
+		if match := yamlErrorLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			fieldErr.Line, _ = strconv.Atoi(match[1])
+			fieldErr.Err = errors.New(match[2])
+		}
+		return nil, []error{fieldErr}
+	}
+	if node.Kind == 0 {
+		return &node, nil // the document is empty
+	}
+	if errs := schema.ValidateYAML(document, &node); len(errs) > 0 {
+		return &node, errs
+	}
+	if err := node.Decode(out); err != nil {
+		return &node, []error{cargo.NewFieldError(document, cargo.RuleInvalidValue, err)}
+	}
+	return &node, nil
+}
+
+type validationProblem struct {
+	File     string         `json:"file"`
+	Line     int            `json:"line,omitempty"`
+	Column   int            `json:"column,omitempty"`
+	Pointer  string         `json:"pointer,omitempty"`
+	Rule     string         `json:"rule,omitempty"`
+	Severity cargo.Severity `json:"severity"`
+	Message  string         `json:"message"`
+}
+
+func (p validationProblem) Error() string {
+	location := p.File
+	if p.Line > 0 {
+		location += ":" + strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			location += ":" + strconv.Itoa(p.Column)
+		}
+	}
+	message := location + ": " + string(p.Severity) + ": " + p.Message
+	if p.Rule != "" {
+		message += " [" + p.Rule + "]"
+	}
+	return message
+}
+
+func (v Validate) problems(errs []error) []validationProblem {
+	problems := make([]validationProblem, 0, len(errs))
+	for _, err := range errs {
+		problem := validationProblem{File: v.Options.Kilnfile, Severity: cargo.SeverityError, Message: err.Error()}
+		var fieldErr *cargo.FieldError
+		if errors.As(err, &fieldErr) {
+			if fieldErr.Document == cargo.KilnfileLockDocument {
+				problem.File = v.Options.KilnfileLockPath()
+			}
+			problem.Line, problem.Column, problem.Pointer = fieldErr.Line, fieldErr.Column, fieldErr.Pointer
+			problem.Rule, problem.Severity = fieldErr.Rule, fieldErr.Severity
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+func (v Validate) printJSON(data any) error {
+	buf, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	v.outLogger.Println(string(buf))
 	return nil
 }
 
@@ -56,9 +209,91 @@ func (list errorList) Error() string {
 	return strings.Join(messages, "\n")
 }
 
+// sarifLog is the subset of the SARIF 2.1.0 format used to report validation problems
+// to code scanning tools.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID               string `json:"id"`
+	ShortDescription struct {
+		Text string `json:"text"`
+	} `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId,omitempty"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func newSARIFLog(problems []validationProblem) sarifLog {
+	run := sarifRun{Results: make([]sarifResult, 0, len(problems))}
+	run.Tool.Driver.Name = "kiln"
+	run.Tool.Driver.InformationURI = "https://github.com/pivotal-cf/kiln"
+	for _, rule := range cargo.ValidationRules() {
+		r := sarifRule{ID: rule.ID}
+		r.ShortDescription.Text = rule.Description
+		r.DefaultConfiguration.Level = string(rule.Severity)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+	}
+	for _, problem := range problems {
+		result := sarifResult{RuleID: problem.Rule, Level: string(problem.Severity)}
+		result.Message.Text = problem.Message
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = problem.File
+		if problem.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: problem.Line, StartColumn: problem.Column}
+		}
+		result.Locations = []sarifLocation{location}
+		run.Results = append(run.Results, result)
+	}
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
 func (v Validate) Usage() jhanda.Usage {
 	return jhanda.Usage{
-		Description:      "Validate checks for common Kilnfile and Kilnfile.lock mistakes",
+		Description:      "Validate checks for common Kilnfile and Kilnfile.lock mistakes, like unknown fields, values with the wrong type, and locked versions that do not match their constraints. Problems are reported with their file, line, column, and rule ID. Only errors fail validation; warnings are printed. Rules may be turned off with the Kilnfile validation field or a \"# kiln:ignore <rule>\" comment.",
 		ShortDescription: "validate Kilnfile and Kilnfile.lock",
 		Flags:            v.Options,
 	}
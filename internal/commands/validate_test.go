@@ -0,0 +1,227 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+)
+
+var _ = Describe("validate", func() {
+	const validLock = `---
+releases:
+  - name: bpm
+    version: 1.2.3
+    remote_source: bosh.io
+`
+
+	var (
+		fs     billy.Filesystem
+		output strings.Builder
+
+		validate commands.Validate
+	)
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		output.Reset()
+		validate = commands.NewValidate(log.New(&output, "", 0), fs)
+	})
+
+	writeKilnfiles := func(kilnfile, lock string) {
+		Expect(util.WriteFile(fs, "Kilnfile", []byte(kilnfile), 0o644)).To(Succeed())
+		Expect(util.WriteFile(fs, "Kilnfile.lock", []byte(lock), 0o644)).To(Succeed())
+	}
+
+	When("the Kilnfile and Kilnfile.lock are valid", func() {
+		BeforeEach(func() {
+			writeKilnfiles(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+    version: ~1
+`, validLock)
+		})
+
+		It("succeeds", func() {
+			Expect(validate.Execute(nil)).To(Succeed())
+			Expect(output.String()).To(BeEmpty())
+		})
+	})
+
+	When("the Kilnfile has an unknown field", func() {
+		BeforeEach(func() {
+			writeKilnfiles(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+    verison: ~1
+`, validLock)
+		})
+
+		It("returns the position of the field", func() {
+			err := validate.Execute(nil)
+			Expect(err).To(MatchError(`Kilnfile:6:5: error: unknown field "verison" (did you mean "version"?) [unknown-field]`))
+		})
+	})
+
+	When("the Kilnfile.lock has a version that does not match the constraint", func() {
+		BeforeEach(func() {
+			writeKilnfiles(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+    version: ~2
+`, validLock)
+		})
+
+		It("returns the position of the locked version", func() {
+			err := validate.Execute(nil)
+			Expect(err).To(MatchError(ContainSubstring(`Kilnfile.lock:4:14: error: spec bpm version in lock "1.2.3" does not match constraint "~2"`)))
+		})
+
+		It("prints the problems as JSON", func() {
+			err := validate.Execute([]string{"--format", "json"})
+			Expect(err).To(MatchError("found 1 errors in the Kilnfile and Kilnfile.lock"))
+
+			var result struct {
+				Problems []struct {
+					File    string `json:"file"`
+					Line    int    `json:"line"`
+					Column  int    `json:"column"`
+					Pointer string `json:"pointer"`
+				} `json:"problems"`
+			}
+			Expect(json.Unmarshal([]byte(output.String()), &result)).To(Succeed())
+			Expect(result.Problems).To(HaveLen(1))
+			Expect(result.Problems[0].File).To(Equal("Kilnfile.lock"))
+			Expect(result.Problems[0].Line).To(Equal(4))
+			Expect(result.Problems[0].Column).To(Equal(14))
+			Expect(result.Problems[0].Pointer).To(Equal("/releases/0/version"))
+		})
+
+		It("prints the problems as SARIF", func() {
+			err := validate.Execute([]string{"--format", "sarif"})
+			Expect(err).To(HaveOccurred())
+
+			var result struct {
+				Version string `json:"version"`
+				Runs    []struct {
+					Results []struct {
+						Level     string `json:"level"`
+						Locations []struct {
+							PhysicalLocation struct {
+								ArtifactLocation struct {
+									URI string `json:"uri"`
+								} `json:"artifactLocation"`
+								Region struct {
+									StartLine int `json:"startLine"`
+								} `json:"region"`
+							} `json:"physicalLocation"`
+						} `json:"locations"`
+					} `json:"results"`
+				} `json:"runs"`
+			}
+			Expect(json.Unmarshal([]byte(output.String()), &result)).To(Succeed())
+			Expect(result.Version).To(Equal("2.1.0"))
+			Expect(result.Runs).To(HaveLen(1))
+			Expect(result.Runs[0].Results).To(HaveLen(1))
+			Expect(result.Runs[0].Results[0].Level).To(Equal("error"))
+			location := result.Runs[0].Results[0].Locations[0].PhysicalLocation
+			Expect(location.ArtifactLocation.URI).To(Equal("Kilnfile.lock"))
+			Expect(location.Region.StartLine).To(Equal(4))
+		})
+	})
+
+	When("a release source is not used", func() {
+		const kilnfile = `---
+release_sources:
+  - type: bosh.io
+  - type: github
+    org: cloudfoundry
+releases:
+  - name: bpm
+`
+
+		It("prints a warning and succeeds", func() {
+			writeKilnfiles(kilnfile, validLock)
+			Expect(validate.Execute(nil)).To(Succeed())
+			Expect(output.String()).To(Equal(`Kilnfile:4:5: warning: release source "cloudfoundry" is not the remote_source of any release in Kilnfile.lock [unused-release-source]` + "\n"))
+		})
+
+		It("reports the rule in JSON and SARIF", func() {
+			writeKilnfiles(kilnfile, validLock)
+			Expect(validate.Execute([]string{"--format", "json"})).To(Succeed())
+			Expect(output.String()).To(ContainSubstring(`"rule": "unused-release-source"`))
+			Expect(output.String()).To(ContainSubstring(`"severity": "warning"`))
+
+			output.Reset()
+			Expect(validate.Execute([]string{"--format", "sarif"})).To(Succeed())
+			Expect(output.String()).To(ContainSubstring(`"ruleId": "unused-release-source"`))
+			Expect(output.String()).To(ContainSubstring(`"level": "warning"`))
+		})
+
+		It("does not report rules ignored with a comment", func() {
+			writeKilnfiles(strings.Replace(kilnfile, "  - type: github", "  - type: github # kiln:ignore unused-release-source", 1), validLock)
+			Expect(validate.Execute(nil)).To(Succeed())
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("does not report rules ignored in the Kilnfile validation configuration", func() {
+			writeKilnfiles(kilnfile+"validation:\n  ignore: [unused-release-source]\n", validLock)
+			Expect(validate.Execute(nil)).To(Succeed())
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("fails when the Kilnfile validation configuration makes the rule an error", func() {
+			writeKilnfiles(kilnfile+"validation:\n  severities:\n    unused-release-source: error\n", validLock)
+			err := validate.Execute(nil)
+			Expect(err).To(MatchError(ContainSubstring("Kilnfile:4:5: error: release source")))
+		})
+	})
+
+	When("a bake configuration path does not exist", func() {
+		It("returns the position of the path", func() {
+			writeKilnfiles(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: bpm
+bake_configurations:
+  - tile_name: ert
+    metadata_filepath: base.yml
+`, validLock)
+			err := validate.Execute(nil)
+			Expect(err).To(MatchError(`Kilnfile:8:24: error: metadata_filepath "base.yml" of bake configuration "ert" does not exist [bake-configuration-path-missing]`))
+		})
+	})
+
+	When("the Kilnfile is not valid YAML", func() {
+		BeforeEach(func() {
+			writeKilnfiles("releases:\n  - name: bpm\n   version: ~1\n", validLock)
+		})
+
+		It("returns the line of the syntax error", func() {
+			err := validate.Execute(nil)
+			Expect(err).To(MatchError("Kilnfile:1: error: did not find expected '-' indicator [yaml-syntax]"))
+		})
+	})
+
+	When("the format is not known", func() {
+		It("returns an error", func() {
+			writeKilnfiles("{}", "{}")
+			err := validate.Execute([]string{"--format", "xml"})
+			Expect(err).To(MatchError(ContainSubstring(`unknown format "xml"`)))
+		})
+	})
+})
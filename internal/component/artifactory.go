@@ -2,7 +2,10 @@ package component
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -28,6 +31,8 @@ type ArtifactoryReleaseSource struct {
 	Client *http.Client
 	logger *log.Logger
 	ID     string
+
+	credentials *releaseSourceCredentials
 }
 
 type ArtifactoryFileMetadata struct {
@@ -65,10 +70,11 @@ func NewArtifactoryReleaseSource(c cargo.ReleaseSourceConfig) *ArtifactoryReleas
 		ReleaseSourceConfig: c,
 		ID:                  c.ID,
 		logger:              log.New(os.Stderr, "[Artifactory release source] ", log.Default().Flags()),
+		credentials:         newReleaseSourceCredentials(DefaultCredentialProvider()),
 	}
 }
 
-func (ars *ArtifactoryReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+func (ars *ArtifactoryReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (_ Local, err error) {
 	u, err := url.Parse(ars.ArtifactoryHost)
 	if err != nil {
 		return Local{}, fmt.Errorf("error parsing artifactory host: %w", err)
@@ -80,14 +86,6 @@ func (ars *ArtifactoryReleaseSource) DownloadRelease(releaseDir string, remoteRe
 	downloadURL += "/" + ars.Repo + "/" + remoteRelease.RemotePath
 
 	ars.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeArtifactory, ars.ID)
-	resp, err := ars.getWithAuth(downloadURL)
-	if err != nil {
-		return Local{}, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return Local{}, fmt.Errorf("failed to download %s release from artifactory with error code %d", remoteRelease.Name, resp.StatusCode)
-	}
 
 	filePath := filepath.Join(releaseDir, filepath.Base(remoteRelease.RemotePath))
 
@@ -95,10 +93,13 @@ func (ars *ArtifactoryReleaseSource) DownloadRelease(releaseDir string, remoteRe
 	if err != nil {
 		return Local{}, err
 	}
-	defer func() { _ = out.Close() }()
+	defer removePartialDownload(out, &err)
 
-	_, err = io.Copy(out, resp.Body)
-	_ = resp.Body.Close()
+	err = downloadHTTPFile(ctx, ars.Client, newRetryPolicy(ars.Retry), ars.logger, out, func(ctx context.Context) (*http.Request, error) {
+		return ars.newRequestWithAuth(ctx, http.MethodGet, downloadURL)
+	}, func(statusCode int) string {
+		return fmt.Sprintf("failed to download %s release from artifactory with error code %d", remoteRelease.Name, statusCode)
+	})
 	if err != nil {
 		return Local{}, err
 	}
@@ -119,11 +120,11 @@ func (ars *ArtifactoryReleaseSource) DownloadRelease(releaseDir string, remoteRe
 	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
 }
 
-func (ars *ArtifactoryReleaseSource) getFileSHA1(release cargo.BOSHReleaseTarballLock) (string, error) {
+func (ars *ArtifactoryReleaseSource) getFileSHA1(ctx context.Context, release cargo.BOSHReleaseTarballLock) (string, error) {
 	fullURL := ars.ArtifactoryHost + "/api/storage/" + ars.Repo + "/" + release.RemotePath
 	ars.logger.Printf("Getting %s file info from artifactory", release.Name)
 
-	resp, err := ars.getWithAuth(fullURL)
+	resp, err := ars.getWithAuth(ctx, fullURL)
 	if err != nil {
 		return "", err
 	}
@@ -151,14 +152,14 @@ func (ars *ArtifactoryReleaseSource) Configuration() cargo.ReleaseSourceConfig {
 
 // GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
 // fields on Requirement to download a specific release.
-func (ars *ArtifactoryReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (ars *ArtifactoryReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	remotePath, err := ars.RemotePath(spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
 	fullUrl := fmt.Sprintf("%s/%s/%s/%s", ars.ArtifactoryHost, "api/storage", ars.Repo, remotePath)
-	response, err := ars.getWithAuth(fullUrl)
+	response, err := ars.getWithAuth(ctx, fullUrl)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -184,7 +185,187 @@ func (ars *ArtifactoryReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTar
 
 // FindReleaseVersion may use any of the fields on Requirement to return the best matching
 // release.
-func (ars *ArtifactoryReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+//
+// It searches the folder of path_template that does not depend on the version, and its
+// subfolders, with an AQL query so releases are found in any layout below it. When the
+// server does not allow AQL searches, it falls back to listing the folder containing the
+// path_template path.
+func (ars *ArtifactoryReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+	_, prefix, err := pathTemplateVersionPattern(ars.PathTemplate, spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	items, err := ars.searchReleaseFiles(ctx, spec.Name, prefix)
+	if err != nil {
+		if errors.Is(err, errAQLUnavailable) {
+			return ars.findReleaseVersionInFolder(ctx, spec)
+		}
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	var (
+		foundRelease cargo.BOSHReleaseTarballLock
+		foundVersion *semver.Version
+	)
+	for _, item := range items {
+		if !strings.HasPrefix(item.remotePath(), prefix) {
+			continue
+		}
+		version, stemcellOS, stemcellVersion, ok := item.release(spec.Name, spec.StemcellOS)
+		if !ok || stemcellOS != spec.StemcellOS {
+			continue
+		}
+		if spec.StemcellVersion != "" && stemcellVersion != spec.StemcellVersion {
+			continue
+		}
+		v, err := semver.NewVersion(version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if foundVersion != nil && !v.GreaterThan(foundVersion) {
+			continue
+		}
+		foundVersion = v
+		foundRelease = cargo.BOSHReleaseTarballLock{
+			Name:         spec.Name,
+			Version:      version,
+			SHA1:         item.SHA1,
+			RemotePath:   item.remotePath(),
+			RemoteSource: ars.ReleaseSourceConfig.ID,
+		}
+	}
+
+	if foundVersion == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return foundRelease, nil
+}
+
+// Artifactory properties set on uploaded releases. FindReleaseVersion prefers them to
+// parsing the file name.
+const (
+	artifactoryPropertyReleaseName     = "bosh.release.name"
+	artifactoryPropertyReleaseVersion  = "bosh.release.version"
+	artifactoryPropertyReleaseSHA1     = "bosh.release.sha1"
+	artifactoryPropertyStemcellOS      = "bosh.stemcell.os"
+	artifactoryPropertyStemcellVersion = "bosh.stemcell.version"
+)
+
+const errAQLUnavailable stringError = "AQL search is not available"
+
+type artifactoryAQLResult struct {
+	Results []artifactoryAQLItem `json:"results"`
+}
+
+type artifactoryAQLItem struct {
+	Repo       string `json:"repo"`
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	SHA1       string `json:"actual_sha1"`
+	Properties []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"properties"`
+}
+
+func (item artifactoryAQLItem) property(key string) string {
+	for _, p := range item.Properties {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (item artifactoryAQLItem) remotePath() string {
+	if item.Path == "" || item.Path == "." {
+		return item.Name
+	}
+	return item.Path + "/" + item.Name
+}
+
+// release returns the version and stemcell of the release stored in the item. The
+// Artifactory properties set by UploadRelease are used when present, otherwise they
+// are parsed from file names like "name-version.tgz" or "name-version-os-stemcell_version.tgz".
+func (item artifactoryAQLItem) release(releaseName, stemcellOS string) (string, string, string, bool) {
+	if item.property(artifactoryPropertyReleaseName) == releaseName {
+		if version := item.property(artifactoryPropertyReleaseVersion); version != "" {
+			return version, item.property(artifactoryPropertyStemcellOS), item.property(artifactoryPropertyStemcellVersion), true
+		}
+	}
+	rest, found := strings.CutPrefix(item.Name, releaseName+"-")
+	if !found {
+		return "", "", "", false
+	}
+	rest = strings.TrimSuffix(rest, ".tgz")
+	if stemcellOS == "" {
+		// compiled release names would otherwise parse as prereleases
+		return rest, "", "", !hasStemcellSuffix(rest)
+	}
+	version, stemcellVersion, found := strings.Cut(rest, "-"+stemcellOS+"-")
+	return version, stemcellOS, stemcellVersion, found
+}
+
+// searchReleaseFiles returns the tarballs under prefix in the repository that are named after
+// the release or have the release name property. It returns errAQLUnavailable when the server
+// does not allow AQL searches.
+func (ars *ArtifactoryReleaseSource) searchReleaseFiles(ctx context.Context, releaseName, prefix string) ([]artifactoryAQLItem, error) {
+	conditions := []map[string]any{{"$or": []map[string]any{
+		{"name": map[string]string{"$match": releaseName + "-*.tgz"}},
+		{"@" + artifactoryPropertyReleaseName: releaseName},
+	}}}
+	if folder := strings.TrimSuffix(prefix, "/"); folder != "" {
+		conditions = append(conditions, map[string]any{"$or": []map[string]any{
+			{"path": folder},
+			{"path": map[string]string{"$match": folder + "/*"}},
+		}})
+	}
+	criteria, err := json.Marshal(map[string]any{
+		"repo": ars.Repo,
+		"$and": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	query := `items.find(` + string(criteria) + `).include("repo","path","name","actual_sha1","property")`
+
+	request, err := ars.newRequestWithAuth(ctx, http.MethodPost, ars.ArtifactoryHost+"/api/search/aql")
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(strings.NewReader(query))
+	request.ContentLength = int64(len(query))
+	request.Header.Set("Content-Type", "text/plain")
+
+	response, err := ars.Client.Do(request)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden, http.StatusMethodNotAllowed:
+		return nil, errAQLUnavailable
+	default:
+		return nil, fmt.Errorf("unexpected http status from AQL search: %s", http.StatusText(response.StatusCode))
+	}
+
+	var result artifactoryAQLResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("json from AQL search is malformed: %s", err)
+	}
+	return result.Results, nil
+}
+
+func (ars *ArtifactoryReleaseSource) findReleaseVersionInFolder(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	remotePath, err := ars.RemotePath(spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -192,7 +373,7 @@ func (ars *ArtifactoryReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTa
 
 	fullUrl := fmt.Sprintf("%s/%s/%s/%s", ars.ArtifactoryHost, "api/storage", ars.Repo, path.Dir(remotePath))
 
-	response, err := ars.getWithAuth(fullUrl)
+	response, err := ars.getWithAuth(ctx, fullUrl)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -227,7 +408,7 @@ func (ars *ArtifactoryReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTa
 	}
 
 	foundRelease := cargo.BOSHReleaseTarballLock{}
-	constraint, err := spec.VersionConstraints()
+	constraint, err := spec.VersionMatcher()
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -277,14 +458,17 @@ func (ars *ArtifactoryReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTa
 	if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
 		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
 	}
-	foundRelease.SHA1, err = ars.getFileSHA1(foundRelease)
+	foundRelease.SHA1, err = ars.getFileSHA1(ctx, foundRelease)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 	return foundRelease, nil
 }
 
-func (ars *ArtifactoryReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+// UploadRelease first asks Artifactory to deploy the release by checksum so bytes already
+// stored on the server are not uploaded again. The release name, version, stemcell and
+// SHA1 are attached to the file as Artifactory properties.
+func (ars *ArtifactoryReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
 	remotePath, err := ars.RemotePath(spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -292,36 +476,153 @@ func (ars *ArtifactoryReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarball
 
 	ars.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, ars.ID, remotePath)
 
-	fullUrl := ars.ArtifactoryHost + "/artifactory/" + ars.Repo + "/" + remotePath
-
-	request, err := http.NewRequest(http.MethodPut, fullUrl, file)
+	body, cleanup, err := seekableUploadBody(file)
 	if err != nil {
-		fmt.Println(err)
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
-	request.SetBasicAuth(ars.Username, ars.Password)
-	// TODO: check Sha1/2
-	// request.Header.Set("X-Checksum-Sha1", spec.??? )
+	defer cleanup()
 
-	response, err := ars.Client.Do(request)
-	if err != nil {
-		return cargo.BOSHReleaseTarballLock{}, wrapVPNError(err)
+	sum1, sum256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(sum1, sum256), contextReader{ctx: ctx, r: body}); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("error hashing file contents: %w", err)
+	}
+	sha1Sum, sha256Sum := hex.EncodeToString(sum1.Sum(nil)), hex.EncodeToString(sum256.Sum(nil))
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("error reseting file cursor: %w", err)
 	}
 
-	switch response.StatusCode {
-	case http.StatusCreated:
-	default:
-		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("response contained errror status code: %d %s", response.StatusCode, response.Status)
+	fullUrl := ars.ArtifactoryHost + "/artifactory/" + ars.Repo + "/" + remotePath + artifactoryMatrixParameters(spec, sha1Sum)
+
+	deployed, err := ars.put(ctx, fullUrl, sha1Sum, sha256Sum, nil)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if deployed {
+		ars.logger.Printf("release %q is already stored on %s; deployed by checksum\n", spec.Name, ars.ID)
+	} else if _, err := ars.put(ctx, fullUrl, sha1Sum, sha256Sum, body); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
 	return cargo.BOSHReleaseTarballLock{
 		Name:         spec.Name,
 		Version:      spec.Version,
+		SHA1:         sha1Sum,
 		RemotePath:   remotePath,
 		RemoteSource: ars.ReleaseSourceConfig.ID,
 	}, nil
 }
 
+// CheckUpload requests a checksum deploy of a random checksum to the path_template location.
+// Artifactory checks the deploy permission first and then responds with 404 because it does
+// not have a file with the checksum, so nothing is uploaded.
+func (ars *ArtifactoryReleaseSource) CheckUpload(ctx context.Context) error {
+	remotePath, err := ars.RemotePath(uploadCheckSpec)
+	if err != nil {
+		return err
+	}
+	var sum [sha1.Size]byte
+	if _, err := rand.Read(sum[:]); err != nil {
+		return err
+	}
+	randomSHA1 := hex.EncodeToString(sum[:])
+	deployed, err := ars.put(ctx, ars.ArtifactoryHost+"/artifactory/"+ars.Repo+"/"+remotePath, randomSHA1, "", nil)
+	if err != nil {
+		return err
+	}
+	if deployed {
+		return fmt.Errorf("artifactory deployed a file with random checksum %s to %s", randomSHA1, remotePath)
+	}
+	return nil
+}
+
+// put uploads body to fullUrl. When body is nil it requests a checksum deploy and reports
+// false when Artifactory does not have a file with the checksums.
+func (ars *ArtifactoryReleaseSource) put(ctx context.Context, fullUrl, sha1Sum, sha256Sum string, body io.ReadSeeker) (bool, error) {
+	request, err := ars.newRequestWithAuth(ctx, http.MethodPut, fullUrl)
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("X-Checksum-Sha1", sha1Sum)
+	if sha256Sum != "" {
+		request.Header.Set("X-Checksum-Sha256", sha256Sum)
+	}
+	if body == nil {
+		request.Header.Set("X-Checksum-Deploy", "true")
+	} else {
+		size, err := body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return false, err
+		}
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		request.Body = io.NopCloser(contextReader{ctx: ctx, r: body})
+		request.ContentLength = size
+	}
+
+	response, err := ars.Client.Do(request)
+	if err != nil {
+		return false, wrapVPNError(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	switch response.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		if body == nil {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("response contained errror status code: %d %s", response.StatusCode, response.Status)
+}
+
+// artifactoryMatrixParameters returns the matrix parameters that set the release properties
+// when a file is deployed.
+func artifactoryMatrixParameters(spec cargo.BOSHReleaseTarballSpecification, sha1Sum string) string {
+	var params strings.Builder
+	for _, p := range []struct{ key, value string }{
+		{artifactoryPropertyReleaseName, spec.Name},
+		{artifactoryPropertyReleaseVersion, spec.Version},
+		{artifactoryPropertyStemcellOS, spec.StemcellOS},
+		{artifactoryPropertyStemcellVersion, spec.StemcellVersion},
+		{artifactoryPropertyReleaseSHA1, sha1Sum},
+	} {
+		if p.value == "" {
+			continue
+		}
+		params.WriteString(";" + p.key + "=" + url.PathEscape(p.value))
+	}
+	return params.String()
+}
+
+// seekableUploadBody returns file when it can seek, so it can be hashed before it is uploaded.
+// Other readers are copied to a temporary file that is removed by cleanup.
+func seekableUploadBody(file io.Reader) (io.ReadSeeker, func(), error) {
+	if rs, ok := file.(io.ReadSeeker); ok {
+		return rs, func() {}, nil
+	}
+	tmp, err := os.CreateTemp("", "kiln-upload-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		closeAndIgnoreError(tmp)
+		_ = os.Remove(tmp.Name())
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return tmp, cleanup, nil
+}
+
 func (ars *ArtifactoryReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
 	pathBuf := new(bytes.Buffer)
 
@@ -340,18 +641,30 @@ func (ars *ArtifactoryReleaseSource) pathTemplate() *template.Template {
 			Parse(ars.ReleaseSourceConfig.PathTemplate))
 }
 
-func (ars *ArtifactoryReleaseSource) getWithAuth(url string) (*http.Response, error) {
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+func (ars *ArtifactoryReleaseSource) getWithAuth(ctx context.Context, url string) (*http.Response, error) {
+	request, err := ars.newRequestWithAuth(ctx, http.MethodGet, url)
 	if err != nil {
 		return nil, err
 	}
-	if ars.Username != "" {
-		request.SetBasicAuth(ars.Username, ars.Password)
-	}
 	response, err := ars.Client.Do(request)
 	return response, wrapVPNError(err)
 }
 
+func (ars *ArtifactoryReleaseSource) newRequestWithAuth(ctx context.Context, method, url string) (*http.Request, error) {
+	config, err := ars.credentials.resolve(ctx, ars.ReleaseSourceConfig)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.Username != "" {
+		request.SetBasicAuth(config.Username, config.Password)
+	}
+	return request, nil
+}
+
 type vpnError struct {
 	wrapped error
 }
@@ -2,6 +2,10 @@ package component_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -9,8 +13,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -65,7 +72,18 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 	})
 
 	Describe("read operations", func() {
+		var (
+			aqlStatus  int
+			aqlResults []map[string]any
+			aqlQueries []string
+		)
 		BeforeEach(func() {
+			aqlStatus = http.StatusOK
+			aqlQueries = nil
+			aqlResults = []map[string]any{
+				{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango", "name": "mango-2.3.4-smoothie-9.9.tgz", "actual_sha1": "some-sha"},
+			}
+
 			requireAuth := requireBasicAuthMiddleware(correctUsername, correctPassword)
 
 			artifactoryRouter.Handler(http.MethodGet, "/api/storage/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", applyMiddleware(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
@@ -73,6 +91,15 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 				// language=json
 				_, _ = io.WriteString(res, `{"checksums": {"sha1":  "some-sha"}}`)
 			}), requireAuth))
+			artifactoryRouter.Handler(http.MethodPost, "/api/search/aql", applyMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				aqlQueries = append(aqlQueries, string(must(io.ReadAll(req.Body))))
+				if aqlStatus != http.StatusOK {
+					res.WriteHeader(aqlStatus)
+					return
+				}
+				res.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(res).Encode(map[string]any{"results": aqlResults})
+			}), requireAuth))
 			artifactoryRouter.Handler(http.MethodGet, "/api/storage/basket/bosh-releases/smoothie/9.9/mango", applyMiddleware(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
 				res.WriteHeader(http.StatusOK)
 				// language=json
@@ -90,7 +117,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 		})
 		When("the server has the a file at the expected path", func() {
 			It("resolves the lock from the spec", func() { // testing GetMatchedRelease
-				resultLock, resultErr := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
+				resultLock, resultErr := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:            "mango",
 					Version:         "2.3.4",
 					StemcellOS:      "smoothie",
@@ -109,7 +136,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 			})
 
 			It("finds the bosh release", func() { // testing FindReleaseVersion
-				resultLock, resultErr := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{
+				resultLock, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:            "mango",
 					Version:         "2.3.4",
 					StemcellOS:      "smoothie",
@@ -128,9 +155,110 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 				}))
 			})
 
+			It("searches the repository for the release", func() {
+				_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+					Name:            "mango",
+					StemcellOS:      "smoothie",
+					StemcellVersion: "9.9",
+				}, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(aqlQueries).To(HaveLen(1))
+				Expect(aqlQueries[0]).To(HavePrefix("items.find("))
+				Expect(aqlQueries[0]).To(ContainSubstring(`"repo":"basket"`))
+				Expect(aqlQueries[0]).To(ContainSubstring(`"$match":"mango-*.tgz"`))
+				Expect(aqlQueries[0]).To(ContainSubstring(`"@bosh.release.name":"mango"`))
+				Expect(aqlQueries[0]).To(ContainSubstring(`"path":"bosh-releases/smoothie/9.9/mango"`))
+				Expect(aqlQueries[0]).To(ContainSubstring(`"path":{"$match":"bosh-releases/smoothie/9.9/mango/*"}`))
+			})
+
+			When("the search finds releases in other layouts", func() {
+				BeforeEach(func() {
+					aqlResults = []map[string]any{
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango", "name": "mango-2.3.4-smoothie-9.9.tgz", "actual_sha1": "some-sha"},
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango/legacy", "name": "mango-2.4.0-smoothie-9.9.tgz", "actual_sha1": "legacy-sha"},
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango/legacy", "name": "mango-2.9.0-smoothie-8.8.tgz", "actual_sha1": "other-stemcell-sha"},
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango/legacy", "name": "mango-3.0.0-smoothie-9.9.tgz", "actual_sha1": "major-sha"},
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango/legacy", "name": "mango-sauce-2.8.0-smoothie-9.9.tgz", "actual_sha1": "sauce-sha"},
+						{"repo": "basket", "path": "other-team/mango", "name": "mango-2.7.0-smoothie-9.9.tgz", "actual_sha1": "other-team-sha"},
+						{"repo": "basket", "path": "bosh-releases/smoothie/9.9/mango/uploads", "name": "release.tgz", "actual_sha1": "property-sha", "properties": []map[string]string{
+							{"key": "bosh.release.name", "value": "mango"},
+							{"key": "bosh.release.version", "value": "2.5.0"},
+							{"key": "bosh.stemcell.os", "value": "smoothie"},
+							{"key": "bosh.stemcell.version", "value": "9.9"},
+						}},
+					}
+				})
+
+				It("returns the highest version matching the constraint and stemcell", func() {
+					resultLock, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+						Name:            "mango",
+						Version:         "~2",
+						StemcellOS:      "smoothie",
+						StemcellVersion: "9.9",
+					}, false)
+
+					Expect(resultErr).NotTo(HaveOccurred())
+					Expect(resultLock).To(Equal(cargo.BOSHReleaseTarballLock{
+						Name:         "mango",
+						Version:      "2.5.0",
+						SHA1:         "property-sha",
+						RemotePath:   "bosh-releases/smoothie/9.9/mango/uploads/release.tgz",
+						RemoteSource: "some-mango-tree",
+					}))
+				})
+
+				It("does not return compiled releases for an uncompiled spec", func() {
+					_, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+						Name:    "mango",
+						Version: "~2",
+					}, false)
+					Expect(component.IsErrNotFound(resultErr)).To(BeTrue())
+				})
+			})
+
+			When("the path template does not include the stemcell", func() {
+				BeforeEach(func() {
+					config.PathTemplate = "bosh-releases/{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+					aqlResults = []map[string]any{
+						{"repo": "basket", "path": "bosh-releases/mango", "name": "mango-2.3.4.tgz", "actual_sha1": "uncompiled-sha"},
+						{"repo": "basket", "path": "bosh-releases/mango", "name": "mango-2.6.0-smoothie-9.9.tgz", "actual_sha1": "compiled-sha"},
+					}
+				})
+
+				It("does not parse compiled releases as prereleases", func() {
+					resultLock, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+						Name:    "mango",
+						Channel: cargo.ReleaseChannelDev,
+					}, false)
+
+					Expect(resultErr).NotTo(HaveOccurred())
+					Expect(resultLock.Version).To(Equal("2.3.4"))
+					Expect(resultLock.RemotePath).To(Equal("bosh-releases/mango/mango-2.3.4.tgz"))
+				})
+			})
+
+			When("the server does not allow AQL searches", func() {
+				BeforeEach(func() {
+					aqlStatus = http.StatusForbidden
+				})
+
+				It("lists the release folder", func() {
+					resultLock, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+						Name:            "mango",
+						Version:         "2.3.4",
+						StemcellOS:      "smoothie",
+						StemcellVersion: "9.9",
+					}, false)
+
+					Expect(resultErr).NotTo(HaveOccurred())
+					Expect(resultLock.SHA1).To(Equal("some-sha"))
+					Expect(resultLock.RemotePath).To(Equal("bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz"))
+				})
+			})
+
 			It("downloads the release", func() { // teesting DownloadRelease
 				By("calling FindReleaseVersion")
-				local, resultErr := source.DownloadRelease(releasesDirectory, cargo.BOSHReleaseTarballLock{
+				local, resultErr := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
 					Name:         "mango",
 					Version:      "2.3.4",
 					RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
@@ -149,7 +277,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 
 				It("downloads the release", func() {
 					By("calling FindReleaseVersion")
-					local, resultErr := source.DownloadRelease(releasesDirectory, cargo.BOSHReleaseTarballLock{
+					local, resultErr := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
 						Name:         "mango",
 						Version:      "2.3.4",
 						RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
@@ -167,7 +295,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 					source.Client = server.Client()
 				})
 				It("returns an error", func() {
-					local, resultErr := source.DownloadRelease(releasesDirectory, cargo.BOSHReleaseTarballLock{
+					local, resultErr := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
 						Name:         "mango",
 						Version:      "2.3.4",
 						RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
@@ -180,53 +308,264 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 			})
 		})
 	})
+	When("the download fails with a transient error", func() {
+		const releaseContents = "some release tarball contents"
+		var (
+			requests     int
+			rangeHeaders []string
+			progress     *recordingDownloadProgress
+		)
+		BeforeEach(func() {
+			requests = 0
+			rangeHeaders = nil
+			progress = new(recordingDownloadProgress)
+			config.Retry = cargo.RetryConfig{InitialBackoff: time.Millisecond}
+		})
+
+		downloadMango := func() (component.Local, error) {
+			return source.DownloadRelease(component.ContextWithDownloadProgress(context.Background(), progress), releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
+				RemoteSource: "some-mango-tree",
+			})
+		}
+
+		When("the server is unavailable", func() {
+			BeforeEach(func() {
+				artifactoryRouter.HandlerFunc(http.MethodGet, "/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", func(res http.ResponseWriter, _ *http.Request) {
+					requests++
+					if requests == 1 {
+						res.Header().Set("Retry-After", "0")
+						res.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					_, _ = io.WriteString(res, releaseContents)
+				})
+			})
+
+			It("retries the download", func() {
+				local, err := downloadMango()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(requests).To(Equal(2))
+				Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(releaseContents)))
+			})
+
+			When("retries are disabled", func() {
+				BeforeEach(func() {
+					config.Retry.MaxRetries = -1
+				})
+				It("returns the error", func() {
+					_, err := downloadMango()
+					Expect(err).To(MatchError(ContainSubstring("error code 503")))
+					Expect(requests).To(Equal(1))
+				})
+			})
+		})
+
+		When("the connection drops part way through", func() {
+			BeforeEach(func() {
+				artifactoryRouter.HandlerFunc(http.MethodGet, "/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", func(res http.ResponseWriter, req *http.Request) {
+					requests++
+					rangeHeaders = append(rangeHeaders, req.Header.Get("Range"))
+					if requests == 1 {
+						res.Header().Set("Accept-Ranges", "bytes")
+						res.Header().Set("Content-Length", strconv.Itoa(len(releaseContents)))
+						res.WriteHeader(http.StatusOK)
+						_, _ = io.WriteString(res, releaseContents[:10])
+						res.(http.Flusher).Flush()
+						conn, _, err := res.(http.Hijacker).Hijack()
+						Expect(err).NotTo(HaveOccurred())
+						_ = conn.Close()
+						return
+					}
+					res.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(releaseContents)-1, len(releaseContents)))
+					res.WriteHeader(http.StatusPartialContent)
+					_, _ = io.WriteString(res, releaseContents[10:])
+				})
+			})
+
+			It("resumes the download", func() {
+				local, err := downloadMango()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rangeHeaders).To(Equal([]string{"", "bytes=10-"}))
+				Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(releaseContents)))
+				sum := sha1.Sum([]byte(releaseContents))
+				Expect(local.Lock.SHA1).To(Equal(hex.EncodeToString(sum[:])))
+				Expect(progress.written).To(BeEquivalentTo(len(releaseContents)))
+			})
+		})
+
+		When("the connection drops and the server sends the whole file again", func() {
+			BeforeEach(func() {
+				artifactoryRouter.HandlerFunc(http.MethodGet, "/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", func(res http.ResponseWriter, req *http.Request) {
+					requests++
+					res.Header().Set("Accept-Ranges", "bytes")
+					res.Header().Set("Content-Length", strconv.Itoa(len(releaseContents)))
+					res.WriteHeader(http.StatusOK)
+					if requests > 1 {
+						_, _ = io.WriteString(res, releaseContents)
+						return
+					}
+					_, _ = io.WriteString(res, releaseContents[:10])
+					res.(http.Flusher).Flush()
+					conn, _, err := res.(http.Hijacker).Hijack()
+					Expect(err).NotTo(HaveOccurred())
+					_ = conn.Close()
+				})
+			})
+
+			It("does not report the bytes of the first attempt twice", func() {
+				local, err := downloadMango()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(requests).To(Equal(2))
+				Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(releaseContents)))
+				Expect(progress.written).To(BeEquivalentTo(len(releaseContents)))
+			})
+		})
+	})
+
+	When("the download is cancelled part way through", func() {
+		var (
+			ctx             context.Context
+			cancel          context.CancelFunc
+			partialResponse chan struct{}
+		)
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			partialResponse = make(chan struct{})
+
+			artifactoryRouter.Handler(http.MethodGet, "/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(res, "some partial tarball contents")
+				res.(http.Flusher).Flush()
+				close(partialResponse)
+				<-req.Context().Done()
+			}))
+		})
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("returns the context error and removes the partially written file", func() {
+			go func() {
+				<-partialResponse
+				cancel()
+			}()
+
+			_, resultErr := source.DownloadRelease(ctx, releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
+				RemoteSource: "some-mango-tree",
+			})
+
+			Expect(resultErr).To(MatchError(context.Canceled))
+			Expect(filepath.Join(releasesDirectory, "mango-2.3.4-smoothie-9.9.tgz")).NotTo(BeAnExistingFile())
+		})
+	})
+
 	When("uploading releases", func() { // testing UploadRelease
-		var serverReleasesDirectory string
+		const releaseContents = "some release tarball contents"
+		type deployRequest struct {
+			checksumDeploy bool
+			sha1           string
+			properties     map[string]string
+			body           string
+		}
+		var (
+			storedChecksums []string
+			deployRequests  []deployRequest
+
+			releaseSHA1 string
+		)
 		BeforeEach(func() {
-			serverReleasesDirectory = must(os.MkdirTemp("", "artifactory_server"))
+			storedChecksums = nil
+			deployRequests = nil
+			sum := sha1.Sum([]byte(releaseContents))
+			releaseSHA1 = hex.EncodeToString(sum[:])
 
 			requireAuth := requireBasicAuthMiddleware(correctUsername, correctPassword)
 
-			artifactoryRouter.Handler(http.MethodPut, "/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz", applyMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-				fileName := path.Base(req.URL.Path)
-				filePath := filepath.Join(serverReleasesDirectory, fileName)
-				f, err := os.Create(filePath)
-				if err != nil {
-					log.Fatal("failed to create file in", filePath)
-				}
-				defer closeAndIgnoreError(f)
+			artifactoryRouter.Handler(http.MethodPut, "/artifactory/basket/*filepath", applyMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 				defer closeAndIgnoreError(req.Body)
-				_, _ = io.Copy(f, req.Body)
+				segments := strings.Split(req.URL.Path, ";")
+				Expect(segments[0]).To(Equal("/artifactory/basket/bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz"))
+				dr := deployRequest{
+					checksumDeploy: req.Header.Get("X-Checksum-Deploy") == "true",
+					sha1:           req.Header.Get("X-Checksum-Sha1"),
+					properties:     make(map[string]string),
+					body:           string(must(io.ReadAll(req.Body))),
+				}
+				for _, param := range segments[1:] {
+					key, value, _ := strings.Cut(param, "=")
+					dr.properties[key] = value
+				}
+				deployRequests = append(deployRequests, dr)
+
+				if dr.checksumDeploy && !slices.Contains(storedChecksums, dr.sha1) {
+					res.WriteHeader(http.StatusNotFound)
+					return
+				}
 				res.WriteHeader(http.StatusCreated)
 			}), requireAuth))
 		})
-		AfterEach(func() {
-			_ = os.RemoveAll(serverReleasesDirectory)
-		})
 
-		It("it uploads the file to the server", func() { // testing UploadRelease
-			f, err := os.Open(filepath.Join("testdata", "some-release.tgz"))
-			Expect(err).NotTo(HaveOccurred())
-			defer closeAndIgnoreError(f)
-
-			resultLock, resultErr := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+		uploadMango := func() (cargo.BOSHReleaseTarballLock, error) {
+			return source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 				Name:            "mango",
 				Version:         "2.3.4",
 				StemcellOS:      "smoothie",
 				StemcellVersion: "9.9",
-			}, f)
+			}, strings.NewReader(releaseContents))
+		}
+
+		It("it uploads the file to the server", func() { // testing UploadRelease
+			resultLock, resultErr := uploadMango()
 
 			Expect(resultErr).NotTo(HaveOccurred())
 			Expect(resultLock).To(Equal(cargo.BOSHReleaseTarballLock{
-				Name:    "mango",
-				Version: "2.3.4",
-				// StemcellOS:      "smoothie",
-				// StemcellVersion: "9.9",
-				// SHA1:         "some-sha",
+				Name:         "mango",
+				Version:      "2.3.4",
+				SHA1:         releaseSHA1,
 				RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
 				RemoteSource: "some-mango-tree",
 			}))
-			Expect(filepath.Join(serverReleasesDirectory, "mango-2.3.4-smoothie-9.9.tgz")).To(BeAnExistingFile())
+			Expect(deployRequests).To(HaveLen(2))
+			Expect(deployRequests[0].checksumDeploy).To(BeTrue())
+			Expect(deployRequests[1].checksumDeploy).To(BeFalse())
+			Expect(deployRequests[1].sha1).To(Equal(releaseSHA1))
+			Expect(deployRequests[1].body).To(Equal(releaseContents))
+		})
+
+		It("attaches the release properties", func() {
+			_, resultErr := uploadMango()
+
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(deployRequests[len(deployRequests)-1].properties).To(Equal(map[string]string{
+				"bosh.release.name":     "mango",
+				"bosh.release.version":  "2.3.4",
+				"bosh.stemcell.os":      "smoothie",
+				"bosh.stemcell.version": "9.9",
+				"bosh.release.sha1":     releaseSHA1,
+			}))
+		})
+
+		When("the server already stores the release bytes", func() {
+			BeforeEach(func() {
+				storedChecksums = []string{releaseSHA1}
+			})
+
+			It("deploys the release by checksum", func() {
+				resultLock, resultErr := uploadMango()
+
+				Expect(resultErr).NotTo(HaveOccurred())
+				Expect(resultLock.SHA1).To(Equal(releaseSHA1))
+				Expect(deployRequests).To(HaveLen(1))
+				Expect(deployRequests[0].checksumDeploy).To(BeTrue())
+				Expect(deployRequests[0].body).To(BeEmpty())
+				Expect(deployRequests[0].properties).To(HaveKeyWithValue("bosh.release.name", "mango"))
+			})
 		})
 	})
 
@@ -236,7 +575,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 		})
 		Describe("GetMatchedRelease", func() {
 			It("returns a helpful message", func() {
-				_, resultErr := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
+				_, resultErr := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:            "mango",
 					Version:         "2.3.4",
 					StemcellOS:      "smoothie",
@@ -248,7 +587,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 		})
 		Describe("FindReleaseVersion", func() {
 			It("returns a helpful message", func() {
-				_, resultErr := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{
+				_, resultErr := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:            "mango",
 					Version:         "2.3.4",
 					StemcellOS:      "smoothie",
@@ -260,7 +599,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 		})
 		Describe("DownloadRelease", func() {
 			It("returns a helpful message", func() {
-				_, resultErr := source.DownloadRelease(releasesDirectory, cargo.BOSHReleaseTarballLock{
+				_, resultErr := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
 					Name:         "mango",
 					Version:      "2.3.4",
 					RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
@@ -272,7 +611,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 		})
 		Describe("UploadRelease", func() {
 			It("returns a helpful message", func() {
-				_, resultErr := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+				_, resultErr := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:            "mango",
 					Version:         "2.3.4",
 					StemcellOS:      "smoothie",
@@ -292,7 +631,7 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 			})
 		})
 		It("returns ErrNotFound", func() {
-			_, err := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{
+			_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
 				Name:            "missing-release",
 				Version:         "1.2.3",
 				StemcellOS:      "ubuntu-jammy",
@@ -0,0 +1,479 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	azureStorageVersion = "2021-08-06"
+
+	// the well-known account of the Azurite emulator, used for UseDevelopmentStorage=true
+	azureDevelopmentAccountName = "devstoreaccount1"
+	azureDevelopmentAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	azureDevelopmentEndpoint    = "http://127.0.0.1:10000/" + azureDevelopmentAccountName
+)
+
+// AzureReleaseSource stores releases as block blobs in an Azure Blob Storage container.
+// The storage account and its credentials (an account key or a shared access signature)
+// are read from the connection string in connection_string. Set endpoint to use another
+// blob endpoint, for example the Azurite emulator.
+type AzureReleaseSource struct {
+	objectStoreReleaseSource
+}
+
+// NewAzureReleaseSource will provision a new AzureReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewAzureReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *AzureReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeAzure {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.Container == "" {
+		panic(`Missing required field "container" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.ID == "" {
+		c.ID = c.Container
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[Azure release source] ", log.Default().Flags())
+	}
+	return &AzureReleaseSource{objectStoreReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+		store:               newAzureContainer(c),
+	}}
+}
+
+const (
+	// azureMaxPutBlobSize is the largest blob that may be written with a single Put Blob request.
+	azureMaxPutBlobSize = 5000 * 1024 * 1024
+	// azureBlockSize is the size of the blocks staged for larger blobs. A blob has at most
+	// 50,000 blocks, so blobs of up to 4.7 TiB can be uploaded.
+	azureBlockSize = 100 * 1024 * 1024
+)
+
+func newAzureContainer(c cargo.ReleaseSourceConfig) *azureContainer {
+	container := &azureContainer{
+		config:       c,
+		credentials:  newReleaseSourceCredentials(DefaultCredentialProvider()),
+		putBlobLimit: azureMaxPutBlobSize,
+		blockSize:    azureBlockSize,
+	}
+	container.client = &http.Client{Transport: &azureSharedKeyTransport{
+		account: container.account,
+		base:    http.DefaultTransport,
+	}}
+	return container
+}
+
+type azureContainer struct {
+	config      cargo.ReleaseSourceConfig
+	credentials *releaseSourceCredentials
+	client      *http.Client
+
+	// putBlobLimit is the largest blob uploaded with a single request; larger blobs are
+	// uploaded in blocks of blockSize.
+	putBlobLimit int64
+	blockSize    int64
+
+	acct lazyResult[azureAccount]
+}
+
+// azureAccount is a storage account parsed from a connection string.
+type azureAccount struct {
+	name     string
+	key      []byte
+	endpoint string
+	sas      string
+}
+
+// account resolves connection_string when it is first needed.
+func (c *azureContainer) account(ctx context.Context) (azureAccount, error) {
+	return c.acct.get(ctx, c.resolveAccount)
+}
+
+func (c *azureContainer) resolveAccount(ctx context.Context) (azureAccount, error) {
+	config, err := c.credentials.resolve(ctx, c.config)
+	if err != nil {
+		return azureAccount{}, err
+	}
+	account, err := parseAzureConnectionString(config.ConnectionString)
+	if err != nil {
+		return azureAccount{}, fmt.Errorf("release source %q has an invalid connection_string: %w", c.config.ID, err)
+	}
+	if config.Endpoint != "" {
+		account.endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	if account.endpoint == "" {
+		return azureAccount{}, fmt.Errorf("release source %q requires connection_string or endpoint", c.config.ID)
+	}
+	return account, nil
+}
+
+// parseAzureConnectionString parses the parts of a storage account connection string used
+// for blobs. The errors do not include the connection string because it holds secrets.
+func parseAzureConnectionString(connectionString string) (azureAccount, error) {
+	settings := make(map[string]string)
+	for _, part := range strings.Split(connectionString, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return azureAccount{}, errors.New("expected semicolon separated key=value settings")
+		}
+		settings[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if strings.EqualFold(settings["usedevelopmentstorage"], "true") {
+		key, _ := base64.StdEncoding.DecodeString(azureDevelopmentAccountKey)
+		return azureAccount{name: azureDevelopmentAccountName, key: key, endpoint: azureDevelopmentEndpoint}, nil
+	}
+
+	account := azureAccount{
+		name:     settings["accountname"],
+		endpoint: strings.TrimSuffix(settings["blobendpoint"], "/"),
+		sas:      strings.TrimPrefix(settings["sharedaccesssignature"], "?"),
+	}
+	if accountKey := settings["accountkey"]; accountKey != "" {
+		key, err := base64.StdEncoding.DecodeString(accountKey)
+		if err != nil {
+			return azureAccount{}, errors.New("AccountKey is not base64 encoded")
+		}
+		account.key = key
+	}
+	if account.key != nil && account.name == "" {
+		return azureAccount{}, errors.New("AccountKey requires AccountName")
+	}
+	if account.endpoint == "" && account.name != "" {
+		protocol := settings["defaultendpointsprotocol"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		suffix := settings["endpointsuffix"]
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		account.endpoint = protocol + "://" + account.name + ".blob." + suffix
+	}
+	return account, nil
+}
+
+func (c *azureContainer) objectURL(key string) string {
+	return "azure://" + c.config.Container + "/" + key
+}
+
+// url returns the URL of the blob with key, or of the container when key is empty.
+func (c *azureContainer) url(ctx context.Context, key string, query url.Values) (string, error) {
+	account, err := c.account(ctx)
+	if err != nil {
+		return "", err
+	}
+	u := account.endpoint + "/" + url.PathEscape(c.config.Container)
+	if key != "" {
+		segments := strings.Split(key, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		u += "/" + strings.Join(segments, "/")
+	}
+	rawQuery := query.Encode()
+	if account.sas != "" && account.key == nil {
+		if rawQuery != "" {
+			rawQuery += "&"
+		}
+		rawQuery += account.sas
+	}
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	return u, nil
+}
+
+func (c *azureContainer) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u, err := c.url(ctx, key, query)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u, body)
+}
+
+func (c *azureContainer) exists(ctx context.Context, key string) (bool, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, objectStoreStatusError(res, "get "+c.objectURL(key))
+	}
+}
+
+func (c *azureContainer) list(ctx context.Context, prefix string, fn func(string)) error {
+	var marker string
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := c.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+		res, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		if res.StatusCode != http.StatusOK {
+			err = objectStoreStatusError(res, "list "+c.objectURL(prefix))
+		} else {
+			err = xml.NewDecoder(res.Body).Decode(&page)
+		}
+		closeAndIgnoreError(res.Body)
+		if err != nil {
+			return err
+		}
+		for _, blob := range page.Blobs {
+			fn(blob.Name)
+		}
+		if page.NextMarker == "" {
+			return nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+func (c *azureContainer) download(ctx context.Context, key string, file *os.File, policy retryPolicy, logger *log.Logger) error {
+	return downloadHTTPFile(ctx, c.client, policy, logger, file, func(ctx context.Context) (*http.Request, error) {
+		return c.newRequest(ctx, http.MethodGet, key, nil, nil)
+	}, func(statusCode int) string {
+		return fmt.Sprintf("failed to download %s with error code %d", c.objectURL(key), statusCode)
+	})
+}
+
+// upload puts a block blob with a single request. Without overwrite the request is
+// conditional on the blob not existing, so a release uploaded at the same time is not replaced.
+func (c *azureContainer) upload(ctx context.Context, key string, body io.ReadSeeker, size int64, overwrite bool) error {
+	if size > c.putBlobLimit {
+		return c.uploadBlocks(ctx, key, body, size, overwrite)
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, key, nil, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	if !overwrite {
+		req.Header.Set("If-None-Match", "*")
+	}
+	return c.commitUpload(req, key, overwrite)
+}
+
+// commitUpload sends a request that creates the blob at key, Put Blob or Put Block List.
+func (c *azureContainer) commitUpload(req *http.Request, key string, overwrite bool) error {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		if !overwrite {
+			return fmt.Errorf("%s %w", c.objectURL(key), ErrAlreadyExists)
+		}
+		return objectStoreStatusError(res, "upload "+c.objectURL(key))
+	default:
+		return objectStoreStatusError(res, "upload "+c.objectURL(key))
+	}
+}
+
+// uploadBlocks uploads blobs larger than a single Put Blob request allows. The body is staged
+// in blocks with Put Block and the blob is created by committing them with Put Block List.
+func (c *azureContainer) uploadBlocks(ctx context.Context, key string, body io.Reader, size int64, overwrite bool) error {
+	if !overwrite {
+		// check first so the blocks are not uploaded for nothing
+		found, err := c.exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if found {
+			return fmt.Errorf("%s %w", c.objectURL(key), ErrAlreadyExists)
+		}
+	}
+
+	var blockList bytes.Buffer
+	blockList.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for offset, n := int64(0), 0; offset < size; offset, n = offset+c.blockSize, n+1 {
+		length := min(c.blockSize, size-offset)
+		// the IDs of the blocks of a blob must all have the same length
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%06d", n)))
+		if err := c.putBlock(ctx, key, blockID, io.LimitReader(body, length), length); err != nil {
+			return err
+		}
+		blockList.WriteString("<Latest>" + blockID + "</Latest>")
+	}
+	blockList.WriteString("</BlockList>")
+
+	req, err := c.newRequest(ctx, http.MethodPut, key, url.Values{"comp": {"blocklist"}}, &blockList)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("x-ms-blob-content-type", "application/octet-stream")
+	if !overwrite {
+		req.Header.Set("If-None-Match", "*")
+	}
+	return c.commitUpload(req, key, overwrite)
+}
+
+func (c *azureContainer) putBlock(ctx context.Context, key, blockID string, body io.Reader, length int64) error {
+	req, err := c.newRequest(ctx, http.MethodPut, key, url.Values{"comp": {"block"}, "blockid": {blockID}}, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = length
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusCreated {
+		return objectStoreStatusError(res, "upload a block of "+c.objectURL(key))
+	}
+	return nil
+}
+
+// checkUpload stages a block for the blob without committing it. Uncommitted blocks are not
+// visible and are discarded by the service.
+func (c *azureContainer) checkUpload(ctx context.Context, key string) error {
+	const block = "kiln upload check"
+	query := url.Values{"comp": {"block"}, "blockid": {base64.StdEncoding.EncodeToString([]byte(block))}}
+	req, err := c.newRequest(ctx, http.MethodPut, key, query, strings.NewReader(block))
+	if err != nil {
+		return err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusCreated {
+		return objectStoreStatusError(res, "stage a block for "+c.objectURL(key))
+	}
+	return nil
+}
+
+// azureSharedKeyTransport adds the headers required by the Blob service to requests and
+// signs them with the account key. Requests are signed when they are sent so headers added
+// after a request is created, like Range when a download is resumed, are signed.
+type azureSharedKeyTransport struct {
+	account func(ctx context.Context) (azureAccount, error)
+	base    http.RoundTripper
+}
+
+func (t *azureSharedKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	account, err := t.account(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("x-ms-version", azureStorageVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if account.key != nil {
+		mac := hmac.New(sha256.New, account.key)
+		mac.Write([]byte(azureStringToSign(req, account.name)))
+		req.Header.Set("Authorization", "SharedKey "+account.name+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	return t.base.RoundTrip(req)
+}
+
+// azureStringToSign returns the string signed for Shared Key authorization.
+// See https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func azureStringToSign(req *http.Request, accountName string) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var canonicalHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasPrefix(name, "x-ms-") {
+			canonicalHeaders = append(canonicalHeaders, name+":"+strings.Join(values, ","))
+		}
+	}
+	slices.Sort(canonicalHeaders)
+
+	var resource bytes.Buffer
+	resource.WriteString("/" + accountName)
+	if p := req.URL.EscapedPath(); p != "" {
+		resource.WriteString(p)
+	} else {
+		resource.WriteString("/")
+	}
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		values := query[name]
+		slices.Sort(values)
+		resource.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(values, ","))
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // x-ms-date is used instead of Date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(canonicalHeaders, "\n"),
+		resource.String(),
+	}, "\n")
+}
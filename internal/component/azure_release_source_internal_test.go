@@ -0,0 +1,183 @@
+package component
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestAzureStringToSign(t *testing.T) {
+	please := NewWithT(t)
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:10000/devstoreaccount1/releases?restype=container&comp=list&prefix=bpm%2F", nil)
+	please.Expect(err).NotTo(HaveOccurred())
+	req.Header.Set("x-ms-version", azureStorageVersion)
+	req.Header.Set("x-ms-date", "Sun, 18 Oct 2026 12:00:00 GMT")
+	req.Header.Set("Range", "bytes=10-")
+
+	please.Expect(azureStringToSign(req, "devstoreaccount1")).To(Equal("GET\n\n\n\n\n\n\n\n\n\n\nbytes=10-\n" +
+		"x-ms-date:Sun, 18 Oct 2026 12:00:00 GMT\n" +
+		"x-ms-version:2021-08-06\n" +
+		"/devstoreaccount1/devstoreaccount1/releases\n" +
+		"comp:list\n" +
+		"prefix:bpm/\n" +
+		"restype:container"))
+}
+
+func TestAzureStringToSign_upload(t *testing.T) {
+	please := NewWithT(t)
+	req, err := http.NewRequest(http.MethodPut, "https://example.blob.core.windows.net/releases/bpm/bpm-1.2.0.tgz", nil)
+	please.Expect(err).NotTo(HaveOccurred())
+	req.ContentLength = 42
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("If-None-Match", "*")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	please.Expect(azureStringToSign(req, "example")).To(Equal("PUT\n\n\n42\n\napplication/octet-stream\n\n\n\n*\n\n\n" +
+		"x-ms-blob-type:BlockBlob\n" +
+		"/example/releases/bpm/bpm-1.2.0.tgz"))
+}
+
+func TestParseAzureConnectionString(t *testing.T) {
+	t.Run("account key", func(t *testing.T) {
+		please := NewWithT(t)
+		account, err := parseAzureConnectionString("DefaultEndpointsProtocol=https;AccountName=example;AccountKey=c2VjcmV0;EndpointSuffix=core.usgovcloudapi.net")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(account).To(Equal(azureAccount{
+			name:     "example",
+			key:      []byte("secret"),
+			endpoint: "https://example.blob.core.usgovcloudapi.net",
+		}))
+	})
+
+	t.Run("default endpoint", func(t *testing.T) {
+		please := NewWithT(t)
+		account, err := parseAzureConnectionString("AccountName=example;AccountKey=c2VjcmV0")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(account.endpoint).To(Equal("https://example.blob.core.windows.net"))
+	})
+
+	t.Run("shared access signature", func(t *testing.T) {
+		please := NewWithT(t)
+		account, err := parseAzureConnectionString("BlobEndpoint=https://example.blob.core.windows.net/;SharedAccessSignature=?sv=2021-08-06&sig=abc")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(account).To(Equal(azureAccount{
+			endpoint: "https://example.blob.core.windows.net",
+			sas:      "sv=2021-08-06&sig=abc",
+		}))
+	})
+
+	t.Run("development storage", func(t *testing.T) {
+		please := NewWithT(t)
+		account, err := parseAzureConnectionString("UseDevelopmentStorage=true")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(account.name).To(Equal("devstoreaccount1"))
+		please.Expect(account.endpoint).To(Equal("http://127.0.0.1:10000/devstoreaccount1"))
+		please.Expect(account.key).NotTo(BeEmpty())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		please := NewWithT(t)
+		_, err := parseAzureConnectionString("AccountName=example;AccountKey=not base64!")
+		please.Expect(err).To(MatchError("AccountKey is not base64 encoded"))
+		_, err = parseAzureConnectionString("AccountKey=c2VjcmV0")
+		please.Expect(err).To(MatchError("AccountKey requires AccountName"))
+		_, err = parseAzureConnectionString("secret")
+		please.Expect(err).To(HaveOccurred())
+		please.Expect(err.Error()).NotTo(ContainSubstring("secret"))
+	})
+}
+
+func TestAzureContainer_account_after_a_cancelled_lookup(t *testing.T) {
+	please := NewWithT(t)
+	container := newAzureContainer(cargo.ReleaseSourceConfig{ID: "releases", Container: "releases"})
+	container.credentials = newReleaseSourceCredentials(contextCredentialProvider{secrets: map[string]string{
+		"connection_string": "AccountName=example;AccountKey=c2VjcmV0",
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := container.account(ctx)
+	please.Expect(err).To(MatchError(context.Canceled))
+
+	account, err := container.account(context.Background())
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(account.name).To(Equal("example"))
+}
+
+func TestAzureContainer_uploadBlocks(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		exists   bool
+		requests []string
+		blocks   = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		query := r.URL.Query()
+		requests = append(requests, r.Method+" "+query.Get("comp"))
+		switch {
+		case r.Method == http.MethodHead && exists:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case query.Get("comp") == "block":
+			id, _ := base64.StdEncoding.DecodeString(query.Get("blockid"))
+			blocks[string(id)] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case query.Get("comp") == "blocklist":
+			if r.Header.Get("If-None-Match") != "*" || !strings.Contains(string(body), "<Latest>") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			blocks["list"] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	container := newAzureContainer(cargo.ReleaseSourceConfig{
+		ID:               "releases",
+		Container:        "releases",
+		ConnectionString: "UseDevelopmentStorage=true",
+		Endpoint:         server.URL + "/devstoreaccount1",
+	})
+	container.credentials = nil
+	container.putBlobLimit = 8
+	container.blockSize = 5
+
+	t.Run("stages blocks and commits the block list", func(t *testing.T) {
+		please := NewWithT(t)
+		err := container.upload(context.Background(), "bpm/bpm-1.2.0.tgz", strings.NewReader("0123456789ab"), 12, false)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(requests).To(Equal([]string{"HEAD ", "PUT block", "PUT block", "PUT block", "PUT blocklist"}))
+		please.Expect(blocks).To(HaveKeyWithValue("block-000000", "01234"))
+		please.Expect(blocks).To(HaveKeyWithValue("block-000001", "56789"))
+		please.Expect(blocks).To(HaveKeyWithValue("block-000002", "ab"))
+		please.Expect(blocks["list"]).To(ContainSubstring("<Latest>" + base64.StdEncoding.EncodeToString([]byte("block-000000")) + "</Latest><Latest>" +
+			base64.StdEncoding.EncodeToString([]byte("block-000001")) + "</Latest><Latest>" +
+			base64.StdEncoding.EncodeToString([]byte("block-000002")) + "</Latest>"))
+	})
+
+	t.Run("does not stage blocks for a blob that exists", func(t *testing.T) {
+		please := NewWithT(t)
+		mu.Lock()
+		exists, requests = true, nil
+		mu.Unlock()
+		err := container.upload(context.Background(), "bpm/bpm-1.2.0.tgz", strings.NewReader("0123456789ab"), 12, false)
+		please.Expect(IsErrAlreadyExists(err)).To(BeTrue())
+		please.Expect(requests).To(Equal([]string{"HEAD "}))
+	})
+}
@@ -0,0 +1,286 @@
+package component_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("AzureReleaseSource", func() {
+	var (
+		server        *httptest.Server
+		container     *fakeAzureContainer
+		config        cargo.ReleaseSourceConfig
+		releaseSource *component.AzureReleaseSource
+		releasesDir   string
+	)
+
+	BeforeEach(func() {
+		container = &fakeAzureContainer{
+			path: "/devstoreaccount1/releases",
+			blobs: map[string][]byte{
+				"bpm/bpm-1.1.0.tgz": []byte("bpm 1.1.0"),
+				"bpm/bpm-1.2.0.tgz": []byte("bpm 1.2.0"),
+				"bpm/bpm-2.0.0.tgz": []byte("bpm 2.0.0"),
+				"uaa/uaa-7.0.0.tgz": []byte("uaa 7.0.0"),
+			},
+		}
+		server = httptest.NewServer(container)
+		releasesDir = must(os.MkdirTemp("", "releases"))
+
+		config = cargo.ReleaseSourceConfig{
+			Type:             component.ReleaseSourceTypeAzure,
+			Container:        "releases",
+			ConnectionString: "UseDevelopmentStorage=true",
+			Endpoint:         server.URL + "/devstoreaccount1",
+			PathTemplate:     "{{.Name}}/{{.Name}}-{{.Version}}.tgz",
+		}
+	})
+
+	JustBeforeEach(func() {
+		releaseSource = component.NewAzureReleaseSource(config, log.New(GinkgoWriter, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(releasesDir)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, log.New(GinkgoWriter, "", 0))).To(BeAssignableToTypeOf(releaseSource))
+	})
+
+	It("defaults the ID to the container", func() {
+		Expect(releaseSource.Configuration().ID).To(Equal("releases"))
+	})
+
+	It("signs requests with the account key", func() {
+		_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(container.authorizations()).To(HaveEach(HavePrefix("SharedKey devstoreaccount1:")))
+	})
+
+	Describe("GetMatchedRelease", func() {
+		It("returns ErrNotFound for missing blobs", func() {
+			_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "9.9.9"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("FindReleaseVersion", func() {
+		It("lists every page of blobs under the prefix", func() {
+			lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "~1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "bpm",
+				Version:      "1.2.0",
+				SHA1:         sha1Hex("bpm 1.2.0"),
+				RemotePath:   "bpm/bpm-1.2.0.tgz",
+				RemoteSource: "releases",
+			}))
+			Expect(container.listPages()).To(BeNumerically(">", 1))
+		})
+	})
+
+	Describe("DownloadRelease", func() {
+		It("writes the blob to the releases directory", func() {
+			local, err := releaseSource.DownloadRelease(context.Background(), releasesDir, cargo.BOSHReleaseTarballLock{Name: "uaa", Version: "7.0.0", RemotePath: "uaa/uaa-7.0.0.tgz"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.Lock.SHA1).To(Equal(sha1Hex("uaa 7.0.0")))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("uaa 7.0.0")))
+		})
+	})
+
+	Describe("UploadRelease", func() {
+		It("puts a block blob at the path_template location", func() {
+			lock, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, strings.NewReader("banana 1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.SHA1).To(Equal(sha1Hex("banana 1.0.0")))
+			Expect(lock.RemotePath).To(Equal("banana/banana-1.0.0.tgz"))
+			Expect(container.blob("banana/banana-1.0.0.tgz")).To(Equal([]byte("banana 1.0.0")))
+		})
+
+		When("the blob already exists", func() {
+			It("returns ErrAlreadyExists", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"}, strings.NewReader("new bpm"))
+				Expect(component.IsErrAlreadyExists(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("azure://releases/bpm/bpm-1.2.0.tgz")))
+				Expect(container.blob("bpm/bpm-1.2.0.tgz")).To(Equal([]byte("bpm 1.2.0")))
+			})
+
+			It("replaces it when overwriting is allowed", func() {
+				_, err := releaseSource.UploadRelease(component.ContextWithOverwrite(context.Background()), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"}, strings.NewReader("new bpm"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(container.blob("bpm/bpm-1.2.0.tgz")).To(Equal([]byte("new bpm")))
+			})
+		})
+	})
+
+	Describe("CheckUpload", func() {
+		It("stages a block without committing it", func() {
+			Expect(releaseSource.CheckUpload(context.Background())).To(Succeed())
+			Expect(container.stagedBlocks).To(Equal([]string{"kiln-upload-check/kiln-upload-check-0.0.0.tgz"}))
+			Expect(container.blob("kiln-upload-check/kiln-upload-check-0.0.0.tgz")).To(BeNil())
+		})
+	})
+
+	When("the connection string has a shared access signature", func() {
+		BeforeEach(func() {
+			config.Endpoint = ""
+			config.ConnectionString = "BlobEndpoint=" + server.URL + "/devstoreaccount1/;SharedAccessSignature=sv=2021-08-06&sig=c2lnbmF0dXJl"
+		})
+
+		It("adds it to the requests", func() {
+			_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(container.authorizations()).To(HaveEach(BeEmpty()))
+			Expect(container.signatures()).To(HaveEach("c2lnbmF0dXJl"))
+		})
+	})
+
+	When("the connection string is not valid", func() {
+		BeforeEach(func() {
+			config.ConnectionString = "AccountName=devstoreaccount1;AccountKey=not base64!"
+		})
+
+		It("returns an error without the connection string", func() {
+			_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+			Expect(err).To(MatchError(ContainSubstring(`release source "releases" has an invalid connection_string`)))
+			Expect(err.Error()).NotTo(ContainSubstring("not base64!"))
+		})
+	})
+})
+
+// fakeAzureContainer serves the parts of the Blob service API used by AzureReleaseSource,
+// like Azurite. Listings are split into pages of two blobs.
+type fakeAzureContainer struct {
+	path string
+
+	mu           sync.Mutex
+	blobs        map[string][]byte
+	auth         []string
+	sigs         []string
+	pages        int
+	stagedBlocks []string
+}
+
+func (c *fakeAzureContainer) blob(key string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blobs[key]
+}
+
+func (c *fakeAzureContainer) authorizations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.auth)
+}
+
+func (c *fakeAzureContainer) signatures() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.sigs)
+}
+
+func (c *fakeAzureContainer) listPages() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pages
+}
+
+func (c *fakeAzureContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.auth = append(c.auth, r.Header.Get("Authorization"))
+	query := r.URL.Query()
+	c.sigs = append(c.sigs, query.Get("sig"))
+	if r.Header.Get("x-ms-version") == "" || r.Header.Get("x-ms-date") == "" {
+		http.Error(w, "missing required headers", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == c.path {
+		if r.Method != http.MethodGet || query.Get("restype") != "container" || query.Get("comp") != "list" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		c.list(w, query.Get("prefix"), query.Get("marker"))
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, c.path+"/")
+	if !ok {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		content, ok := c.blobs[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		c.stagedBlocks = append(c.stagedBlocks, key)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			http.Error(w, "missing blob type", http.StatusBadRequest)
+			return
+		}
+		if _, exists := c.blobs[key]; exists && r.Header.Get("If-None-Match") == "*" {
+			http.Error(w, "BlobAlreadyExists", http.StatusConflict)
+			return
+		}
+		c.blobs[key] = must(io.ReadAll(r.Body))
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (c *fakeAzureContainer) list(w http.ResponseWriter, prefix, marker string) {
+	c.pages++
+	var keys []string
+	for key := range c.blobs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	start, _ := strconv.Atoi(marker)
+	end := min(start+2, len(keys))
+	type blob struct {
+		Name string `xml:"Name"`
+	}
+	result := struct {
+		XMLName    xml.Name `xml:"EnumerationResults"`
+		Blobs      []blob   `xml:"Blobs>Blob"`
+		NextMarker string   `xml:"NextMarker"`
+	}{}
+	for _, key := range keys[start:end] {
+		result.Blobs = append(result.Blobs, blob{Name: key})
+	}
+	if end < len(keys) {
+		result.NextMarker = strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
@@ -83,13 +84,13 @@ func unsetStemcell(spec cargo.BOSHReleaseTarballSpecification) cargo.BOSHRelease
 	return spec
 }
 
-func (src BOSHIOReleaseSource) GetMatchedRelease(requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (src BOSHIOReleaseSource) GetMatchedRelease(ctx context.Context, requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	requirement = unsetStemcell(requirement)
 
 	for _, repo := range repos {
 		for _, suf := range suffixes {
 			fullName := repo + "/" + requirement.Name + suf
-			exists, err := src.releaseExistOnBoshio(fullName, requirement.Version)
+			exists, err := src.releaseExistOnBoshio(ctx, fullName, requirement.Version)
 			if err != nil {
 				return cargo.BOSHReleaseTarballLock{}, err
 			}
@@ -103,10 +104,10 @@ func (src BOSHIOReleaseSource) GetMatchedRelease(requirement cargo.BOSHReleaseTa
 	return cargo.BOSHReleaseTarballLock{}, ErrNotFound
 }
 
-func (src BOSHIOReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+func (src BOSHIOReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
 	spec = unsetStemcell(spec)
 
-	constraint, err := spec.VersionConstraints()
+	constraint, err := spec.VersionMatcher()
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -116,7 +117,7 @@ func (src BOSHIOReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballS
 	for _, repo := range repos {
 		for _, suf := range suffixes {
 			fullName := repo + "/" + spec.Name + suf
-			releaseResponses, err := src.getReleases(fullName)
+			releaseResponses, err := src.getReleases(ctx, fullName)
 			if err != nil {
 				return cargo.BOSHReleaseTarballLock{}, err
 			}
@@ -139,26 +140,24 @@ func (src BOSHIOReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballS
 	return cargo.BOSHReleaseTarballLock{}, ErrNotFound
 }
 
-func (src BOSHIOReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+func (src BOSHIOReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (_ Local, err error) {
 	src.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeBOSHIO, src.ID())
 
 	downloadURL := remoteRelease.RemotePath
 
-	resp, err := http.Get(downloadURL)
-	if err != nil {
-		return Local{}, err
-	}
-
 	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
 
 	out, err := os.Create(filePath)
 	if err != nil {
 		return Local{}, err
 	}
-	defer closeAndIgnoreError(out)
+	defer removePartialDownload(out, &err)
 
-	_, err = io.Copy(out, resp.Body)
-	_ = resp.Body.Close()
+	err = downloadHTTPFile(ctx, http.DefaultClient, newRetryPolicy(src.Retry), src.logger, out, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	}, func(statusCode int) string {
+		return fmt.Sprintf("failed to download %s release from bosh.io with error code %d", remoteRelease.Name, statusCode)
+	})
 	if err != nil {
 		return Local{}, err
 	}
@@ -193,9 +192,16 @@ func (src BOSHIOReleaseSource) createReleaseRemote(spec cargo.BOSHReleaseTarball
 	return releaseRemote
 }
 
-func (src BOSHIOReleaseSource) getReleases(name string) ([]releaseResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/api/v1/releases/github.com/%s", src.serverURI, name))
+func (src BOSHIOReleaseSource) getReleases(ctx context.Context, name string) ([]releaseResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/v1/releases/github.com/%s", src.serverURI, name), nil)
 	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("bosh.io API is down with error: %w", err)
 	}
 	if resp.StatusCode >= 500 {
@@ -232,8 +238,8 @@ type releaseResponse struct {
 	SHA     string `json:"sha1"`
 }
 
-func (src BOSHIOReleaseSource) releaseExistOnBoshio(name, version string) (bool, error) {
-	releaseResponses, err := src.getReleases(name)
+func (src BOSHIOReleaseSource) releaseExistOnBoshio(ctx context.Context, name, version string) (bool, error) {
+	releaseResponses, err := src.getReleases(ctx, name)
 	if err != nil {
 		return false, err
 	}
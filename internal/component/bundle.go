@@ -0,0 +1,315 @@
+package component
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	// ReleaseSourceTypeBundle is the type of the release source returned by NewBundleReleaseSource.
+	// Bundles are passed on the command line so the type can not be used in a Kilnfile.
+	ReleaseSourceTypeBundle = "bundle"
+
+	BundleManifestFileName     = "bundle.yml"
+	BundleKilnfileFileName     = "Kilnfile"
+	BundleKilnfileLockFileName = "Kilnfile.lock"
+
+	bundleReleasesDirectory = "releases"
+)
+
+// BundleManifest is the first file in a bundle. It records the checksums of the other files.
+type BundleManifest struct {
+	Files    []BundleFile    `yaml:"files"`
+	Releases []BundleRelease `yaml:"releases"`
+}
+
+type BundleFile struct {
+	Path   string `yaml:"path"`
+	SHA256 string `yaml:"sha256"`
+}
+
+type BundleRelease struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Path    string `yaml:"path"`
+	SHA1    string `yaml:"sha1"`
+	SHA256  string `yaml:"sha256"`
+}
+
+// WriteBundle writes an uncompressed tar archive with the manifest, the Kilnfile, the
+// Kilnfile.lock and the release tarballs. Release tarballs are already compressed, and
+// leaving the archive uncompressed lets readers skip to a release without reading the
+// ones before it.
+func WriteBundle(ctx context.Context, w io.Writer, kilnfile, kilnfileLock []byte, releases []Local) error {
+	manifest := BundleManifest{
+		Files: []BundleFile{
+			{Path: BundleKilnfileFileName, SHA256: bytesSHA256(kilnfile)},
+			{Path: BundleKilnfileLockFileName, SHA256: bytesSHA256(kilnfileLock)},
+		},
+	}
+	for _, release := range releases {
+		sum1, sum256, err := fileSums(ctx, release.LocalPath)
+		if err != nil {
+			return fmt.Errorf("failed to checksum release %s %s: %w", release.Lock.Name, release.Lock.Version, err)
+		}
+		manifest.Releases = append(manifest.Releases, BundleRelease{
+			Name:    release.Lock.Name,
+			Version: release.Lock.Version,
+			Path:    path.Join(bundleReleasesDirectory, filepath.Base(release.LocalPath)),
+			SHA1:    sum1,
+			SHA256:  sum256,
+		})
+	}
+	manifestBuf, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	for _, file := range []struct {
+		name     string
+		contents []byte
+	}{
+		{name: BundleManifestFileName, contents: manifestBuf},
+		{name: BundleKilnfileFileName, contents: kilnfile},
+		{name: BundleKilnfileLockFileName, contents: kilnfileLock},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(file.contents)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.contents); err != nil {
+			return err
+		}
+	}
+	for i, release := range releases {
+		if err := writeBundleFile(ctx, tw, manifest.Releases[i].Path, release.LocalPath); err != nil {
+			return fmt.Errorf("failed to add release %s %s to the bundle: %w", release.Lock.Name, release.Lock.Version, err)
+		}
+	}
+	return tw.Close()
+}
+
+func writeBundleFile(ctx context.Context, tw *tar.Writer, name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(f)
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, contextReader{ctx: ctx, r: f})
+	return err
+}
+
+// Bundle reads an archive written by WriteBundle.
+type Bundle struct {
+	Path     string
+	Manifest BundleManifest
+}
+
+// OpenBundle reads the manifest of the bundle at bundlePath.
+func OpenBundle(bundlePath string) (*Bundle, error) {
+	bundle := &Bundle{Path: bundlePath}
+	buf, err := bundle.ReadFile(context.Background(), BundleManifestFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %q: %w", bundlePath, err)
+	}
+	if err := yaml.Unmarshal(buf, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest of bundle %q: %w", bundlePath, err)
+	}
+	return bundle, nil
+}
+
+// ReadFile returns the contents of the Kilnfile or Kilnfile.lock after checking them
+// against the manifest.
+func (bundle *Bundle) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	var buf []byte
+	err := bundle.walk(ctx, name, func(r io.Reader) (err error) {
+		buf, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if name == BundleManifestFileName {
+		return buf, nil
+	}
+	for _, file := range bundle.Manifest.Files {
+		if file.Path == name {
+			if sum := bytesSHA256(buf); sum != file.SHA256 {
+				return nil, fmt.Errorf("%s in bundle %q has an incorrect SHA256 - expected %q, got %q", name, bundle.Path, file.SHA256, sum)
+			}
+			return buf, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not listed in the manifest of bundle %q", name, bundle.Path)
+}
+
+// Release returns the release with the name and version.
+func (bundle *Bundle) Release(name, version string) (BundleRelease, bool) {
+	for _, release := range bundle.Manifest.Releases {
+		if release.Name == name && release.Version == version {
+			return release, true
+		}
+	}
+	return BundleRelease{}, false
+}
+
+// ExtractRelease writes the release tarball into releasesDir. It returns the SHA1 and SHA256
+// of the written file; the caller must compare them with the Kilnfile.lock. When extraction
+// fails no file is left in releasesDir.
+func (bundle *Bundle) ExtractRelease(ctx context.Context, releasesDir string, release BundleRelease) (Local, error) {
+	outputFile := filepath.Join(releasesDir, path.Base(release.Path))
+	err := bundle.walk(ctx, release.Path, func(r io.Reader) (err error) {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer removePartialDownload(f, &err)
+		_, err = io.Copy(progressWriter(ctx, f, 0), contextReader{ctx: ctx, r: r})
+		return err
+	})
+	if err != nil {
+		return Local{}, err
+	}
+	sum1, sum256, err := fileSums(ctx, outputFile)
+	if err != nil {
+		_ = os.Remove(outputFile)
+		return Local{}, err
+	}
+	return Local{
+		Lock: cargo.BOSHReleaseTarballLock{
+			Name:         release.Name,
+			Version:      release.Version,
+			SHA1:         sum1,
+			SHA256:       sum256,
+			RemoteSource: bundle.Path,
+			RemotePath:   release.Path,
+		},
+		LocalPath: outputFile,
+	}, nil
+}
+
+// walk calls fn with the contents of the named file in the archive.
+func (bundle *Bundle) walk(ctx context.Context, name string, fn func(io.Reader) error) error {
+	f, err := os.Open(bundle.Path)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(f)
+
+	// tar.Reader seeks over the contents of the files that are skipped
+	tr := tar.NewReader(f)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%s %w in bundle", name, ErrNotFound)
+			}
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && path.Clean(header.Name) == name {
+			return fn(tr)
+		}
+	}
+}
+
+func bytesSHA256(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// BundleReleaseSource is a read-only release source for the releases in a bundle.
+type BundleReleaseSource struct {
+	bundle *Bundle
+}
+
+func NewBundleReleaseSource(bundle *Bundle) BundleReleaseSource {
+	return BundleReleaseSource{bundle: bundle}
+}
+
+func (src BundleReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return cargo.ReleaseSourceConfig{ID: src.bundle.Path, Type: ReleaseSourceTypeBundle}
+}
+
+func (src BundleReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	if err := ctx.Err(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	release, found := src.bundle.Release(spec.Name, spec.Version)
+	if !found {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return src.lock(release), nil
+}
+
+func (src BundleReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+	if err := ctx.Err(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	var (
+		found        BundleRelease
+		foundVersion *semver.Version
+	)
+	for _, release := range src.bundle.Manifest.Releases {
+		if release.Name != spec.Name {
+			continue
+		}
+		version, err := semver.NewVersion(release.Version)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		if foundVersion == nil || version.GreaterThan(foundVersion) {
+			found, foundVersion = release, version
+		}
+	}
+	if foundVersion == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return src.lock(found), nil
+}
+
+// DownloadRelease extracts the release with the name and version of remoteRelease.
+func (src BundleReleaseSource) DownloadRelease(ctx context.Context, releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+	release, found := src.bundle.Release(remoteRelease.Name, remoteRelease.Version)
+	if !found {
+		return Local{}, fmt.Errorf("release %s %s %w in bundle %q", remoteRelease.Name, remoteRelease.Version, ErrNotFound, src.bundle.Path)
+	}
+	return src.bundle.ExtractRelease(ctx, releasesDir, release)
+}
+
+func (src BundleReleaseSource) lock(release BundleRelease) cargo.BOSHReleaseTarballLock {
+	return cargo.BOSHReleaseTarballLock{
+		Name:         release.Name,
+		Version:      release.Version,
+		SHA1:         release.SHA1,
+		SHA256:       release.SHA256,
+		RemoteSource: src.bundle.Path,
+		RemotePath:   release.Path,
+	}
+}
@@ -0,0 +1,118 @@
+package component_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("bundles", func() {
+	var (
+		tmpDir, bundlePath string
+		releases           []component.Local
+	)
+
+	sha1Of := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		tmpDir = must(os.MkdirTemp("", "bundle"))
+		bundlePath = filepath.Join(tmpDir, "tile.bundle")
+		releases = nil
+		for _, rel := range []struct{ name, version string }{{"bpm", "1.1.0"}, {"bpm", "1.2.0"}, {"routing", "0.2.0"}} {
+			p := filepath.Join(tmpDir, rel.name+"-"+rel.version+".tgz")
+			Expect(os.WriteFile(p, []byte(rel.name+rel.version), 0o644)).To(Succeed())
+			releases = append(releases, component.Local{Lock: cargo.BOSHReleaseTarballLock{Name: rel.name, Version: rel.version}, LocalPath: p})
+		}
+
+		var buf bytes.Buffer
+		Expect(component.WriteBundle(context.Background(), &buf, []byte("slug: tile\n"), []byte("releases: []\n"), releases)).To(Succeed())
+		Expect(os.WriteFile(bundlePath, buf.Bytes(), 0o644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("writes the manifest first", func() {
+		f := must(os.Open(bundlePath))
+		defer func() { _ = f.Close() }()
+		header := must(tar.NewReader(f).Next())
+		Expect(header.Name).To(Equal(component.BundleManifestFileName))
+	})
+
+	It("records the checksums of the releases", func() {
+		bundle := must(component.OpenBundle(bundlePath))
+		release, found := bundle.Release("routing", "0.2.0")
+		Expect(found).To(BeTrue())
+		Expect(release.Path).To(Equal("releases/routing-0.2.0.tgz"))
+		Expect(release.SHA1).To(Equal(sha1Of("routing0.2.0")))
+	})
+
+	It("reads the Kilnfile", func() {
+		bundle := must(component.OpenBundle(bundlePath))
+		Expect(bundle.ReadFile(context.Background(), component.BundleKilnfileFileName)).To(Equal([]byte("slug: tile\n")))
+	})
+
+	When("a file does not match the manifest", func() {
+		BeforeEach(func() {
+			buf := must(os.ReadFile(bundlePath))
+			Expect(os.WriteFile(bundlePath, bytes.Replace(buf, []byte("slug: tile"), []byte("slug: evil"), 1), 0o644)).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			bundle := must(component.OpenBundle(bundlePath))
+			_, err := bundle.ReadFile(context.Background(), component.BundleKilnfileFileName)
+			Expect(err).To(MatchError(ContainSubstring("incorrect SHA256")))
+		})
+	})
+
+	Describe("BundleReleaseSource", func() {
+		var source component.BundleReleaseSource
+
+		BeforeEach(func() {
+			source = component.NewBundleReleaseSource(must(component.OpenBundle(bundlePath)))
+		})
+
+		It("does not need network access", func() {
+			Expect(component.NeedsNetwork(source.Configuration())).To(BeFalse())
+		})
+
+		It("finds the highest matching version", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "~1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("1.2.0"))
+			Expect(lock.SHA1).To(Equal(sha1Of("bpm1.2.0")))
+		})
+
+		It("does not find releases that are not in the bundle", func() {
+			_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "uaa", Version: "1.0.0"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+
+		It("extracts releases", func() {
+			releasesDir := must(os.MkdirTemp(tmpDir, "releases"))
+			local, err := source.DownloadRelease(context.Background(), releasesDir, cargo.BOSHReleaseTarballLock{Name: "bpm", Version: "1.1.0", RemoteSource: "bosh.io"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "bpm-1.1.0.tgz")))
+			Expect(local.Lock.SHA1).To(Equal(sha1Of("bpm1.1.0")))
+
+			f := must(os.Open(local.LocalPath))
+			defer func() { _ = f.Close() }()
+			Expect(io.ReadAll(f)).To(Equal([]byte("bpm1.1.0")))
+		})
+	})
+})
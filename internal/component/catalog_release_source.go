@@ -0,0 +1,386 @@
+package component
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// CatalogReleaseSource is a fake release source backed by a catalog file listing the
+// available releases. It does not make network requests, so Kilnfile workflows can be run
+// deterministically in CI. Stub tarballs are read from paths relative to the catalog;
+// when a release does not have a path, a minimal release tarball is generated.
+type CatalogReleaseSource struct {
+	cargo.ReleaseSourceConfig
+	logger *log.Logger
+
+	// mu serializes uploads, which rewrite the catalog
+	mu *sync.Mutex
+}
+
+// ReleaseCatalog is the structure of a YAML or JSON release catalog file.
+type ReleaseCatalog struct {
+	Releases []ReleaseCatalogEntry `yaml:"releases" json:"releases"`
+}
+
+type ReleaseCatalogEntry struct {
+	Name            string `yaml:"name"                       json:"name"`
+	Version         string `yaml:"version"                    json:"version"`
+	StemcellOS      string `yaml:"stemcell_os,omitempty"      json:"stemcell_os,omitempty"`
+	StemcellVersion string `yaml:"stemcell_version,omitempty" json:"stemcell_version,omitempty"`
+	SHA1            string `yaml:"sha1,omitempty"             json:"sha1,omitempty"`
+	// Path is the stub tarball for the release relative to the catalog file.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+}
+
+const defaultCatalogPathTemplate = "{{.Name}}-{{.Version}}{{if .StemcellOS}}-{{.StemcellOS}}-{{.StemcellVersion}}{{end}}.tgz"
+
+// NewCatalogReleaseSource will provision a new CatalogReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewCatalogReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *CatalogReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeCatalog {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.Catalog == "" {
+		panic(`Missing required field "catalog" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.ID == "" {
+		c.ID = c.Catalog
+	}
+	if c.PathTemplate == "" {
+		c.PathTemplate = defaultCatalogPathTemplate
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[catalog release source] ", log.Default().Flags())
+	}
+	return &CatalogReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+		mu:                  new(sync.Mutex),
+	}
+}
+
+func (src *CatalogReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease returns the catalog entry with the Name and Version of the spec. When
+// the spec has a stemcell, an entry compiled against it is preferred.
+func (src *CatalogReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	if err := ctx.Err(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	catalog, err := src.loadCatalog()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	var (
+		found ReleaseCatalogEntry
+		ok    bool
+	)
+	for _, entry := range catalog.Releases {
+		if entry.Name != spec.Name || entry.Version != spec.Version || !entry.matchesStemcell(spec) {
+			continue
+		}
+		if !ok || entry.StemcellOS != "" {
+			found, ok = entry, true
+		}
+	}
+	if !ok {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return src.lock(ctx, found, false)
+}
+
+// FindReleaseVersion returns the highest version in the catalog matching the spec.
+func (src *CatalogReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	if err := ctx.Err(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	catalog, err := src.loadCatalog()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	var (
+		found        ReleaseCatalogEntry
+		foundVersion *semver.Version
+	)
+	for _, entry := range catalog.Releases {
+		if entry.Name != spec.Name || !entry.matchesStemcell(spec) {
+			continue
+		}
+		version, err := semver.NewVersion(entry.Version)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		if foundVersion == nil || version.GreaterThan(foundVersion) ||
+			(version.Equal(foundVersion) && found.StemcellOS == "" && entry.StemcellOS != "") {
+			found, foundVersion = entry, version
+		}
+	}
+	if foundVersion == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return src.lock(ctx, found, noDownload)
+}
+
+// DownloadRelease copies the stub tarball of the catalog entry at the lock's RemotePath
+// into releaseDir, or writes a generated one when the entry does not have a path.
+func (src *CatalogReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, lock cargo.BOSHReleaseTarballLock) (_ Local, err error) {
+	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeCatalog, src.ID)
+
+	catalog, err := src.loadCatalog()
+	if err != nil {
+		return Local{}, err
+	}
+	entry, ok := src.entryAt(catalog, lock.RemotePath)
+	if !ok {
+		return Local{}, fmt.Errorf("catalog %s does not have a release at %q: %w", src.Catalog, lock.RemotePath, ErrNotFound)
+	}
+
+	outputFile := filepath.Join(releaseDir, path.Base(lock.RemotePath))
+	if entry.Path != "" {
+		err = linkOrCopy(ctx, outputFile, src.filePath(entry.Path))
+	} else {
+		err = os.WriteFile(outputFile, stubReleaseTarball(entry), 0o644)
+	}
+	if err != nil {
+		return Local{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(outputFile)
+		}
+	}()
+	reportDownloadedFile(ctx, outputFile)
+
+	lock.SHA1, err = fileSHA1(ctx, outputFile)
+	if err != nil {
+		return Local{}, err
+	}
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// UploadRelease writes the release next to the catalog at the path_template location and
+// adds it to the catalog, replacing an entry for the same release and stemcell.
+func (src *CatalogReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID, remotePath)
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	catalog, err := src.loadCatalog()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	outputFile := src.filePath(remotePath)
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0o755); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	hash := sha1.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), contextReader{ctx: ctx, r: file})
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(outputFile)
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	entry := ReleaseCatalogEntry{
+		Name:            spec.Name,
+		Version:         spec.Version,
+		StemcellOS:      spec.StemcellOS,
+		StemcellVersion: spec.StemcellVersion,
+		SHA1:            hex.EncodeToString(hash.Sum(nil)),
+		Path:            remotePath,
+	}
+	catalog.Releases = slices.DeleteFunc(catalog.Releases, func(e ReleaseCatalogEntry) bool {
+		return e.Name == entry.Name && e.Version == entry.Version && e.StemcellOS == entry.StemcellOS && e.StemcellVersion == entry.StemcellVersion
+	})
+	catalog.Releases = append(catalog.Releases, entry)
+	if err := src.writeCatalog(catalog); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         entry.SHA1,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+func (src *CatalogReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	pathBuf := new(bytes.Buffer)
+
+	err := template.Must(
+		template.New("remote-path").
+			Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
+			Parse(src.PathTemplate)).
+		Execute(pathBuf, spec)
+	if err != nil {
+		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
+	}
+
+	return path.Clean(pathBuf.String()), nil
+}
+
+func (src *CatalogReleaseSource) lock(ctx context.Context, entry ReleaseCatalogEntry, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.entryRemotePath(entry)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:            entry.Name,
+		Version:         entry.Version,
+		SHA1:            entry.SHA1,
+		StemcellOS:      entry.StemcellOS,
+		StemcellVersion: entry.StemcellVersion,
+		RemotePath:      remotePath,
+		RemoteSource:    src.ID,
+	}
+	switch {
+	case lock.SHA1 != "":
+	case noDownload:
+		lock.SHA1 = "not-calculated"
+	case entry.Path != "":
+		lock.SHA1, err = fileSHA1(ctx, src.filePath(entry.Path))
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+	default:
+		sum := sha1.Sum(stubReleaseTarball(entry))
+		lock.SHA1 = hex.EncodeToString(sum[:])
+	}
+	return lock, nil
+}
+
+func (src *CatalogReleaseSource) entryRemotePath(entry ReleaseCatalogEntry) (string, error) {
+	if entry.Path != "" {
+		return path.Clean(entry.Path), nil
+	}
+	return src.RemotePath(cargo.BOSHReleaseTarballSpecification{
+		Name:            entry.Name,
+		Version:         entry.Version,
+		StemcellOS:      entry.StemcellOS,
+		StemcellVersion: entry.StemcellVersion,
+	})
+}
+
+func (src *CatalogReleaseSource) entryAt(catalog ReleaseCatalog, remotePath string) (ReleaseCatalogEntry, bool) {
+	for _, entry := range catalog.Releases {
+		if p, err := src.entryRemotePath(entry); err == nil && p == path.Clean(remotePath) {
+			return entry, true
+		}
+	}
+	return ReleaseCatalogEntry{}, false
+}
+
+func (src *CatalogReleaseSource) loadCatalog() (ReleaseCatalog, error) {
+	var catalog ReleaseCatalog
+	buf, err := os.ReadFile(src.Catalog)
+	if err != nil {
+		return catalog, fmt.Errorf("failed to read release catalog: %w", err)
+	}
+	// YAML is a superset of JSON so both catalog formats are parsed here
+	if err := yaml.Unmarshal(buf, &catalog); err != nil {
+		return catalog, fmt.Errorf("failed to parse release catalog %s: %w", src.Catalog, err)
+	}
+	return catalog, nil
+}
+
+func (src *CatalogReleaseSource) writeCatalog(catalog ReleaseCatalog) error {
+	var (
+		buf []byte
+		err error
+	)
+	if strings.EqualFold(filepath.Ext(src.Catalog), ".json") {
+		buf, err = json.MarshalIndent(catalog, "", "  ")
+		buf = append(buf, '\n')
+	} else {
+		buf, err = yaml.Marshal(catalog)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(src.Catalog, buf, 0o644)
+}
+
+func (src *CatalogReleaseSource) filePath(remotePath string) string {
+	return filepath.Join(filepath.Dir(src.Catalog), filepath.FromSlash(remotePath))
+}
+
+func (entry ReleaseCatalogEntry) matchesStemcell(spec cargo.BOSHReleaseTarballSpecification) bool {
+	if entry.StemcellOS == "" {
+		return true
+	}
+	return entry.StemcellOS == spec.StemcellOS && (spec.StemcellVersion == "" || entry.StemcellVersion == spec.StemcellVersion)
+}
+
+// stubReleaseTarball returns a gzipped tarball with only a release.MF for the entry. The
+// tarball does not depend on the time it was generated, so its SHA1 is stable.
+func stubReleaseTarball(entry ReleaseCatalogEntry) []byte {
+	manifest := cargo.BOSHReleaseManifest{
+		Name:       entry.Name,
+		Version:    entry.Version,
+		CommitHash: "catalog",
+	}
+	if entry.StemcellOS != "" {
+		manifest.CompiledPackages = []cargo.CompiledBOSHReleasePackage{{
+			Name:     entry.Name,
+			Version:  entry.Version,
+			Stemcell: entry.StemcellOS + "/" + entry.StemcellVersion,
+		}}
+	}
+	manifestBuf, err := yaml.Marshal(manifest)
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	_ = tw.WriteHeader(&tar.Header{Name: "./release.MF", Mode: 0o644, Size: int64(len(manifestBuf)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(manifestBuf)
+	_ = tw.Close()
+	_ = gw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,233 @@
+package component_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("interacting with BOSH releases in a release catalog", func() {
+	var (
+		source *component.CatalogReleaseSource
+		config cargo.ReleaseSourceConfig
+
+		dir, releasesDirectory string
+		catalog                component.ReleaseCatalog
+	)
+
+	sha1Of := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		dir = must(os.MkdirTemp("", "release-catalog"))
+		releasesDirectory = must(os.MkdirTemp("", "releases"))
+
+		Expect(os.MkdirAll(filepath.Join(dir, "stubs"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "stubs", "mango-2.4.0.tgz"), []byte("mango 2.4.0"), 0o644)).To(Succeed())
+
+		config = cargo.ReleaseSourceConfig{
+			Type:    component.ReleaseSourceTypeCatalog,
+			ID:      "fake",
+			Catalog: filepath.Join(dir, "catalog.yml"),
+		}
+		catalog = component.ReleaseCatalog{
+			Releases: []component.ReleaseCatalogEntry{
+				{Name: "mango", Version: "2.3.4", SHA1: "a1b2c3"},
+				{Name: "mango", Version: "2.4.0", Path: "stubs/mango-2.4.0.tgz"},
+				{Name: "mango", Version: "2.4.0", StemcellOS: "smoothie", StemcellVersion: "9.9"},
+				{Name: "mango", Version: "3.0.0"},
+				{Name: "papaya", Version: "1.0.0"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		buf, err := yaml.Marshal(catalog)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(config.Catalog, buf, 0o644)).To(Succeed())
+		source = component.NewCatalogReleaseSource(config, log.New(GinkgoWriter, "", 0))
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, log.New(GinkgoWriter, "", 0))).To(BeAssignableToTypeOf(source))
+	})
+
+	It("does not need network access", func() {
+		Expect(component.NeedsNetwork(config)).To(BeFalse())
+	})
+
+	Describe("GetMatchedRelease", func() {
+		It("returns the release from the catalog", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				SHA1:         "a1b2c3",
+				RemotePath:   "mango-2.3.4.tgz",
+				RemoteSource: "fake",
+			}))
+		})
+
+		It("prefers a release compiled against the stemcell", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.4.0", StemcellOS: "smoothie", StemcellVersion: "9.9"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.RemotePath).To(Equal("mango-2.4.0-smoothie-9.9.tgz"))
+			Expect(lock.StemcellOS).To(Equal("smoothie"))
+		})
+
+		It("calculates the SHA1 of the stub tarball", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.4.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.RemotePath).To(Equal("stubs/mango-2.4.0.tgz"))
+			Expect(lock.SHA1).To(Equal(sha1Of("mango 2.4.0")))
+		})
+
+		It("returns ErrNotFound for releases missing from the catalog", func() {
+			_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "9.9.9"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+
+		When("the catalog does not exist", func() {
+			It("returns an error", func() {
+				Expect(os.Remove(config.Catalog)).To(Succeed())
+				_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"})
+				Expect(err).To(MatchError(ContainSubstring("failed to read release catalog")))
+				Expect(component.IsErrNotFound(err)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("FindReleaseVersion", func() {
+		It("returns the highest version matching the constraint", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~2"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.4.0"))
+			Expect(lock.RemotePath).To(Equal("stubs/mango-2.4.0.tgz"))
+		})
+
+		It("does not calculate checksums when noDownload is set", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("3.0.0"))
+			Expect(lock.SHA1).To(Equal("not-calculated"))
+		})
+
+		It("returns ErrNotFound when no version matches", func() {
+			_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~4"}, false)
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("DownloadRelease", func() {
+		It("copies the stub tarball", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.4.0"})
+			Expect(err).NotTo(HaveOccurred())
+
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, lock)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDirectory, "mango-2.4.0.tgz")))
+			Expect(local.Lock.SHA1).To(Equal(lock.SHA1))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("mango 2.4.0")))
+		})
+
+		It("generates a release tarball when the catalog does not have a path", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.4.0", StemcellOS: "smoothie", StemcellVersion: "9.9"}, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, lock)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.Lock.SHA1).To(Equal(lock.SHA1), "the SHA1 of generated tarballs should be stable")
+
+			tarball, err := cargo.OpenBOSHReleaseTarball(local.LocalPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tarball.Manifest.Name).To(Equal("mango"))
+			Expect(tarball.Manifest.Version).To(Equal("2.4.0"))
+			stemcellOS, stemcellVersion, ok := tarball.Manifest.Stemcell()
+			Expect(ok).To(BeTrue())
+			Expect(stemcellOS).To(Equal("smoothie"))
+			Expect(stemcellVersion).To(Equal("9.9"))
+		})
+
+		It("returns an error for releases missing from the catalog", func() {
+			_, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.0.0", RemotePath: "banana-1.0.0.tgz"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("UploadRelease", func() {
+		It("writes the release and adds it to the catalog", func() {
+			lock, err := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, bytes.NewBufferString("banana 1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "banana",
+				Version:      "1.0.0",
+				SHA1:         sha1Of("banana 1.0.0"),
+				RemotePath:   "banana-1.0.0.tgz",
+				RemoteSource: "fake",
+			}))
+			Expect(os.ReadFile(filepath.Join(dir, "banana-1.0.0.tgz"))).To(Equal([]byte("banana 1.0.0")))
+
+			found, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(Equal(lock))
+
+			var updated component.ReleaseCatalog
+			Expect(yaml.Unmarshal(must(os.ReadFile(config.Catalog)), &updated)).To(Succeed())
+			Expect(updated.Releases).To(HaveLen(len(catalog.Releases) + 1))
+		})
+
+		It("replaces the catalog entry of the same release", func() {
+			_, err := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"}, bytes.NewBufferString("new mango"))
+			Expect(err).NotTo(HaveOccurred())
+
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.SHA1).To(Equal(sha1Of("new mango")))
+		})
+
+		When("the catalog is JSON", func() {
+			BeforeEach(func() {
+				config.Catalog = filepath.Join(dir, "catalog.json")
+			})
+
+			It("keeps the catalog JSON", func() {
+				_, err := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, bytes.NewBufferString("banana 1.0.0"))
+				Expect(err).NotTo(HaveOccurred())
+
+				var updated component.ReleaseCatalog
+				Expect(json.Unmarshal(must(os.ReadFile(config.Catalog)), &updated)).To(Succeed())
+				Expect(updated.Releases).To(ContainElement(HaveField("Name", "banana")))
+			})
+		})
+	})
+
+	Describe("RemotePath", func() {
+		BeforeEach(func() {
+			config.PathTemplate = "{{.Name}}/{{.Version}}/release.tgz"
+		})
+
+		It("uses the path_template", func() {
+			Expect(source.RemotePath(cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"})).To(Equal("banana/1.0.0/release.tgz"))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package component
 
 import (
 	"io"
+	"os"
 
 	"github.com/pivotal-cf/kiln/pkg/cargo"
 )
@@ -18,3 +19,12 @@ type Local struct {
 }
 
 func closeAndIgnoreError(c io.Closer) { _ = c.Close() }
+
+// removePartialDownload closes the file and, if the download failed (or was cancelled),
+// removes it so no partially written tarball is left in the releases directory.
+func removePartialDownload(f *os.File, err *error) {
+	closeAndIgnoreError(f)
+	if *err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
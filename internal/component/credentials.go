@@ -0,0 +1,328 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"golang.org/x/oauth2"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	// CredentialHelperEnvironmentVariable names the credential helper executable used by DefaultCredentialProvider.
+	CredentialHelperEnvironmentVariable = "KILN_CREDENTIAL_HELPER"
+	// SecretsDirectoryEnvironmentVariable overrides the secrets directory used by DefaultCredentialProvider.
+	SecretsDirectoryEnvironmentVariable = "KILN_SECRETS_DIR"
+)
+
+// CredentialProvider looks up the secrets of release sources that are not set in the Kilnfile.
+// The key is the YAML name of the secret field, for example "secret_access_key".
+//
+// Implementations must not include secrets in the errors they return.
+type CredentialProvider interface {
+	Credential(ctx context.Context, source cargo.ReleaseSourceConfig, key string) (secret string, found bool, err error)
+}
+
+// CredentialProviders looks up a secret with each provider in order and returns the first one found.
+type CredentialProviders []CredentialProvider
+
+func (providers CredentialProviders) Credential(ctx context.Context, source cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	for _, provider := range providers {
+		secret, found, err := provider.Credential(ctx, source, key)
+		if err != nil || found {
+			return secret, found, err
+		}
+	}
+	return "", false, nil
+}
+
+// DefaultCredentialProvider looks up secrets in environment variables, then in the secrets
+// directory, then with the credential helper named by KILN_CREDENTIAL_HELPER when it is set.
+func DefaultCredentialProvider() CredentialProviders {
+	providers := CredentialProviders{EnvironmentCredentials{}}
+	if dir, err := DefaultSecretsDirectory(); err == nil {
+		providers = append(providers, SecretsDirectoryCredentials{Directory: dir})
+	}
+	if helper := os.Getenv(CredentialHelperEnvironmentVariable); helper != "" {
+		providers = append(providers, NewCredentialHelper(helper))
+	}
+	return providers
+}
+
+// DefaultSecretsDirectory returns KILN_SECRETS_DIR or ~/.kiln/secrets.
+func DefaultSecretsDirectory() (string, error) {
+	if dir := os.Getenv(SecretsDirectoryEnvironmentVariable); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kiln", "secrets"), nil
+}
+
+// EnvironmentCredentials looks up secrets in environment variables named KILN_<SOURCE_ID>_<KEY>,
+// for example KILN_COMPILED_RELEASES_SECRET_ACCESS_KEY for the release source with ID
+// "compiled-releases". Characters that are not letters or digits are replaced with underscores.
+type EnvironmentCredentials struct {
+	// LookupEnv defaults to os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+}
+
+func (provider EnvironmentCredentials) Credential(_ context.Context, source cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	lookupEnv := provider.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	secret, found := lookupEnv(CredentialEnvironmentVariable(cargo.BOSHReleaseTarballSourceID(source), key))
+	return secret, found && secret != "", nil
+}
+
+// CredentialEnvironmentVariable returns the name of the environment variable EnvironmentCredentials reads.
+func CredentialEnvironmentVariable(sourceID, key string) string {
+	name := strings.ToUpper("kiln_" + sourceID + "_" + key)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// SecretsDirectoryCredentials looks up secrets in files named <Directory>/<source ID>/<key>.
+// Leading and trailing white space in the files is ignored.
+type SecretsDirectoryCredentials struct {
+	Directory string
+}
+
+func (provider SecretsDirectoryCredentials) Credential(_ context.Context, source cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	id := cargo.BOSHReleaseTarballSourceID(source)
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", false, nil
+	}
+	buf, err := os.ReadFile(filepath.Join(provider.Directory, id, key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to read secret %q for release source %q from the secrets directory: %w", key, id, err)
+	}
+	secret := strings.TrimSpace(string(buf))
+	return secret, secret != "", nil
+}
+
+// CredentialHelper runs an executable to look up secrets, like git and docker credential helpers.
+//
+// The executable is run with the argument "get" once per release source. It receives a JSON
+// object with the release source "id" and "type" on standard input and must write a JSON object
+// that maps secret keys to secrets on standard output, for example {"github_token": "..."}.
+// Keys it does not know should be left out. Standard error is passed through so the helper can
+// prompt or log; the helper must exit with a non-zero status when it fails.
+type CredentialHelper struct {
+	Command string
+
+	mu      sync.Mutex
+	secrets map[string]map[string]string
+}
+
+func NewCredentialHelper(command string) *CredentialHelper {
+	return &CredentialHelper{Command: command}
+}
+
+func (helper *CredentialHelper) Credential(ctx context.Context, source cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	id := cargo.BOSHReleaseTarballSourceID(source)
+
+	helper.mu.Lock()
+	defer helper.mu.Unlock()
+	secrets, ok := helper.secrets[id]
+	if !ok {
+		var err error
+		secrets, err = helper.get(ctx, id, source.Type)
+		if err != nil {
+			return "", false, err
+		}
+		if helper.secrets == nil {
+			helper.secrets = make(map[string]map[string]string)
+		}
+		helper.secrets[id] = secrets
+	}
+	secret, found := secrets[key]
+	return secret, found && secret != "", nil
+}
+
+func (helper *CredentialHelper) get(ctx context.Context, id, sourceType string) (map[string]string, error) {
+	input, err := json.Marshal(struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}{ID: id, Type: sourceType})
+	if err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, helper.Command, "get")
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %q failed for release source %q: %w", helper.Command, id, err)
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(output.Bytes(), &secrets); err != nil {
+		// the output may contain secrets so it is not included in the error
+		return nil, fmt.Errorf("credential helper %q wrote invalid output for release source %q: expected a JSON object with string values", helper.Command, id)
+	}
+	return secrets, nil
+}
+
+// releaseSourceCredentials fills in the secrets of a release source configuration that are not
+// set in the Kilnfile. The provider is only consulted the first time the release source needs its
+// secrets, so commands that do not use a release source do not need its credentials.
+type releaseSourceCredentials struct {
+	provider CredentialProvider
+	required []string
+
+	secrets lazyResult[map[string]string]
+}
+
+func newReleaseSourceCredentials(provider CredentialProvider, required ...string) *releaseSourceCredentials {
+	return &releaseSourceCredentials{provider: provider, required: required}
+}
+
+// resolve returns a copy of config with the missing secrets filled in. A nil receiver returns config.
+func (c *releaseSourceCredentials) resolve(ctx context.Context, config cargo.ReleaseSourceConfig) (cargo.ReleaseSourceConfig, error) {
+	if c == nil {
+		return config, nil
+	}
+	secrets, err := c.secrets.get(ctx, func(ctx context.Context) (map[string]string, error) {
+		return c.lookup(ctx, config)
+	})
+	if err != nil {
+		return config, err
+	}
+	for key, field := range config.SecretFields() {
+		if secret, ok := secrets[key]; ok {
+			*field = secret
+		}
+	}
+	return config, nil
+}
+
+func (c *releaseSourceCredentials) lookup(ctx context.Context, config cargo.ReleaseSourceConfig) (map[string]string, error) {
+	secrets := make(map[string]string)
+	fields := config.SecretFields()
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if *fields[key] != "" || c.provider == nil {
+			continue
+		}
+		secret, found, err := c.provider.Credential(ctx, config, key)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			secrets[key] = secret
+		}
+	}
+	for _, key := range c.required {
+		if _, found := secrets[key]; !found && *fields[key] == "" {
+			id := cargo.BOSHReleaseTarballSourceID(config)
+			return nil, fmt.Errorf("release source %q requires %s: set it in the Kilnfile, the environment variable %s, the file %s in the secrets directory, or with a credential helper",
+				id, key, CredentialEnvironmentVariable(id, key), filepath.Join(id, key))
+		}
+	}
+	return secrets, nil
+}
+
+// lazyResult keeps the result of a lookup that is made when it is first needed, like
+// sync.Once. A lookup that fails after its context is done is not kept, so a cancelled or
+// timed out command does not make the error permanent for later calls.
+type lazyResult[T any] struct {
+	mu    sync.Mutex
+	done  bool
+	value T
+	err   error
+}
+
+func (r *lazyResult[T]) get(ctx context.Context, lookup func(ctx context.Context) (T, error)) (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.done {
+		value, err := lookup(ctx)
+		if err != nil && ctx.Err() != nil {
+			return value, err
+		}
+		r.value, r.err, r.done = value, err, true
+	}
+	return r.value, r.err
+}
+
+// awsCredentialsProvider resolves S3 credentials when the first request is made.
+type awsCredentialsProvider struct {
+	config      cargo.ReleaseSourceConfig
+	credentials *releaseSourceCredentials
+	retrieved   bool
+}
+
+func (p *awsCredentialsProvider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(context.Background())
+}
+
+func (p *awsCredentialsProvider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	config, err := p.credentials.resolve(ctx, p.config)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	p.retrieved = true
+	return credentials.Value{
+		AccessKeyID:     config.AccessKeyId,
+		SecretAccessKey: config.SecretAccessKey,
+		ProviderName:    "kiln",
+	}, nil
+}
+
+func (p *awsCredentialsProvider) IsExpired() bool { return !p.retrieved }
+
+// githubTokenSource resolves the GitHub credentials when the first request is made. Tokens of
+// GitHub Apps are requested again when they expire.
+type githubTokenSource struct {
+	config      cargo.ReleaseSourceConfig
+	credentials *releaseSourceCredentials
+
+	once   sync.Once
+	source oauth2.TokenSource
+	err    error
+}
+
+func (s *githubTokenSource) Token() (*oauth2.Token, error) {
+	s.once.Do(func() {
+		config, err := s.credentials.resolve(context.Background(), s.config)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.source, s.err = githubConfig(config).TokenSource(context.Background())
+		if s.err == nil && s.source == nil {
+			s.source = oauth2.StaticTokenSource(&oauth2.Token{})
+		}
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.source.Token()
+}
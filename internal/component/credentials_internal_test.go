@@ -0,0 +1,238 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestCredentialEnvironmentVariable(t *testing.T) {
+	please := NewWithT(t)
+	please.Expect(CredentialEnvironmentVariable("compiled-releases", "secret_access_key")).To(Equal("KILN_COMPILED_RELEASES_SECRET_ACCESS_KEY"))
+	please.Expect(CredentialEnvironmentVariable("my.bucket", "github_token")).To(Equal("KILN_MY_BUCKET_GITHUB_TOKEN"))
+}
+
+func TestEnvironmentCredentials(t *testing.T) {
+	please := NewWithT(t)
+	provider := EnvironmentCredentials{LookupEnv: func(name string) (string, bool) {
+		if name == "KILN_SOME_BUCKET_SECRET_ACCESS_KEY" {
+			return "some-secret", true
+		}
+		return "", false
+	}}
+	source := cargo.ReleaseSourceConfig{ID: "some-bucket", Type: ReleaseSourceTypeS3}
+
+	secret, found, err := provider.Credential(context.Background(), source, "secret_access_key")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(found).To(BeTrue())
+	please.Expect(secret).To(Equal("some-secret"))
+
+	_, found, err = provider.Credential(context.Background(), source, "access_key_id")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(found).To(BeFalse())
+}
+
+func TestSecretsDirectoryCredentials(t *testing.T) {
+	please := NewWithT(t)
+	dir := t.TempDir()
+	please.Expect(os.MkdirAll(filepath.Join(dir, "some-bucket"), 0o700)).To(Succeed())
+	please.Expect(os.WriteFile(filepath.Join(dir, "some-bucket", "secret_access_key"), []byte("some-secret\n"), 0o600)).To(Succeed())
+	provider := SecretsDirectoryCredentials{Directory: dir}
+
+	secret, found, err := provider.Credential(context.Background(), cargo.ReleaseSourceConfig{ID: "some-bucket"}, "secret_access_key")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(found).To(BeTrue())
+	please.Expect(secret).To(Equal("some-secret"))
+
+	_, found, err = provider.Credential(context.Background(), cargo.ReleaseSourceConfig{ID: "other-bucket"}, "secret_access_key")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(found).To(BeFalse())
+
+	_, found, err = provider.Credential(context.Background(), cargo.ReleaseSourceConfig{ID: ".."}, "secret_access_key")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(found).To(BeFalse(), "it does not read files outside the secrets directory")
+}
+
+func TestCredentialHelper(t *testing.T) {
+	writeHelper := func(t *testing.T, script string) (string, string) {
+		t.Helper()
+		dir := t.TempDir()
+		helper := filepath.Join(dir, "kiln-credential-helper")
+		if err := os.WriteFile(helper, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		return helper, filepath.Join(dir, "calls")
+	}
+
+	t.Run("returns secrets for the release source", func(t *testing.T) {
+		please := NewWithT(t)
+		helper, calls := writeHelper(t, `cat >> "$(dirname "$0")/calls"; echo >> "$(dirname "$0")/calls"
+echo '{"github_token": "some-token"}'
+`)
+		provider := NewCredentialHelper(helper)
+		source := cargo.ReleaseSourceConfig{ID: "some-org", Type: ReleaseSourceTypeGithub}
+
+		secret, found, err := provider.Credential(context.Background(), source, "github_token")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(found).To(BeTrue())
+		please.Expect(secret).To(Equal("some-token"))
+
+		_, found, err = provider.Credential(context.Background(), source, "password")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(found).To(BeFalse())
+
+		input, err := os.ReadFile(calls)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(strings.TrimSpace(string(input))).To(Equal(`{"id":"some-org","type":"github"}`), "it runs the helper once per release source")
+	})
+
+	t.Run("the helper fails", func(t *testing.T) {
+		please := NewWithT(t)
+		helper, _ := writeHelper(t, "echo 'some-token'\nexit 3\n")
+		_, _, err := NewCredentialHelper(helper).Credential(context.Background(), cargo.ReleaseSourceConfig{ID: "some-org"}, "github_token")
+		please.Expect(err).To(MatchError(ContainSubstring("exit status 3")))
+		please.Expect(err.Error()).NotTo(ContainSubstring("some-token"))
+	})
+
+	t.Run("the helper writes invalid output", func(t *testing.T) {
+		please := NewWithT(t)
+		helper, _ := writeHelper(t, "echo 'github_token=some-token'\n")
+		_, _, err := NewCredentialHelper(helper).Credential(context.Background(), cargo.ReleaseSourceConfig{ID: "some-org"}, "github_token")
+		please.Expect(err).To(MatchError(ContainSubstring("invalid output")))
+		please.Expect(err.Error()).NotTo(ContainSubstring("some-token"))
+	})
+}
+
+type countingCredentialProvider struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (provider *countingCredentialProvider) Credential(_ context.Context, _ cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	provider.calls++
+	secret, found := provider.secrets[key]
+	return secret, found, nil
+}
+
+func TestReleaseSourceCredentials(t *testing.T) {
+	t.Run("fills in missing secrets once", func(t *testing.T) {
+		please := NewWithT(t)
+		provider := &countingCredentialProvider{secrets: map[string]string{"access_key_id": "from-provider", "secret_access_key": "from-provider"}}
+		credentials := newReleaseSourceCredentials(provider, "access_key_id", "secret_access_key")
+		config := cargo.ReleaseSourceConfig{ID: "some-bucket", AccessKeyId: "from-kilnfile"}
+
+		please.Expect(provider.calls).To(Equal(0), "it does not look up secrets before they are needed")
+
+		resolved, err := credentials.resolve(context.Background(), config)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(resolved.AccessKeyId).To(Equal("from-kilnfile"))
+		please.Expect(resolved.SecretAccessKey).To(Equal("from-provider"))
+		please.Expect(config.SecretAccessKey).To(BeEmpty(), "it does not modify the configuration")
+
+		calls := provider.calls
+		_, err = credentials.resolve(context.Background(), config)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(provider.calls).To(Equal(calls))
+	})
+
+	t.Run("a required secret is missing", func(t *testing.T) {
+		please := NewWithT(t)
+		credentials := newReleaseSourceCredentials(CredentialProviders{}, "github_token")
+		_, err := credentials.resolve(context.Background(), cargo.ReleaseSourceConfig{ID: "some-org"})
+		please.Expect(err).To(MatchError(And(
+			ContainSubstring(`release source "some-org" requires github_token`),
+			ContainSubstring("KILN_SOME_ORG_GITHUB_TOKEN"),
+		)))
+	})
+
+	t.Run("the provider fails", func(t *testing.T) {
+		please := NewWithT(t)
+		credentials := newReleaseSourceCredentials(CredentialProviders{failingCredentialProvider{}})
+		_, err := credentials.resolve(context.Background(), cargo.ReleaseSourceConfig{ID: "some-org"})
+		please.Expect(err).To(MatchError("banana"))
+	})
+
+	t.Run("a cancelled lookup is not kept", func(t *testing.T) {
+		please := NewWithT(t)
+		provider := contextCredentialProvider{secrets: map[string]string{"github_token": "some-token"}}
+		credentials := newReleaseSourceCredentials(provider, "github_token")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := credentials.resolve(ctx, cargo.ReleaseSourceConfig{ID: "some-org"})
+		please.Expect(err).To(MatchError(context.Canceled))
+
+		resolved, err := credentials.resolve(context.Background(), cargo.ReleaseSourceConfig{ID: "some-org"})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(resolved.GithubToken).To(Equal("some-token"))
+	})
+}
+
+// contextCredentialProvider fails like a credential helper when its context is done.
+type contextCredentialProvider struct {
+	secrets map[string]string
+}
+
+func (provider contextCredentialProvider) Credential(ctx context.Context, _ cargo.ReleaseSourceConfig, key string) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	secret, found := provider.secrets[key]
+	return secret, found, nil
+}
+
+type failingCredentialProvider struct{}
+
+func (failingCredentialProvider) Credential(context.Context, cargo.ReleaseSourceConfig, string) (string, bool, error) {
+	return "", false, errors.New("banana")
+}
+
+func TestArtifactoryReleaseSource_credentials_from_environment(t *testing.T) {
+	please := NewWithT(t)
+	t.Setenv("KILN_SOME_ARTIFACTORY_USERNAME", "some-user")
+	t.Setenv("KILN_SOME_ARTIFACTORY_PASSWORD", "some-password")
+
+	var username, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	source := NewArtifactoryReleaseSource(cargo.ReleaseSourceConfig{
+		Type:            ReleaseSourceTypeArtifactory,
+		ID:              "some-artifactory",
+		ArtifactoryHost: server.URL,
+		Repo:            "some-repo",
+		PathTemplate:    "{{.Name}}/{{.Name}}-{{.Version}}.tgz",
+	})
+	_, _ = source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"})
+
+	please.Expect(username).To(Equal("some-user"))
+	please.Expect(password).To(Equal("some-password"))
+}
+
+func TestNewGithubReleaseSource_does_not_require_a_token_until_used(t *testing.T) {
+	please := NewWithT(t)
+	t.Setenv(SecretsDirectoryEnvironmentVariable, t.TempDir())
+	t.Setenv(CredentialHelperEnvironmentVariable, "")
+
+	var source *GithubReleaseSource
+	please.Expect(func() {
+		source = NewGithubReleaseSource(cargo.ReleaseSourceConfig{Type: ReleaseSourceTypeGithub, Org: "some-org"})
+	}).NotTo(Panic())
+
+	_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+		Name: "banana", Version: "1.2.3", GitHubRepository: "https://github.com/some-org/banana-release",
+	})
+	please.Expect(err).To(MatchError(ContainSubstring("requires github_token")))
+}
@@ -0,0 +1,302 @@
+package component
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// DirectoryReleaseSource resolves releases from a directory on the local filesystem,
+// for example a mounted network share. The path_template is evaluated relative to Root.
+type DirectoryReleaseSource struct {
+	cargo.ReleaseSourceConfig
+	logger *log.Logger
+}
+
+// NewDirectoryReleaseSource will provision a new DirectoryReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewDirectoryReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *DirectoryReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeDirectory {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.Root == "" {
+		panic(`Missing required field "root" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.ID == "" {
+		c.ID = c.Root
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[directory release source] ", log.Default().Flags())
+	}
+	return &DirectoryReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+	}
+}
+
+func (src *DirectoryReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
+// fields on Requirement to download a specific release.
+func (src *DirectoryReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	if err := ctx.Err(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	info, err := os.Stat(src.filePath(remotePath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+		}
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if info.IsDir() {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// FindReleaseVersion walks Root and returns the highest version of the release whose
+// path matches path_template.
+func (src *DirectoryReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	pattern, prefix, err := pathTemplateVersionPattern(src.PathTemplate, spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	newest := newestPathVersion{pattern: pattern, matcher: constraint}
+	err = filepath.WalkDir(src.filePath(path.Dir(prefix+"x")), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src.Root, p)
+		if err != nil {
+			return err
+		}
+		newest.add(filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if newest.version == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      newest.version.Original(),
+		RemotePath:   newest.path,
+		RemoteSource: src.ID,
+	}
+	if noDownload {
+		lock.SHA1 = "not-calculated"
+	} else {
+		lock.SHA1, err = fileSHA1(ctx, src.filePath(newest.path))
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+	}
+	return lock, nil
+}
+
+// DownloadRelease hard-links the release into releaseDir. When a link can not be created,
+// for example because releaseDir is on another device, the file is copied.
+func (src *DirectoryReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, lock cargo.BOSHReleaseTarballLock) (_ Local, err error) {
+	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeDirectory, src.ID)
+
+	sourcePath := src.filePath(lock.RemotePath)
+	outputFile := filepath.Join(releaseDir, filepath.Base(lock.RemotePath))
+
+	if err := linkOrCopy(ctx, outputFile, sourcePath); err != nil {
+		return Local{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(outputFile)
+		}
+	}()
+	reportDownloadedFile(ctx, outputFile)
+
+	lock.SHA1, err = fileSHA1(ctx, outputFile)
+	if err != nil {
+		return Local{}, err
+	}
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// UploadRelease writes the release to the path_template location under Root.
+func (src *DirectoryReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID, remotePath)
+
+	outputFile := src.filePath(remotePath)
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0o755); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	// write to a temporary file first so readers never see a partially written release
+	tmp, err := os.CreateTemp(filepath.Dir(outputFile), "."+filepath.Base(outputFile)+".*")
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hash := sha1.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), contextReader{ctx: ctx, r: file})
+	closeErr := tmp.Close()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if closeErr != nil {
+		return cargo.BOSHReleaseTarballLock{}, closeErr
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if err := os.Rename(tmp.Name(), outputFile); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         hex.EncodeToString(hash.Sum(nil)),
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// CheckUpload creates and removes a temporary file in the directory UploadRelease would write to.
+func (src *DirectoryReleaseSource) CheckUpload(context.Context) error {
+	remotePath, err := src.RemotePath(uploadCheckSpec)
+	if err != nil {
+		return err
+	}
+	dir := src.Root
+	for _, d := range strings.Split(path.Dir(remotePath), "/") {
+		if _, err := os.Stat(filepath.Join(dir, d)); err != nil {
+			break
+		}
+		dir = filepath.Join(dir, d)
+	}
+	tmp, err := os.CreateTemp(dir, ".kiln-upload-check-*")
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(tmp)
+	return os.Remove(tmp.Name())
+}
+
+func (src *DirectoryReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	remotePath, err := renderPathTemplate(src.PathTemplate, spec)
+	if err != nil {
+		return "", err
+	}
+	return path.Clean(remotePath), nil
+}
+
+func (src *DirectoryReleaseSource) filePath(remotePath string) string {
+	return filepath.Join(src.Root, filepath.FromSlash(remotePath))
+}
+
+// linkOrCopy hard-links src to dst, replacing dst. When a link can not be created the
+// file is copied.
+func linkOrCopy(ctx context.Context, dst, src string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_ = os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(ctx, dst, src)
+}
+
+func copyFile(ctx context.Context, dst, src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(in)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer removePartialDownload(out, &err)
+
+	_, err = io.Copy(out, contextReader{ctx: ctx, r: in})
+	return err
+}
+
+func fileSHA1(ctx context.Context, p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer closeAndIgnoreError(f)
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, contextReader{ctx: ctx, r: f}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contextReader stops reading once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
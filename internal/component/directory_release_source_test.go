@@ -0,0 +1,200 @@
+package component_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("interacting with BOSH releases in a directory", func() {
+	var (
+		source *component.DirectoryReleaseSource
+		config cargo.ReleaseSourceConfig
+
+		root, releasesDirectory string
+	)
+
+	writeRelease := func(name string, content string) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		Expect(os.WriteFile(p, []byte(content), 0o644)).To(Succeed())
+	}
+
+	sha1Of := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		root = must(os.MkdirTemp("", "release-share"))
+		releasesDirectory = must(os.MkdirTemp("", "releases"))
+
+		config = cargo.ReleaseSourceConfig{
+			Type:         component.ReleaseSourceTypeDirectory,
+			ID:           "nfs",
+			Root:         root,
+			PathTemplate: "{{.Name}}/{{.Name}}-{{.Version}}{{if .StemcellOS}}-{{.StemcellOS}}-{{.StemcellVersion}}{{end}}.tgz",
+		}
+
+		writeRelease("mango/mango-2.3.4.tgz", "mango 2.3.4")
+		writeRelease("mango/mango-2.4.0.tgz", "mango 2.4.0")
+		writeRelease("mango/mango-3.0.0-rc.1.tgz", "mango 3.0.0-rc.1")
+		writeRelease("mango/mango-2.5.0-smoothie-9.9.tgz", "compiled mango 2.5.0")
+		writeRelease("mango/README.md", "not a release")
+	})
+
+	JustBeforeEach(func() {
+		source = component.NewDirectoryReleaseSource(config, log.New(GinkgoWriter, "", 0))
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(root)
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, log.New(GinkgoWriter, "", 0))).To(BeAssignableToTypeOf(source))
+	})
+
+	Describe("GetMatchedRelease", func() {
+		It("resolves the lock from the spec", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				RemotePath:   "mango/mango-2.3.4.tgz",
+				RemoteSource: "nfs",
+			}))
+		})
+
+		It("returns ErrNotFound when the file does not exist", func() {
+			_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "9.9.9"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("FindReleaseVersion", func() {
+		It("finds the latest version matching the constraint", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~2"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.4.0",
+				SHA1:         sha1Of("mango 2.4.0"),
+				RemotePath:   "mango/mango-2.4.0.tgz",
+				RemoteSource: "nfs",
+			}))
+		})
+
+		It("finds the compiled release for the stemcell", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", StemcellOS: "smoothie", StemcellVersion: "9.9",
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.5.0"))
+			Expect(lock.RemotePath).To(Equal("mango/mango-2.5.0-smoothie-9.9.tgz"))
+			Expect(lock.SHA1).To(Equal("not-calculated"))
+		})
+
+		It("finds release candidates on the rc channel", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: ">=2", Channel: cargo.ReleaseChannelRC,
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("3.0.0-rc.1"))
+		})
+
+		It("skips release candidates on the stable channel", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: ">=2.4.0-rc.1", Channel: cargo.ReleaseChannelStable,
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.4.0"))
+		})
+
+		It("returns ErrNotFound when nothing matches", func() {
+			_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana"}, false)
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+
+		When("the path template does not use the version", func() {
+			BeforeEach(func() {
+				config.PathTemplate = "{{.Name}}.tgz"
+			})
+			It("returns an error", func() {
+				_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango"}, false)
+				Expect(err).To(MatchError(ContainSubstring("does not include {{.Version}}")))
+			})
+		})
+	})
+
+	Describe("DownloadRelease", func() {
+		It("puts the release in the releases directory", func() {
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name: "mango", Version: "2.3.4", RemotePath: "mango/mango-2.3.4.tgz", RemoteSource: "nfs",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDirectory, "mango-2.3.4.tgz")))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("mango 2.3.4")))
+			Expect(local.Lock.SHA1).To(Equal(sha1Of("mango 2.3.4")))
+		})
+
+		When("the context is cancelled", func() {
+			It("does not leave a file behind", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err := source.DownloadRelease(ctx, releasesDirectory, cargo.BOSHReleaseTarballLock{
+					Name: "mango", Version: "2.3.4", RemotePath: "mango/mango-2.3.4.tgz", RemoteSource: "nfs",
+				})
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(filepath.Join(releasesDirectory, "mango-2.3.4.tgz")).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("UploadRelease", func() {
+		It("writes the release under the root", func() {
+			lock, err := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, bytes.NewBufferString("banana 1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "banana",
+				Version:      "1.0.0",
+				SHA1:         sha1Of("banana 1.0.0"),
+				RemotePath:   "banana/banana-1.0.0.tgz",
+				RemoteSource: "nfs",
+			}))
+			Expect(os.ReadFile(filepath.Join(root, "banana", "banana-1.0.0.tgz"))).To(Equal([]byte("banana 1.0.0")))
+
+			_, err = source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("CheckUpload", func() {
+		It("does not leave files behind", func() {
+			Expect(source.CheckUpload(context.Background())).To(Succeed())
+			Expect(os.ReadDir(root)).To(HaveLen(1))
+		})
+
+		When("the root does not exist", func() {
+			BeforeEach(func() {
+				config.Root = filepath.Join(root, "missing")
+			})
+
+			It("returns an error", func() {
+				Expect(source.CheckUpload(context.Background())).NotTo(Succeed())
+			})
+		})
+	})
+})
@@ -0,0 +1,97 @@
+package component
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// DownloadProgress is notified while a release source writes a release tarball. Pass one to
+// DownloadRelease with ContextWithDownloadProgress. Implementations must be safe for use by
+// multiple goroutines because some sources write parts of a file concurrently.
+type DownloadProgress interface {
+	// SetSize is called with the size of the tarball when the source knows it.
+	SetSize(n int64)
+	// Add is called with the number of bytes written.
+	Add(n int64)
+	// Reset is called when a download is retried with the number of bytes kept from the
+	// earlier attempts: the resume offset, or 0 when the download starts over.
+	Reset(n int64)
+}
+
+type downloadProgressKey struct{}
+
+// ContextWithDownloadProgress returns a context that makes DownloadRelease report to progress.
+func ContextWithDownloadProgress(ctx context.Context, progress DownloadProgress) context.Context {
+	return context.WithValue(ctx, downloadProgressKey{}, progress)
+}
+
+func downloadProgressFromContext(ctx context.Context) (DownloadProgress, bool) {
+	progress, ok := ctx.Value(downloadProgressKey{}).(DownloadProgress)
+	return progress, ok && progress != nil
+}
+
+// resetDownloadProgress reports that a retried download continues from offset n.
+func resetDownloadProgress(ctx context.Context, n int64) {
+	if progress, ok := downloadProgressFromContext(ctx); ok {
+		progress.Reset(n)
+	}
+}
+
+// progressWriter reports bytes written to w to the DownloadProgress in ctx. The size is
+// ignored when it is not positive (for example an unknown Content-Length).
+func progressWriter(ctx context.Context, w io.Writer, size int64) io.Writer {
+	progress, ok := downloadProgressFromContext(ctx)
+	if !ok {
+		return w
+	}
+	if size > 0 {
+		progress.SetSize(size)
+	}
+	return &downloadProgressWriter{w: w, progress: progress}
+}
+
+type downloadProgressWriter struct {
+	w        io.Writer
+	progress DownloadProgress
+}
+
+func (pw *downloadProgressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.progress.Add(int64(n))
+	return n, err
+}
+
+// progressWriterAt is like progressWriter for downloaders that write parts of a file out of order.
+func progressWriterAt(ctx context.Context, w io.WriterAt) io.WriterAt {
+	progress, ok := downloadProgressFromContext(ctx)
+	if !ok {
+		return w
+	}
+	return &downloadProgressWriterAt{w: w, progress: progress}
+}
+
+type downloadProgressWriterAt struct {
+	w        io.WriterAt
+	progress DownloadProgress
+}
+
+func (pw *downloadProgressWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := pw.w.WriteAt(p, off)
+	pw.progress.Add(int64(n))
+	return n, err
+}
+
+// reportDownloadedFile reports a file that was linked (not written) as fully downloaded.
+func reportDownloadedFile(ctx context.Context, filePath string) {
+	progress, ok := downloadProgressFromContext(ctx)
+	if !ok {
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+	progress.SetSize(info.Size())
+	progress.Add(info.Size())
+}
@@ -12,6 +12,14 @@ func IsErrNotFound(err error) bool {
 	return errors.Is(err, ErrNotFound)
 }
 
+// ErrAlreadyExists is returned by ReleaseUploader implementations that refuse to replace
+// a release that was uploaded before. See ContextWithOverwrite.
+const ErrAlreadyExists stringError = "already exists"
+
+func IsErrAlreadyExists(err error) bool {
+	return errors.Is(err, ErrAlreadyExists)
+}
+
 type stringError string
 
 func (str stringError) Error() string { return string(str) }
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/kiln/internal/component"
@@ -9,11 +10,12 @@ import (
 )
 
 type MultiReleaseSource struct {
-	DownloadReleaseStub        func(string, cargo.BOSHReleaseTarballLock) (component.Local, error)
+	DownloadReleaseStub        func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, error)
 	downloadReleaseMutex       sync.RWMutex
 	downloadReleaseArgsForCall []struct {
-		arg1 string
-		arg2 cargo.BOSHReleaseTarballLock
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
 	}
 	downloadReleaseReturns struct {
 		result1 component.Local
@@ -36,11 +38,12 @@ type MultiReleaseSource struct {
 		result1 component.ReleaseSource
 		result2 error
 	}
-	FindReleaseVersionStub        func(cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)
+	FindReleaseVersionStub        func(context.Context, cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)
 	findReleaseVersionMutex       sync.RWMutex
 	findReleaseVersionArgsForCall []struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 bool
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 bool
 	}
 	findReleaseVersionReturns struct {
 		result1 cargo.BOSHReleaseTarballLock
@@ -50,10 +53,11 @@ type MultiReleaseSource struct {
 		result1 cargo.BOSHReleaseTarballLock
 		result2 error
 	}
-	GetMatchedReleaseStub        func(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
+	GetMatchedReleaseStub        func(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
 	getMatchedReleaseMutex       sync.RWMutex
 	getMatchedReleaseArgsForCall []struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
 	}
 	getMatchedReleaseReturns struct {
 		result1 cargo.BOSHReleaseTarballLock
@@ -72,19 +76,20 @@ type MultiReleaseSource struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *MultiReleaseSource) DownloadRelease(arg1 string, arg2 cargo.BOSHReleaseTarballLock) (component.Local, error) {
+func (fake *MultiReleaseSource) DownloadRelease(arg1 context.Context, arg2 string, arg3 cargo.BOSHReleaseTarballLock) (component.Local, error) {
 	fake.downloadReleaseMutex.Lock()
 	ret, specificReturn := fake.downloadReleaseReturnsOnCall[len(fake.downloadReleaseArgsForCall)]
 	fake.downloadReleaseArgsForCall = append(fake.downloadReleaseArgsForCall, struct {
-		arg1 string
-		arg2 cargo.BOSHReleaseTarballLock
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 cargo.BOSHReleaseTarballLock
+	}{arg1, arg2, arg3})
 	stub := fake.DownloadReleaseStub
 	fakeReturns := fake.downloadReleaseReturns
-	fake.recordInvocation("DownloadRelease", []interface{}{arg1, arg2})
+	fake.recordInvocation("DownloadRelease", []interface{}{arg1, arg2, arg3})
 	fake.downloadReleaseMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -98,17 +103,17 @@ func (fake *MultiReleaseSource) DownloadReleaseCallCount() int {
 	return len(fake.downloadReleaseArgsForCall)
 }
 
-func (fake *MultiReleaseSource) DownloadReleaseCalls(stub func(string, cargo.BOSHReleaseTarballLock) (component.Local, error)) {
+func (fake *MultiReleaseSource) DownloadReleaseCalls(stub func(context.Context, string, cargo.BOSHReleaseTarballLock) (component.Local, error)) {
 	fake.downloadReleaseMutex.Lock()
 	defer fake.downloadReleaseMutex.Unlock()
 	fake.DownloadReleaseStub = stub
 }
 
-func (fake *MultiReleaseSource) DownloadReleaseArgsForCall(i int) (string, cargo.BOSHReleaseTarballLock) {
+func (fake *MultiReleaseSource) DownloadReleaseArgsForCall(i int) (context.Context, string, cargo.BOSHReleaseTarballLock) {
 	fake.downloadReleaseMutex.RLock()
 	defer fake.downloadReleaseMutex.RUnlock()
 	argsForCall := fake.downloadReleaseArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *MultiReleaseSource) DownloadReleaseReturns(result1 component.Local, result2 error) {
@@ -201,19 +206,20 @@ func (fake *MultiReleaseSource) FindByIDReturnsOnCall(i int, result1 component.R
 	}{result1, result2}
 }
 
-func (fake *MultiReleaseSource) FindReleaseVersion(arg1 cargo.BOSHReleaseTarballSpecification, arg2 bool) (cargo.BOSHReleaseTarballLock, error) {
+func (fake *MultiReleaseSource) FindReleaseVersion(arg1 context.Context, arg2 cargo.BOSHReleaseTarballSpecification, arg3 bool) (cargo.BOSHReleaseTarballLock, error) {
 	fake.findReleaseVersionMutex.Lock()
 	ret, specificReturn := fake.findReleaseVersionReturnsOnCall[len(fake.findReleaseVersionArgsForCall)]
 	fake.findReleaseVersionArgsForCall = append(fake.findReleaseVersionArgsForCall, struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-		arg2 bool
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+		arg3 bool
+	}{arg1, arg2, arg3})
 	stub := fake.FindReleaseVersionStub
 	fakeReturns := fake.findReleaseVersionReturns
-	fake.recordInvocation("FindReleaseVersion", []interface{}{arg1, arg2})
+	fake.recordInvocation("FindReleaseVersion", []interface{}{arg1, arg2, arg3})
 	fake.findReleaseVersionMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -227,17 +233,17 @@ func (fake *MultiReleaseSource) FindReleaseVersionCallCount() int {
 	return len(fake.findReleaseVersionArgsForCall)
 }
 
-func (fake *MultiReleaseSource) FindReleaseVersionCalls(stub func(cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)) {
+func (fake *MultiReleaseSource) FindReleaseVersionCalls(stub func(context.Context, cargo.BOSHReleaseTarballSpecification, bool) (cargo.BOSHReleaseTarballLock, error)) {
 	fake.findReleaseVersionMutex.Lock()
 	defer fake.findReleaseVersionMutex.Unlock()
 	fake.FindReleaseVersionStub = stub
 }
 
-func (fake *MultiReleaseSource) FindReleaseVersionArgsForCall(i int) (cargo.BOSHReleaseTarballSpecification, bool) {
+func (fake *MultiReleaseSource) FindReleaseVersionArgsForCall(i int) (context.Context, cargo.BOSHReleaseTarballSpecification, bool) {
 	fake.findReleaseVersionMutex.RLock()
 	defer fake.findReleaseVersionMutex.RUnlock()
 	argsForCall := fake.findReleaseVersionArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *MultiReleaseSource) FindReleaseVersionReturns(result1 cargo.BOSHReleaseTarballLock, result2 error) {
@@ -266,18 +272,19 @@ func (fake *MultiReleaseSource) FindReleaseVersionReturnsOnCall(i int, result1 c
 	}{result1, result2}
 }
 
-func (fake *MultiReleaseSource) GetMatchedRelease(arg1 cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (fake *MultiReleaseSource) GetMatchedRelease(arg1 context.Context, arg2 cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	fake.getMatchedReleaseMutex.Lock()
 	ret, specificReturn := fake.getMatchedReleaseReturnsOnCall[len(fake.getMatchedReleaseArgsForCall)]
 	fake.getMatchedReleaseArgsForCall = append(fake.getMatchedReleaseArgsForCall, struct {
-		arg1 cargo.BOSHReleaseTarballSpecification
-	}{arg1})
+		arg1 context.Context
+		arg2 cargo.BOSHReleaseTarballSpecification
+	}{arg1, arg2})
 	stub := fake.GetMatchedReleaseStub
 	fakeReturns := fake.getMatchedReleaseReturns
-	fake.recordInvocation("GetMatchedRelease", []interface{}{arg1})
+	fake.recordInvocation("GetMatchedRelease", []interface{}{arg1, arg2})
 	fake.getMatchedReleaseMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -291,17 +298,17 @@ func (fake *MultiReleaseSource) GetMatchedReleaseCallCount() int {
 	return len(fake.getMatchedReleaseArgsForCall)
 }
 
-func (fake *MultiReleaseSource) GetMatchedReleaseCalls(stub func(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)) {
+func (fake *MultiReleaseSource) GetMatchedReleaseCalls(stub func(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)) {
 	fake.getMatchedReleaseMutex.Lock()
 	defer fake.getMatchedReleaseMutex.Unlock()
 	fake.GetMatchedReleaseStub = stub
 }
 
-func (fake *MultiReleaseSource) GetMatchedReleaseArgsForCall(i int) cargo.BOSHReleaseTarballSpecification {
+func (fake *MultiReleaseSource) GetMatchedReleaseArgsForCall(i int) (context.Context, cargo.BOSHReleaseTarballSpecification) {
 	fake.getMatchedReleaseMutex.RLock()
 	defer fake.getMatchedReleaseMutex.RUnlock()
 	argsForCall := fake.getMatchedReleaseArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *MultiReleaseSource) GetMatchedReleaseReturns(result1 cargo.BOSHReleaseTarballLock, result2 error) {
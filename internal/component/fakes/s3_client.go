@@ -4,34 +4,70 @@ package fakes
 import (
 	"sync"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/pivotal-cf/kiln/internal/component"
 )
 
 type S3Client struct {
-	HeadObjectStub        func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
-	headObjectMutex       sync.RWMutex
-	headObjectArgsForCall []struct {
-		arg1 *s3.HeadObjectInput
+	AbortMultipartUploadWithContextStub        func(aws.Context, *s3.AbortMultipartUploadInput, ...request.Option) (*s3.AbortMultipartUploadOutput, error)
+	abortMultipartUploadWithContextMutex       sync.RWMutex
+	abortMultipartUploadWithContextArgsForCall []struct {
+		arg1 aws.Context
+		arg2 *s3.AbortMultipartUploadInput
+		arg3 []request.Option
 	}
-	headObjectReturns struct {
+	abortMultipartUploadWithContextReturns struct {
+		result1 *s3.AbortMultipartUploadOutput
+		result2 error
+	}
+	abortMultipartUploadWithContextReturnsOnCall map[int]struct {
+		result1 *s3.AbortMultipartUploadOutput
+		result2 error
+	}
+	CreateMultipartUploadWithContextStub        func(aws.Context, *s3.CreateMultipartUploadInput, ...request.Option) (*s3.CreateMultipartUploadOutput, error)
+	createMultipartUploadWithContextMutex       sync.RWMutex
+	createMultipartUploadWithContextArgsForCall []struct {
+		arg1 aws.Context
+		arg2 *s3.CreateMultipartUploadInput
+		arg3 []request.Option
+	}
+	createMultipartUploadWithContextReturns struct {
+		result1 *s3.CreateMultipartUploadOutput
+		result2 error
+	}
+	createMultipartUploadWithContextReturnsOnCall map[int]struct {
+		result1 *s3.CreateMultipartUploadOutput
+		result2 error
+	}
+	HeadObjectWithContextStub        func(aws.Context, *s3.HeadObjectInput, ...request.Option) (*s3.HeadObjectOutput, error)
+	headObjectWithContextMutex       sync.RWMutex
+	headObjectWithContextArgsForCall []struct {
+		arg1 aws.Context
+		arg2 *s3.HeadObjectInput
+		arg3 []request.Option
+	}
+	headObjectWithContextReturns struct {
 		result1 *s3.HeadObjectOutput
 		result2 error
 	}
-	headObjectReturnsOnCall map[int]struct {
+	headObjectWithContextReturnsOnCall map[int]struct {
 		result1 *s3.HeadObjectOutput
 		result2 error
 	}
-	ListObjectsV2Stub        func(*s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
-	listObjectsV2Mutex       sync.RWMutex
-	listObjectsV2ArgsForCall []struct {
-		arg1 *s3.ListObjectsV2Input
+	ListObjectsV2WithContextStub        func(aws.Context, *s3.ListObjectsV2Input, ...request.Option) (*s3.ListObjectsV2Output, error)
+	listObjectsV2WithContextMutex       sync.RWMutex
+	listObjectsV2WithContextArgsForCall []struct {
+		arg1 aws.Context
+		arg2 *s3.ListObjectsV2Input
+		arg3 []request.Option
 	}
-	listObjectsV2Returns struct {
+	listObjectsV2WithContextReturns struct {
 		result1 *s3.ListObjectsV2Output
 		result2 error
 	}
-	listObjectsV2ReturnsOnCall map[int]struct {
+	listObjectsV2WithContextReturnsOnCall map[int]struct {
 		result1 *s3.ListObjectsV2Output
 		result2 error
 	}
@@ -39,18 +75,152 @@ type S3Client struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *S3Client) HeadObject(arg1 *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
-	fake.headObjectMutex.Lock()
-	ret, specificReturn := fake.headObjectReturnsOnCall[len(fake.headObjectArgsForCall)]
-	fake.headObjectArgsForCall = append(fake.headObjectArgsForCall, struct {
-		arg1 *s3.HeadObjectInput
-	}{arg1})
-	stub := fake.HeadObjectStub
-	fakeReturns := fake.headObjectReturns
-	fake.recordInvocation("HeadObject", []interface{}{arg1})
-	fake.headObjectMutex.Unlock()
+func (fake *S3Client) AbortMultipartUploadWithContext(arg1 aws.Context, arg2 *s3.AbortMultipartUploadInput, arg3 ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	fake.abortMultipartUploadWithContextMutex.Lock()
+	ret, specificReturn := fake.abortMultipartUploadWithContextReturnsOnCall[len(fake.abortMultipartUploadWithContextArgsForCall)]
+	fake.abortMultipartUploadWithContextArgsForCall = append(fake.abortMultipartUploadWithContextArgsForCall, struct {
+		arg1 aws.Context
+		arg2 *s3.AbortMultipartUploadInput
+		arg3 []request.Option
+	}{arg1, arg2, arg3})
+	stub := fake.AbortMultipartUploadWithContextStub
+	fakeReturns := fake.abortMultipartUploadWithContextReturns
+	fake.recordInvocation("AbortMultipartUploadWithContext", []interface{}{arg1, arg2, arg3})
+	fake.abortMultipartUploadWithContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *S3Client) AbortMultipartUploadWithContextCallCount() int {
+	fake.abortMultipartUploadWithContextMutex.RLock()
+	defer fake.abortMultipartUploadWithContextMutex.RUnlock()
+	return len(fake.abortMultipartUploadWithContextArgsForCall)
+}
+
+func (fake *S3Client) AbortMultipartUploadWithContextCalls(stub func(aws.Context, *s3.AbortMultipartUploadInput, ...request.Option) (*s3.AbortMultipartUploadOutput, error)) {
+	fake.abortMultipartUploadWithContextMutex.Lock()
+	defer fake.abortMultipartUploadWithContextMutex.Unlock()
+	fake.AbortMultipartUploadWithContextStub = stub
+}
+
+func (fake *S3Client) AbortMultipartUploadWithContextArgsForCall(i int) (aws.Context, *s3.AbortMultipartUploadInput, []request.Option) {
+	fake.abortMultipartUploadWithContextMutex.RLock()
+	defer fake.abortMultipartUploadWithContextMutex.RUnlock()
+	argsForCall := fake.abortMultipartUploadWithContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *S3Client) AbortMultipartUploadWithContextReturns(result1 *s3.AbortMultipartUploadOutput, result2 error) {
+	fake.abortMultipartUploadWithContextMutex.Lock()
+	defer fake.abortMultipartUploadWithContextMutex.Unlock()
+	fake.AbortMultipartUploadWithContextStub = nil
+	fake.abortMultipartUploadWithContextReturns = struct {
+		result1 *s3.AbortMultipartUploadOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *S3Client) AbortMultipartUploadWithContextReturnsOnCall(i int, result1 *s3.AbortMultipartUploadOutput, result2 error) {
+	fake.abortMultipartUploadWithContextMutex.Lock()
+	defer fake.abortMultipartUploadWithContextMutex.Unlock()
+	fake.AbortMultipartUploadWithContextStub = nil
+	if fake.abortMultipartUploadWithContextReturnsOnCall == nil {
+		fake.abortMultipartUploadWithContextReturnsOnCall = make(map[int]struct {
+			result1 *s3.AbortMultipartUploadOutput
+			result2 error
+		})
+	}
+	fake.abortMultipartUploadWithContextReturnsOnCall[i] = struct {
+		result1 *s3.AbortMultipartUploadOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContext(arg1 aws.Context, arg2 *s3.CreateMultipartUploadInput, arg3 ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	fake.createMultipartUploadWithContextMutex.Lock()
+	ret, specificReturn := fake.createMultipartUploadWithContextReturnsOnCall[len(fake.createMultipartUploadWithContextArgsForCall)]
+	fake.createMultipartUploadWithContextArgsForCall = append(fake.createMultipartUploadWithContextArgsForCall, struct {
+		arg1 aws.Context
+		arg2 *s3.CreateMultipartUploadInput
+		arg3 []request.Option
+	}{arg1, arg2, arg3})
+	stub := fake.CreateMultipartUploadWithContextStub
+	fakeReturns := fake.createMultipartUploadWithContextReturns
+	fake.recordInvocation("CreateMultipartUploadWithContext", []interface{}{arg1, arg2, arg3})
+	fake.createMultipartUploadWithContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContextCallCount() int {
+	fake.createMultipartUploadWithContextMutex.RLock()
+	defer fake.createMultipartUploadWithContextMutex.RUnlock()
+	return len(fake.createMultipartUploadWithContextArgsForCall)
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContextCalls(stub func(aws.Context, *s3.CreateMultipartUploadInput, ...request.Option) (*s3.CreateMultipartUploadOutput, error)) {
+	fake.createMultipartUploadWithContextMutex.Lock()
+	defer fake.createMultipartUploadWithContextMutex.Unlock()
+	fake.CreateMultipartUploadWithContextStub = stub
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContextArgsForCall(i int) (aws.Context, *s3.CreateMultipartUploadInput, []request.Option) {
+	fake.createMultipartUploadWithContextMutex.RLock()
+	defer fake.createMultipartUploadWithContextMutex.RUnlock()
+	argsForCall := fake.createMultipartUploadWithContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContextReturns(result1 *s3.CreateMultipartUploadOutput, result2 error) {
+	fake.createMultipartUploadWithContextMutex.Lock()
+	defer fake.createMultipartUploadWithContextMutex.Unlock()
+	fake.CreateMultipartUploadWithContextStub = nil
+	fake.createMultipartUploadWithContextReturns = struct {
+		result1 *s3.CreateMultipartUploadOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *S3Client) CreateMultipartUploadWithContextReturnsOnCall(i int, result1 *s3.CreateMultipartUploadOutput, result2 error) {
+	fake.createMultipartUploadWithContextMutex.Lock()
+	defer fake.createMultipartUploadWithContextMutex.Unlock()
+	fake.CreateMultipartUploadWithContextStub = nil
+	if fake.createMultipartUploadWithContextReturnsOnCall == nil {
+		fake.createMultipartUploadWithContextReturnsOnCall = make(map[int]struct {
+			result1 *s3.CreateMultipartUploadOutput
+			result2 error
+		})
+	}
+	fake.createMultipartUploadWithContextReturnsOnCall[i] = struct {
+		result1 *s3.CreateMultipartUploadOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *S3Client) HeadObjectWithContext(arg1 aws.Context, arg2 *s3.HeadObjectInput, arg3 ...request.Option) (*s3.HeadObjectOutput, error) {
+	fake.headObjectWithContextMutex.Lock()
+	ret, specificReturn := fake.headObjectWithContextReturnsOnCall[len(fake.headObjectWithContextArgsForCall)]
+	fake.headObjectWithContextArgsForCall = append(fake.headObjectWithContextArgsForCall, struct {
+		arg1 aws.Context
+		arg2 *s3.HeadObjectInput
+		arg3 []request.Option
+	}{arg1, arg2, arg3})
+	stub := fake.HeadObjectWithContextStub
+	fakeReturns := fake.headObjectWithContextReturns
+	fake.recordInvocation("HeadObjectWithContext", []interface{}{arg1, arg2, arg3})
+	fake.headObjectWithContextMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -58,63 +228,65 @@ func (fake *S3Client) HeadObject(arg1 *s3.HeadObjectInput) (*s3.HeadObjectOutput
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *S3Client) HeadObjectCallCount() int {
-	fake.headObjectMutex.RLock()
-	defer fake.headObjectMutex.RUnlock()
-	return len(fake.headObjectArgsForCall)
+func (fake *S3Client) HeadObjectWithContextCallCount() int {
+	fake.headObjectWithContextMutex.RLock()
+	defer fake.headObjectWithContextMutex.RUnlock()
+	return len(fake.headObjectWithContextArgsForCall)
 }
 
-func (fake *S3Client) HeadObjectCalls(stub func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error)) {
-	fake.headObjectMutex.Lock()
-	defer fake.headObjectMutex.Unlock()
-	fake.HeadObjectStub = stub
+func (fake *S3Client) HeadObjectWithContextCalls(stub func(aws.Context, *s3.HeadObjectInput, ...request.Option) (*s3.HeadObjectOutput, error)) {
+	fake.headObjectWithContextMutex.Lock()
+	defer fake.headObjectWithContextMutex.Unlock()
+	fake.HeadObjectWithContextStub = stub
 }
 
-func (fake *S3Client) HeadObjectArgsForCall(i int) *s3.HeadObjectInput {
-	fake.headObjectMutex.RLock()
-	defer fake.headObjectMutex.RUnlock()
-	argsForCall := fake.headObjectArgsForCall[i]
-	return argsForCall.arg1
+func (fake *S3Client) HeadObjectWithContextArgsForCall(i int) (aws.Context, *s3.HeadObjectInput, []request.Option) {
+	fake.headObjectWithContextMutex.RLock()
+	defer fake.headObjectWithContextMutex.RUnlock()
+	argsForCall := fake.headObjectWithContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *S3Client) HeadObjectReturns(result1 *s3.HeadObjectOutput, result2 error) {
-	fake.headObjectMutex.Lock()
-	defer fake.headObjectMutex.Unlock()
-	fake.HeadObjectStub = nil
-	fake.headObjectReturns = struct {
+func (fake *S3Client) HeadObjectWithContextReturns(result1 *s3.HeadObjectOutput, result2 error) {
+	fake.headObjectWithContextMutex.Lock()
+	defer fake.headObjectWithContextMutex.Unlock()
+	fake.HeadObjectWithContextStub = nil
+	fake.headObjectWithContextReturns = struct {
 		result1 *s3.HeadObjectOutput
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *S3Client) HeadObjectReturnsOnCall(i int, result1 *s3.HeadObjectOutput, result2 error) {
-	fake.headObjectMutex.Lock()
-	defer fake.headObjectMutex.Unlock()
-	fake.HeadObjectStub = nil
-	if fake.headObjectReturnsOnCall == nil {
-		fake.headObjectReturnsOnCall = make(map[int]struct {
+func (fake *S3Client) HeadObjectWithContextReturnsOnCall(i int, result1 *s3.HeadObjectOutput, result2 error) {
+	fake.headObjectWithContextMutex.Lock()
+	defer fake.headObjectWithContextMutex.Unlock()
+	fake.HeadObjectWithContextStub = nil
+	if fake.headObjectWithContextReturnsOnCall == nil {
+		fake.headObjectWithContextReturnsOnCall = make(map[int]struct {
 			result1 *s3.HeadObjectOutput
 			result2 error
 		})
 	}
-	fake.headObjectReturnsOnCall[i] = struct {
+	fake.headObjectWithContextReturnsOnCall[i] = struct {
 		result1 *s3.HeadObjectOutput
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *S3Client) ListObjectsV2(arg1 *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
-	fake.listObjectsV2Mutex.Lock()
-	ret, specificReturn := fake.listObjectsV2ReturnsOnCall[len(fake.listObjectsV2ArgsForCall)]
-	fake.listObjectsV2ArgsForCall = append(fake.listObjectsV2ArgsForCall, struct {
-		arg1 *s3.ListObjectsV2Input
-	}{arg1})
-	stub := fake.ListObjectsV2Stub
-	fakeReturns := fake.listObjectsV2Returns
-	fake.recordInvocation("ListObjectsV2", []interface{}{arg1})
-	fake.listObjectsV2Mutex.Unlock()
+func (fake *S3Client) ListObjectsV2WithContext(arg1 aws.Context, arg2 *s3.ListObjectsV2Input, arg3 ...request.Option) (*s3.ListObjectsV2Output, error) {
+	fake.listObjectsV2WithContextMutex.Lock()
+	ret, specificReturn := fake.listObjectsV2WithContextReturnsOnCall[len(fake.listObjectsV2WithContextArgsForCall)]
+	fake.listObjectsV2WithContextArgsForCall = append(fake.listObjectsV2WithContextArgsForCall, struct {
+		arg1 aws.Context
+		arg2 *s3.ListObjectsV2Input
+		arg3 []request.Option
+	}{arg1, arg2, arg3})
+	stub := fake.ListObjectsV2WithContextStub
+	fakeReturns := fake.listObjectsV2WithContextReturns
+	fake.recordInvocation("ListObjectsV2WithContext", []interface{}{arg1, arg2, arg3})
+	fake.listObjectsV2WithContextMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -122,46 +294,46 @@ func (fake *S3Client) ListObjectsV2(arg1 *s3.ListObjectsV2Input) (*s3.ListObject
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *S3Client) ListObjectsV2CallCount() int {
-	fake.listObjectsV2Mutex.RLock()
-	defer fake.listObjectsV2Mutex.RUnlock()
-	return len(fake.listObjectsV2ArgsForCall)
+func (fake *S3Client) ListObjectsV2WithContextCallCount() int {
+	fake.listObjectsV2WithContextMutex.RLock()
+	defer fake.listObjectsV2WithContextMutex.RUnlock()
+	return len(fake.listObjectsV2WithContextArgsForCall)
 }
 
-func (fake *S3Client) ListObjectsV2Calls(stub func(*s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)) {
-	fake.listObjectsV2Mutex.Lock()
-	defer fake.listObjectsV2Mutex.Unlock()
-	fake.ListObjectsV2Stub = stub
+func (fake *S3Client) ListObjectsV2WithContextCalls(stub func(aws.Context, *s3.ListObjectsV2Input, ...request.Option) (*s3.ListObjectsV2Output, error)) {
+	fake.listObjectsV2WithContextMutex.Lock()
+	defer fake.listObjectsV2WithContextMutex.Unlock()
+	fake.ListObjectsV2WithContextStub = stub
 }
 
-func (fake *S3Client) ListObjectsV2ArgsForCall(i int) *s3.ListObjectsV2Input {
-	fake.listObjectsV2Mutex.RLock()
-	defer fake.listObjectsV2Mutex.RUnlock()
-	argsForCall := fake.listObjectsV2ArgsForCall[i]
-	return argsForCall.arg1
+func (fake *S3Client) ListObjectsV2WithContextArgsForCall(i int) (aws.Context, *s3.ListObjectsV2Input, []request.Option) {
+	fake.listObjectsV2WithContextMutex.RLock()
+	defer fake.listObjectsV2WithContextMutex.RUnlock()
+	argsForCall := fake.listObjectsV2WithContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *S3Client) ListObjectsV2Returns(result1 *s3.ListObjectsV2Output, result2 error) {
-	fake.listObjectsV2Mutex.Lock()
-	defer fake.listObjectsV2Mutex.Unlock()
-	fake.ListObjectsV2Stub = nil
-	fake.listObjectsV2Returns = struct {
+func (fake *S3Client) ListObjectsV2WithContextReturns(result1 *s3.ListObjectsV2Output, result2 error) {
+	fake.listObjectsV2WithContextMutex.Lock()
+	defer fake.listObjectsV2WithContextMutex.Unlock()
+	fake.ListObjectsV2WithContextStub = nil
+	fake.listObjectsV2WithContextReturns = struct {
 		result1 *s3.ListObjectsV2Output
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *S3Client) ListObjectsV2ReturnsOnCall(i int, result1 *s3.ListObjectsV2Output, result2 error) {
-	fake.listObjectsV2Mutex.Lock()
-	defer fake.listObjectsV2Mutex.Unlock()
-	fake.ListObjectsV2Stub = nil
-	if fake.listObjectsV2ReturnsOnCall == nil {
-		fake.listObjectsV2ReturnsOnCall = make(map[int]struct {
+func (fake *S3Client) ListObjectsV2WithContextReturnsOnCall(i int, result1 *s3.ListObjectsV2Output, result2 error) {
+	fake.listObjectsV2WithContextMutex.Lock()
+	defer fake.listObjectsV2WithContextMutex.Unlock()
+	fake.ListObjectsV2WithContextStub = nil
+	if fake.listObjectsV2WithContextReturnsOnCall == nil {
+		fake.listObjectsV2WithContextReturnsOnCall = make(map[int]struct {
 			result1 *s3.ListObjectsV2Output
 			result2 error
 		})
 	}
-	fake.listObjectsV2ReturnsOnCall[i] = struct {
+	fake.listObjectsV2WithContextReturnsOnCall[i] = struct {
 		result1 *s3.ListObjectsV2Output
 		result2 error
 	}{result1, result2}
@@ -170,10 +342,14 @@ func (fake *S3Client) ListObjectsV2ReturnsOnCall(i int, result1 *s3.ListObjectsV
 func (fake *S3Client) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.headObjectMutex.RLock()
-	defer fake.headObjectMutex.RUnlock()
-	fake.listObjectsV2Mutex.RLock()
-	defer fake.listObjectsV2Mutex.RUnlock()
+	fake.abortMultipartUploadWithContextMutex.RLock()
+	defer fake.abortMultipartUploadWithContextMutex.RUnlock()
+	fake.createMultipartUploadWithContextMutex.RLock()
+	defer fake.createMultipartUploadWithContextMutex.RUnlock()
+	fake.headObjectWithContextMutex.RLock()
+	defer fake.headObjectWithContextMutex.RUnlock()
+	fake.listObjectsV2WithContextMutex.RLock()
+	defer fake.listObjectsV2WithContextMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -4,22 +4,24 @@ package fakes
 import (
 	"sync"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/pivotal-cf/kiln/internal/component"
 )
 
 type S3Uploader struct {
-	UploadStub        func(*s3manager.UploadInput, ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
-	uploadMutex       sync.RWMutex
-	uploadArgsForCall []struct {
-		arg1 *s3manager.UploadInput
-		arg2 []func(*s3manager.Uploader)
+	UploadWithContextStub        func(aws.Context, *s3manager.UploadInput, ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+	uploadWithContextMutex       sync.RWMutex
+	uploadWithContextArgsForCall []struct {
+		arg1 aws.Context
+		arg2 *s3manager.UploadInput
+		arg3 []func(*s3manager.Uploader)
 	}
-	uploadReturns struct {
+	uploadWithContextReturns struct {
 		result1 *s3manager.UploadOutput
 		result2 error
 	}
-	uploadReturnsOnCall map[int]struct {
+	uploadWithContextReturnsOnCall map[int]struct {
 		result1 *s3manager.UploadOutput
 		result2 error
 	}
@@ -27,19 +29,20 @@ type S3Uploader struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *S3Uploader) Upload(arg1 *s3manager.UploadInput, arg2 ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
-	fake.uploadMutex.Lock()
-	ret, specificReturn := fake.uploadReturnsOnCall[len(fake.uploadArgsForCall)]
-	fake.uploadArgsForCall = append(fake.uploadArgsForCall, struct {
-		arg1 *s3manager.UploadInput
-		arg2 []func(*s3manager.Uploader)
-	}{arg1, arg2})
-	stub := fake.UploadStub
-	fakeReturns := fake.uploadReturns
-	fake.recordInvocation("Upload", []interface{}{arg1, arg2})
-	fake.uploadMutex.Unlock()
+func (fake *S3Uploader) UploadWithContext(arg1 aws.Context, arg2 *s3manager.UploadInput, arg3 ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	fake.uploadWithContextMutex.Lock()
+	ret, specificReturn := fake.uploadWithContextReturnsOnCall[len(fake.uploadWithContextArgsForCall)]
+	fake.uploadWithContextArgsForCall = append(fake.uploadWithContextArgsForCall, struct {
+		arg1 aws.Context
+		arg2 *s3manager.UploadInput
+		arg3 []func(*s3manager.Uploader)
+	}{arg1, arg2, arg3})
+	stub := fake.UploadWithContextStub
+	fakeReturns := fake.uploadWithContextReturns
+	fake.recordInvocation("UploadWithContext", []interface{}{arg1, arg2, arg3})
+	fake.uploadWithContextMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2...)
+		return stub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -47,46 +50,46 @@ func (fake *S3Uploader) Upload(arg1 *s3manager.UploadInput, arg2 ...func(*s3mana
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *S3Uploader) UploadCallCount() int {
-	fake.uploadMutex.RLock()
-	defer fake.uploadMutex.RUnlock()
-	return len(fake.uploadArgsForCall)
+func (fake *S3Uploader) UploadWithContextCallCount() int {
+	fake.uploadWithContextMutex.RLock()
+	defer fake.uploadWithContextMutex.RUnlock()
+	return len(fake.uploadWithContextArgsForCall)
 }
 
-func (fake *S3Uploader) UploadCalls(stub func(*s3manager.UploadInput, ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)) {
-	fake.uploadMutex.Lock()
-	defer fake.uploadMutex.Unlock()
-	fake.UploadStub = stub
+func (fake *S3Uploader) UploadWithContextCalls(stub func(aws.Context, *s3manager.UploadInput, ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)) {
+	fake.uploadWithContextMutex.Lock()
+	defer fake.uploadWithContextMutex.Unlock()
+	fake.UploadWithContextStub = stub
 }
 
-func (fake *S3Uploader) UploadArgsForCall(i int) (*s3manager.UploadInput, []func(*s3manager.Uploader)) {
-	fake.uploadMutex.RLock()
-	defer fake.uploadMutex.RUnlock()
-	argsForCall := fake.uploadArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+func (fake *S3Uploader) UploadWithContextArgsForCall(i int) (aws.Context, *s3manager.UploadInput, []func(*s3manager.Uploader)) {
+	fake.uploadWithContextMutex.RLock()
+	defer fake.uploadWithContextMutex.RUnlock()
+	argsForCall := fake.uploadWithContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *S3Uploader) UploadReturns(result1 *s3manager.UploadOutput, result2 error) {
-	fake.uploadMutex.Lock()
-	defer fake.uploadMutex.Unlock()
-	fake.UploadStub = nil
-	fake.uploadReturns = struct {
+func (fake *S3Uploader) UploadWithContextReturns(result1 *s3manager.UploadOutput, result2 error) {
+	fake.uploadWithContextMutex.Lock()
+	defer fake.uploadWithContextMutex.Unlock()
+	fake.UploadWithContextStub = nil
+	fake.uploadWithContextReturns = struct {
 		result1 *s3manager.UploadOutput
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *S3Uploader) UploadReturnsOnCall(i int, result1 *s3manager.UploadOutput, result2 error) {
-	fake.uploadMutex.Lock()
-	defer fake.uploadMutex.Unlock()
-	fake.UploadStub = nil
-	if fake.uploadReturnsOnCall == nil {
-		fake.uploadReturnsOnCall = make(map[int]struct {
+func (fake *S3Uploader) UploadWithContextReturnsOnCall(i int, result1 *s3manager.UploadOutput, result2 error) {
+	fake.uploadWithContextMutex.Lock()
+	defer fake.uploadWithContextMutex.Unlock()
+	fake.UploadWithContextStub = nil
+	if fake.uploadWithContextReturnsOnCall == nil {
+		fake.uploadWithContextReturnsOnCall = make(map[int]struct {
 			result1 *s3manager.UploadOutput
 			result2 error
 		})
 	}
-	fake.uploadReturnsOnCall[i] = struct {
+	fake.uploadWithContextReturnsOnCall[i] = struct {
 		result1 *s3manager.UploadOutput
 		result2 error
 	}{result1, result2}
@@ -95,8 +98,8 @@ func (fake *S3Uploader) UploadReturnsOnCall(i int, result1 *s3manager.UploadOutp
 func (fake *S3Uploader) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.uploadMutex.RLock()
-	defer fake.uploadMutex.RUnlock()
+	fake.uploadWithContextMutex.RLock()
+	defer fake.uploadWithContextMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,282 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsDefaultTokenURL = "https://oauth2.googleapis.com/token"
+	gcsReadWriteScope  = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// GCSReleaseSource stores releases as objects in a Google Cloud Storage bucket using the
+// JSON API. Requests are authenticated with the service account key in service_account_key;
+// without one they are not authenticated, which works for public buckets and emulators like
+// fake-gcs-server (set endpoint to the emulator URL).
+type GCSReleaseSource struct {
+	objectStoreReleaseSource
+}
+
+// NewGCSReleaseSource will provision a new GCSReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewGCSReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *GCSReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeGCS {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.Bucket == "" {
+		panic(`Missing required field "bucket" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.ID == "" {
+		c.ID = c.Bucket
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[GCS release source] ", log.Default().Flags())
+	}
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = gcsDefaultEndpoint
+	}
+	return &GCSReleaseSource{objectStoreReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+		store: &gcsBucket{
+			endpoint: endpoint,
+			bucket:   c.Bucket,
+			client: &http.Client{Transport: &gcsTransport{
+				config:      c,
+				credentials: newReleaseSourceCredentials(DefaultCredentialProvider()),
+				base:        http.DefaultTransport,
+			}},
+		},
+	}}
+}
+
+type gcsBucket struct {
+	endpoint, bucket string
+	client           *http.Client
+}
+
+func (b *gcsBucket) objectURL(key string) string {
+	return "gs://" + b.bucket + "/" + key
+}
+
+func (b *gcsBucket) objectsURL() string {
+	return b.endpoint + "/storage/v1/b/" + url.PathEscape(b.bucket) + "/o"
+}
+
+func (b *gcsBucket) exists(ctx context.Context, key string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.objectsURL()+"/"+url.PathEscape(key)+"?fields=name", nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, objectStoreStatusError(res, "get "+b.objectURL(key))
+	}
+}
+
+func (b *gcsBucket) list(ctx context.Context, prefix string, fn func(string)) error {
+	var pageToken string
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.objectsURL()+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		res, err := b.client.Do(req)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if res.StatusCode != http.StatusOK {
+			err = objectStoreStatusError(res, "list "+b.objectURL(prefix))
+		} else {
+			err = json.NewDecoder(res.Body).Decode(&page)
+		}
+		closeAndIgnoreError(res.Body)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			fn(item.Name)
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (b *gcsBucket) download(ctx context.Context, key string, file *os.File, policy retryPolicy, logger *log.Logger) error {
+	return downloadHTTPFile(ctx, b.client, policy, logger, file, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, b.objectsURL()+"/"+url.PathEscape(key)+"?alt=media", nil)
+	}, func(statusCode int) string {
+		return fmt.Sprintf("failed to download %s with error code %d", b.objectURL(key), statusCode)
+	})
+}
+
+// upload uses a single request. Without overwrite the request is conditional on the object
+// not existing, so a release uploaded at the same time is not replaced.
+func (b *gcsBucket) upload(ctx context.Context, key string, body io.ReadSeeker, size int64, overwrite bool) error {
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+	if !overwrite {
+		query.Set("ifGenerationMatch", "0")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.uploadURL()+"?"+query.Encode(), io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("%s %w", b.objectURL(key), ErrAlreadyExists)
+	default:
+		return objectStoreStatusError(res, "upload "+b.objectURL(key))
+	}
+}
+
+// checkUpload starts a resumable upload and cancels it. Starting an upload requires the
+// same permission as uploading an object.
+func (b *gcsBucket) checkUpload(ctx context.Context, key string) error {
+	query := url.Values{"uploadType": {"resumable"}, "name": {key}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.uploadURL()+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Upload-Content-Type", "application/octet-stream")
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return objectStoreStatusError(res, "start an upload to "+b.objectURL(key))
+	}
+	session := res.Header.Get("Location")
+	if session == "" {
+		return errors.New("the response starting an upload did not include the upload session URL")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodDelete, session, nil)
+	if err != nil {
+		return err
+	}
+	res, err = b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	// cancelled upload sessions respond with the non-standard status 499
+	if res.StatusCode >= http.StatusInternalServerError {
+		return objectStoreStatusError(res, "cancel the upload to "+b.objectURL(key))
+	}
+	return nil
+}
+
+func (b *gcsBucket) uploadURL() string {
+	return b.endpoint + "/upload/storage/v1/b/" + url.PathEscape(b.bucket) + "/o"
+}
+
+// gcsTransport adds an access token for the service account in service_account_key to
+// requests. The key is looked up and parsed when the first request is made.
+type gcsTransport struct {
+	config      cargo.ReleaseSourceConfig
+	credentials *releaseSourceCredentials
+	base        http.RoundTripper
+
+	tokens lazyResult[oauth2.TokenSource]
+}
+
+func (t *gcsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tokens, err := t.tokens.get(req.Context(), t.tokenSource)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		return t.base.RoundTrip(req)
+	}
+	token, err := tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an access token for release source %q: %w", t.config.ID, err)
+	}
+	req = req.Clone(req.Context())
+	token.SetAuthHeader(req)
+	return t.base.RoundTrip(req)
+}
+
+// tokenSource returns nil when the release source does not have a service account key.
+func (t *gcsTransport) tokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	config, err := t.credentials.resolve(ctx, t.config)
+	if err != nil {
+		return nil, err
+	}
+	if config.ServiceAccountKey == "" {
+		return nil, nil
+	}
+	var key struct {
+		ClientEmail  string `json:"client_email"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal([]byte(config.ServiceAccountKey), &key); err != nil || key.ClientEmail == "" || key.PrivateKey == "" {
+		// the key is a secret so the parse error is not included
+		return nil, fmt.Errorf("release source %q has an invalid service_account_key: expected the JSON key of a service account", t.config.ID)
+	}
+	jwtConfig := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		Scopes:       []string{gcsReadWriteScope},
+		TokenURL:     key.TokenURI,
+	}
+	if jwtConfig.TokenURL == "" {
+		jwtConfig.TokenURL = gcsDefaultTokenURL
+	}
+	// the token source outlives the request, so it must not use the request context
+	tokenCtx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: t.base})
+	return oauth2.ReuseTokenSource(nil, jwtConfig.TokenSource(tokenCtx)), nil
+}
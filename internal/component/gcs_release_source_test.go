@@ -0,0 +1,394 @@
+package component_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("GCSReleaseSource", func() {
+	var (
+		server        *httptest.Server
+		bucket        *fakeGCSBucket
+		config        cargo.ReleaseSourceConfig
+		releaseSource *component.GCSReleaseSource
+		releasesDir   string
+	)
+
+	BeforeEach(func() {
+		bucket = &fakeGCSBucket{
+			name: "releases",
+			objects: map[string][]byte{
+				"bpm/bpm-1.1.0.tgz":                   []byte("bpm 1.1.0"),
+				"bpm/bpm-1.2.0.tgz":                   []byte("bpm 1.2.0"),
+				"bpm/bpm-2.0.0.tgz":                   []byte("bpm 2.0.0"),
+				"bpm/bpm-1.3.0-ubuntu-jammy-1.10.tgz": []byte("compiled bpm 1.3.0"),
+				"uaa/uaa-7.0.0.tgz":                   []byte("uaa 7.0.0"),
+			},
+		}
+		server = httptest.NewServer(bucket)
+		bucket.url = server.URL
+		releasesDir = must(os.MkdirTemp("", "releases"))
+
+		config = cargo.ReleaseSourceConfig{
+			Type:         component.ReleaseSourceTypeGCS,
+			Bucket:       "releases",
+			Endpoint:     server.URL,
+			PathTemplate: "{{.Name}}/{{.Name}}-{{.Version}}.tgz",
+		}
+	})
+
+	JustBeforeEach(func() {
+		releaseSource = component.NewGCSReleaseSource(config, log.New(GinkgoWriter, "", 0))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(releasesDir)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, log.New(GinkgoWriter, "", 0))).To(BeAssignableToTypeOf(releaseSource))
+	})
+
+	It("defaults the ID to the bucket", func() {
+		Expect(releaseSource.Configuration().ID).To(Equal("releases"))
+	})
+
+	Describe("GetMatchedRelease", func() {
+		It("finds the object at the path_template location", func() {
+			lock, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "bpm",
+				Version:      "1.2.0",
+				RemotePath:   "bpm/bpm-1.2.0.tgz",
+				RemoteSource: "releases",
+			}))
+		})
+
+		It("returns ErrNotFound for missing objects", func() {
+			_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "9.9.9"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("FindReleaseVersion", func() {
+		It("lists every page of objects under the prefix", func() {
+			lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "~1"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "bpm",
+				Version:      "1.2.0",
+				SHA1:         sha1Hex("bpm 1.2.0"),
+				RemotePath:   "bpm/bpm-1.2.0.tgz",
+				RemoteSource: "releases",
+			}))
+			Expect(bucket.listPrefixes()).To(HaveEach("bpm/"))
+			Expect(len(bucket.listPrefixes())).To(BeNumerically(">", 1))
+		})
+
+		It("does not download the release when noDownload is set", func() {
+			lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.0.0"))
+			Expect(lock.SHA1).To(Equal("not-calculated"))
+		})
+
+		When("the path template includes the stemcell", func() {
+			BeforeEach(func() {
+				config.PathTemplate = "{{.Name}}/{{.Name}}-{{.Version}}-{{.StemcellOS}}-{{.StemcellVersion}}.tgz"
+				bucket.objects["bpm/bpm-1.4.0-rc.1-ubuntu-jammy-1.10.tgz"] = []byte("compiled bpm 1.4.0-rc.1")
+			})
+
+			It("skips compiled releases when the stemcell is not set", func() {
+				_, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm"}, true)
+				Expect(component.IsErrNotFound(err)).To(BeTrue())
+			})
+
+			It("does not take a release candidate for the final version", func() {
+				lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+					Name: "bpm", StemcellOS: "ubuntu-jammy", StemcellVersion: "1.10",
+				}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Version).To(Equal("1.3.0"))
+				Expect(lock.RemotePath).To(Equal("bpm/bpm-1.3.0-ubuntu-jammy-1.10.tgz"))
+			})
+
+			It("finds release candidates on the rc channel", func() {
+				lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+					Name: "bpm", Channel: cargo.ReleaseChannelRC, StemcellOS: "ubuntu-jammy", StemcellVersion: "1.10",
+				}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Version).To(Equal("1.4.0-rc.1"))
+				Expect(lock.RemotePath).To(Equal("bpm/bpm-1.4.0-rc.1-ubuntu-jammy-1.10.tgz"))
+			})
+
+			It("matches any stemcell OS when only the stemcell version is set", func() {
+				lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+					Name: "bpm", Channel: cargo.ReleaseChannelRC, StemcellVersion: "1.10",
+				}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Version).To(Equal("1.4.0-rc.1"))
+				Expect(lock.RemotePath).To(Equal("bpm/bpm-1.4.0-rc.1-ubuntu-jammy-1.10.tgz"))
+			})
+		})
+
+		It("returns ErrNotFound when no version matches", func() {
+			_, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "~3"}, true)
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("DownloadRelease", func() {
+		It("writes the object to the releases directory", func() {
+			local, err := releaseSource.DownloadRelease(context.Background(), releasesDir, cargo.BOSHReleaseTarballLock{Name: "uaa", Version: "7.0.0", RemotePath: "uaa/uaa-7.0.0.tgz"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "uaa-7.0.0.tgz")))
+			Expect(local.Lock.SHA1).To(Equal(sha1Hex("uaa 7.0.0")))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("uaa 7.0.0")))
+		})
+
+		It("returns an error for missing objects", func() {
+			_, err := releaseSource.DownloadRelease(context.Background(), releasesDir, cargo.BOSHReleaseTarballLock{Name: "uaa", Version: "1.0.0", RemotePath: "uaa/uaa-1.0.0.tgz"})
+			Expect(err).To(MatchError(ContainSubstring("failed to download gs://releases/uaa/uaa-1.0.0.tgz with error code 404")))
+		})
+	})
+
+	Describe("UploadRelease", func() {
+		It("uploads the release to the path_template location", func() {
+			lock, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, strings.NewReader("banana 1.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "banana",
+				Version:      "1.0.0",
+				SHA1:         sha1Hex("banana 1.0.0"),
+				RemotePath:   "banana/banana-1.0.0.tgz",
+				RemoteSource: "releases",
+			}))
+			Expect(bucket.object("banana/banana-1.0.0.tgz")).To(Equal([]byte("banana 1.0.0")))
+		})
+
+		It("uploads readers that can not seek", func() {
+			_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.0.0"}, io.MultiReader(strings.NewReader("banana "), strings.NewReader("1.0.0")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bucket.object("banana/banana-1.0.0.tgz")).To(Equal([]byte("banana 1.0.0")))
+		})
+
+		When("the object already exists", func() {
+			It("returns ErrAlreadyExists", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"}, strings.NewReader("new bpm"))
+				Expect(component.IsErrAlreadyExists(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("gs://releases/bpm/bpm-1.2.0.tgz")))
+				Expect(bucket.object("bpm/bpm-1.2.0.tgz")).To(Equal([]byte("bpm 1.2.0")))
+			})
+
+			It("replaces it when overwriting is allowed", func() {
+				_, err := releaseSource.UploadRelease(component.ContextWithOverwrite(context.Background()), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"}, strings.NewReader("new bpm"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bucket.object("bpm/bpm-1.2.0.tgz")).To(Equal([]byte("new bpm")))
+			})
+		})
+	})
+
+	Describe("CheckUpload", func() {
+		It("starts and cancels a resumable upload", func() {
+			Expect(releaseSource.CheckUpload(context.Background())).To(Succeed())
+			Expect(bucket.resumableUploads).To(Equal([]string{"kiln-upload-check/kiln-upload-check-0.0.0.tgz"}))
+			Expect(bucket.cancelledUploads).To(Equal(1))
+			Expect(bucket.object("kiln-upload-check/kiln-upload-check-0.0.0.tgz")).To(BeNil())
+		})
+
+		When("uploads are not allowed", func() {
+			BeforeEach(func() {
+				bucket.readOnly = true
+			})
+
+			It("returns the error", func() {
+				Expect(releaseSource.CheckUpload(context.Background())).To(MatchError(ContainSubstring("403 Forbidden")))
+			})
+		})
+	})
+
+	When("a service account key is configured", func() {
+		BeforeEach(func() {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			keyBuf, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			serviceAccountKey, err := json.Marshal(map[string]string{
+				"type":           "service_account",
+				"client_email":   "kiln@example.iam.gserviceaccount.com",
+				"private_key_id": "key-1",
+				"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBuf})),
+				"token_uri":      server.URL + "/token",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			config.ServiceAccountKey = string(serviceAccountKey)
+		})
+
+		It("authenticates requests with an access token", func() {
+			_, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm"}, true)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bucket.tokenRequests).To(Equal(1))
+			Expect(bucket.authorizations()).To(HaveEach("Bearer gcs-token"))
+		})
+
+		When("the key is not valid", func() {
+			BeforeEach(func() {
+				config.ServiceAccountKey = `{"private_key": "secret"}`
+			})
+
+			It("returns an error without the key", func() {
+				_, err := releaseSource.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.2.0"})
+				Expect(err).To(MatchError(ContainSubstring("invalid service_account_key")))
+				Expect(err.Error()).NotTo(ContainSubstring("secret"))
+			})
+		})
+	})
+})
+
+// fakeGCSBucket serves the parts of the Cloud Storage JSON API used by GCSReleaseSource,
+// like fake-gcs-server. Listings are split into pages of two objects.
+type fakeGCSBucket struct {
+	name, url string
+	readOnly  bool
+
+	mu               sync.Mutex
+	objects          map[string][]byte
+	prefixes         []string
+	auth             []string
+	tokenRequests    int
+	resumableUploads []string
+	cancelledUploads int
+}
+
+func (b *fakeGCSBucket) object(key string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.objects[key]
+}
+
+func (b *fakeGCSBucket) listPrefixes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.prefixes)
+}
+
+func (b *fakeGCSBucket) authorizations() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.auth)
+}
+
+func (b *fakeGCSBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		b.tokenRequests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "gcs-token", "token_type": "Bearer", "expires_in": 3600}`))
+		return
+	}
+	b.auth = append(b.auth, r.Header.Get("Authorization"))
+
+	objectsPath := "/storage/v1/b/" + b.name + "/o"
+	uploadPath := "/upload/storage/v1/b/" + b.name + "/o"
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == objectsPath:
+		b.list(w, query)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.EscapedPath(), objectsPath+"/"):
+		key, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), objectsPath+"/"))
+		Expect(err).NotTo(HaveOccurred())
+		content, ok := b.objects[key]
+		if !ok {
+			http.Error(w, `{"error": {"code": 404}}`, http.StatusNotFound)
+			return
+		}
+		if query.Get("alt") == "media" {
+			http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": key, "size": strconv.Itoa(len(content))})
+	case r.Method == http.MethodPost && r.URL.Path == uploadPath && b.readOnly:
+		http.Error(w, `{"error": {"code": 403}}`, http.StatusForbidden)
+	case r.Method == http.MethodPost && r.URL.Path == uploadPath && query.Get("uploadType") == "media":
+		key := query.Get("name")
+		if _, exists := b.objects[key]; exists && query.Get("ifGenerationMatch") == "0" {
+			http.Error(w, `{"error": {"code": 412}}`, http.StatusPreconditionFailed)
+			return
+		}
+		b.objects[key] = must(io.ReadAll(r.Body))
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": key})
+	case r.Method == http.MethodPost && r.URL.Path == uploadPath && query.Get("uploadType") == "resumable":
+		b.resumableUploads = append(b.resumableUploads, query.Get("name"))
+		w.Header().Set("Location", b.url+"/upload/session/"+strconv.Itoa(len(b.resumableUploads)))
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/upload/session/"):
+		b.cancelledUploads++
+		w.WriteHeader(499)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+	}
+}
+
+func (b *fakeGCSBucket) list(w http.ResponseWriter, query url.Values) {
+	prefix := query.Get("prefix")
+	b.prefixes = append(b.prefixes, prefix)
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	start, _ := strconv.Atoi(query.Get("pageToken"))
+	end := min(start+2, len(keys))
+	type item struct {
+		Name string `json:"name"`
+	}
+	page := struct {
+		Items         []item `json:"items"`
+		NextPageToken string `json:"nextPageToken,omitempty"`
+	}{}
+	for _, key := range keys[start:end] {
+		page.Items = append(page.Items, item{Name: key})
+	}
+	if end < len(keys) {
+		page.NextPageToken = strconv.Itoa(end)
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func sha1Hex(content string) string {
+	sum := sha1.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
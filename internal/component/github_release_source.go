@@ -3,7 +3,6 @@ package component
 import (
 	"context"
 	"crypto/sha1"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -38,17 +37,24 @@ func NewGithubReleaseSource(c cargo.ReleaseSourceConfig) *GithubReleaseSource {
 	if c.Type != "" && c.Type != ReleaseSourceTypeGithub {
 		panic(panicMessageWrongReleaseSourceType)
 	}
-	if c.GithubToken == "" {
-		panic("no token passed for github release source")
-	}
 	if c.Org == "" {
 		panic("no github org passed for github release source")
 	}
 
 	ctx := context.TODO()
-	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.GithubToken})
-	tokenClient := oauth2.NewClient(ctx, tokenSource)
-	githubClient := github.NewClient(tokenClient)
+	required := "github_token"
+	if githubConfig(c).UsesApp() {
+		required = "github_app_private_key"
+	}
+	tokenSource := &githubTokenSource{
+		config:      c,
+		credentials: newReleaseSourceCredentials(DefaultCredentialProvider(), required),
+	}
+	tokenClient := oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, tokenSource))
+	githubClient, err := gh.NewClient(githubConfig(c), tokenClient)
+	if err != nil {
+		panic(fmt.Sprintf("invalid github_host for github release source: %s", err))
+	}
 
 	return &GithubReleaseSource{
 		ReleaseSourceConfig: c,
@@ -69,13 +75,12 @@ func (grs *GithubReleaseSource) Configuration() cargo.ReleaseSourceConfig {
 
 // GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
 // fields on Requirement to download a specific release.
-func (grs *GithubReleaseSource) GetMatchedRelease(s cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (grs *GithubReleaseSource) GetMatchedRelease(ctx context.Context, s cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	_, err := semver.NewVersion(s.Version)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("expected version to be an exact version")
 	}
 
-	ctx := context.TODO()
 	release, err := grs.GetGithubReleaseWithTag(ctx, s)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -91,9 +96,9 @@ type ReleaseByTagGetter interface {
 }
 
 func (grs *GithubReleaseSource) GetGithubReleaseWithTag(ctx context.Context, s cargo.BOSHReleaseTarballSpecification) (*github.RepositoryRelease, error) {
-	repoOwner, repoName, err := gh.RepositoryOwnerAndNameFromPath(s.GitHubRepository)
+	repoOwner, repoName, err := grs.repository(s)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
 	release, response, err := grs.GetReleaseByTag(ctx, repoOwner, repoName, "v"+s.Version)
@@ -113,14 +118,14 @@ func (grs *GithubReleaseSource) GetGithubReleaseWithTag(ctx context.Context, s c
 }
 
 func (grs *GithubReleaseSource) GetLatestMatchingRelease(ctx context.Context, s cargo.BOSHReleaseTarballSpecification) (*github.RepositoryRelease, error) {
-	c, err := s.VersionConstraints()
+	c, err := s.VersionMatcher()
 	if err != nil {
 		return nil, fmt.Errorf("expected version to be a constraint")
 	}
 
-	repoOwner, repoName, err := gh.RepositoryOwnerAndNameFromPath(s.GitHubRepository)
+	repoOwner, repoName, err := grs.repository(s)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
 	if repoOwner != grs.Org {
@@ -146,6 +151,9 @@ func (grs *GithubReleaseSource) GetLatestMatchingRelease(ctx context.Context, s
 
 		foundHigherVersion := false
 		for _, release := range releases {
+			if !s.Channel.AllowsGitHubRelease(release.GetDraft(), release.GetPrerelease()) {
+				continue
+			}
 			v, err := semver.NewVersion(release.GetTagName())
 			if err != nil {
 				continue
@@ -178,8 +186,7 @@ func (grs *GithubReleaseSource) GetLatestMatchingRelease(ctx context.Context, s
 
 // FindReleaseVersion may use any of the fields on Requirement to return the best matching
 // release.
-func (grs *GithubReleaseSource) FindReleaseVersion(s cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
-	ctx := context.TODO()
+func (grs *GithubReleaseSource) FindReleaseVersion(ctx context.Context, s cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
 	release, err := grs.GetLatestMatchingRelease(ctx, s)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -234,6 +241,30 @@ func (grs *GithubReleaseSource) getReleaseSHA1(ctx context.Context, s cargo.BOSH
 	return calculateSHA1(rc)
 }
 
+// repository returns the owner and name of the GitHub repository of the release. Releases
+// in repositories on another GitHub host are not found.
+func (grs *GithubReleaseSource) repository(s cargo.BOSHReleaseTarballSpecification) (owner, repo string, err error) {
+	host, owner, repo, err := gh.RepositoryHostOwnerAndNameFromPath(s.GitHubRepository)
+	if err != nil {
+		return "", "", ErrNotFound
+	}
+	if host != "" && !strings.EqualFold(host, githubConfig(grs.ReleaseSourceConfig).HostName()) {
+		return "", "", ErrNotFound
+	}
+	return owner, repo, nil
+}
+
+// githubConfig returns the host and credentials of a github release source.
+func githubConfig(c cargo.ReleaseSourceConfig) gh.Config {
+	return gh.Config{
+		Host:              c.GithubHost,
+		Token:             c.GithubToken,
+		AppID:             c.GithubAppID,
+		AppInstallationID: c.GithubAppInstallationID,
+		AppPrivateKey:     c.GithubAppPrivateKey,
+	}
+}
+
 //counterfeiter:generate -o ./fakes/releases_lister.go --fake-name ReleasesLister . ReleasesLister
 
 type ReleasesLister interface {
@@ -243,9 +274,9 @@ type ReleasesLister interface {
 // DownloadRelease downloads the release and writes the resulting file to the releasesDir.
 // It should also calculate and set the SHA1 field on the Local result; it does not need
 // to ensure the sums match, the caller must verify this.
-func (grs *GithubReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+func (grs *GithubReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
 	grs.Logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeGithub, grs.ID)
-	return downloadRelease(context.TODO(), releaseDir, remoteRelease, grs, grs.Logger)
+	return downloadRelease(ctx, releaseDir, remoteRelease, grs, newRetryPolicy(grs.Retry), grs.Logger)
 }
 
 //counterfeiter:generate -o ./fakes/release_by_tag_getter_asset_downloader.go --fake-name ReleaseByTagGetterAssetDownloader . ReleaseByTagGetterAssetDownloader
@@ -255,7 +286,7 @@ type ReleaseByTagGetterAssetDownloader interface {
 	ReleaseAssetDownloader
 }
 
-func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock, client ReleaseByTagGetterAssetDownloader, logger *log.Logger) (Local, error) {
+func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock, client ReleaseByTagGetterAssetDownloader, policy retryPolicy, logger *log.Logger) (_ Local, err error) {
 	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
 
 	remoteUrl, err := url.Parse(remoteRelease.RemotePath)
@@ -280,29 +311,60 @@ func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo
 		return Local{}, errors.New("failed to download file for release: expected release asset not found")
 	}
 
-	rc, _, err := client.DownloadReleaseAsset(ctx, org, repo, assetFile.GetID(), http.DefaultClient)
-	if err != nil {
-		fmt.Printf("failed to download file for release: %+v: ", err)
-		return Local{}, err
-	}
-	defer closeAndIgnoreError(rc)
-
 	file, err := os.Create(filePath)
 	if err != nil {
 		fmt.Printf("failed to create file for release: %+v: ", err)
 		return Local{}, err
 	}
-	defer closeAndIgnoreError(file)
+	defer removePartialDownload(file, &err)
+
+	// The asset API redirects to a download URL that accepts byte ranges. When it does,
+	// the download is resumed from the redirect URL instead of starting over on a retry.
+	var redirectURL string
+	err = policy.do(ctx, logger, "download of "+filePath, func() error {
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		resetDownloadProgress(ctx, 0)
 
-	hash := sha1.New()
+		rc, location, err := client.DownloadReleaseAsset(ctx, org, repo, assetFile.GetID(), nil)
+		if err != nil {
+			fmt.Printf("failed to download file for release: %+v: ", err)
+			return err
+		}
+		if rc == nil {
+			redirectURL = location
+			return nil
+		}
+		defer closeAndIgnoreError(rc)
 
-	mw := io.MultiWriter(file, hash)
-	_, err = io.Copy(mw, rc)
+		_, err = io.Copy(progressWriter(ctx, file, int64(assetFile.GetSize())), rc)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return fmt.Errorf("failed to download file for release: %w", err)
+		}
+		return nil
+	})
+	if err == nil && redirectURL != "" {
+		err = downloadHTTPFile(ctx, http.DefaultClient, policy, logger, file, func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, redirectURL, nil)
+		}, func(statusCode int) string {
+			return fmt.Sprintf("failed to download %s release asset from GitHub with error code %d", remoteRelease.Name, statusCode)
+		})
+	}
 	if err != nil {
-		return Local{}, fmt.Errorf("failed to calculate checksum for downloaded file: %+v: ", err)
+		return Local{}, err
 	}
 
-	remoteRelease.SHA1 = hex.EncodeToString(hash.Sum(nil))
+	remoteRelease.SHA1, err = fileSHA1(ctx, filePath)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to calculate checksum for downloaded file: %w", err)
+	}
 
 	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
 }
@@ -3,11 +3,18 @@ package component
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v40/github"
 
@@ -59,7 +66,7 @@ func TestGithubReleaseSource_downloadRelease(t *testing.T) {
 	downloader.DownloadReleaseAssetReturns(io.NopCloser(asset), "", nil)
 
 	logger := log.New(io.Discard, "", 0)
-	local, err := downloadRelease(context.Background(), tempDir, lock, downloader, logger)
+	local, err := downloadRelease(context.Background(), tempDir, lock, downloader, newRetryPolicy(cargo.RetryConfig{}), logger)
 	please.Expect(err).NotTo(HaveOccurred())
 
 	{
@@ -79,4 +86,93 @@ func TestGithubReleaseSource_downloadRelease(t *testing.T) {
 	please.Expect(local.Lock.SHA1).To(Equal("3a2be7b07a1a19072bf54c95a8c4a3fe0cdb35d4"))
 }
 
+func TestGithubReleaseSource_downloadRelease_resume(t *testing.T) {
+	const contents = "some release tarball contents\n"
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:       "routing",
+		Version:    "0.239.0",
+		RemotePath: "https://github.com/cloudfoundry/routing-release/releases/download/v0.239.0/routing-0.239.0.tgz",
+	}
+
+	please := NewWithT(t)
+
+	var rangeHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeaders = append(rangeHeaders, r.Header.Get("Range"))
+		if len(rangeHeaders) == 1 {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, contents[:10])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(contents)-1, len(contents)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.WriteString(w, contents[10:])
+	}))
+	t.Cleanup(server.Close)
+
+	downloader := new(fakes_internal.ReleaseByTagGetterAssetDownloader)
+	downloader.GetReleaseByTagReturns(&github.RepositoryRelease{
+		Assets: []*github.ReleaseAsset{
+			{
+				Name: ptr("routing-0.239.0.tgz"),
+				ID:   ptr(int64(42)),
+			},
+		},
+	}, nil, nil)
+	downloader.DownloadReleaseAssetReturns(nil, server.URL+"/routing-0.239.0.tgz", nil)
+
+	logger := log.New(io.Discard, "", 0)
+	local, err := downloadRelease(context.Background(), t.TempDir(), lock, downloader, newRetryPolicy(cargo.RetryConfig{InitialBackoff: time.Millisecond}), logger)
+	please.Expect(err).NotTo(HaveOccurred())
+
+	please.Expect(downloader.DownloadReleaseAssetCallCount()).To(Equal(1), "it only asks the asset API for the download URL once")
+	_, _, _, id, client := downloader.DownloadReleaseAssetArgsForCall(0)
+	please.Expect(id).To(Equal(int64(42)))
+	please.Expect(client).To(BeNil(), "it follows the redirect itself so it can resume the download")
+
+	please.Expect(rangeHeaders).To(Equal([]string{"", "bytes=10-"}))
+	please.Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(contents)))
+	sum := sha1.Sum([]byte(contents))
+	please.Expect(local.Lock.SHA1).To(Equal(hex.EncodeToString(sum[:])))
+}
+
 func ptr[T any](v T) *T { return &v }
+
+func TestNewGithubReleaseSource_enterprise(t *testing.T) {
+	please := NewWithT(t)
+
+	var paths, authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	source := NewGithubReleaseSource(cargo.ReleaseSourceConfig{
+		Type:        ReleaseSourceTypeGithub,
+		Org:         "some-org",
+		GithubHost:  server.URL,
+		GithubToken: "some-token",
+	})
+
+	_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+		Name: "banana", Version: "1.2.3", GitHubRepository: "https://github.com/some-org/banana-release",
+	})
+	please.Expect(IsErrNotFound(err)).To(BeTrue())
+	please.Expect(paths).To(BeEmpty(), "it does not look for repositories on other hosts")
+
+	_, err = source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+		Name: "banana", Version: "1.2.3", GitHubRepository: server.URL + "/some-org/banana-release",
+	})
+	please.Expect(err).To(HaveOccurred())
+	please.Expect(paths).To(ContainElement("/api/v3/repos/some-org/banana-release/releases/tags/v1.2.3"))
+	please.Expect(authorizations).To(HaveEach("Bearer some-token"))
+}
@@ -98,7 +98,7 @@ func TestGithubReleaseSource_ComponentLockFromGithubRelease(t *testing.T) {
 			},
 		}
 
-		lock, err := grsMock.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
+		lock, err := grsMock.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 			Name:             "routing",
 			Version:          "0.226.0",
 			GitHubRepository: "https://github.com/cloudfoundry/routing-release",
@@ -188,7 +188,7 @@ func TestGithubReleaseSource_ComponentLockFromGithubRelease(t *testing.T) {
 		}
 
 		// When...
-		lock, err := grsMock.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
+		lock, err := grsMock.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 			Name:             "routing",
 			Version:          "0.226.0",
 			GitHubRepository: "https://github.com/cloudfoundry/routing-release",
@@ -230,7 +230,7 @@ func TestGithubReleaseSource_FindReleaseVersion(t *testing.T) {
 			Version: "garbage",
 		}
 		grs := component.NewGithubReleaseSource(cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeGithub, GithubToken: "fake_token", Org: "cloudfoundry"})
-		_, err := grs.FindReleaseVersion(s, false)
+		_, err := grs.FindReleaseVersion(context.Background(), s, false)
 
 		t.Run("it returns an error about version not being specific", func(t *testing.T) {
 			damnIt := NewWithT(t)
@@ -286,7 +286,7 @@ func TestGithubReleaseSource_FindReleaseVersion(t *testing.T) {
 			},
 		}
 
-		lock, err := grsMock.FindReleaseVersion(s, true)
+		lock, err := grsMock.FindReleaseVersion(context.Background(), s, true)
 		please.Expect(err).ToNot(HaveOccurred())
 
 		please.Expect(lock.SHA1).To(Equal("not-calculated"))
@@ -300,7 +300,7 @@ func TestGithubReleaseSource_GetMatchedRelease(t *testing.T) {
 			Version: ">1.0.0",
 		}
 		grs := component.NewGithubReleaseSource(cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeGithub, GithubToken: "fake_token", Org: "cloudfoundry"})
-		_, err := grs.GetMatchedRelease(s)
+		_, err := grs.GetMatchedRelease(context.Background(), s)
 
 		t.Run("it returns an error about version not being specific", func(t *testing.T) {
 			damnIt := NewWithT(t)
@@ -388,6 +388,7 @@ func TestGetGithubReleaseWithTag(t *testing.T) {
 
 func TestGetLatestMatchingRelease(t *testing.T) {
 	strPtr := func(s string) *string { return &s }
+	boolPtr := func(b bool) *bool { return &b }
 
 	t.Run("when get release with tag api request fails", func(t *testing.T) {
 		damnIt := NewWithT(t)
@@ -501,6 +502,51 @@ func TestGetLatestMatchingRelease(t *testing.T) {
 		damnIt.Expect(releaseGetter.ListReleasesCallCount()).To(Equal(3))
 	})
 
+	t.Run("when the release has a channel", func(t *testing.T) {
+		releases := []*github.RepositoryRelease{
+			{TagName: strPtr("v2.1.0"), Draft: boolPtr(true)},
+			{TagName: strPtr("v2.0.5-dev.3")},
+			{TagName: strPtr("v2.0.5-rc.1"), Prerelease: boolPtr(true)},
+			{TagName: strPtr("v2.0.4"), Prerelease: boolPtr(true)},
+			{TagName: strPtr("v2.0.3")},
+		}
+		for _, tt := range []struct {
+			name        string
+			channel     cargo.ReleaseChannel
+			expectedTag string
+		}{
+			{name: "no channel", channel: "", expectedTag: "v2.1.0"},
+			{name: "stable", channel: cargo.ReleaseChannelStable, expectedTag: "v2.0.3"},
+			{name: "rc", channel: cargo.ReleaseChannelRC, expectedTag: "v2.0.5-rc.1"},
+			{name: "dev", channel: cargo.ReleaseChannelDev, expectedTag: "v2.1.0"},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				damnIt := NewWithT(t)
+
+				releaseGetter := new(fakes.ReleasesLister)
+				releaseGetter.ListReleasesReturnsOnCall(0, releases, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil)
+				releaseGetter.ListReleasesReturns(nil, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil)
+
+				grsMock := &component.GithubReleaseSource{
+					Logger:         log.New(io.Discard, "", 0),
+					ReleasesLister: releaseGetter,
+					ReleaseSourceConfig: cargo.ReleaseSourceConfig{
+						Org: "test-org",
+					},
+				}
+
+				rel, err := grsMock.GetLatestMatchingRelease(context.TODO(), cargo.BOSHReleaseTarballSpecification{
+					Name:             "test",
+					Version:          "~2",
+					Channel:          tt.channel,
+					GitHubRepository: "git@github.com:test-org/test.git",
+				})
+				damnIt.Expect(err).NotTo(HaveOccurred())
+				damnIt.Expect(rel.GetTagName()).To(Equal(tt.expectedTag))
+			})
+		}
+	})
+
 	t.Run("component repo does not match release source org", func(t *testing.T) {
 		// given
 		var (
@@ -538,7 +584,7 @@ func TestDownloadReleaseAsset(t *testing.T) {
 		GithubToken: os.Getenv("GITHUB_TOKEN"),
 		Org:         "cloudfoundry",
 	})
-	testLock, err := grs.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{Name: "routing", Version: "0.226.0", GitHubRepository: "https://github.com/cloudfoundry/routing-release"})
+	testLock, err := grs.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "routing", Version: "0.226.0", GitHubRepository: "https://github.com/cloudfoundry/routing-release"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -550,7 +596,7 @@ func TestDownloadReleaseAsset(t *testing.T) {
 			_ = os.RemoveAll(tempDir)
 		})
 
-		local, err := grs.DownloadRelease(tempDir, testLock)
+		local, err := grs.DownloadRelease(context.Background(), tempDir, testLock)
 		damnIt.Expect(err).NotTo(HaveOccurred())
 
 		damnIt.Expect(local.LocalPath).NotTo(BeAnExistingFile(), "it creates the expected asset")
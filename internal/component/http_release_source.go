@@ -0,0 +1,377 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// HTTPReleaseSource downloads releases from a plain HTTP(S) server. The path_template
+// renders the download URL. Versions are discovered from the document at IndexURL; it
+// may be a JSON or YAML release index (see HTTPReleaseIndex) or an HTML directory listing
+// like the ones generated by nginx autoindex. When IndexURL is not set the directory
+// containing the rendered download URL is used.
+type HTTPReleaseSource struct {
+	cargo.ReleaseSourceConfig
+	Client *http.Client
+	logger *log.Logger
+
+	credentials *releaseSourceCredentials
+}
+
+// HTTPReleaseIndex is the structure of a JSON or YAML release index document.
+// The URL of an entry may be relative to the index; when it is not set the path_template
+// is used.
+type HTTPReleaseIndex struct {
+	Releases []HTTPReleaseIndexEntry `yaml:"releases" json:"releases"`
+}
+
+type HTTPReleaseIndexEntry struct {
+	Name            string `yaml:"name"                       json:"name"`
+	Version         string `yaml:"version"                    json:"version"`
+	StemcellOS      string `yaml:"stemcell_os,omitempty"      json:"stemcell_os,omitempty"`
+	StemcellVersion string `yaml:"stemcell_version,omitempty" json:"stemcell_version,omitempty"`
+	URL             string `yaml:"url,omitempty"              json:"url,omitempty"`
+	SHA1            string `yaml:"sha1,omitempty"             json:"sha1,omitempty"`
+}
+
+// NewHTTPReleaseSource will provision a new HTTPReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewHTTPReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *HTTPReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeHTTP {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.ID == "" {
+		c.ID = ReleaseSourceTypeHTTP
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[HTTP release source] ", log.Default().Flags())
+	}
+	return &HTTPReleaseSource{
+		ReleaseSourceConfig: c,
+		Client:              http.DefaultClient,
+		logger:              logger,
+		credentials:         newReleaseSourceCredentials(DefaultCredentialProvider()),
+	}
+}
+
+func (src *HTTPReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
+// fields on Requirement to download a specific release.
+func (src *HTTPReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	res, err := src.request(ctx, http.MethodHead, remotePath)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode == http.StatusMethodNotAllowed {
+		res, err = src.request(ctx, http.MethodGet, remotePath)
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+		closeAndIgnoreError(res.Body)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	default:
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("unexpected http status: %s", res.Status)
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// FindReleaseVersion reads the index and returns the highest version matching the
+// constraint on spec.
+func (src *HTTPReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	entries, err := src.indexEntries(ctx, spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	var (
+		foundVersion *semver.Version
+		found        HTTPReleaseIndexEntry
+	)
+	for _, entry := range entries {
+		if entry.Name != spec.Name || entry.StemcellOS != spec.StemcellOS || entry.StemcellVersion != spec.StemcellVersion {
+			continue
+		}
+		version, err := semver.NewVersion(entry.Version)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		if foundVersion == nil || version.GreaterThan(foundVersion) {
+			foundVersion, found = version, entry
+		}
+	}
+	if foundVersion == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      found.Version,
+		SHA1:         found.SHA1,
+		RemotePath:   found.URL,
+		RemoteSource: src.ID,
+	}
+	if lock.SHA1 == "" {
+		if noDownload {
+			lock.SHA1 = "not-calculated"
+		} else {
+			lock.SHA1, err = src.remoteSHA1(ctx, lock.RemotePath)
+			if err != nil {
+				return cargo.BOSHReleaseTarballLock{}, err
+			}
+		}
+	}
+	return lock, nil
+}
+
+// DownloadRelease downloads the release from the URL in remoteRelease.RemotePath.
+func (src *HTTPReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (_ Local, err error) {
+	src.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeHTTP, src.ID)
+
+	res, err := src.request(ctx, http.MethodGet, remoteRelease.RemotePath)
+	if err != nil {
+		return Local{}, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return Local{}, fmt.Errorf("failed to download %s release from %s with error code %d", remoteRelease.Name, src.ID, res.StatusCode)
+	}
+
+	u, err := url.Parse(remoteRelease.RemotePath)
+	if err != nil {
+		return Local{}, err
+	}
+	filePath := filepath.Join(releaseDir, path.Base(u.Path))
+
+	out, err := os.Create(filePath)
+	if err != nil {
+		return Local{}, err
+	}
+	defer removePartialDownload(out, &err)
+
+	hash := sha1.New()
+	if _, err = io.Copy(io.MultiWriter(progressWriter(ctx, out, res.ContentLength), hash), res.Body); err != nil {
+		if ctx.Err() != nil {
+			return Local{}, ctx.Err()
+		}
+		return Local{}, err
+	}
+
+	remoteRelease.SHA1 = hex.EncodeToString(hash.Sum(nil))
+	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
+}
+
+func (src *HTTPReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	pathBuf := new(bytes.Buffer)
+
+	err := src.pathTemplate().Execute(pathBuf, spec)
+	if err != nil {
+		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
+	}
+
+	return pathBuf.String(), nil
+}
+
+func (src *HTTPReleaseSource) pathTemplate() *template.Template {
+	return template.Must(
+		template.New("remote-path").
+			Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
+			Parse(src.ReleaseSourceConfig.PathTemplate))
+}
+
+// indexEntries fetches the index for the release and returns its entries with
+// absolute URLs.
+func (src *HTTPReleaseSource) indexEntries(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) ([]HTTPReleaseIndexEntry, error) {
+	placeholderSpec := spec
+	placeholderSpec.Version = versionPlaceholder
+	rendered, err := src.RemotePath(placeholderSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	indexURL := src.IndexURL
+	if indexURL == "" {
+		_, prefix, err := renderedTemplateVersionPattern(rendered, src.PathTemplate)
+		if err != nil {
+			return nil, err
+		}
+		indexURL = prefix[:strings.LastIndex(prefix, "/")+1]
+	} else {
+		buf := new(bytes.Buffer)
+		indexTemplate, err := template.New("index-url").Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).Parse(indexURL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse index_url: %w", err)
+		}
+		if err := indexTemplate.Execute(buf, spec); err != nil {
+			return nil, fmt.Errorf("unable to evaluate index_url: %w", err)
+		}
+		indexURL = buf.String()
+	}
+
+	res, err := src.request(ctx, http.MethodGet, indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("failed to get release index %s: unexpected http status: %s", indexURL, res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType == "text/html" || bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		pattern, _, err := renderedTemplateVersionPattern(normalizeURL(rendered), src.PathTemplate)
+		if err != nil {
+			return nil, err
+		}
+		return parseAutoIndex(res.Request.URL, body, pattern, spec), nil
+	}
+
+	var index HTTPReleaseIndex
+	if err := yaml.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse release index %s: %w", indexURL, err)
+	}
+	for i, entry := range index.Releases {
+		if entry.URL == "" {
+			entry.URL, err = src.RemotePath(cargo.BOSHReleaseTarballSpecification{
+				Name:            entry.Name,
+				Version:         entry.Version,
+				StemcellOS:      entry.StemcellOS,
+				StemcellVersion: entry.StemcellVersion,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		u, err := res.Request.URL.Parse(entry.URL)
+		if err != nil {
+			return nil, fmt.Errorf("release index entry for %s %s has invalid url: %w", entry.Name, entry.Version, err)
+		}
+		entry.URL = u.String()
+		index.Releases[i] = entry
+	}
+	return index.Releases, nil
+}
+
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+// parseAutoIndex returns an entry for each link in an HTML directory listing that matches
+// the rendered path_template pattern.
+func parseAutoIndex(base *url.URL, body []byte, pattern *regexp.Regexp, spec cargo.BOSHReleaseTarballSpecification) []HTTPReleaseIndexEntry {
+	var entries []HTTPReleaseIndexEntry
+	for _, match := range hrefPattern.FindAllSubmatch(body, -1) {
+		u, err := base.Parse(string(match[1]))
+		if err != nil {
+			continue
+		}
+		versionMatch := pattern.FindStringSubmatch(normalizeURL(u.String()))
+		if versionMatch == nil || hasStemcellSuffix(versionMatch[1]) {
+			continue
+		}
+		entries = append(entries, HTTPReleaseIndexEntry{
+			Name:            spec.Name,
+			Version:         versionMatch[1],
+			StemcellOS:      spec.StemcellOS,
+			StemcellVersion: spec.StemcellVersion,
+			URL:             u.String(),
+		})
+	}
+	return entries
+}
+
+// normalizeURL unescapes the path so links written by different servers can be compared.
+func normalizeURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+func (src *HTTPReleaseSource) remoteSHA1(ctx context.Context, u string) (string, error) {
+	res, err := src.request(ctx, http.MethodGet, u)
+	if err != nil {
+		return "", err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s with error code %d", u, res.StatusCode)
+	}
+	hash := sha1.New()
+	if _, err := io.Copy(hash, res.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (src *HTTPReleaseSource) request(ctx context.Context, method, u string) (*http.Response, error) {
+	config, err := src.credentials.resolve(ctx, src.ReleaseSourceConfig)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+config.Token)
+	case config.Username != "":
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+	res, err := src.Client.Do(req)
+	return res, wrapVPNError(err)
+}
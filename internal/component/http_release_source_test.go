@@ -0,0 +1,247 @@
+package component_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("interacting with BOSH releases on an HTTP server", func() {
+	var (
+		source *component.HTTPReleaseSource
+		config cargo.ReleaseSourceConfig
+		server *httptest.Server
+		router *httprouter.Router
+
+		releasesDirectory string
+	)
+
+	serveFile := func(content string) http.HandlerFunc {
+		return func(res http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(res, content)
+		}
+	}
+
+	sha1Of := func(content string) string {
+		sum := sha1.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		releasesDirectory = must(os.MkdirTemp("", "releases"))
+
+		router = httprouter.New()
+		router.HandlerFunc(http.MethodGet, "/releases/mango/mango-2.3.4.tgz", serveFile("mango 2.3.4"))
+		router.HandlerFunc(http.MethodGet, "/releases/mango/mango-2.4.0.tgz", serveFile("mango 2.4.0"))
+
+		config = cargo.ReleaseSourceConfig{
+			Type: component.ReleaseSourceTypeHTTP,
+			ID:   "internal-releases",
+		}
+	})
+
+	JustBeforeEach(func() {
+		server = httptest.NewServer(router)
+		config.PathTemplate = server.URL + "/releases/{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+		if config.IndexURL != "" {
+			config.IndexURL = server.URL + config.IndexURL
+		}
+		source = component.NewHTTPReleaseSource(config, log.New(GinkgoWriter, "", 0))
+		source.Client = server.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, nil)).To(BeAssignableToTypeOf(source))
+	})
+
+	Describe("GetMatchedRelease", func() {
+		It("resolves the lock from the spec", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				RemotePath:   server.URL + "/releases/mango/mango-2.3.4.tgz",
+				RemoteSource: "internal-releases",
+			}))
+		})
+
+		It("returns ErrNotFound when the server does not have the file", func() {
+			_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "9.9.9"})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("FindReleaseVersion", func() {
+		When("the server has an HTML directory listing", func() {
+			BeforeEach(func() {
+				router.HandlerFunc(http.MethodGet, "/releases/mango/", func(res http.ResponseWriter, _ *http.Request) {
+					res.Header().Set("Content-Type", "text/html")
+					// language=html
+					_, _ = io.WriteString(res, `<html><head><title>Index of /releases/mango/</title></head><body><h1>Index of /releases/mango/</h1><hr><pre><a href="../">../</a>
+<a href="mango-2.3.4.tgz">mango-2.3.4.tgz</a>                                    01-Jan-2024 00:00     11
+<a href="mango-2.4.0.tgz">mango-2.4.0.tgz</a>                                    01-Jan-2024 00:00     11
+<a href="mango-3.0.0.tgz">mango-3.0.0.tgz</a>                                    01-Jan-2024 00:00     11
+<a href="notes.txt">notes.txt</a>                                                01-Jan-2024 00:00     11
+</pre><hr></body></html>`)
+				})
+			})
+
+			It("finds the latest version matching the constraint", func() {
+				lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~2"}, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+					Name:         "mango",
+					Version:      "2.4.0",
+					SHA1:         sha1Of("mango 2.4.0"),
+					RemotePath:   server.URL + "/releases/mango/mango-2.4.0.tgz",
+					RemoteSource: "internal-releases",
+				}))
+			})
+
+			It("returns ErrNotFound when no link matches", func() {
+				_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~4"}, false)
+				Expect(component.IsErrNotFound(err)).To(BeTrue())
+			})
+		})
+
+		When("index_url points to a JSON index", func() {
+			BeforeEach(func() {
+				config.IndexURL = "/index.json"
+				router.HandlerFunc(http.MethodGet, "/index.json", func(res http.ResponseWriter, _ *http.Request) {
+					res.Header().Set("Content-Type", "application/json")
+					// language=json
+					_, _ = io.WriteString(res, `{"releases": [
+						{"name": "mango", "version": "2.3.4", "sha1": "some-sha"},
+						{"name": "mango", "version": "2.4.0", "url": "releases/mango/mango-2.4.0.tgz", "sha1": "other-sha"},
+						{"name": "banana", "version": "9.0.0"}
+					]}`)
+				})
+			})
+
+			It("finds the latest version matching the constraint", func() {
+				lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango"}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+					Name:         "mango",
+					Version:      "2.4.0",
+					SHA1:         "other-sha",
+					RemotePath:   server.URL + "/releases/mango/mango-2.4.0.tgz",
+					RemoteSource: "internal-releases",
+				}))
+			})
+
+			It("uses the path_template when an entry has no url", func() {
+				lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "2.3.4"}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.RemotePath).To(Equal(server.URL + "/releases/mango/mango-2.3.4.tgz"))
+				Expect(lock.SHA1).To(Equal("some-sha"))
+			})
+		})
+
+		When("index_url points to a YAML index", func() {
+			BeforeEach(func() {
+				config.IndexURL = "/{{.Name}}/index.yml"
+				router.HandlerFunc(http.MethodGet, "/mango/index.yml", func(res http.ResponseWriter, _ *http.Request) {
+					res.Header().Set("Content-Type", "application/yaml")
+					// language=yaml
+					_, _ = io.WriteString(res, "releases:\n- name: mango\n  version: 2.3.4\n")
+				})
+			})
+
+			It("calculates the sum when the index does not have one", func() {
+				lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "mango"}, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Version).To(Equal("2.3.4"))
+				Expect(lock.SHA1).To(Equal(sha1Of("mango 2.3.4")))
+			})
+		})
+	})
+
+	Describe("DownloadRelease", func() {
+		It("downloads the release", func() {
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name: "mango", Version: "2.3.4", RemotePath: server.URL + "/releases/mango/mango-2.3.4.tgz",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDirectory, "mango-2.3.4.tgz")))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("mango 2.3.4")))
+			Expect(local.Lock.SHA1).To(Equal(sha1Of("mango 2.3.4")))
+		})
+
+		It("reports download progress", func() {
+			progress := new(recordingDownloadProgress)
+			ctx := component.ContextWithDownloadProgress(context.Background(), progress)
+			_, err := source.DownloadRelease(ctx, releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name: "mango", Version: "2.3.4", RemotePath: server.URL + "/releases/mango/mango-2.3.4.tgz",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(progress.size).To(BeEquivalentTo(len("mango 2.3.4")))
+			Expect(progress.written).To(BeEquivalentTo(len("mango 2.3.4")))
+		})
+	})
+
+	Describe("authentication", func() {
+		var authorization string
+		BeforeEach(func() {
+			router.HandlerFunc(http.MethodGet, "/releases/secret/secret-1.0.0.tgz", func(res http.ResponseWriter, req *http.Request) {
+				authorization = req.Header.Get("Authorization")
+				_, _ = io.WriteString(res, "secret")
+			})
+		})
+
+		When("a token is configured", func() {
+			BeforeEach(func() {
+				config.Token = "some-token"
+			})
+			It("sends a bearer token", func() {
+				_, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
+					Name: "secret", Version: "1.0.0", RemotePath: server.URL + "/releases/secret/secret-1.0.0.tgz",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(authorization).To(Equal("Bearer some-token"))
+			})
+		})
+
+		When("a username and password are configured", func() {
+			BeforeEach(func() {
+				config.Username = "kim"
+				config.Password = "mango_rice!"
+			})
+			It("uses basic auth", func() {
+				_, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
+					Name: "secret", Version: "1.0.0", RemotePath: server.URL + "/releases/secret/secret-1.0.0.tgz",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(authorization).To(HavePrefix("Basic "))
+			})
+		})
+	})
+})
+
+type recordingDownloadProgress struct {
+	size, written int64
+}
+
+func (p *recordingDownloadProgress) SetSize(n int64) { p.size = n }
+func (p *recordingDownloadProgress) Add(n int64)     { p.written += n }
+func (p *recordingDownloadProgress) Reset(n int64)   { p.written = n }
@@ -2,8 +2,10 @@ package component
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
@@ -44,6 +46,7 @@ func (l LocalReleaseDirectory) GetLocalReleases(releasesDir string) ([]Local, er
 			Name:    releaseTarball.Manifest.Name,
 			Version: releaseTarball.Manifest.Version,
 			SHA1:    releaseTarball.SHA1,
+			SHA256:  releaseTarball.SHA256,
 		}
 
 		stemcellOS, stemcellVersion, ok := releaseTarball.Manifest.Stemcell()
@@ -106,13 +109,21 @@ func (l LocalReleaseDirectory) deleteReleases(releasesToDelete []Local) error {
 }
 
 func CalculateSum(releasePath string, fs billy.Filesystem) (string, error) {
+	return calculateSum(releasePath, fs, sha1.New())
+}
+
+// CalculateSHA256Sum is like CalculateSum but returns the SHA256 sum.
+func CalculateSHA256Sum(releasePath string, fs billy.Filesystem) (string, error) {
+	return calculateSum(releasePath, fs, sha256.New())
+}
+
+func calculateSum(releasePath string, fs billy.Filesystem, h hash.Hash) (string, error) {
 	f, err := fs.Open(releasePath)
 	if err != nil {
 		return "", err
 	}
 	defer closeAndIgnoreError(f)
 
-	h := sha1.New()
 	_, err = io.Copy(h, f)
 	if err != nil {
 		return "", err
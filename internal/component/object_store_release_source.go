@@ -0,0 +1,195 @@
+package component
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// objectStore is the part of a cloud object storage API used by objectStoreReleaseSource.
+type objectStore interface {
+	// objectURL returns the URL of the object used in messages, for example gs://bucket/key.
+	objectURL(key string) string
+	exists(ctx context.Context, key string) (bool, error)
+	// list calls fn with the key of each object under prefix.
+	list(ctx context.Context, prefix string, fn func(key string)) error
+	download(ctx context.Context, key string, file *os.File, policy retryPolicy, logger *log.Logger) error
+	// upload returns ErrAlreadyExists when the object exists and overwrite is false.
+	upload(ctx context.Context, key string, body io.ReadSeeker, size int64, overwrite bool) error
+	// checkUpload checks that objects may be written at key without writing one.
+	checkUpload(ctx context.Context, key string) error
+}
+
+// objectStoreReleaseSource implements the release source methods shared by the release
+// sources that store releases as objects named by path_template.
+type objectStoreReleaseSource struct {
+	cargo.ReleaseSourceConfig
+	store  objectStore
+	logger *log.Logger
+}
+
+func (src *objectStoreReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
+// fields on Requirement to download a specific release.
+func (src *objectStoreReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	found, err := src.store.exists(ctx, remotePath)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if !found {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// FindReleaseVersion lists the objects under the part of path_template that does not depend
+// on the version and returns the highest version matching the constraint. Compiled
+// releases are skipped unless the spec sets a stemcell.
+func (src *objectStoreReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	pattern, prefix, err := pathTemplateVersionPattern(src.PathTemplate, spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	newest := newestPathVersion{pattern: pattern, matcher: constraint}
+	if err := src.store.list(ctx, prefix, newest.add); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if newest.version == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      newest.version.Original(),
+		RemotePath:   newest.path,
+		RemoteSource: src.ID,
+	}
+	if noDownload {
+		lock.SHA1 = "not-calculated"
+		return lock, nil
+	}
+
+	// the object stores do not record a SHA1, so the release is downloaded to calculate it
+	dir, err := os.MkdirTemp("", "kiln-find-release-version-*")
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	local, err := src.DownloadRelease(ctx, dir, lock)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	lock.SHA1 = local.Lock.SHA1
+	return lock, nil
+}
+
+func (src *objectStoreReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, lock cargo.BOSHReleaseTarballLock) (_ Local, err error) {
+	src.logger.Printf(logLineDownload, lock.Name, src.Type, src.ID)
+
+	outputFile := filepath.Join(releaseDir, filepath.Base(lock.RemotePath))
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to create file %q: %w", outputFile, err)
+	}
+	defer removePartialDownload(out, &err)
+
+	if err = src.store.download(ctx, lock.RemotePath, out, newRetryPolicy(src.Retry), src.logger); err != nil {
+		return Local{}, err
+	}
+
+	lock.SHA1, err = fileSHA1(ctx, outputFile)
+	if err != nil {
+		return Local{}, err
+	}
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// UploadRelease uploads the release to the path_template location. It returns
+// ErrAlreadyExists when the object exists, unless ctx was returned by ContextWithOverwrite.
+func (src *objectStoreReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	body, cleanup, err := seekableUploadBody(file)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer cleanup()
+	hash := sha1.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if _, err := body.Seek(-size, io.SeekCurrent); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID, src.store.objectURL(remotePath))
+
+	if err := src.store.upload(ctx, remotePath, body, size, AllowsOverwrite(ctx)); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         hex.EncodeToString(hash.Sum(nil)),
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// CheckUpload checks that releases may be written at the path_template location without
+// uploading one.
+func (src *objectStoreReleaseSource) CheckUpload(ctx context.Context) error {
+	remotePath, err := src.RemotePath(uploadCheckSpec)
+	if err != nil {
+		return err
+	}
+	return src.store.checkUpload(ctx, remotePath)
+}
+
+func (src *objectStoreReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return renderPathTemplate(src.PathTemplate, spec)
+}
+
+// objectStoreStatusError returns an httpStatusError for an unexpected response, including
+// the start of the response body, which usually explains the failure.
+func objectStoreStatusError(res *http.Response, operation string) error {
+	buf, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	message := fmt.Sprintf("failed to %s: %s", operation, res.Status)
+	if detail := strings.TrimSpace(string(buf)); detail != "" {
+		message += ": " + detail
+	}
+	return newHTTPStatusError(res, message)
+}
@@ -0,0 +1,652 @@
+package component
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	_ "crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	// OCIArtifactTypeBOSHReleaseTarball is the artifactType set on manifests pushed by OCIReleaseSource.
+	OCIArtifactTypeBOSHReleaseTarball = "application/vnd.cloudfoundry.bosh.release.v1"
+
+	// OCIMediaTypeBOSHReleaseTarball is the media type of the layer holding the BOSH release tarball.
+	OCIMediaTypeBOSHReleaseTarball = "application/vnd.cloudfoundry.bosh.release.v1.tar+gzip"
+
+	// OCIAnnotationSHA1 records the SHA1 sum of the release tarball so the lock can be
+	// populated without downloading the blob.
+	OCIAnnotationSHA1 = "io.pivotal.kiln.release.sha1"
+)
+
+// OCIReleaseSource stores BOSH release tarballs as OCI artifacts in a container registry.
+//
+// Each release is pushed to the repository "{repo}/{release name}" on the registry and
+// tagged with the release version. Compiled releases have the stemcell appended to the
+// tag: "{version}-{stemcell os}-{stemcell version}". Since "+" is not allowed in a tag,
+// it is replaced with "_".
+type OCIReleaseSource struct {
+	cargo.ReleaseSourceConfig
+	Client *http.Client
+	logger *log.Logger
+
+	tokensMutex sync.Mutex
+	tokens      map[string]string
+
+	credentials *releaseSourceCredentials
+}
+
+// NewOCIReleaseSource will provision a new OCIReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewOCIReleaseSource(c cargo.ReleaseSourceConfig) *OCIReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeOCI {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.ID == "" {
+		c.ID = c.Registry
+	}
+	return &OCIReleaseSource{
+		ReleaseSourceConfig: c,
+		Client:              http.DefaultClient,
+		logger:              log.New(os.Stderr, "[OCI release source] ", log.Default().Flags()),
+		tokens:              make(map[string]string),
+		credentials:         newReleaseSourceCredentials(DefaultCredentialProvider()),
+	}
+}
+
+func (src *OCIReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
+// fields on Requirement to download a specific release.
+func (src *OCIReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	repository, tag := src.repository(spec.Name), ociTag(spec.Version, spec.StemcellOS, spec.StemcellVersion)
+
+	manifest, err := src.getManifest(ctx, repository, tag)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         manifest.Annotations[OCIAnnotationSHA1],
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// FindReleaseVersion lists the tags in the release repository and returns
+// the highest version matching the constraint on spec.
+func (src *OCIReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionMatcher()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	repository := src.repository(spec.Name)
+	tags, err := src.listTags(ctx, repository)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	var (
+		foundVersion *semver.Version
+		foundTag     string
+	)
+	stemcellSuffix := ociTag("", spec.StemcellOS, spec.StemcellVersion)
+	for _, tag := range tags {
+		versionString := tag
+		if stemcellSuffix != "" {
+			if !strings.HasSuffix(tag, stemcellSuffix) {
+				continue
+			}
+			versionString = strings.TrimSuffix(tag, stemcellSuffix)
+		} else if hasStemcellSuffix(tag) {
+			// compiled release tags would otherwise parse as prereleases
+			continue
+		}
+		version, err := semver.NewVersion(strings.ReplaceAll(versionString, "_", "+"))
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		if foundVersion == nil || version.GreaterThan(foundVersion) {
+			foundVersion, foundTag = version, tag
+		}
+	}
+	if foundVersion == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	manifest, err := src.getManifest(ctx, repository, foundTag)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:            spec.Name,
+		Version:         foundVersion.Original(),
+		StemcellOS:      spec.StemcellOS,
+		StemcellVersion: spec.StemcellVersion,
+		SHA1:            manifest.Annotations[OCIAnnotationSHA1],
+		RemotePath:      repository + ":" + foundTag,
+		RemoteSource:    src.ID,
+	}
+	if lock.SHA1 == "" && !noDownload {
+		tmpDir, err := os.MkdirTemp("", "kiln-oci-release-")
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+		defer func() {
+			_ = os.RemoveAll(tmpDir)
+		}()
+		local, err := src.DownloadRelease(ctx, tmpDir, lock)
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+		lock.SHA1 = local.Lock.SHA1
+	}
+	return lock, nil
+}
+
+// DownloadRelease pulls the release tarball layer of the artifact referenced by
+// remoteRelease.RemotePath and verifies its digest.
+func (src *OCIReleaseSource) DownloadRelease(ctx context.Context, releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (_ Local, err error) {
+	repository, reference, err := parseOCIReference(remoteRelease.RemotePath)
+	if err != nil {
+		return Local{}, err
+	}
+
+	src.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeOCI, src.ID)
+
+	manifest, err := src.getManifest(ctx, repository, reference)
+	if err != nil {
+		return Local{}, err
+	}
+	layer, err := releaseTarballLayer(manifest)
+	if err != nil {
+		return Local{}, fmt.Errorf("artifact %s: %w", remoteRelease.RemotePath, err)
+	}
+
+	res, err := src.do(ctx, http.MethodGet, src.registryURL("v2", repository, "blobs", layer.Digest.String()), nil, nil, repository, "pull")
+	if err != nil {
+		return Local{}, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return Local{}, ociResponseError(res, "failed to download blob "+layer.Digest.String())
+	}
+
+	fileName := layer.Annotations[ocispec.AnnotationTitle]
+	if fileName == "" {
+		fileName = fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version)
+	}
+	filePath := filepath.Join(releasesDir, filepath.Base(fileName))
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return Local{}, err
+	}
+	defer removePartialDownload(file, &err)
+
+	sha1Hash := sha1.New()
+	verifier := layer.Digest.Verifier()
+	if _, err = io.Copy(io.MultiWriter(progressWriter(ctx, file, layer.Size), sha1Hash, verifier), res.Body); err != nil {
+		if ctx.Err() != nil {
+			return Local{}, ctx.Err()
+		}
+		return Local{}, err
+	}
+	if !verifier.Verified() {
+		return Local{}, fmt.Errorf("downloaded blob for %s does not match digest %s", remoteRelease.Name, layer.Digest)
+	}
+
+	remoteRelease.SHA1 = hex.EncodeToString(sha1Hash.Sum(nil))
+	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
+}
+
+// UploadRelease pushes the release tarball as a blob and tags a manifest referencing it.
+func (src *OCIReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	repository, tag := src.repository(spec.Name), ociTag(spec.Version, spec.StemcellOS, spec.StemcellVersion)
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID, remotePath)
+
+	sha1Hash := sha1.New()
+	layer, err := src.pushBlob(ctx, repository, io.TeeReader(file, sha1Hash))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	layer.MediaType = OCIMediaTypeBOSHReleaseTarball
+	layer.Annotations = map[string]string{
+		ocispec.AnnotationTitle: releaseTarballFileName(spec),
+	}
+	sum := hex.EncodeToString(sha1Hash.Sum(nil))
+
+	config := ocispec.DescriptorEmptyJSON
+	if _, err := src.pushBlob(ctx, repository, bytes.NewReader(config.Data)); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	config.Data = nil
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: OCIArtifactTypeBOSHReleaseTarball,
+		Config:       config,
+		Layers:       []ocispec.Descriptor{layer},
+		Annotations: map[string]string{
+			OCIAnnotationSHA1: sum,
+		},
+	})
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	res, err := src.do(ctx, http.MethodPut, src.registryURL("v2", repository, "manifests", tag), bytes.NewReader(manifest), http.Header{
+		"Content-Type": {ocispec.MediaTypeImageManifest},
+	}, repository, "pull,push")
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusCreated {
+		return cargo.BOSHReleaseTarballLock{}, ociResponseError(res, "failed to push manifest")
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         sum,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID,
+	}, nil
+}
+
+// RemotePath returns the artifact reference "{repository}:{tag}" for the release.
+func (src *OCIReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	if spec.Name == "" || spec.Version == "" {
+		return "", fmt.Errorf("release name and version are required to build an OCI artifact reference")
+	}
+	return src.repository(spec.Name) + ":" + ociTag(spec.Version, spec.StemcellOS, spec.StemcellVersion), nil
+}
+
+func (src *OCIReleaseSource) repository(releaseName string) string {
+	return path.Join(strings.Trim(src.Repo, "/"), releaseName)
+}
+
+func ociTag(version, stemcellOS, stemcellVersion string) string {
+	tag := version
+	if stemcellOS != "" {
+		tag += "-" + stemcellOS + "-" + stemcellVersion
+	}
+	return strings.ReplaceAll(tag, "+", "_")
+}
+
+func parseOCIReference(remotePath string) (string, string, error) {
+	if i := strings.LastIndex(remotePath, "@"); i > 0 {
+		return remotePath[:i], remotePath[i+1:], nil
+	}
+	i := strings.LastIndex(remotePath, ":")
+	if i <= 0 || strings.Contains(remotePath[i:], "/") {
+		return "", "", fmt.Errorf("remote path %q is not an OCI artifact reference", remotePath)
+	}
+	return remotePath[:i], remotePath[i+1:], nil
+}
+
+func releaseTarballLayer(manifest ocispec.Manifest) (ocispec.Descriptor, error) {
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == OCIMediaTypeBOSHReleaseTarball {
+			return layer, nil
+		}
+	}
+	if len(manifest.Layers) == 1 {
+		return manifest.Layers[0], nil
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("manifest does not contain a layer with media type %s", OCIMediaTypeBOSHReleaseTarball)
+}
+
+func releaseTarballFileName(spec cargo.BOSHReleaseTarballSpecification) string {
+	if spec.StemcellOS != "" {
+		return fmt.Sprintf("%s-%s-%s-%s.tgz", spec.Name, spec.Version, spec.StemcellOS, spec.StemcellVersion)
+	}
+	return fmt.Sprintf("%s-%s.tgz", spec.Name, spec.Version)
+}
+
+func (src *OCIReleaseSource) getManifest(ctx context.Context, repository, reference string) (ocispec.Manifest, error) {
+	res, err := src.do(ctx, http.MethodGet, src.registryURL("v2", repository, "manifests", reference), nil, http.Header{
+		"Accept": {ocispec.MediaTypeImageManifest},
+	}, repository, "pull")
+	if err != nil {
+		return ocispec.Manifest{}, err
+	}
+	defer closeAndIgnoreError(res.Body)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ocispec.Manifest{}, ErrNotFound
+	default:
+		return ocispec.Manifest{}, ociResponseError(res, "failed to get manifest "+repository+":"+reference)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.NewDecoder(res.Body).Decode(&manifest); err != nil {
+		return ocispec.Manifest{}, fmt.Errorf("failed to parse manifest %s:%s: %w", repository, reference, err)
+	}
+	return manifest, nil
+}
+
+func (src *OCIReleaseSource) listTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	next := src.registryURL("v2", repository, "tags", "list")
+	for next != "" {
+		res, err := src.do(ctx, http.MethodGet, next, nil, nil, repository, "pull")
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusNotFound {
+			closeAndIgnoreError(res.Body)
+			return nil, ErrNotFound
+		}
+		if res.StatusCode != http.StatusOK {
+			closeAndIgnoreError(res.Body)
+			return nil, ociResponseError(res, "failed to list tags for "+repository)
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		closeAndIgnoreError(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tag list for %s: %w", repository, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		next, err = nextPageURL(res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// nextPageURL parses the Link header used by registries to paginate the tag list.
+func nextPageURL(res *http.Response) (string, error) {
+	link := res.Header.Get("Link")
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return "", nil
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("malformed Link header %q", link)
+	}
+	u, err := res.Request.URL.Parse(link[start+1 : end])
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// pushBlob uploads content with a single streamed PATCH and returns its descriptor.
+func (src *OCIReleaseSource) pushBlob(ctx context.Context, repository string, content io.Reader) (ocispec.Descriptor, error) {
+	const scope = "pull,push"
+	res, err := src.do(ctx, http.MethodPost, src.registryURL("v2", repository, "blobs", "uploads")+"/", nil, nil, repository, scope)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusAccepted {
+		return ocispec.Descriptor{}, ociResponseError(res, "failed to start blob upload")
+	}
+	location, err := res.Location()
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("blob upload response missing location: %w", err)
+	}
+
+	digester := digest.Canonical.Digester()
+	counter := &byteCounter{}
+	res, err = src.do(ctx, http.MethodPatch, location.String(), io.TeeReader(io.TeeReader(content, digester.Hash()), counter), http.Header{
+		"Content-Type": {"application/octet-stream"},
+	}, repository, scope)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusAccepted {
+		return ocispec.Descriptor{}, ociResponseError(res, "failed to upload blob")
+	}
+	location, err = res.Location()
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("blob upload response missing location: %w", err)
+	}
+
+	dgst := digester.Digest()
+	query := location.Query()
+	query.Set("digest", dgst.String())
+	location.RawQuery = query.Encode()
+	res, err = src.do(ctx, http.MethodPut, location.String(), nil, nil, repository, scope)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusCreated {
+		return ocispec.Descriptor{}, ociResponseError(res, "failed to complete blob upload")
+	}
+
+	return ocispec.Descriptor{Digest: dgst, Size: counter.n}, nil
+}
+
+// CheckUpload starts a blob upload in the repository a release would be pushed to and cancels it.
+func (src *OCIReleaseSource) CheckUpload(ctx context.Context) error {
+	repository := src.repository(uploadCheckSpec.Name)
+	const scope = "pull,push"
+	res, err := src.do(ctx, http.MethodPost, src.registryURL("v2", repository, "blobs", "uploads")+"/", nil, nil, repository, scope)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusAccepted {
+		return ociResponseError(res, "failed to start blob upload")
+	}
+	location, err := res.Location()
+	if err != nil {
+		return fmt.Errorf("blob upload response missing location: %w", err)
+	}
+	res, err = src.do(ctx, http.MethodDelete, location.String(), nil, nil, repository, scope)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	// registries that do not support cancelling uploads expire them
+	return nil
+}
+
+type byteCounter struct{ n int64 }
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func (src *OCIReleaseSource) registryURL(parts ...string) string {
+	host := strings.TrimSuffix(src.Registry, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return host + "/" + path.Join(parts...)
+}
+
+// do sends a request to the registry. When the registry challenges for a bearer token,
+// a token is requested for the repository scope and the request is retried once.
+// Requests with a body that can not be replayed are only authorized with a token
+// cached by an earlier request in the same scope.
+func (src *OCIReleaseSource) do(ctx context.Context, method, u string, body io.Reader, header http.Header, repository, actions string) (*http.Response, error) {
+	scope := "repository:" + repository + ":" + actions
+	newRequest := func() (*http.Request, error) {
+		config, err := src.credentials.resolve(ctx, src.ReleaseSourceConfig)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		src.tokensMutex.Lock()
+		token, ok := src.tokens[scope]
+		src.tokensMutex.Unlock()
+		switch {
+		case ok:
+			req.Header.Set("Authorization", "Bearer "+token)
+		case config.Username != "":
+			req.SetBasicAuth(config.Username, config.Password)
+		}
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	res, err := src.Client.Do(req)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized || (body != nil && req.GetBody == nil) {
+		return res, nil
+	}
+	challenge := res.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return res, nil
+	}
+	closeAndIgnoreError(res.Body)
+
+	if err := src.fetchToken(ctx, challenge, scope); err != nil {
+		return nil, err
+	}
+	if body != nil {
+		if body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+	res, err = src.Client.Do(req)
+	return res, wrapVPNError(err)
+}
+
+func (src *OCIReleaseSource) fetchToken(ctx context.Context, challenge, scope string) error {
+	params := parseAuthChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return errors.New("registry bearer challenge does not specify a realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return fmt.Errorf("failed to parse token realm: %w", err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	config, err := src.credentials.resolve(ctx, src.ReleaseSourceConfig)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if config.Username != "" {
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+	res, err := src.Client.Do(req)
+	if err != nil {
+		return wrapVPNError(err)
+	}
+	defer closeAndIgnoreError(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return ociResponseError(res, "failed to get registry token")
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to parse registry token response: %w", err)
+	}
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+
+	src.tokensMutex.Lock()
+	defer src.tokensMutex.Unlock()
+	src.tokens[scope] = token
+	return nil
+}
+
+// parseAuthChallenge parses the parameters of a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example.com/token",service="registry.example.com"`.
+func parseAuthChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	_, rest, _ := strings.Cut(challenge, " ")
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return params
+}
+
+func ociResponseError(res *http.Response, message string) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	buf, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if json.Unmarshal(buf, &body) == nil && len(body.Errors) > 0 {
+		return fmt.Errorf("%s: %s: %s %s", message, res.Status, body.Errors[0].Code, body.Errors[0].Message)
+	}
+	return fmt.Errorf("%s: %s", message, res.Status)
+}
@@ -0,0 +1,372 @@
+package component_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("interacting with BOSH releases in an OCI registry", func() {
+	var (
+		source   *component.OCIReleaseSource
+		config   cargo.ReleaseSourceConfig
+		registry *fakeOCIRegistry
+		server   *httptest.Server
+
+		releasesDirectory string
+		tarball           []byte
+	)
+
+	BeforeEach(func() {
+		releasesDirectory = must(os.MkdirTemp("", "releases"))
+		tarball = must(os.ReadFile(filepath.Join("testdata", "some-release.tgz")))
+
+		registry = newFakeOCIRegistry()
+		config = cargo.ReleaseSourceConfig{
+			Type: component.ReleaseSourceTypeOCI,
+			ID:   "some-registry",
+			Repo: "bosh-releases",
+		}
+	})
+
+	JustBeforeEach(func() {
+		server = httptest.NewServer(registry)
+		config.Registry = server.URL
+		source = component.NewOCIReleaseSource(config)
+		source.Client = server.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("is created by the release source factory", func() {
+		Expect(component.ReleaseSourceFactory(config, nil)).To(BeAssignableToTypeOf(source))
+	})
+
+	It("builds the remote path from the repository and tag", func() {
+		remotePath, err := source.RemotePath(cargo.BOSHReleaseTarballSpecification{
+			Name: "mango", Version: "2.3.4+dev.1", StemcellOS: "smoothie", StemcellVersion: "9.9",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remotePath).To(Equal("bosh-releases/mango:2.3.4_dev.1-smoothie-9.9"))
+	})
+
+	When("a release has been uploaded", func() {
+		var uploadedLock cargo.BOSHReleaseTarballLock
+
+		JustBeforeEach(func() {
+			var err error
+			uploadedLock, err = source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "2.3.4",
+			}, bytes.NewReader(tarball))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "2.4.0", StemcellOS: "smoothie", StemcellVersion: "9.9",
+			}, bytes.NewReader(tarball))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the lock for the uploaded artifact", func() {
+			sum := sha1.Sum(tarball)
+			Expect(uploadedLock).To(Equal(cargo.BOSHReleaseTarballLock{
+				Name:         "mango",
+				Version:      "2.3.4",
+				SHA1:         hex.EncodeToString(sum[:]),
+				RemotePath:   "bosh-releases/mango:2.3.4",
+				RemoteSource: "some-registry",
+			}))
+			Expect(registry.tags("bosh-releases/mango")).To(ConsistOf("2.3.4", "2.4.0-smoothie-9.9"))
+		})
+
+		It("resolves the lock from the spec", func() {
+			lock, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "2.3.4",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock).To(Equal(uploadedLock))
+		})
+
+		It("returns ErrNotFound for a version that was not uploaded", func() {
+			_, err := source.GetMatchedRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "9.9.9",
+			})
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+
+		It("finds the latest built release matching the constraint", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "~2",
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.3.4"))
+			Expect(lock.RemotePath).To(Equal("bosh-releases/mango:2.3.4"))
+			Expect(lock.SHA1).To(Equal(uploadedLock.SHA1))
+		})
+
+		It("skips compiled release tags when the stemcell is not set", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Channel: cargo.ReleaseChannelDev,
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.3.4"))
+			Expect(lock.RemotePath).To(Equal("bosh-releases/mango:2.3.4"))
+		})
+
+		It("finds the compiled release for the stemcell", func() {
+			lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", StemcellOS: "smoothie", StemcellVersion: "9.9",
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("2.4.0"))
+			Expect(lock.RemotePath).To(Equal("bosh-releases/mango:2.4.0-smoothie-9.9"))
+		})
+
+		It("returns ErrNotFound when no tag matches", func() {
+			_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "~3",
+			}, true)
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+
+		When("the registry paginates the tag list", func() {
+			BeforeEach(func() {
+				registry.pageSize = 1
+			})
+			It("reads every page", func() {
+				lock, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+					Name: "mango", StemcellOS: "smoothie", StemcellVersion: "9.9",
+				}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Version).To(Equal("2.4.0"))
+			})
+		})
+
+		It("downloads the release", func() {
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, uploadedLock)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(local.LocalPath).To(Equal(filepath.Join(releasesDirectory, "mango-2.3.4.tgz")))
+			Expect(os.ReadFile(local.LocalPath)).To(Equal(tarball))
+			Expect(local.Lock.SHA1).To(Equal(uploadedLock.SHA1))
+		})
+
+		When("the blob does not match its digest", func() {
+			JustBeforeEach(func() {
+				registry.corruptBlobs()
+			})
+			It("returns an error and removes the file", func() {
+				_, err := source.DownloadRelease(context.Background(), releasesDirectory, uploadedLock)
+				Expect(err).To(MatchError(ContainSubstring("does not match digest")))
+				Expect(filepath.Join(releasesDirectory, "mango-2.3.4.tgz")).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	When("the registry requires a bearer token", func() {
+		BeforeEach(func() {
+			config.Username = "kim"
+			config.Password = "mango_rice!"
+			registry.requireToken = true
+		})
+
+		It("exchanges the credentials for a token", func() {
+			_, err := source.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "mango", Version: "2.3.4",
+			}, bytes.NewReader(tarball))
+			Expect(err).NotTo(HaveOccurred())
+
+			local, err := source.DownloadRelease(context.Background(), releasesDirectory, cargo.BOSHReleaseTarballLock{
+				Name: "mango", Version: "2.3.4", RemotePath: "bosh-releases/mango:2.3.4",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(local.LocalPath)).To(Equal(tarball))
+			Expect(registry.tokenRequests).NotTo(BeZero())
+		})
+	})
+
+	When("the repository does not exist", func() {
+		It("returns ErrNotFound", func() {
+			_, err := source.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name: "missing-release",
+			}, false)
+			Expect(component.IsErrNotFound(err)).To(BeTrue())
+		})
+	})
+})
+
+// fakeOCIRegistry implements the subset of the OCI distribution API used by OCIReleaseSource.
+type fakeOCIRegistry struct {
+	mu sync.Mutex
+
+	blobs     map[string][]byte
+	uploads   map[string]*bytes.Buffer
+	manifests map[string]map[string][]byte
+
+	pageSize      int
+	requireToken  bool
+	tokenRequests int
+}
+
+func newFakeOCIRegistry() *fakeOCIRegistry {
+	return &fakeOCIRegistry{
+		blobs:     make(map[string][]byte),
+		uploads:   make(map[string]*bytes.Buffer),
+		manifests: make(map[string]map[string][]byte),
+	}
+}
+
+func (reg *fakeOCIRegistry) tags(repository string) []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	var tags []string
+	for tag := range reg.manifests[repository] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (reg *fakeOCIRegistry) corruptBlobs() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for d := range reg.blobs {
+		reg.blobs[d] = []byte("corrupted")
+	}
+}
+
+func (reg *fakeOCIRegistry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		reg.tokenRequests++
+		if username, password, ok := req.BasicAuth(); !ok || username != "kim" || password != "mango_rice!" {
+			http.Error(res, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(res).Encode(map[string]string{"token": "some-token"})
+		return
+	}
+	if reg.requireToken && req.Header.Get("Authorization") != "Bearer some-token" {
+		res.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake"`, req.Host))
+		res.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	p := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.HasSuffix(p, "/tags/list") && req.Method == http.MethodGet:
+		reg.listTags(res, req, strings.TrimSuffix(p, "/tags/list"))
+	case strings.Contains(p, "/manifests/"):
+		repository, reference, _ := strings.Cut(p, "/manifests/")
+		reg.manifest(res, req, repository, reference)
+	case strings.Contains(p, "/blobs/uploads/"):
+		repository, id, _ := strings.Cut(p, "/blobs/uploads/")
+		reg.upload(res, req, repository, id)
+	case strings.Contains(p, "/blobs/") && req.Method == http.MethodGet:
+		_, d, _ := strings.Cut(p, "/blobs/")
+		blob, found := reg.blobs[d]
+		if !found {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = res.Write(blob)
+	default:
+		res.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (reg *fakeOCIRegistry) listTags(res http.ResponseWriter, req *http.Request, repository string) {
+	manifests, found := reg.manifests[repository]
+	if !found {
+		res.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(res, `{"errors":[{"code":"NAME_UNKNOWN","message":"repository name not known to registry"}]}`)
+		return
+	}
+	var tags []string
+	for tag := range manifests {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	if last := req.URL.Query().Get("last"); last != "" {
+		i := sort.SearchStrings(tags, last)
+		if i < len(tags) && tags[i] == last {
+			i++
+		}
+		tags = tags[i:]
+	}
+	if reg.pageSize > 0 && len(tags) > reg.pageSize {
+		tags = tags[:reg.pageSize]
+		res.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=%d&last=%s>; rel="next"`, repository, reg.pageSize, tags[len(tags)-1]))
+	}
+	_ = json.NewEncoder(res).Encode(map[string]any{"name": repository, "tags": tags})
+}
+
+func (reg *fakeOCIRegistry) manifest(res http.ResponseWriter, req *http.Request, repository, reference string) {
+	switch req.Method {
+	case http.MethodPut:
+		buf, _ := io.ReadAll(req.Body)
+		if reg.manifests[repository] == nil {
+			reg.manifests[repository] = make(map[string][]byte)
+		}
+		reg.manifests[repository][reference] = buf
+		res.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		buf, found := reg.manifests[repository][reference]
+		if !found {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		res.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = res.Write(buf)
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (reg *fakeOCIRegistry) upload(res http.ResponseWriter, req *http.Request, repository, id string) {
+	switch req.Method {
+	case http.MethodPost:
+		id = fmt.Sprintf("upload-%d", len(reg.uploads))
+		reg.uploads[id] = new(bytes.Buffer)
+	case http.MethodPatch:
+		_, _ = io.Copy(reg.uploads[id], req.Body)
+	case http.MethodPut:
+		_, _ = io.Copy(reg.uploads[id], req.Body)
+		content := reg.uploads[id].Bytes()
+		sum := sha256.Sum256(content)
+		d := "sha256:" + hex.EncodeToString(sum[:])
+		if d != req.URL.Query().Get("digest") {
+			res.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(res, `{"errors":[{"code":"DIGEST_INVALID","message":"provided digest did not match uploaded content"}]}`)
+			return
+		}
+		reg.blobs[d] = content
+		delete(reg.uploads, id)
+		res.WriteHeader(http.StatusCreated)
+		return
+	}
+	res.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", repository, id))
+	res.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,72 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// ErrOffline is returned when a release source that needs network access is used in offline mode.
+const ErrOffline stringError = "needs network access, which is not allowed in offline mode"
+
+func IsErrOffline(err error) bool {
+	return errors.Is(err, ErrOffline)
+}
+
+type offlineKey struct{}
+
+// ContextWithOffline returns a context that makes ReleaseSourceList refuse release sources
+// that need network access. Only releases in directory and catalog release sources can be resolved.
+func ContextWithOffline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offlineKey{}, true)
+}
+
+// IsOffline reports whether ctx was returned by ContextWithOffline.
+func IsOffline(ctx context.Context) bool {
+	offline, _ := ctx.Value(offlineKey{}).(bool)
+	return offline
+}
+
+// NeedsNetwork reports whether a release source with the configuration makes network requests.
+func NeedsNetwork(config cargo.ReleaseSourceConfig) bool {
+	switch config.Type {
+	case ReleaseSourceTypeDirectory, ReleaseSourceTypeCatalog, ReleaseSourceTypeBundle:
+		return false
+	default:
+		return true
+	}
+}
+
+// OfflineError returns an error wrapping ErrOffline for the release source.
+func OfflineError(sourceID string) error {
+	return fmt.Errorf("release source %q %w", sourceID, ErrOffline)
+}
+
+// availableOffline returns the sources that may be used with ctx and the IDs of the ones skipped.
+func (list ReleaseSourceList) availableOffline(ctx context.Context) (ReleaseSourceList, []string) {
+	if !IsOffline(ctx) {
+		return list, nil
+	}
+	var (
+		sources ReleaseSourceList
+		skipped []string
+	)
+	for _, src := range list {
+		if NeedsNetwork(src.Configuration()) {
+			skipped = append(skipped, src.Configuration().ID)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources, skipped
+}
+
+// notFoundOffline explains that a release may exist in release sources skipped in offline mode.
+func notFoundOffline(skipped []string) error {
+	if len(skipped) == 0 {
+		return ErrNotFound
+	}
+	return fmt.Errorf("%w (release sources %q were skipped: they %s)", ErrNotFound, skipped, ErrOffline)
+}
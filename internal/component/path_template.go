@@ -0,0 +1,128 @@
+package component
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// renderPathTemplate evaluates the path_template of a release source for spec.
+func renderPathTemplate(pathTemplate string, spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	pathBuf := new(bytes.Buffer)
+
+	err := template.Must(
+		template.New("remote-path").
+			Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
+			Parse(pathTemplate)).
+		Execute(pathBuf, spec)
+	if err != nil {
+		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
+	}
+
+	return pathBuf.String(), nil
+}
+
+// pathTemplateVersionPattern renders path_template for spec with a placeholder version and
+// returns a pattern capturing the version from a path. It also returns the deepest
+// directory of the path that does not depend on the version, ending with "/", so only
+// the paths under it need to be listed. When only one of StemcellOS and StemcellVersion
+// is set, the other matches any stemcell OS or version.
+func pathTemplateVersionPattern(pathTemplate string, spec cargo.BOSHReleaseTarballSpecification) (*regexp.Regexp, string, error) {
+	spec.Version = versionPlaceholder
+	switch {
+	case spec.StemcellOS == "" && spec.StemcellVersion != "":
+		spec.StemcellOS = stemcellOSPlaceholder
+	case spec.StemcellOS != "" && spec.StemcellVersion == "":
+		spec.StemcellVersion = stemcellVersionPlaceholder
+	}
+	rendered, err := renderPathTemplate(pathTemplate, spec)
+	if err != nil {
+		return nil, "", err
+	}
+	pattern, prefix, err := renderedTemplateVersionPattern(rendered, pathTemplate)
+	if err != nil {
+		return nil, "", err
+	}
+	return pattern, prefix[:strings.LastIndex(prefix, "/")+1], nil
+}
+
+// versionPlaceholder is substituted for the version when turning path_template into a pattern.
+const versionPlaceholder = "KILNVERSIONPLACEHOLDER"
+
+// stemcellOSPlaceholder and stemcellVersionPlaceholder may be substituted for a stemcell
+// field that is not set so it matches any stemcell OS or version.
+const (
+	stemcellOSPlaceholder      = "KILNSTEMCELLOSPLACEHOLDER"
+	stemcellVersionPlaceholder = "KILNSTEMCELLVERSIONPLACEHOLDER"
+)
+
+const (
+	stemcellOSPattern      = `[a-z][a-z0-9]*(?:-[a-z][a-z0-9]*)*`
+	stemcellVersionPattern = `\d+(?:\.\d+)+`
+)
+
+// stemcellSuffix matches the "-ubuntu-jammy-1.10" suffix of a compiled release version.
+var stemcellSuffix = regexp.MustCompile(`-` + stemcellOSPattern + `-` + stemcellVersionPattern + `$`)
+
+// hasStemcellSuffix reports whether a version captured from a path or tag is the version of
+// a compiled release followed by its stemcell, for example "1.2.3-ubuntu-jammy-1.10". Such
+// versions would otherwise parse as prereleases.
+func hasStemcellSuffix(version string) bool {
+	return stemcellSuffix.MatchString(version)
+}
+
+// renderedTemplateVersionPattern turns a path_template rendered with versionPlaceholder into
+// a pattern where the first submatch is the version. It also returns the part of rendered
+// before the version or the first stemcell placeholder.
+func renderedTemplateVersionPattern(rendered, pathTemplate string) (*regexp.Regexp, string, error) {
+	index := strings.Index(rendered, versionPlaceholder)
+	if index < 0 {
+		return nil, "", fmt.Errorf("path_template %q does not include {{.Version}}", pathTemplate)
+	}
+	for _, placeholder := range []string{stemcellOSPlaceholder, stemcellVersionPlaceholder} {
+		if i := strings.Index(rendered, placeholder); i >= 0 && i < index {
+			index = i
+		}
+	}
+	quoted := regexp.QuoteMeta(rendered)
+	// the version is matched lazily so "1.2.3-rc.1-ubuntu-jammy" is split before the stemcell OS
+	quoted = strings.Replace(quoted, versionPlaceholder, `([^/]+?)`, 1)
+	quoted = strings.ReplaceAll(quoted, versionPlaceholder, `[^/]+`)
+	quoted = strings.ReplaceAll(quoted, stemcellOSPlaceholder, stemcellOSPattern)
+	quoted = strings.ReplaceAll(quoted, stemcellVersionPlaceholder, stemcellVersionPattern)
+	pattern, err := regexp.Compile("^" + quoted + "$")
+	if err != nil {
+		return nil, "", err
+	}
+	return pattern, rendered[:index], nil
+}
+
+// newestPathVersion keeps the path with the highest version matching a path_template
+// version pattern that is allowed by the version matcher.
+type newestPathVersion struct {
+	pattern *regexp.Regexp
+	matcher cargo.VersionMatcher
+
+	version *semver.Version
+	path    string
+}
+
+func (newest *newestPathVersion) add(p string) {
+	match := newest.pattern.FindStringSubmatch(p)
+	if match == nil || hasStemcellSuffix(match[1]) {
+		return
+	}
+	version, err := semver.NewVersion(match[1])
+	if err != nil || !newest.matcher.Check(version) {
+		return
+	}
+	if newest.version == nil || version.GreaterThan(newest.version) {
+		newest.version, newest.path = version, p
+	}
+}
@@ -0,0 +1,322 @@
+package component
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	// ReleaseCacheDirectoryEnvironmentVariable overrides the default release cache directory.
+	ReleaseCacheDirectoryEnvironmentVariable = "KILN_CACHE_DIR"
+
+	releaseCacheEntriesDirectory = "releases"
+	releaseCacheEntryFileName    = "entry.json"
+	releaseCacheLocksFileName    = "kilnfile-locks"
+)
+
+// DefaultReleaseCacheDirectory returns the value of KILN_CACHE_DIR or, when it is not set,
+// "~/.kiln/cache".
+func DefaultReleaseCacheDirectory() (string, error) {
+	if dir := os.Getenv(ReleaseCacheDirectoryEnvironmentVariable); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kiln", "cache"), nil
+}
+
+// ReleaseCache is a content addressed store of BOSH release tarballs shared by all tiles on
+// a machine. Entries are keyed by the SHA1 sum in Kilnfile.lock. Files are hard-linked
+// into and out of the cache when the directories are on the same device and copied
+// otherwise.
+//
+// The layout is "{Directory}/releases/{sha1}/{file name}" with the entry metadata in
+// "{Directory}/releases/{sha1}/entry.json".
+type ReleaseCache struct {
+	Directory string
+}
+
+// ReleaseCacheEntry describes a release tarball in the cache.
+type ReleaseCacheEntry struct {
+	SHA1       string    `json:"sha1"`
+	SHA256     string    `json:"sha256,omitempty"`
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	FileName   string    `json:"file_name"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	// Path is the location of the tarball in the cache. It is not stored in entry.json.
+	Path string `json:"-"`
+}
+
+func NewReleaseCache(directory string) ReleaseCache {
+	return ReleaseCache{Directory: directory}
+}
+
+// Get puts the cached tarball for lock into releasesDir. It returns false when the cache
+// does not have the release. An entry whose contents no longer match its SHA1 is removed
+// and treated as missing.
+func (cache ReleaseCache) Get(ctx context.Context, releasesDir string, lock cargo.BOSHReleaseTarballLock) (Local, bool, error) {
+	if lock.SHA1 == "" {
+		return Local{}, false, nil
+	}
+	entry, err := cache.entry(lock.SHA1)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Local{}, false, nil
+		}
+		return Local{}, false, err
+	}
+
+	sum1, sum256, err := fileSums(ctx, entry.Path)
+	if err != nil {
+		return Local{}, false, err
+	}
+	if sum1 != lock.SHA1 {
+		return Local{}, false, cache.Remove(entry)
+	}
+	if lock.SHA256 != "" && sum256 != lock.SHA256 {
+		// the tarball matches the SHA1 but not the SHA256 in Kilnfile.lock so do not use it
+		return Local{}, false, nil
+	}
+	lock.SHA256 = sum256
+
+	outputFile := filepath.Join(releasesDir, entry.FileName)
+	if err := linkOrCopy(ctx, outputFile, entry.Path); err != nil {
+		return Local{}, false, err
+	}
+
+	entry.LastUsedAt = time.Now()
+	if err := cache.writeEntry(entry); err != nil {
+		return Local{}, false, err
+	}
+
+	return Local{Lock: lock, LocalPath: outputFile}, true, nil
+}
+
+// Put adds a downloaded release to the cache. The SHA1 on local.Lock must already be verified.
+func (cache ReleaseCache) Put(ctx context.Context, local Local) error {
+	if local.Lock.SHA1 == "" {
+		return nil
+	}
+	entryDirectory := cache.entryDirectory(local.Lock.SHA1)
+	if _, err := os.Stat(entryDirectory); err == nil {
+		return nil
+	}
+
+	entriesDirectory := filepath.Join(cache.Directory, releaseCacheEntriesDirectory)
+	if err := os.MkdirAll(entriesDirectory, 0o755); err != nil {
+		return err
+	}
+	// populate a temporary directory first so concurrent readers never see a partial entry
+	tmp, err := os.MkdirTemp(entriesDirectory, ".tmp-"+local.Lock.SHA1+"-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	fileName := filepath.Base(local.LocalPath)
+	if err := linkOrCopy(ctx, filepath.Join(tmp, fileName), local.LocalPath); err != nil {
+		return err
+	}
+	info, err := os.Stat(filepath.Join(tmp, fileName))
+	if err != nil {
+		return err
+	}
+	_, sum256, err := fileSums(ctx, filepath.Join(tmp, fileName))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry := ReleaseCacheEntry{
+		SHA1:       local.Lock.SHA1,
+		SHA256:     sum256,
+		Name:       local.Lock.Name,
+		Version:    local.Lock.Version,
+		FileName:   fileName,
+		Size:       info.Size(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if err := writeReleaseCacheEntry(tmp, entry); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, entryDirectory); err != nil {
+		if _, statErr := os.Stat(entryDirectory); statErr == nil {
+			return nil // another process added the entry first
+		}
+		return err
+	}
+	return nil
+}
+
+// Entries returns the cache entries sorted by release name and version.
+func (cache ReleaseCache) Entries() ([]ReleaseCacheEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(cache.Directory, releaseCacheEntriesDirectory))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []ReleaseCacheEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		entry, err := cache.entry(dirEntry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache entry %s: %w", dirEntry.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Version < entries[j].Version
+	})
+	return entries, nil
+}
+
+// Verify returns an error if the tarball for entry does not match its SHA1 or SHA256.
+func (cache ReleaseCache) Verify(ctx context.Context, entry ReleaseCacheEntry) error {
+	sum1, sum256, err := fileSums(ctx, entry.Path)
+	if err != nil {
+		return err
+	}
+	if sum1 != entry.SHA1 {
+		return fmt.Errorf("cached release %s %s has SHA1 %s, expected %s", entry.Name, entry.Version, sum1, entry.SHA1)
+	}
+	if entry.SHA256 != "" && sum256 != entry.SHA256 {
+		return fmt.Errorf("cached release %s %s has SHA256 %s, expected %s", entry.Name, entry.Version, sum256, entry.SHA256)
+	}
+	return nil
+}
+
+// Remove deletes entry from the cache. Hard links in releases directories are not affected.
+func (cache ReleaseCache) Remove(entry ReleaseCacheEntry) error {
+	return os.RemoveAll(cache.entryDirectory(entry.SHA1))
+}
+
+// TrackKilnfileLock records the path of a Kilnfile.lock that uses the cache so entries it
+// references can be kept when pruning.
+func (cache ReleaseCache) TrackKilnfileLock(kilnfileLockPath string) error {
+	p, err := filepath.Abs(kilnfileLockPath)
+	if err != nil {
+		return err
+	}
+	paths, err := cache.TrackedKilnfileLocks()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(paths, p) {
+		return nil
+	}
+	if err := os.MkdirAll(cache.Directory, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(cache.Directory, releaseCacheLocksFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(f)
+	_, err = fmt.Fprintln(f, p)
+	return err
+}
+
+// TrackedKilnfileLocks returns the paths recorded by TrackKilnfileLock.
+func (cache ReleaseCache) TrackedKilnfileLocks() ([]string, error) {
+	f, err := os.Open(filepath.Join(cache.Directory, releaseCacheLocksFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer closeAndIgnoreError(f)
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+func (cache ReleaseCache) entryDirectory(sum string) string {
+	return filepath.Join(cache.Directory, releaseCacheEntriesDirectory, sum)
+}
+
+func (cache ReleaseCache) entry(sum string) (ReleaseCacheEntry, error) {
+	dir := cache.entryDirectory(sum)
+	buf, err := os.ReadFile(filepath.Join(dir, releaseCacheEntryFileName))
+	if err != nil {
+		return ReleaseCacheEntry{}, err
+	}
+	var entry ReleaseCacheEntry
+	if err := json.Unmarshal(buf, &entry); err != nil {
+		return ReleaseCacheEntry{}, err
+	}
+	entry.Path = filepath.Join(dir, entry.FileName)
+	if _, err := os.Stat(entry.Path); err != nil {
+		return ReleaseCacheEntry{}, err
+	}
+	return entry, nil
+}
+
+func (cache ReleaseCache) writeEntry(entry ReleaseCacheEntry) error {
+	return writeReleaseCacheEntry(cache.entryDirectory(entry.SHA1), entry)
+}
+
+func writeReleaseCacheEntry(dir string, entry ReleaseCacheEntry) error {
+	buf, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+releaseCacheEntryFileName)
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, releaseCacheEntryFileName))
+}
+
+// fileSums returns the hex encoded SHA1 and SHA256 sums of the file.
+func fileSums(ctx context.Context, p string) (string, string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", "", err
+	}
+	defer closeAndIgnoreError(f)
+
+	sum1, sum256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(sum1, sum256), contextReader{ctx: ctx, r: f}); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(sum1.Sum(nil)), hex.EncodeToString(sum256.Sum(nil)), nil
+}
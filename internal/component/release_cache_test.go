@@ -0,0 +1,152 @@
+package component_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+var _ = Describe("ReleaseCache", func() {
+	var (
+		cache             component.ReleaseCache
+		cacheDirectory    string
+		releasesDirectory string
+		local             component.Local
+	)
+
+	BeforeEach(func() {
+		cacheDirectory = must(os.MkdirTemp("", "release-cache"))
+		releasesDirectory = must(os.MkdirTemp("", "releases"))
+		cache = component.NewReleaseCache(cacheDirectory)
+
+		content := []byte("mango 2.3.4")
+		sum := sha1.Sum(content)
+		local = component.Local{
+			Lock:      cargo.BOSHReleaseTarballLock{Name: "mango", Version: "2.3.4", SHA1: hex.EncodeToString(sum[:])},
+			LocalPath: filepath.Join(releasesDirectory, "mango-2.3.4.tgz"),
+		}
+		Expect(os.WriteFile(local.LocalPath, content, 0o644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(cacheDirectory)
+		_ = os.RemoveAll(releasesDirectory)
+	})
+
+	It("returns false for a release it does not have", func() {
+		_, found, err := cache.Get(context.Background(), releasesDirectory, local.Lock)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	When("a release is put in the cache", func() {
+		var otherReleasesDirectory string
+
+		BeforeEach(func() {
+			Expect(cache.Put(context.Background(), local)).To(Succeed())
+			otherReleasesDirectory = must(os.MkdirTemp("", "other-releases"))
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(otherReleasesDirectory)
+		})
+
+		It("lists the entry", func() {
+			entries, err := cache.Entries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name).To(Equal("mango"))
+			Expect(entries[0].Version).To(Equal("2.3.4"))
+			Expect(entries[0].SHA1).To(Equal(local.Lock.SHA1))
+			Expect(entries[0].SHA256).To(Equal(sha256Of("mango 2.3.4")))
+			Expect(entries[0].Size).To(BeEquivalentTo(len("mango 2.3.4")))
+			Expect(cache.Verify(context.Background(), entries[0])).To(Succeed())
+		})
+
+		It("puts the cached release in another releases directory", func() {
+			result, found, err := cache.Get(context.Background(), otherReleasesDirectory, local.Lock)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(result.LocalPath).To(Equal(filepath.Join(otherReleasesDirectory, "mango-2.3.4.tgz")))
+			Expect(result.Lock).To(Equal(local.Lock.WithSHA256(sha256Of("mango 2.3.4"))))
+			Expect(os.ReadFile(result.LocalPath)).To(Equal([]byte("mango 2.3.4")))
+		})
+
+		It("does not use the cached release when the SHA256 in the lock does not match", func() {
+			_, found, err := cache.Get(context.Background(), otherReleasesDirectory, local.Lock.WithSHA256("some-other-sum"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+			Expect(cache.Entries()).To(HaveLen(1))
+		})
+
+		It("ignores a second put of the same release", func() {
+			Expect(cache.Put(context.Background(), local)).To(Succeed())
+			Expect(cache.Entries()).To(HaveLen(1))
+		})
+
+		When("the cached file is corrupted", func() {
+			BeforeEach(func() {
+				entries, err := cache.Entries()
+				Expect(err).NotTo(HaveOccurred())
+				// remove the file first so the hard link in the releases directory is not changed
+				Expect(os.Remove(entries[0].Path)).To(Succeed())
+				Expect(os.WriteFile(entries[0].Path, []byte("banana"), 0o644)).To(Succeed())
+			})
+
+			It("fails verification", func() {
+				entries, err := cache.Entries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cache.Verify(context.Background(), entries[0])).To(MatchError(ContainSubstring("expected " + local.Lock.SHA1)))
+			})
+
+			It("removes the entry and reports a miss", func() {
+				_, found, err := cache.Get(context.Background(), otherReleasesDirectory, local.Lock)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeFalse())
+				Expect(cache.Entries()).To(BeEmpty())
+			})
+		})
+
+		It("removes the entry", func() {
+			entries, err := cache.Entries()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.Remove(entries[0])).To(Succeed())
+			Expect(cache.Entries()).To(BeEmpty())
+			Expect(local.LocalPath).To(BeAnExistingFile())
+		})
+	})
+
+	It("tracks Kilnfile.lock paths once", func() {
+		lockPath := filepath.Join(releasesDirectory, "Kilnfile.lock")
+		Expect(cache.TrackKilnfileLock(lockPath)).To(Succeed())
+		Expect(cache.TrackKilnfileLock(lockPath)).To(Succeed())
+		Expect(cache.TrackedKilnfileLocks()).To(Equal([]string{lockPath}))
+	})
+
+	It("uses KILN_CACHE_DIR as the default directory", func() {
+		previous, isSet := os.LookupEnv(component.ReleaseCacheDirectoryEnvironmentVariable)
+		Expect(os.Setenv(component.ReleaseCacheDirectoryEnvironmentVariable, cacheDirectory)).To(Succeed())
+		defer func() {
+			if isSet {
+				_ = os.Setenv(component.ReleaseCacheDirectoryEnvironmentVariable, previous)
+			} else {
+				_ = os.Unsetenv(component.ReleaseCacheDirectoryEnvironmentVariable)
+			}
+		}()
+		Expect(component.DefaultReleaseCacheDirectory()).To(Equal(cacheDirectory))
+	})
+})
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -11,9 +12,9 @@ import (
 // MultiReleaseSource wraps a set of release sources. It is mostly used to generate fakes
 // for testing commands. See ReleaseSourceList for the concrete implementation.
 type MultiReleaseSource interface {
-	GetMatchedRelease(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
-	FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error)
-	DownloadRelease(releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error)
+	GetMatchedRelease(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
+	FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error)
+	DownloadRelease(ctx context.Context, releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error)
 
 	FindByID(string) (ReleaseSource, error)
 
@@ -27,12 +28,41 @@ type MultiReleaseSource interface {
 // should implement this interface. Credentials for this should come from an interpolated
 // cargo.ReleaseSourceConfig.
 type ReleaseUploader interface {
-	GetMatchedRelease(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
-	UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error)
+	GetMatchedRelease(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
+	UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error)
 }
 
 //counterfeiter:generate -o ./fakes/release_uploader.go --fake-name ReleaseUploader . ReleaseUploader
 
+// UploadChecker is implemented by release uploaders that can check whether uploads are
+// allowed without uploading a release.
+type UploadChecker interface {
+	CheckUpload(ctx context.Context) error
+}
+
+// uploadCheckSpec is rendered into the remote path used by CheckUpload implementations so
+// permissions scoped to the path_template location are exercised.
+var uploadCheckSpec = cargo.BOSHReleaseTarballSpecification{
+	Name:            "kiln-upload-check",
+	Version:         "0.0.0",
+	StemcellOS:      "kiln-upload-check",
+	StemcellVersion: "0",
+}
+
+type overwriteKey struct{}
+
+// ContextWithOverwrite returns a context that allows UploadRelease to replace a release
+// that already exists at the remote path.
+func ContextWithOverwrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, overwriteKey{}, true)
+}
+
+// AllowsOverwrite reports whether ctx was returned by ContextWithOverwrite.
+func AllowsOverwrite(ctx context.Context) bool {
+	overwrite, _ := ctx.Value(overwriteKey{}).(bool)
+	return overwrite
+}
+
 // RemotePather is used to get the remote path for a remote release. For example
 // the complete s3 uri.
 //
@@ -45,6 +75,10 @@ type RemotePather interface {
 
 // ReleaseSource represents a source where a tile component BOSH releases may come from.
 // The releases may be compiled or just built bosh releases.
+//
+// Implementations must stop work and return the context error when the context passed
+// to any method is cancelled. DownloadRelease must not leave a partially written file
+// in the releases directory when it fails.
 type ReleaseSource interface {
 	// Configuration returns the configuration of the ReleaseSource that came from the kilnfile.
 	// It should not be modified.
@@ -52,16 +86,16 @@ type ReleaseSource interface {
 
 	// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
 	// fields on Requirement to download a specific release.
-	GetMatchedRelease(cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
+	GetMatchedRelease(context.Context, cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error)
 
 	// FindReleaseVersion may use any of the fields on Requirement to return the best matching
 	// release.
-	FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error)
+	FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error)
 
 	// DownloadRelease downloads the release and writes the resulting file to the releasesDir.
 	// It should also calculate and set the SHA1 field on the Local result; it does not need
 	// to ensure the sums match, the caller must verify this.
-	DownloadRelease(releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error)
+	DownloadRelease(ctx context.Context, releasesDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error)
 }
 
 //counterfeiter:generate -o ./fakes/release_source.go --fake-name ReleaseSource . ReleaseSource
@@ -77,6 +111,12 @@ const (
 	ReleaseSourceTypeS3          = cargo.BOSHReleaseTarballSourceTypeS3
 	ReleaseSourceTypeGithub      = cargo.BOSHReleaseTarballSourceTypeGithub
 	ReleaseSourceTypeArtifactory = cargo.BOSHReleaseTarballSourceTypeArtifactory
+	ReleaseSourceTypeOCI         = cargo.BOSHReleaseTarballSourceTypeOCI
+	ReleaseSourceTypeDirectory   = cargo.BOSHReleaseTarballSourceTypeDirectory
+	ReleaseSourceTypeHTTP        = cargo.BOSHReleaseTarballSourceTypeHTTP
+	ReleaseSourceTypeCatalog     = cargo.BOSHReleaseTarballSourceTypeCatalog
+	ReleaseSourceTypeGCS         = cargo.BOSHReleaseTarballSourceTypeGCS
+	ReleaseSourceTypeAzure       = cargo.BOSHReleaseTarballSourceTypeAzure
 )
 
 // ReleaseSourceFactory returns a configured ReleaseSource based on the Type field on the
@@ -92,6 +132,18 @@ func ReleaseSourceFactory(releaseConfig cargo.ReleaseSourceConfig, outLogger *lo
 		return NewGithubReleaseSource(releaseConfig)
 	case ReleaseSourceTypeArtifactory:
 		return NewArtifactoryReleaseSource(releaseConfig)
+	case ReleaseSourceTypeOCI:
+		return NewOCIReleaseSource(releaseConfig)
+	case ReleaseSourceTypeDirectory:
+		return NewDirectoryReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeHTTP:
+		return NewHTTPReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeCatalog:
+		return NewCatalogReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeGCS:
+		return NewGCSReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeAzure:
+		return NewAzureReleaseSource(releaseConfig, outLogger)
 	default:
 		panic(fmt.Sprintf("unknown release config: %v", releaseConfig))
 	}
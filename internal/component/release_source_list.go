@@ -1,6 +1,7 @@
 package component
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -115,9 +116,32 @@ func NewMultiReleaseSource(sources ...ReleaseSource) ReleaseSourceList {
 	return sources
 }
 
-func (list ReleaseSourceList) GetMatchedRelease(requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
-	for _, src := range list {
-		rel, err := src.GetMatchedRelease(requirement)
+// pinnedTo returns the release sources consulted for the requirement. When the requirement is
+// pinned to release sources only those are returned, in the order of the pin.
+func (list ReleaseSourceList) pinnedTo(requirement cargo.BOSHReleaseTarballSpecification) (ReleaseSourceList, error) {
+	ids := requirement.ReleaseSourceIDs()
+	if len(ids) == 0 {
+		return list, nil
+	}
+	sources := make(ReleaseSourceList, 0, len(ids))
+	for _, id := range ids {
+		src, err := list.FindByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("release %q is pinned to an unavailable release source: %w", requirement.Name, err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+func (list ReleaseSourceList) GetMatchedRelease(ctx context.Context, requirement cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	sources, err := list.pinnedTo(requirement)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	sources, skipped := sources.availableOffline(ctx)
+	for _, src := range sources {
+		rel, err := src.GetMatchedRelease(ctx, requirement)
 		if err != nil {
 			if IsErrNotFound(err) {
 				continue
@@ -126,7 +150,7 @@ func (list ReleaseSourceList) GetMatchedRelease(requirement cargo.BOSHReleaseTar
 		}
 		return rel, nil
 	}
-	return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	return cargo.BOSHReleaseTarballLock{}, notFoundOffline(skipped)
 }
 
 func (list ReleaseSourceList) SetDownloadThreads(n int) {
@@ -139,13 +163,16 @@ func (list ReleaseSourceList) SetDownloadThreads(n int) {
 	}
 }
 
-func (list ReleaseSourceList) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+func (list ReleaseSourceList) DownloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
 	src, err := list.FindByID(remoteRelease.RemoteSource)
 	if err != nil {
 		return Local{}, err
 	}
+	if IsOffline(ctx) && NeedsNetwork(src.Configuration()) {
+		return Local{}, OfflineError(src.Configuration().ID)
+	}
 
-	localRelease, err := src.DownloadRelease(releaseDir, remoteRelease)
+	localRelease, err := src.DownloadRelease(ctx, releaseDir, remoteRelease)
 	if err != nil {
 		return Local{}, scopedError(src.Configuration().ID, err)
 	}
@@ -153,10 +180,15 @@ func (list ReleaseSourceList) DownloadRelease(releaseDir string, remoteRelease c
 	return localRelease, nil
 }
 
-func (list ReleaseSourceList) FindReleaseVersion(requirement cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+func (list ReleaseSourceList) FindReleaseVersion(ctx context.Context, requirement cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	sources, err := list.pinnedTo(requirement)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	sources, skipped := sources.availableOffline(ctx)
 	var foundReleaseLock []cargo.BOSHReleaseTarballLock
-	for _, src := range list {
-		rel, err := src.FindReleaseVersion(requirement, noDownload)
+	for _, src := range sources {
+		rel, err := src.FindReleaseVersion(ctx, requirement, noDownload)
 		if err != nil {
 			if !IsErrNotFound(err) {
 				return cargo.BOSHReleaseTarballLock{}, scopedError(src.Configuration().ID, err)
@@ -166,7 +198,7 @@ func (list ReleaseSourceList) FindReleaseVersion(requirement cargo.BOSHReleaseTa
 		foundReleaseLock = append(foundReleaseLock, rel)
 	}
 	if len(foundReleaseLock) == 0 {
-		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+		return cargo.BOSHReleaseTarballLock{}, notFoundOffline(skipped)
 	}
 	highestLock := foundReleaseLock[0]
 	highestVersion, err := highestLock.ParseVersion()
@@ -1,6 +1,7 @@
 package component_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo"
@@ -58,7 +59,7 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns that match", func() {
-				rel, err := multiSrc.GetMatchedRelease(requirement)
+				rel, err := multiSrc.GetMatchedRelease(context.Background(), requirement)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rel).To(Equal(matchedRelease))
 			})
@@ -71,7 +72,7 @@ var _ = Describe("multiReleaseSource", func() {
 				src2.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
 			})
 			It("returns no match", func() {
-				_, err := multiSrc.GetMatchedRelease(requirement)
+				_, err := multiSrc.GetMatchedRelease(context.Background(), requirement)
 				Expect(err).To(HaveOccurred())
 				Expect(component.IsErrNotFound(err)).To(BeTrue())
 			})
@@ -86,11 +87,67 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns that error", func() {
-				_, err := multiSrc.GetMatchedRelease(requirement)
+				_, err := multiSrc.GetMatchedRelease(context.Background(), requirement)
 				Expect(err).To(MatchError(ContainSubstring(src1.Configuration().ID)))
 				Expect(err).To(MatchError(ContainSubstring(expectedErr.Error())))
 			})
 		})
+
+		When("the release is pinned to release sources", func() {
+			BeforeEach(func() {
+				requirement.ReleaseSources = []string{"src-3", "src-1"}
+				src1.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{Name: releaseName, RemoteSource: "src-1"}, nil)
+				src2.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{Name: releaseName, RemoteSource: "src-2"}, nil)
+				src3.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
+			})
+
+			It("only consults those sources in the pinned order", func() {
+				rel, err := multiSrc.GetMatchedRelease(context.Background(), requirement)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rel.RemoteSource).To(Equal("src-1"))
+				Expect(src3.GetMatchedReleaseCallCount()).To(Equal(1))
+				Expect(src2.GetMatchedReleaseCallCount()).To(Equal(0))
+			})
+		})
+
+		When("the release is pinned to a release source that does not exist", func() {
+			BeforeEach(func() {
+				requirement.ReleaseSource = "src-4"
+			})
+
+			It("returns an error", func() {
+				_, err := multiSrc.GetMatchedRelease(context.Background(), requirement)
+				Expect(err).To(MatchError(ContainSubstring(`pinned to an unavailable release source`)))
+				Expect(component.IsErrNotFound(err)).To(BeFalse())
+			})
+		})
+
+		When("kiln is offline", func() {
+			var ctx context.Context
+
+			BeforeEach(func() {
+				ctx = component.ContextWithOffline(context.Background())
+				src2.ConfigurationReturns(cargo.ReleaseSourceConfig{ID: "src-2", Type: component.ReleaseSourceTypeDirectory})
+			})
+
+			It("only consults directory release sources", func() {
+				src2.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{Name: releaseName, RemoteSource: "src-2"}, nil)
+
+				rel, err := multiSrc.GetMatchedRelease(ctx, requirement)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rel.RemoteSource).To(Equal("src-2"))
+				Expect(src1.GetMatchedReleaseCallCount()).To(Equal(0))
+				Expect(src3.GetMatchedReleaseCallCount()).To(Equal(0))
+			})
+
+			It("says which release sources were skipped when nothing matches", func() {
+				src2.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
+
+				_, err := multiSrc.GetMatchedRelease(ctx, requirement)
+				Expect(component.IsErrNotFound(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring(`["src-1" "src-3"]`)))
+			})
+		})
 	})
 
 	Describe("DownloadRelease", func() {
@@ -115,12 +172,12 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns the local release", func() {
-				l, err := multiSrc.DownloadRelease("somewhere", remote)
+				l, err := multiSrc.DownloadRelease(context.Background(), "somewhere", remote)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(l).To(Equal(local))
 
 				Expect(src2.DownloadReleaseCallCount()).To(Equal(1))
-				dir, r := src2.DownloadReleaseArgsForCall(0)
+				_, dir, r := src2.DownloadReleaseArgsForCall(0)
 				Expect(dir).To(Equal("somewhere"))
 				Expect(r).To(Equal(remote))
 			})
@@ -134,7 +191,7 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns the error", func() {
-				_, err := multiSrc.DownloadRelease("somewhere", remote)
+				_, err := multiSrc.DownloadRelease(context.Background(), "somewhere", remote)
 				Expect(err).To(MatchError(ContainSubstring(src2.Configuration().ID)))
 				Expect(err).To(MatchError(ContainSubstring(expectedErr.Error())))
 			})
@@ -146,7 +203,7 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("errors", func() {
-				_, err := multiSrc.DownloadRelease("somewhere", remote)
+				_, err := multiSrc.DownloadRelease(context.Background(), "somewhere", remote)
 				Expect(err).To(MatchError(ContainSubstring("couldn't find a release source")))
 				Expect(err).To(MatchError(ContainSubstring("no-such-source")))
 				Expect(err).To(MatchError(ContainSubstring(src1.Configuration().ID)))
@@ -154,6 +211,15 @@ var _ = Describe("multiReleaseSource", func() {
 				Expect(err).To(MatchError(ContainSubstring(src3.Configuration().ID)))
 			})
 		})
+
+		When("kiln is offline and the source needs network access", func() {
+			It("errors without consulting the source", func() {
+				_, err := multiSrc.DownloadRelease(component.ContextWithOffline(context.Background()), "somewhere", remote)
+				Expect(component.IsErrOffline(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring(src2.Configuration().ID)))
+				Expect(src2.DownloadReleaseCallCount()).To(Equal(0))
+			})
+		})
 	})
 
 	Describe("FindByID", func() {
@@ -203,7 +269,7 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns that match", func() {
-				rel, err := multiSrc.FindReleaseVersion(requirement, false)
+				rel, err := multiSrc.FindReleaseVersion(context.Background(), requirement, false)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rel).To(Equal(matchedRelease))
 			})
@@ -230,7 +296,7 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns that match", func() {
-				rel, err := multiSrc.FindReleaseVersion(requirement, false)
+				rel, err := multiSrc.FindReleaseVersion(context.Background(), requirement, false)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rel).To(Equal(matchedRelease))
 			})
@@ -257,10 +323,26 @@ var _ = Describe("multiReleaseSource", func() {
 			})
 
 			It("returns the match from the first source", func() {
-				rel, err := multiSrc.FindReleaseVersion(requirement, false)
+				rel, err := multiSrc.FindReleaseVersion(context.Background(), requirement, false)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rel).To(Equal(matchedRelease))
 			})
 		})
+
+		When("the release is pinned to a release source", func() {
+			BeforeEach(func() {
+				requirement.ReleaseSource = "src-2"
+				src1.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{Name: releaseName, Version: releaseVersionNewer, RemoteSource: "src-1"}, nil)
+				src2.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{Name: releaseName, Version: releaseVersion, RemoteSource: "src-2"}, nil)
+			})
+
+			It("does not consult other sources", func() {
+				rel, err := multiSrc.FindReleaseVersion(context.Background(), requirement, false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rel.RemoteSource).To(Equal("src-2"))
+				Expect(src1.FindReleaseVersionCallCount()).To(Equal(0))
+				Expect(src3.FindReleaseVersionCallCount()).To(Equal(0))
+			})
+		})
 	})
 })
@@ -0,0 +1,257 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/google/go-github/v40/github"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// retryPolicy retries operations that fail with transient errors using exponential backoff.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func newRetryPolicy(config cargo.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxRetries:     config.MaxRetries,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+	}
+	if policy.maxRetries == 0 {
+		policy.maxRetries = defaultMaxRetries
+	} else if policy.maxRetries < 0 {
+		policy.maxRetries = 0
+	}
+	if policy.initialBackoff <= 0 {
+		policy.initialBackoff = defaultInitialBackoff
+	}
+	if policy.maxBackoff <= 0 {
+		policy.maxBackoff = defaultMaxBackoff
+	}
+	if policy.maxBackoff < policy.initialBackoff {
+		policy.maxBackoff = policy.initialBackoff
+	}
+	return policy
+}
+
+// do calls fn until it succeeds, returns an error that is not transient, or runs out of retries.
+func (policy retryPolicy) do(ctx context.Context, logger *log.Logger, description string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.maxRetries || !isTransientError(ctx, err) {
+			return err
+		}
+		wait := policy.backoff(attempt)
+		if retryAfter, ok := retryAfterDuration(err); ok {
+			if retryAfter > policy.maxBackoff {
+				// a GitHub rate limit may not reset for an hour; fail now instead of waiting that long
+				return err
+			}
+			wait = retryAfter
+		}
+		if logger != nil {
+			logger.Printf("retrying %s in %s (retry %d of %d): %s", description, wait, attempt+1, policy.maxRetries, err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (policy retryPolicy) backoff(attempt int) time.Duration {
+	wait := policy.initialBackoff
+	for i := 0; i < attempt && wait < policy.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, policy.maxBackoff)
+}
+
+// httpStatusError is returned for responses with unexpected status codes so the retry policy
+// can tell transient server errors from permanent ones.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	message    string
+}
+
+func newHTTPStatusError(res *http.Response, message string) *httpStatusError {
+	err := &httpStatusError{StatusCode: res.StatusCode, message: message}
+	err.RetryAfter, _ = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	return err
+}
+
+func (err *httpStatusError) Error() string { return err.message }
+
+// parseRetryAfter parses the delay-seconds or HTTP-date forms of a Retry-After header.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func retryAfterDuration(err error) (time.Duration, bool) {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.RetryAfter != nil {
+		return *abuseErr.RetryAfter, true
+	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return max(time.Until(rateLimitErr.Rate.Reset.Time), 0), true
+	}
+	return 0, false
+}
+
+func isTransientStatusCode(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientError reports whether retrying an operation that failed with err may succeed.
+func isTransientError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return isTransientStatusCode(statusErr.StatusCode)
+	}
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) && githubErr.Response != nil {
+		return isTransientStatusCode(githubErr.Response.StatusCode)
+	}
+	var abuseErr *github.AbuseRateLimitError
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &abuseErr) || errors.As(err, &rateLimitErr) {
+		return true
+	}
+	var awsRequestErr awserr.RequestFailure
+	if errors.As(err, &awsRequestErr) {
+		return isTransientStatusCode(awsRequestErr.StatusCode())
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "RequestError" {
+		return true
+	}
+
+	// a DNS failure usually means the host is unreachable without the VPN (see vpnError)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// downloadHTTPFile writes the response body for the request created by newRequest to file.
+// Transient failures are retried with the policy. When the server accepts byte ranges a
+// retry resumes from the end of the partially written file instead of starting over.
+//
+// The file must be empty. A response status other than 200 (or 206 for a resumed download)
+// is returned as an httpStatusError with statusMessage.
+func downloadHTTPFile(ctx context.Context, client *http.Client, policy retryPolicy, logger *log.Logger, file *os.File, newRequest func(ctx context.Context) (*http.Request, error), statusMessage func(statusCode int) string) error {
+	var (
+		written   int64
+		resumable bool
+		size      int64 = -1
+	)
+	return policy.do(ctx, logger, "download of "+file.Name(), func() error {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return err
+		}
+		resuming := resumable && written > 0
+		if resuming {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return wrapVPNError(err)
+		}
+		defer closeAndIgnoreError(res.Body)
+
+		switch {
+		case resuming && res.StatusCode == http.StatusPartialContent:
+		case res.StatusCode == http.StatusOK:
+			if written > 0 {
+				// the server sent the whole file again
+				if err := file.Truncate(0); err != nil {
+					return err
+				}
+				written = 0
+			}
+			resumable = res.Header.Get("Accept-Ranges") == "bytes"
+			size = res.ContentLength
+		default:
+			return newHTTPStatusError(res, statusMessage(res.StatusCode))
+		}
+
+		if _, err := file.Seek(written, io.SeekStart); err != nil {
+			return err
+		}
+		resetDownloadProgress(ctx, written)
+		n, err := io.Copy(progressWriter(ctx, file, size), res.Body)
+		written += n
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if size >= 0 && written < size {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+}
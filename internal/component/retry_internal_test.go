@@ -0,0 +1,150 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v40/github"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func Test_newRetryPolicy(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		please := NewWithT(t)
+		policy := newRetryPolicy(cargo.RetryConfig{})
+		please.Expect(policy.maxRetries).To(Equal(defaultMaxRetries))
+		please.Expect(policy.initialBackoff).To(Equal(defaultInitialBackoff))
+		please.Expect(policy.maxBackoff).To(Equal(defaultMaxBackoff))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		please := NewWithT(t)
+		policy := newRetryPolicy(cargo.RetryConfig{MaxRetries: -1})
+		please.Expect(policy.maxRetries).To(Equal(0))
+	})
+	t.Run("backoff", func(t *testing.T) {
+		please := NewWithT(t)
+		policy := newRetryPolicy(cargo.RetryConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+		please.Expect(policy.backoff(0)).To(Equal(time.Second))
+		please.Expect(policy.backoff(1)).To(Equal(2 * time.Second))
+		please.Expect(policy.backoff(2)).To(Equal(4 * time.Second))
+		please.Expect(policy.backoff(3)).To(Equal(5 * time.Second))
+		please.Expect(policy.backoff(60)).To(Equal(5 * time.Second))
+	})
+}
+
+func Test_retryPolicy_do(t *testing.T) {
+	policy := newRetryPolicy(cargo.RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond})
+
+	t.Run("transient errors", func(t *testing.T) {
+		please := NewWithT(t)
+		calls := 0
+		err := policy.do(context.Background(), nil, "test", func() error {
+			calls++
+			return io.ErrUnexpectedEOF
+		})
+		please.Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		please.Expect(calls).To(Equal(3))
+	})
+
+	t.Run("permanent errors", func(t *testing.T) {
+		please := NewWithT(t)
+		calls := 0
+		err := policy.do(context.Background(), nil, "test", func() error {
+			calls++
+			return &httpStatusError{StatusCode: http.StatusNotFound, message: "not found"}
+		})
+		please.Expect(err).To(HaveOccurred())
+		please.Expect(calls).To(Equal(1))
+	})
+
+	t.Run("rate limit resets after the max backoff", func(t *testing.T) {
+		please := NewWithT(t)
+		calls := 0
+		err := policy.do(context.Background(), nil, "test", func() error {
+			calls++
+			return &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}}
+		})
+		var rateLimitErr *github.RateLimitError
+		please.Expect(errors.As(err, &rateLimitErr)).To(BeTrue())
+		please.Expect(calls).To(Equal(1))
+	})
+
+	t.Run("retry after within the max backoff", func(t *testing.T) {
+		please := NewWithT(t)
+		calls := 0
+		err := policy.do(context.Background(), nil, "test", func() error {
+			calls++
+			if calls == 1 {
+				return &httpStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Millisecond, message: "slow down"}
+			}
+			return nil
+		})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(calls).To(Equal(2))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		please := NewWithT(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		err := policy.do(ctx, nil, "test", func() error {
+			calls++
+			return io.ErrUnexpectedEOF
+		})
+		please.Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		please.Expect(calls).To(Equal(1))
+	})
+}
+
+func Test_isTransientError(t *testing.T) {
+	for _, tt := range []struct {
+		Name      string
+		Err       error
+		Transient bool
+	}{
+		{Name: "service unavailable", Err: &httpStatusError{StatusCode: http.StatusServiceUnavailable}, Transient: true},
+		{Name: "too many requests", Err: &httpStatusError{StatusCode: http.StatusTooManyRequests}, Transient: true},
+		{Name: "unauthorized", Err: &httpStatusError{StatusCode: http.StatusUnauthorized}, Transient: false},
+		{Name: "connection reset", Err: fmt.Errorf("read: %w", syscall.ECONNRESET), Transient: true},
+		{Name: "unexpected EOF", Err: io.ErrUnexpectedEOF, Transient: true},
+		{Name: "host not found", Err: wrapVPNError(&net.DNSError{IsNotFound: true}), Transient: false},
+		{Name: "DNS timeout", Err: &net.DNSError{IsTimeout: true}, Transient: true},
+		{Name: "other", Err: errors.New("banana"), Transient: false},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			please := NewWithT(t)
+			please.Expect(isTransientError(context.Background(), tt.Err)).To(Equal(tt.Transient))
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		Value    string
+		Expected time.Duration
+		OK       bool
+	}{
+		{Value: "", OK: false},
+		{Value: "120", Expected: 2 * time.Minute, OK: true},
+		{Value: "Mon, 01 Jan 2024 00:00:30 GMT", Expected: 30 * time.Second, OK: true},
+		{Value: "soon", OK: false},
+	} {
+		t.Run(tt.Value, func(t *testing.T) {
+			please := NewWithT(t)
+			d, ok := parseRetryAfter(tt.Value, now)
+			please.Expect(ok).To(Equal(tt.OK))
+			please.Expect(d).To(Equal(tt.Expected))
+		})
+	}
+}
@@ -1,22 +1,22 @@
 package component
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
-	"text/template"
+	"time"
 
-	"github.com/Masterminds/semver/v3"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -26,18 +26,20 @@ import (
 
 //counterfeiter:generate -o ./fakes/s3_downloader.go --fake-name S3Downloader . S3Downloader
 type S3Downloader interface {
-	Download(w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (n int64, err error)
+	DownloadWithContext(ctx aws.Context, w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (n int64, err error)
 }
 
 //counterfeiter:generate -o ./fakes/s3_uploader.go --fake-name S3Uploader . S3Uploader
 type S3Uploader interface {
-	Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+	UploadWithContext(ctx aws.Context, input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
 }
 
 //counterfeiter:generate -o ./fakes/s3_client.go --fake-name S3Client . S3Client
 type S3Client interface {
-	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
-	ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, options ...request.Option) (*s3.HeadObjectOutput, error)
+	ListObjectsV2WithContext(ctx aws.Context, input *s3.ListObjectsV2Input, options ...request.Option) (*s3.ListObjectsV2Output, error)
+	CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput, options ...request.Option) (*s3.CreateMultipartUploadOutput, error)
+	AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, options ...request.Option) (*s3.AbortMultipartUploadOutput, error)
 }
 
 type S3ReleaseSource struct {
@@ -68,7 +70,10 @@ func NewS3ReleaseSource(c cargo.ReleaseSourceConfig, client S3Client, downloader
 func NewS3ReleaseSourceFromConfig(config cargo.ReleaseSourceConfig, logger *log.Logger) S3ReleaseSource {
 	validateConfig(config)
 
-	awsConfig := awsRegionAndEndpointConfiguration(config).WithCredentials(credentials.NewStaticCredentials(config.AccessKeyId, config.SecretAccessKey, ""))
+	awsConfig := awsRegionAndEndpointConfiguration(config).WithCredentials(credentials.NewCredentials(&awsCredentialsProvider{
+		config:      config,
+		credentials: newReleaseSourceCredentials(DefaultCredentialProvider(), "access_key_id", "secret_access_key"),
+	}))
 	sess, err := session.NewSession(awsConfig)
 	if err != nil {
 		// TODO: add test coverage for this block
@@ -123,7 +128,7 @@ func (src S3ReleaseSource) Publishable() bool                        { return sr
 func (src S3ReleaseSource) Configuration() cargo.ReleaseSourceConfig { return src.ReleaseSourceConfig }
 
 //counterfeiter:generate -o ./fakes/s3_request_failure.go --fake-name S3RequestFailure github.com/aws/aws-sdk-go/service/s3.RequestFailure
-func (src S3ReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+func (src S3ReleaseSource) GetMatchedRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
 	remotePath, err := src.RemotePath(spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -133,10 +138,9 @@ func (src S3ReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecif
 	headRequest.SetBucket(src.ReleaseSourceConfig.Bucket)
 	headRequest.SetKey(remotePath)
 
-	_, err = src.s3Client.HeadObject(headRequest)
+	_, err = src.s3Client.HeadObjectWithContext(ctx, headRequest)
 	if err != nil {
-		requestFailure, ok := err.(s3.RequestFailure)
-		if ok && requestFailure.StatusCode() == 404 {
+		if isS3NotFound(err) {
 			return cargo.BOSHReleaseTarballLock{}, ErrNotFound
 		}
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -150,79 +154,42 @@ func (src S3ReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecif
 	}, nil
 }
 
-func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
-	pathTemplatePattern, _ := regexp.Compile(`^\d+\.\d+`)
-	tasVersion := pathTemplatePattern.FindString(src.ReleaseSourceConfig.PathTemplate)
-	var prefix string
-	if tasVersion != "" {
-		prefix = tasVersion + "/"
-	}
-	prefix += spec.Name + "/"
-
-	releaseResults, err := src.s3Client.ListObjectsV2(&s3.ListObjectsV2Input{
-		Bucket: &src.ReleaseSourceConfig.Bucket,
-		Prefix: &prefix,
-	})
+// FindReleaseVersion lists the keys under the part of path_template that does not depend on
+// the version and returns the highest version matching the constraint. Compiled
+// releases are skipped unless the spec sets a stemcell.
+func (src S3ReleaseSource) FindReleaseVersion(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionMatcher()
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
-
-	semverPattern, err := regexp.Compile(`([-v])\d+(.\d+)*`)
+	pattern, prefix, err := pathTemplateVersionPattern(src.PathTemplate, spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
-	foundRelease := cargo.BOSHReleaseTarballLock{}
-	constraint, err := spec.VersionConstraints()
+	newest := newestPathVersion{pattern: pattern, matcher: constraint}
+	err = src.listObjects(ctx, prefix, func(object *s3.Object) {
+		newest.add(aws.StringValue(object.Key))
+	})
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
-
-	for _, result := range releaseResults.Contents {
-		versions := semverPattern.FindAllString(*result.Key, -1)
-		version := versions[0]
-		stemcellVersion := versions[len(versions)-1]
-		version = strings.Replace(version, "-", "", -1)
-		version = strings.Replace(version, "v", "", -1)
-		stemcellVersion = strings.Replace(stemcellVersion, "-", "", -1)
-		if len(versions) > 1 && stemcellVersion != spec.StemcellVersion {
-			continue
-		}
-		if version != "" {
-			newVersion, _ := semver.NewVersion(version)
-			if !constraint.Check(newVersion) {
-				continue
-			}
-
-			if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
-				foundRelease = cargo.BOSHReleaseTarballLock{
-					Name:         spec.Name,
-					Version:      version,
-					RemotePath:   *result.Key,
-					RemoteSource: src.ReleaseSourceConfig.ID,
-				}
-			} else {
-				foundVersion, _ := semver.NewVersion(foundRelease.Version)
-				if newVersion.GreaterThan(foundVersion) {
-					foundRelease = cargo.BOSHReleaseTarballLock{
-						Name:         spec.Name,
-						Version:      version,
-						RemotePath:   *result.Key,
-						RemoteSource: src.ReleaseSourceConfig.ID,
-					}
-				}
-			}
-		}
-	}
-	if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
+	if newest.version == nil {
 		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
 	}
 
+	foundRelease := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      newest.version.Original(),
+		RemotePath:   newest.path,
+		RemoteSource: src.ReleaseSourceConfig.ID,
+	}
+
 	if noDownload {
 		foundRelease.SHA1 = "not-calculated"
 	} else {
 		var releaseLocal Local
-		releaseLocal, err = src.DownloadRelease("/tmp", foundRelease)
+		releaseLocal, err = src.DownloadRelease(ctx, "/tmp", foundRelease)
 		if err != nil {
 			return cargo.BOSHReleaseTarballLock{}, err
 		}
@@ -231,7 +198,7 @@ func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpeci
 	return foundRelease, nil
 }
 
-func (src S3ReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHReleaseTarballLock) (Local, error) {
+func (src S3ReleaseSource) DownloadRelease(ctx context.Context, releaseDir string, lock cargo.BOSHReleaseTarballLock) (_ Local, err error) {
 	setConcurrency := func(dl *s3manager.Downloader) {
 		if src.DownloadThreads > 0 {
 			dl.Concurrency = src.DownloadThreads
@@ -248,12 +215,20 @@ func (src S3ReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHRel
 	if err != nil {
 		return Local{}, fmt.Errorf("failed to create file %q: %w", outputFile, err)
 	}
-	defer closeAndIgnoreError(file)
+	defer removePartialDownload(file, &err)
 
-	_, err = src.s3Downloader.Download(file, &s3.GetObjectInput{
-		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
-		Key:    aws.String(lock.RemotePath),
-	}, setConcurrency)
+	// the downloader already retries failed parts; this retries the whole file when that is not enough
+	err = newRetryPolicy(src.Retry).do(ctx, src.logger, "download of "+outputFile, func() error {
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		resetDownloadProgress(ctx, 0)
+		_, err := src.s3Downloader.DownloadWithContext(ctx, progressWriterAt(ctx, file), &s3.GetObjectInput{
+			Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
+			Key:    aws.String(lock.RemotePath),
+		}, setConcurrency)
+		return err
+	})
 	if err != nil {
 		return Local{}, fmt.Errorf("failed to download file: %w\n", err)
 	}
@@ -274,19 +249,26 @@ func (src S3ReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHRel
 	return Local{Lock: lock, LocalPath: outputFile}, nil
 }
 
-func (src S3ReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+// UploadRelease uploads the release in parts in parallel. The part size and number of parallel
+// uploads can be set with upload_part_size_mb and upload_concurrency. It returns ErrAlreadyExists
+// when the key exists, unless ctx was returned by ContextWithOverwrite.
+func (src S3ReleaseSource) UploadRelease(ctx context.Context, spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
 	remotePath, err := src.RemotePath(spec)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
+	if err := src.checkOverwrite(ctx, remotePath); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
 	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ReleaseSourceConfig.Bucket, remotePath)
 
-	_, err = src.s3Uploader.Upload(&s3manager.UploadInput{
+	_, err = src.s3Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
 		Key:    aws.String(remotePath),
 		Body:   file,
-	})
+	}, src.configureUploader)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -299,20 +281,90 @@ func (src S3ReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecificat
 	}, nil
 }
 
-func (src S3ReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
-	pathBuf := new(bytes.Buffer)
+// CheckUpload starts a multipart upload at the path_template location and aborts it. Starting
+// a multipart upload requires the same permission as uploading an object.
+func (src S3ReleaseSource) CheckUpload(ctx context.Context) error {
+	remotePath, err := src.RemotePath(uploadCheckSpec)
+	if err != nil {
+		return err
+	}
+	upload, err := src.s3Client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
+		Key:    aws.String(remotePath),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = src.s3Client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(src.ReleaseSourceConfig.Bucket),
+		Key:      aws.String(remotePath),
+		UploadId: upload.UploadId,
+	})
+	return err
+}
+
+func (src S3ReleaseSource) configureUploader(uploader *s3manager.Uploader) {
+	if src.UploadPartSizeMB > 0 {
+		uploader.PartSize = int64(src.UploadPartSizeMB) * 1024 * 1024
+	}
+	if src.UploadConcurrency > 0 {
+		uploader.Concurrency = src.UploadConcurrency
+	}
+}
 
-	err := src.pathTemplate().Execute(pathBuf, spec)
+// checkOverwrite returns ErrAlreadyExists when key exists and overwriting is not allowed.
+// Buckets with S3 Object Lock keep locked versions when a key is overwritten, so that is
+// logged instead of failing.
+func (src S3ReleaseSource) checkOverwrite(ctx context.Context, key string) error {
+	head, err := src.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
+		if isS3NotFound(err) {
+			return nil
+		}
+		return err
 	}
+	if !AllowsOverwrite(ctx) {
+		return fmt.Errorf("s3://%s/%s %w", src.ReleaseSourceConfig.Bucket, key, ErrAlreadyExists)
+	}
+	if aws.StringValue(head.ObjectLockLegalHoldStatus) == s3.ObjectLockLegalHoldStatusOn {
+		src.logger.Printf("s3://%s/%s has a legal hold; the existing version is kept after it is overwritten\n", src.ReleaseSourceConfig.Bucket, key)
+	} else if until := aws.TimeValue(head.ObjectLockRetainUntilDate); until.After(time.Now()) {
+		src.logger.Printf("s3://%s/%s is locked (%s) until %s; the existing version is kept after it is overwritten\n", src.ReleaseSourceConfig.Bucket, key, aws.StringValue(head.ObjectLockMode), until.Format(time.RFC3339))
+	}
+	return nil
+}
 
-	return pathBuf.String(), nil
+// listObjects calls fn with each object under prefix, requesting further pages while the
+// listing is truncated.
+func (src S3ReleaseSource) listObjects(ctx context.Context, prefix string, fn func(*s3.Object)) error {
+	var continuationToken *string
+	for {
+		output, err := src.s3Client.ListObjectsV2WithContext(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(src.ReleaseSourceConfig.Bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, object := range output.Contents {
+			fn(object)
+		}
+		if !aws.BoolValue(output.IsTruncated) || aws.StringValue(output.NextContinuationToken) == "" {
+			return nil
+		}
+		continuationToken = output.NextContinuationToken
+	}
 }
 
-func (src S3ReleaseSource) pathTemplate() *template.Template {
-	return template.Must(
-		template.New("remote-path").
-			Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
-			Parse(src.ReleaseSourceConfig.PathTemplate))
+func isS3NotFound(err error) bool {
+	var requestFailure s3.RequestFailure
+	return errors.As(err, &requestFailure) && requestFailure.StatusCode() == http.StatusNotFound
+}
+
+func (src S3ReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return renderPathTemplate(src.PathTemplate, spec)
 }
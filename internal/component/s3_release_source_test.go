@@ -1,19 +1,30 @@
 package component_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/go-git/go-billy/v5/osfs"
@@ -110,7 +121,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			logger = log.New(GinkgoWriter, "", 0)
 			fakeS3Downloader = new(fetcherFakes.S3Downloader)
 			// fakeS3Downloader writes the given S3 bucket and key into the output file for easy verification
-			fakeS3Downloader.DownloadStub = func(writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
+			fakeS3Downloader.DownloadWithContextStub = func(_ context.Context, writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
 				n, err := writer.WriteAt([]byte(fmt.Sprintf("%s/%s", *objectInput.Bucket, *objectInput.Key)), 0)
 				return int64(n), err
 			}
@@ -128,9 +139,9 @@ var _ = Describe("S3ReleaseSource", func() {
 
 		It("downloads the appropriate versions of built releases listed in remoteReleases", func() {
 			releaseSource.DownloadThreads = 7
-			localRelease, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+			localRelease, err := releaseSource.DownloadRelease(context.Background(), releaseDir, remoteRelease)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(fakeS3Downloader.DownloadCallCount()).To(Equal(1))
+			Expect(fakeS3Downloader.DownloadWithContextCallCount()).To(Equal(1))
 
 			releasePath := filepath.Join(releaseDir, expectedLocalFilename)
 			releaseContents, err := os.ReadFile(releasePath)
@@ -140,7 +151,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			sha1, err := component.CalculateSum(releasePath, osfs.New(""))
 			Expect(err).NotTo(HaveOccurred())
 
-			_, _, opts := fakeS3Downloader.DownloadArgsForCall(0)
+			_, _, _, opts := fakeS3Downloader.DownloadWithContextArgsForCall(0)
 			verifySetsConcurrency(opts, 7)
 
 			Expect(localRelease).To(Equal(component.Local{
@@ -152,11 +163,11 @@ var _ = Describe("S3ReleaseSource", func() {
 		Context("when number of threads is not specified", func() {
 			It("uses the s3manager package's default download concurrency", func() {
 				releaseSource.DownloadThreads = 0
-				_, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+				_, err := releaseSource.DownloadRelease(context.Background(), releaseDir, remoteRelease)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeS3Downloader.DownloadCallCount()).To(Equal(1))
+				Expect(fakeS3Downloader.DownloadWithContextCallCount()).To(Equal(1))
 
-				_, _, opts := fakeS3Downloader.DownloadArgsForCall(0)
+				_, _, _, opts := fakeS3Downloader.DownloadWithContextArgsForCall(0)
 				verifySetsConcurrency(opts, s3manager.DefaultDownloadConcurrency)
 			})
 		})
@@ -164,7 +175,7 @@ var _ = Describe("S3ReleaseSource", func() {
 		Context("failure cases", func() {
 			Context("when a file can't be created", func() {
 				It("returns an error", func() {
-					_, err := releaseSource.DownloadRelease("/non-existent-folder", remoteRelease)
+					_, err := releaseSource.DownloadRelease(context.Background(), "/non-existent-folder", remoteRelease)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("/non-existent-folder"))
 				})
@@ -172,13 +183,13 @@ var _ = Describe("S3ReleaseSource", func() {
 
 			Context("when a file can't be downloaded", func() {
 				BeforeEach(func() {
-					fakeS3Downloader.DownloadCalls(func(w io.WriterAt, i *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
+					fakeS3Downloader.DownloadWithContextCalls(func(_ context.Context, w io.WriterAt, i *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
 						return 0, errors.New("503 Service Unavailable")
 					})
 				})
 
 				It("returns an error", func() {
-					_, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+					_, err := releaseSource.DownloadRelease(context.Background(), releaseDir, remoteRelease)
 					Expect(err).To(HaveOccurred())
 					Expect(err).To(MatchError("failed to download file: 503 Service Unavailable\n"))
 				})
@@ -208,7 +219,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			}
 
 			fakeS3Client = new(fetcherFakes.S3Client)
-			fakeS3Client.HeadObjectReturns(new(s3.HeadObjectOutput), nil)
+			fakeS3Client.HeadObjectWithContextReturns(new(s3.HeadObjectOutput), nil)
 
 			logger = log.New(nil, "", 0)
 
@@ -227,11 +238,11 @@ var _ = Describe("S3ReleaseSource", func() {
 		})
 
 		It("searches for the requested release", func() {
-			remoteRelease, err := releaseSource.GetMatchedRelease(desiredRelease)
+			remoteRelease, err := releaseSource.GetMatchedRelease(context.Background(), desiredRelease)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(fakeS3Client.HeadObjectCallCount()).To(Equal(1))
-			input := fakeS3Client.HeadObjectArgsForCall(0)
+			Expect(fakeS3Client.HeadObjectWithContextCallCount()).To(Equal(1))
+			_, input, _ := fakeS3Client.HeadObjectWithContextArgsForCall(0)
 			Expect(input.Bucket).To(PointTo(BeEquivalentTo(bucket)))
 			Expect(input.Key).To(PointTo(BeEquivalentTo(bpmKey)))
 
@@ -247,11 +258,11 @@ var _ = Describe("S3ReleaseSource", func() {
 			BeforeEach(func() {
 				notFoundError := new(fetcherFakes.S3RequestFailure)
 				notFoundError.StatusCodeReturns(404)
-				fakeS3Client.HeadObjectReturns(nil, notFoundError)
+				fakeS3Client.HeadObjectWithContextReturns(nil, notFoundError)
 			})
 
 			It("returns not found", func() {
-				_, err := releaseSource.GetMatchedRelease(desiredRelease)
+				_, err := releaseSource.GetMatchedRelease(context.Background(), desiredRelease)
 				Expect(err).To(HaveOccurred())
 				Expect(component.IsErrNotFound(err)).To(BeTrue())
 			})
@@ -274,7 +285,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("returns a descriptive error", func() {
-				_, err := releaseSource.GetMatchedRelease(desiredRelease)
+				_, err := releaseSource.GetMatchedRelease(context.Background(), desiredRelease)
 
 				Expect(err).To(MatchError(ContainSubstring(`unable to evaluate path_template`)))
 			})
@@ -306,7 +317,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				object1Key := "uaa/uaa-1.2.2.tgz"
 				object2Key := "uaa/uaa-1.2.3.tgz"
 				object3Key := "uaa/uaa-1.1.1.tgz"
-				fakeS3Client.ListObjectsV2Returns(&s3.ListObjectsV2Output{
+				fakeS3Client.ListObjectsV2WithContextReturns(&s3.ListObjectsV2Output{
 					Contents: []*s3.Object{
 						{Key: &object1Key},
 						{Key: &object3Key},
@@ -316,7 +327,7 @@ var _ = Describe("S3ReleaseSource", func() {
 
 				fakeS3Downloader = new(fetcherFakes.S3Downloader)
 				// fakeS3Downloader writes the given S3 bucket and key into the output file for easy verification
-				fakeS3Downloader.DownloadStub = func(writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
+				fakeS3Downloader.DownloadWithContextStub = func(_ context.Context, writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
 					n, err := writer.WriteAt([]byte(fmt.Sprintf("%s/%s", *objectInput.Bucket, *objectInput.Key)), 0)
 					return int64(n), err
 				}
@@ -339,11 +350,11 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("gets the version that satisfies the constraint", func() {
-				remoteRelease, err := releaseSource.FindReleaseVersion(desiredRelease, false)
+				remoteRelease, err := releaseSource.FindReleaseVersion(context.Background(), desiredRelease, false)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(fakeS3Client.ListObjectsV2CallCount()).To(Equal(1))
-				input := fakeS3Client.ListObjectsV2ArgsForCall(0)
+				Expect(fakeS3Client.ListObjectsV2WithContextCallCount()).To(Equal(1))
+				_, input, _ := fakeS3Client.ListObjectsV2WithContextArgsForCall(0)
 				Expect(*input.Prefix).To(Equal("uaa/"))
 
 				Expect(remoteRelease).To(Equal(
@@ -368,7 +379,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				object2Key := "uaa/uaa-123.tgz"
 				object3Key := "uaa/uaa-123.tgz"
 				object4Key := "uaa/uaa-121.tgz"
-				fakeS3Client.ListObjectsV2Returns(&s3.ListObjectsV2Output{
+				fakeS3Client.ListObjectsV2WithContextReturns(&s3.ListObjectsV2Output{
 					Contents: []*s3.Object{
 						{Key: &object1Key},
 						{Key: &object3Key},
@@ -380,7 +391,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				logger = log.New(GinkgoWriter, "", 0)
 				fakeS3Downloader = new(fetcherFakes.S3Downloader)
 				// fakeS3Downloader writes the given S3 bucket and key into the output file for easy verification
-				fakeS3Downloader.DownloadStub = func(writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
+				fakeS3Downloader.DownloadWithContextStub = func(_ context.Context, writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
 					n, err := writer.WriteAt([]byte(fmt.Sprintf("%s/%s", *objectInput.Bucket, *objectInput.Key)), 0)
 					return int64(n), err
 				}
@@ -401,11 +412,11 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("gets the latest version of a release", func() {
-				remoteRelease, err := releaseSource.FindReleaseVersion(desiredRelease, false)
+				remoteRelease, err := releaseSource.FindReleaseVersion(context.Background(), desiredRelease, false)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(fakeS3Client.ListObjectsV2CallCount()).To(Equal(1))
-				input := fakeS3Client.ListObjectsV2ArgsForCall(0)
+				Expect(fakeS3Client.ListObjectsV2WithContextCallCount()).To(Equal(1))
+				_, input, _ := fakeS3Client.ListObjectsV2WithContextArgsForCall(0)
 				Expect(*input.Prefix).To(Equal("uaa/"))
 
 				Expect(remoteRelease).To(Equal(cargo.BOSHReleaseTarballLock{
@@ -431,7 +442,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				object2Key := "uaa/uaa-123.tgz"
 				object3Key := "uaa/uaa-123.tgz"
 				object4Key := "uaa/uaa-121.tgz"
-				fakeS3Client.ListObjectsV2Returns(&s3.ListObjectsV2Output{
+				fakeS3Client.ListObjectsV2WithContextReturns(&s3.ListObjectsV2Output{
 					Contents: []*s3.Object{
 						{Key: &object1Key},
 						{Key: &object3Key},
@@ -442,7 +453,7 @@ var _ = Describe("S3ReleaseSource", func() {
 
 				logger = log.New(GinkgoWriter, "", 0)
 				fakeS3Downloader = new(fetcherFakes.S3Downloader)
-				fakeS3Downloader.DownloadStub = func(wa io.WriterAt, goi *s3.GetObjectInput, f ...func(*s3manager.Downloader)) (int64, error) {
+				fakeS3Downloader.DownloadWithContextStub = func(_ context.Context, wa io.WriterAt, goi *s3.GetObjectInput, f ...func(*s3manager.Downloader)) (int64, error) {
 					Fail("Download called when noDownload=true")
 					return -1, nil
 				}
@@ -463,11 +474,11 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("gets the latest version of a release", func() {
-				remoteRelease, err := releaseSource.FindReleaseVersion(desiredRelease, true)
+				remoteRelease, err := releaseSource.FindReleaseVersion(context.Background(), desiredRelease, true)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(fakeS3Client.ListObjectsV2CallCount()).To(Equal(1))
-				input := fakeS3Client.ListObjectsV2ArgsForCall(0)
+				Expect(fakeS3Client.ListObjectsV2WithContextCallCount()).To(Equal(1))
+				_, input, _ := fakeS3Client.ListObjectsV2WithContextArgsForCall(0)
 				Expect(*input.Prefix).To(Equal("uaa/"))
 
 				Expect(remoteRelease).To(Equal(cargo.BOSHReleaseTarballLock{
@@ -505,7 +516,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				object2Key := "2.11/uaa/uaa-1.2.3-ubuntu-xenial-621.71.tgz"
 				object3Key := "2.11/uaa/uaa-1.2.1-ubuntu-xenial-621.71.tgz"
 				object4Key := "2.11/uaa/uaa-1.2.3-ubuntu-xenial-622.71.tgz"
-				fakeS3Client.ListObjectsV2Returns(&s3.ListObjectsV2Output{
+				fakeS3Client.ListObjectsV2WithContextReturns(&s3.ListObjectsV2Output{
 					Contents: []*s3.Object{
 						{Key: &object1Key},
 						{Key: &object4Key},
@@ -517,7 +528,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				logger = log.New(GinkgoWriter, "", 0)
 				fakeS3Downloader := new(fetcherFakes.S3Downloader)
 				// fakeS3Downloader writes the given S3 bucket and key into the output file for easy verification
-				fakeS3Downloader.DownloadStub = func(writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
+				fakeS3Downloader.DownloadWithContextStub = func(_ context.Context, writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
 					n, err := writer.WriteAt([]byte(fmt.Sprintf("%s/%s", *objectInput.Bucket, *objectInput.Key)), 0)
 					return int64(n), err
 				}
@@ -538,11 +549,11 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("gets the latest version of a release", func() {
-				remoteRelease, err := releaseSource.FindReleaseVersion(desiredRelease, false)
+				remoteRelease, err := releaseSource.FindReleaseVersion(context.Background(), desiredRelease, false)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(fakeS3Client.ListObjectsV2CallCount()).To(Equal(1))
-				input := fakeS3Client.ListObjectsV2ArgsForCall(0)
+				Expect(fakeS3Client.ListObjectsV2WithContextCallCount()).To(Equal(1))
+				_, input, _ := fakeS3Client.ListObjectsV2WithContextArgsForCall(0)
 				Expect(*input.Prefix).To(Equal("2.11/uaa/"))
 
 				Expect(remoteRelease).To(Equal(releaseID.Lock().WithRemote(sourceID, uaaKey).WithSHA1("78facf87f730395fb263fb5e89157c438fc1d8a9")))
@@ -552,41 +563,51 @@ var _ = Describe("S3ReleaseSource", func() {
 
 	Describe("UploadRelease", func() {
 		var (
+			s3Client      *fetcherFakes.S3Client
 			s3Uploader    *fetcherFakes.S3Uploader
 			releaseSource component.S3ReleaseSource
+			config        cargo.ReleaseSourceConfig
+			logBuffer     *strings.Builder
 			file          io.Reader
 		)
 
 		BeforeEach(func() {
+			s3Client = new(fetcherFakes.S3Client)
+			notFoundError := new(fetcherFakes.S3RequestFailure)
+			notFoundError.StatusCodeReturns(404)
+			s3Client.HeadObjectWithContextReturns(nil, notFoundError)
 			s3Uploader = new(fetcherFakes.S3Uploader)
-			releaseSource = component.NewS3ReleaseSource(
-				cargo.ReleaseSourceConfig{
-					ID:           sourceID,
-					Bucket:       "orange-bucket",
-					PathTemplate: `{{.Name}}/{{.Name}}-{{.Version}}.tgz`,
-					Publishable:  false,
-				},
-				nil,
-				nil,
-				s3Uploader,
-				log.New(GinkgoWriter, "", 0),
-			)
+			config = cargo.ReleaseSourceConfig{
+				ID:           sourceID,
+				Bucket:       "orange-bucket",
+				PathTemplate: `{{.Name}}/{{.Name}}-{{.Version}}.tgz`,
+				Publishable:  false,
+			}
+			logBuffer = new(strings.Builder)
 			file = strings.NewReader("banana banana")
 		})
 
+		JustBeforeEach(func() {
+			releaseSource = component.NewS3ReleaseSource(config, s3Client, nil, s3Uploader, log.New(logBuffer, "", 0))
+		})
+
 		Context("happy path", func() {
 			It("uploads the file to the correct location", func() {
-				_, err := releaseSource.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:    "banana",
 					Version: "1.2.3",
 				}, file)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(s3Uploader.UploadCallCount()).To(Equal(1))
+				Expect(s3Client.HeadObjectWithContextCallCount()).To(Equal(1))
+				_, head, _ := s3Client.HeadObjectWithContextArgsForCall(0)
+				Expect(head.Key).To(PointTo(Equal("banana/banana-1.2.3.tgz")))
+
+				Expect(s3Uploader.UploadWithContextCallCount()).To(Equal(1))
 
-				opts, fns := s3Uploader.UploadArgsForCall(0)
+				_, opts, fns := s3Uploader.UploadWithContextArgsForCall(0)
 
-				Expect(fns).To(HaveLen(0))
+				Expect(fns).To(HaveLen(1))
 
 				Expect(opts.Bucket).To(PointTo(Equal("orange-bucket")))
 				Expect(opts.Key).To(PointTo(Equal("banana/banana-1.2.3.tgz")))
@@ -594,7 +615,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 
 			It("returns the remote release", func() {
-				remoteRelease, err := releaseSource.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+				remoteRelease, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:    "banana",
 					Version: "1.2.3",
 				}, file)
@@ -607,26 +628,94 @@ var _ = Describe("S3ReleaseSource", func() {
 					RemoteSource: "orange-bucket",
 				}))
 			})
+
+			It("uses the default multipart settings", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, fns := s3Uploader.UploadWithContextArgsForCall(0)
+				uploader := &s3manager.Uploader{PartSize: s3manager.DefaultUploadPartSize, Concurrency: s3manager.DefaultUploadConcurrency}
+				fns[0](uploader)
+				Expect(uploader.PartSize).To(Equal(int64(s3manager.DefaultUploadPartSize)))
+				Expect(uploader.Concurrency).To(Equal(s3manager.DefaultUploadConcurrency))
+			})
+		})
+
+		When("the part size and concurrency are configured", func() {
+			BeforeEach(func() {
+				config.UploadPartSizeMB = 64
+				config.UploadConcurrency = 10
+			})
+
+			It("configures the multipart upload", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, fns := s3Uploader.UploadWithContextArgsForCall(0)
+				Expect(fns).To(HaveLen(1))
+				uploader := new(s3manager.Uploader)
+				fns[0](uploader)
+				Expect(uploader.PartSize).To(Equal(int64(64 * 1024 * 1024)))
+				Expect(uploader.Concurrency).To(Equal(10))
+			})
+		})
+
+		When("the key already exists", func() {
+			BeforeEach(func() {
+				s3Client.HeadObjectWithContextReturns(new(s3.HeadObjectOutput), nil)
+			})
+
+			It("does not overwrite it", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+				Expect(component.IsErrAlreadyExists(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("s3://orange-bucket/banana/banana-1.2.3.tgz")))
+				Expect(s3Uploader.UploadWithContextCallCount()).To(Equal(0))
+			})
+
+			When("overwriting is allowed", func() {
+				It("uploads the file", func() {
+					_, err := releaseSource.UploadRelease(component.ContextWithOverwrite(context.Background()), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(s3Uploader.UploadWithContextCallCount()).To(Equal(1))
+				})
+			})
+
+			When("the object is protected by S3 Object Lock", func() {
+				BeforeEach(func() {
+					s3Client.HeadObjectWithContextReturns(&s3.HeadObjectOutput{
+						ObjectLockMode:            aws.String(s3.ObjectLockModeCompliance),
+						ObjectLockRetainUntilDate: aws.Time(time.Now().Add(time.Hour)),
+					}, nil)
+				})
+
+				It("logs that the locked version is kept", func() {
+					_, err := releaseSource.UploadRelease(component.ContextWithOverwrite(context.Background()), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(logBuffer.String()).To(ContainSubstring("is locked (COMPLIANCE)"))
+					Expect(s3Uploader.UploadWithContextCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		When("checking for the key fails", func() {
+			BeforeEach(func() {
+				s3Client.HeadObjectWithContextReturns(nil, errors.New("lemon"))
+			})
+
+			It("returns the error", func() {
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{Name: "banana", Version: "1.2.3"}, file)
+				Expect(err).To(MatchError("lemon"))
+				Expect(s3Uploader.UploadWithContextCallCount()).To(Equal(0))
+			})
 		})
 
 		When("there is an error evaluating the path template", func() {
 			BeforeEach(func() {
-				releaseSource = component.NewS3ReleaseSource(
-					cargo.ReleaseSourceConfig{
-						ID:           sourceID,
-						Bucket:       "orange-bucket",
-						PathTemplate: `{{.NoSuchField}}`,
-						Publishable:  false,
-					},
-					nil,
-					nil,
-					s3Uploader,
-					log.New(GinkgoWriter, "", 0),
-				)
+				config.PathTemplate = `{{.NoSuchField}}`
 			})
 
 			It("returns a descriptive error", func() {
-				_, err := releaseSource.UploadRelease(cargo.BOSHReleaseTarballSpecification{
+				_, err := releaseSource.UploadRelease(context.Background(), cargo.BOSHReleaseTarballSpecification{
 					Name:    "banana",
 					Version: "1.2.3",
 				}, file)
@@ -636,6 +725,48 @@ var _ = Describe("S3ReleaseSource", func() {
 		})
 	})
 
+	Describe("CheckUpload", func() {
+		var (
+			s3Client      *fetcherFakes.S3Client
+			releaseSource component.S3ReleaseSource
+		)
+
+		BeforeEach(func() {
+			s3Client = new(fetcherFakes.S3Client)
+			s3Client.CreateMultipartUploadWithContextReturns(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+			releaseSource = component.NewS3ReleaseSource(cargo.ReleaseSourceConfig{
+				ID:           sourceID,
+				Bucket:       "orange-bucket",
+				PathTemplate: `{{.Name}}/{{.Name}}-{{.Version}}.tgz`,
+			}, s3Client, nil, nil, log.New(GinkgoWriter, "", 0))
+		})
+
+		It("starts and aborts a multipart upload", func() {
+			Expect(releaseSource.CheckUpload(context.Background())).To(Succeed())
+
+			Expect(s3Client.CreateMultipartUploadWithContextCallCount()).To(Equal(1))
+			_, createInput, _ := s3Client.CreateMultipartUploadWithContextArgsForCall(0)
+			Expect(*createInput.Bucket).To(Equal("orange-bucket"))
+			Expect(*createInput.Key).To(Equal("kiln-upload-check/kiln-upload-check-0.0.0.tgz"))
+
+			Expect(s3Client.AbortMultipartUploadWithContextCallCount()).To(Equal(1))
+			_, abortInput, _ := s3Client.AbortMultipartUploadWithContextArgsForCall(0)
+			Expect(*abortInput.Key).To(Equal(*createInput.Key))
+			Expect(*abortInput.UploadId).To(Equal("upload-1"))
+		})
+
+		When("the upload is not allowed", func() {
+			BeforeEach(func() {
+				s3Client.CreateMultipartUploadWithContextReturns(nil, errors.New("AccessDenied"))
+			})
+
+			It("returns the error", func() {
+				Expect(releaseSource.CheckUpload(context.Background())).To(MatchError("AccessDenied"))
+				Expect(s3Client.AbortMultipartUploadWithContextCallCount()).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("RemotePath", func() {
 		var (
 			releaseSource component.S3ReleaseSource
@@ -692,4 +823,156 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 		})
 	})
+
+	Describe("with an S3 compatible endpoint", func() {
+		var (
+			server        *httptest.Server
+			bucket        *fakeS3Bucket
+			releaseSource component.S3ReleaseSource
+			releasesDir   string
+		)
+
+		BeforeEach(func() {
+			bucket = &fakeS3Bucket{name: "releases", pageSize: 2, objects: map[string][]byte{
+				"2.11/uaa/uaa-1.2.1-ubuntu-jammy-1.10.tgz":  []byte("uaa 1.2.1"),
+				"2.11/uaa/uaa-1.2.3-ubuntu-jammy-1.10.tgz":  []byte("uaa 1.2.3"),
+				"2.11/uaa/uaa-1.3.0-ubuntu-jammy-1.11.tgz":  []byte("uaa 1.3.0"),
+				"2.11/uaa/uaa-2.0.0-ubuntu-jammy-1.10.tgz":  []byte("uaa 2.0.0"),
+				"2.11/uaa/uaa-1.2.2-ubuntu-xenial-1.10.tgz": []byte("uaa 1.2.2"),
+				"2.11/bpm/bpm-1.0.0-ubuntu-jammy-1.10.tgz":  []byte("bpm 1.0.0"),
+			}}
+			server = httptest.NewServer(bucket)
+			releasesDir = must(os.MkdirTemp("", "s3-releases"))
+
+			releaseSource = component.NewS3ReleaseSourceFromConfig(cargo.ReleaseSourceConfig{
+				Type:            component.ReleaseSourceTypeS3,
+				ID:              sourceID,
+				Bucket:          "releases",
+				Region:          "us-east-1",
+				Endpoint:        server.URL,
+				AccessKeyId:     "some-key",
+				SecretAccessKey: "some-secret",
+				PathTemplate:    `2.11/{{.Name}}/{{.Name}}-{{.Version}}-{{.StemcellOS}}-{{.StemcellVersion}}.tgz`,
+			}, log.New(GinkgoWriter, "", 0))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			_ = os.RemoveAll(releasesDir)
+		})
+
+		It("lists the release prefix page by page", func() {
+			lock, err := releaseSource.FindReleaseVersion(context.Background(), cargo.BOSHReleaseTarballSpecification{
+				Name:            "uaa",
+				Version:         "~1.2",
+				StemcellOS:      "ubuntu-jammy",
+				StemcellVersion: "1.10",
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Version).To(Equal("1.2.3"))
+			Expect(lock.RemotePath).To(Equal("2.11/uaa/uaa-1.2.3-ubuntu-jammy-1.10.tgz"))
+
+			Expect(bucket.listedPrefixes).To(Equal([]string{"2.11/uaa/", "2.11/uaa/", "2.11/uaa/"}))
+		})
+
+		It("downloads the release", func() {
+			local, err := releaseSource.DownloadRelease(context.Background(), releasesDir, cargo.BOSHReleaseTarballLock{
+				Name: "bpm", Version: "1.0.0", RemotePath: "2.11/bpm/bpm-1.0.0-ubuntu-jammy-1.10.tgz",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte("bpm 1.0.0")))
+		})
+
+		It("uploads releases and does not overwrite them", func() {
+			spec := cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: "1.1.0", StemcellOS: "ubuntu-jammy", StemcellVersion: "1.10"}
+
+			_, err := releaseSource.UploadRelease(context.Background(), spec, strings.NewReader("bpm 1.1.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bucket.objects).To(HaveKeyWithValue("2.11/bpm/bpm-1.1.0-ubuntu-jammy-1.10.tgz", []byte("bpm 1.1.0")))
+
+			_, err = releaseSource.UploadRelease(context.Background(), spec, strings.NewReader("changed"))
+			Expect(component.IsErrAlreadyExists(err)).To(BeTrue())
+
+			_, err = releaseSource.UploadRelease(component.ContextWithOverwrite(context.Background()), spec, strings.NewReader("changed"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bucket.objects).To(HaveKeyWithValue("2.11/bpm/bpm-1.1.0-ubuntu-jammy-1.10.tgz", []byte("changed")))
+		})
+	})
 })
+
+// fakeS3Bucket is a minimal stand-in for an S3 compatible server with a single bucket
+// using path style requests. Listings return at most pageSize keys per page.
+type fakeS3Bucket struct {
+	name     string
+	pageSize int
+	objects  map[string][]byte
+
+	mu             sync.Mutex
+	listedPrefixes []string
+}
+
+func (b *fakeS3Bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var key string
+	if r.URL.Path != "/"+b.name {
+		var found bool
+		if key, found = strings.CutPrefix(r.URL.Path, "/"+b.name+"/"); !found {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		b.list(w, r.URL.Query())
+	case r.Method == http.MethodPut:
+		b.objects[key] = must(io.ReadAll(r.Body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		content, ok := b.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (b *fakeS3Bucket) list(w http.ResponseWriter, query url.Values) {
+	prefix := query.Get("prefix")
+	b.listedPrefixes = append(b.listedPrefixes, prefix)
+
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	end := min(start+b.pageSize, len(keys))
+
+	type object struct {
+		Key  string
+		Size int
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []object
+	}{Name: b.name, Prefix: prefix, KeyCount: end - start, IsTruncated: end < len(keys)}
+	if result.IsTruncated {
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	for _, key := range keys[start:end] {
+		result.Contents = append(result.Contents, object{Key: key, Size: len(b.objects[key])})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,239 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v40/github"
+	"golang.org/x/oauth2"
+)
+
+const defaultHost = "github.com"
+
+// Config selects the GitHub host and how to authenticate with it.
+//
+// Set Token to use a personal access token, or AppID, AppInstallationID and AppPrivateKey
+// to use installation tokens of a GitHub App. Installation tokens expire after an hour;
+// clients returned by HTTPClient and Client request a new one when needed.
+type Config struct {
+	// Host is the host name (or URL) of a GitHub Enterprise Server instance.
+	// It defaults to github.com.
+	Host string
+
+	Token string
+
+	AppID             int64
+	AppInstallationID int64
+	// AppPrivateKey is the PEM encoded private key of the GitHub App.
+	AppPrivateKey string
+}
+
+// UsesApp reports whether any of the GitHub App fields are set.
+func (c Config) UsesApp() bool {
+	return c.AppID != 0 || c.AppInstallationID != 0 || c.AppPrivateKey != ""
+}
+
+// HostName returns the host name of the GitHub instance, for example "github.com".
+func (c Config) HostName() string {
+	if c.Host == "" {
+		return defaultHost
+	}
+	if u, err := url.Parse(c.Host); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strings.TrimSuffix(c.Host, "/")
+}
+
+// IsEnterprise reports whether the configuration names a GitHub Enterprise Server instance.
+func (c Config) IsEnterprise() bool {
+	return c.HostName() != defaultHost
+}
+
+// APIURL returns the base URL of the REST API with a trailing slash.
+func (c Config) APIURL() string {
+	if !c.IsEnterprise() {
+		return "https://api.github.com/"
+	}
+	base := c.Host
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	base = strings.TrimSuffix(base, "/")
+	if !strings.HasSuffix(base, "/api/v3") {
+		base += "/api/v3"
+	}
+	return base + "/"
+}
+
+// UploadURL returns the base URL for uploads with a trailing slash.
+func (c Config) UploadURL() string {
+	if !c.IsEnterprise() {
+		return "https://uploads.github.com/"
+	}
+	return strings.TrimSuffix(c.APIURL(), "api/v3/") + "api/uploads/"
+}
+
+// TokenSource returns a token source that caches tokens until they expire.
+// It returns nil when no credentials are configured.
+func (c Config) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if !c.UsesApp() {
+		if c.Token == "" {
+			return nil, nil
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token}), nil
+	}
+	source, err := c.installationTokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(nil, source), nil
+}
+
+// HTTPClient returns a client that authenticates requests with the configured credentials.
+func (c Config) HTTPClient(ctx context.Context) (*http.Client, error) {
+	source, err := c.TokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return http.DefaultClient, nil
+	}
+	return oauth2.NewClient(ctx, source), nil
+}
+
+// Client returns a GitHub API client for the configured host.
+func (c Config) Client(ctx context.Context) (*github.Client, error) {
+	httpClient, err := c.HTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c, httpClient)
+}
+
+// NewClient returns a GitHub API client for the configured host that sends requests with httpClient.
+func NewClient(c Config, httpClient *http.Client) (*github.Client, error) {
+	if !c.IsEnterprise() {
+		return github.NewClient(httpClient), nil
+	}
+	return github.NewEnterpriseClient(c.APIURL(), c.UploadURL(), httpClient)
+}
+
+func (c Config) installationTokenSource(ctx context.Context) (*appInstallationTokenSource, error) {
+	if c.AppID == 0 || c.AppInstallationID == 0 || c.AppPrivateKey == "" {
+		return nil, errors.New("GitHub App authentication requires an app ID, an installation ID and a private key")
+	}
+	key, err := parseRSAPrivateKey([]byte(c.AppPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	return &appInstallationTokenSource{
+		ctx:            ctx,
+		apiURL:         c.APIURL(),
+		appID:          c.AppID,
+		installationID: c.AppInstallationID,
+		key:            key,
+		client:         http.DefaultClient,
+		now:            time.Now,
+	}, nil
+}
+
+// appInstallationTokenSource requests a new installation access token each time Token is called.
+// Wrap it with oauth2.ReuseTokenSource to only request a token when the previous one expired.
+type appInstallationTokenSource struct {
+	ctx            context.Context
+	apiURL         string
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *http.Client
+	now            func() time.Time
+}
+
+func (s *appInstallationTokenSource) Token() (*oauth2.Token, error) {
+	signed, err := s.jwt()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%sapp/installations/%d/access_tokens", s.apiURL, s.installationID)
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request GitHub App installation token: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to request GitHub App installation token: %s", res.Status)
+	}
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App installation token response: %w", err)
+	}
+	return &oauth2.Token{AccessToken: body.Token, TokenType: "token", Expiry: body.ExpiresAt}, nil
+}
+
+// jwt returns the token that authenticates as the app. GitHub accepts tokens that expire
+// within 10 minutes; the issued at time is set in the past to allow for clock drift.
+func (s *appInstallationTokenSource) jwt() (string, error) {
+	now := s.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parseRSAPrivateKey(buf []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("expected a PEM encoded key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("expected an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,135 @@
+package gh_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pivotal-cf/kiln/internal/gh"
+)
+
+func TestConfig_urls(t *testing.T) {
+	for _, tt := range []struct {
+		Name, Host, HostName, APIURL, UploadURL string
+	}{
+		{Name: "default", HostName: "github.com", APIURL: "https://api.github.com/", UploadURL: "https://uploads.github.com/"},
+		{Name: "enterprise host", Host: "github.example.com", HostName: "github.example.com", APIURL: "https://github.example.com/api/v3/", UploadURL: "https://github.example.com/api/uploads/"},
+		{Name: "enterprise url", Host: "https://github.example.com/", HostName: "github.example.com", APIURL: "https://github.example.com/api/v3/", UploadURL: "https://github.example.com/api/uploads/"},
+		{Name: "enterprise api url", Host: "https://github.example.com/api/v3", HostName: "github.example.com", APIURL: "https://github.example.com/api/v3/", UploadURL: "https://github.example.com/api/uploads/"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			c := gh.Config{Host: tt.Host}
+			assert.Equal(t, tt.HostName, c.HostName())
+			assert.Equal(t, tt.APIURL, c.APIURL())
+			assert.Equal(t, tt.UploadURL, c.UploadURL())
+		})
+	}
+}
+
+func TestConfig_Client_enterprise(t *testing.T) {
+	client, err := gh.Config{Host: "github.example.com", Token: "xxx"}.Client(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.example.com/api/v3/", client.BaseURL.String())
+}
+
+func TestConfig_TokenSource_app(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var (
+		requests  int
+		expiresIn time.Duration
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		claims, err := verifyJWT(&key.PublicKey, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || claims["iss"] != "7" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("installation-token-%d", requests),
+			"expires_at": time.Now().Add(expiresIn).UTC().Format(time.RFC3339),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	config := gh.Config{Host: server.URL, AppID: 7, AppInstallationID: 42, AppPrivateKey: string(keyPEM)}
+
+	t.Run("it reuses tokens until they expire", func(t *testing.T) {
+		requests, expiresIn = 0, time.Hour
+		source, err := config.TokenSource(context.Background())
+		require.NoError(t, err)
+
+		token, err := source.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "installation-token-1", token.AccessToken)
+		_, err = source.Token()
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("it requests a new token when the token expired", func(t *testing.T) {
+		requests, expiresIn = 0, time.Second
+		source, err := config.TokenSource(context.Background())
+		require.NoError(t, err)
+
+		_, err = source.Token()
+		require.NoError(t, err)
+		token, err := source.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "installation-token-2", token.AccessToken)
+	})
+
+	t.Run("the private key is invalid", func(t *testing.T) {
+		_, err := gh.Config{AppID: 7, AppInstallationID: 42, AppPrivateKey: "banana"}.TokenSource(context.Background())
+		require.ErrorContains(t, err, "private key")
+	})
+
+	t.Run("the installation is missing", func(t *testing.T) {
+		_, err := gh.Config{AppID: 7, AppPrivateKey: string(keyPEM)}.TokenSource(context.Background())
+		require.ErrorContains(t, err, "installation ID")
+	})
+}
+
+func verifyJWT(key *rsa.PublicKey, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, err
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	return claims, json.Unmarshal(buf, &claims)
+}
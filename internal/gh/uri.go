@@ -4,26 +4,43 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
 // RepositoryOwnerAndNameFromPath is from the github-release-source branch
 // once that one is merged we should that one instead of this one
 func RepositoryOwnerAndNameFromPath(urlStr string) (owner, repo string, err error) {
+	_, owner, repo, err = RepositoryHostOwnerAndNameFromPath(urlStr)
+	return owner, repo, err
+}
+
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@([\w.-]+):`)
+
+// RepositoryHostOwnerAndNameFromPath is like RepositoryOwnerAndNameFromPath and also returns
+// the host of the repository URL, for example "github.com". SSH URLs like
+// git@github.example.com:org/repo.git are supported.
+func RepositoryHostOwnerAndNameFromPath(urlStr string) (host, owner, repo string, err error) {
 	wrapError := func(urlStr string, err error) error {
 		return fmt.Errorf("failed to parse owner and repo name from URI %q: %w", urlStr, err)
 	}
-	urlStr = strings.TrimPrefix(urlStr, "git@github.com:")
+	if match := scpLikeURL.FindStringSubmatch(urlStr); match != nil {
+		host = match[1]
+		urlStr = strings.TrimPrefix(urlStr, match[0])
+	}
 	u, err := url.Parse(urlStr)
 	if err != nil {
-		return "", "", wrapError(urlStr, err)
+		return "", "", "", wrapError(urlStr, err)
+	}
+	if host == "" {
+		host = u.Host
 	}
 	if filepath.Ext(u.Path) == ".git" {
 		u.Path = strings.TrimSuffix(u.Path, ".git")
 	}
 	owner, repo, found := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
 	if !found || owner == "" || repo == "" {
-		return owner, repo, wrapError(urlStr, fmt.Errorf("path missing expected parts"))
+		return host, owner, repo, wrapError(urlStr, fmt.Errorf("path missing expected parts"))
 	}
-	return owner, repo, nil
+	return host, owner, repo, nil
 }
@@ -63,3 +63,20 @@ func Test_RepositoryOwnerAndNameFromPath(t *testing.T) {
 		})
 	}
 }
+
+func Test_RepositoryHostOwnerAndNameFromPath(t *testing.T) {
+	for _, tt := range []struct {
+		Name, URI, Host string
+	}{
+		{Name: "https url", URI: "https://github.example.com/platform/some-tile", Host: "github.example.com"},
+		{Name: "ssh url", URI: "git@github.example.com:platform/some-tile.git", Host: "github.example.com"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			host, owner, repo, err := gh.RepositoryHostOwnerAndNameFromPath(tt.URI)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Host, host)
+			assert.Equal(t, "platform", owner)
+			assert.Equal(t, "some-tile", repo)
+		})
+	}
+}
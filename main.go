@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/pivotal-cf/jhanda"
@@ -24,6 +27,7 @@ func main() {
 	var global struct {
 		Help    bool `short:"h" long:"help"    description:"prints this usage information"   default:"false"`
 		Version bool `short:"v" long:"version" description:"prints the kiln release version" default:"false"`
+		Offline bool `long:"offline" description:"only use releases in the releases directory, the release cache, and directory and catalog release sources" default:"false"`
 	}
 
 	args, err := jhanda.Parse(&global, os.Args[1:])
@@ -53,6 +57,24 @@ func main() {
 		command = "help"
 	}
 
+	// Cancel in-flight release source operations on SIGINT or SIGTERM.
+	// Once cancelled, the default signal behavior is restored so a second
+	// signal terminates kiln immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if global.Offline {
+		switch command {
+		case "publish", "release-notes", "find-stemcell-version":
+			log.Fatalf("kiln %s needs network access and can not be run with --offline", command)
+		}
+		ctx = component.ContextWithOffline(ctx)
+	}
+
 	fs := osfs.New("")
 
 	releaseManifestReader := builder.NewReleaseManifestReader()
@@ -65,6 +87,9 @@ func main() {
 	})
 	ruFinder := commands.ReleaseUploaderFinder(func(kilnfile cargo.Kilnfile, sourceID string) (component.ReleaseUploader, error) {
 		repo := component.NewReleaseSourceRepo(kilnfile, outLogger)
+		if src, err := repo.FindByID(sourceID); err == nil && global.Offline && component.NeedsNetwork(src.Configuration()) {
+			return nil, component.OfflineError(sourceID)
+		}
 		return repo.FindReleaseUploader(sourceID)
 	})
 	rpFinder := commands.RemotePatherFinder(func(kilnfile cargo.Kilnfile, sourceID string) (component.RemotePather, error) {
@@ -72,8 +97,13 @@ func main() {
 		return repo.FindRemotePather(sourceID)
 	})
 
+	var releaseCache commands.ReleaseCache
+	if releaseCacheDirectory, err := component.DefaultReleaseCacheDirectory(); err == nil {
+		releaseCache = component.NewReleaseCache(releaseCacheDirectory)
+	}
+
 	commandSet := jhanda.CommandSet{}
-	fetch := commands.NewFetch(outLogger, mrsProvider, localReleaseDirectory)
+	fetch := commands.NewFetch(ctx, outLogger, mrsProvider, localReleaseDirectory, releaseCache)
 	commandSet["fetch"] = fetch
 
 	bakeCommand := commands.NewBake(fs, releasesService, outLogger, errLogger, fetch)
@@ -84,31 +114,43 @@ func main() {
 	commandSet["test"] = commands.NewTileTest()
 	commandSet["help"] = commands.NewHelp(os.Stdout, globalFlagsUsage, commandSet)
 	commandSet["version"] = commands.NewVersion(outLogger, version)
-	commandSet["update-release"] = commands.NewUpdateRelease(outLogger, fs, mrsProvider)
+	commandSet["update-release"] = commands.NewUpdateRelease(ctx, outLogger, fs, mrsProvider)
+	commandSet["update-releases"] = commands.NewUpdateReleases(ctx, outLogger, fs, mrsProvider)
 	commandSet["upload-release"] = commands.UploadRelease{
+		Context:               ctx,
 		FS:                    fs,
 		Logger:                outLogger,
 		ReleaseUploaderFinder: ruFinder,
 	}
 	commandSet["sync-with-local"] = commands.NewSyncWithLocal(fs, localReleaseDirectory, rpFinder, outLogger)
+	commandSet["mirror"] = commands.NewMirror(ctx, outLogger, fs, mrsProvider, ruFinder)
+	commandSet["bundle"] = commands.NewBundle(ctx, outLogger, fs, localReleaseDirectory)
 	commandSet["publish"] = commands.NewPublish(outLogger, errLogger, osfs.New(""))
 
 	commandSet["update-stemcell"] = commands.UpdateStemcell{
+		Context:                    ctx,
 		Logger:                     outLogger,
 		MultiReleaseSourceProvider: mrsProvider,
 		FS:                         osfs.New(""),
 	}
 
-	// commandSet["fetch"] = commands.NewFetch(outLogger, mrsProvider, localReleaseDirectory)
+	// commandSet["fetch"] = commands.NewFetch(ctx, outLogger, mrsProvider, localReleaseDirectory, releaseCache)
 	commandSet["glaze"] = commands.NewGlaze()
 
 	commandSet["generate-osm-manifest"] = commands.NewOSM(outLogger, nil)
 
-	commandSet["find-release-version"] = commands.NewFindReleaseVersion(outLogger, mrsProvider)
+	commandSet["find-release-version"] = commands.NewFindReleaseVersion(ctx, outLogger, mrsProvider)
 
 	commandSet["find-stemcell-version"] = commands.NewFindStemcellVersion(outLogger, pivnetService)
 
-	commandSet["validate"] = commands.NewValidate(osfs.New(""))
+	commandSet["validate"] = commands.NewValidate(outLogger, osfs.New(""))
+	commandSet["schema"] = commands.NewSchema(outLogger)
+	commandSet["kilnfile"] = commands.NewKilnfile(outLogger, fs)
+	commandSet["cache"] = commands.NewCache(ctx, outLogger)
+	commandSet["lock"] = commands.NewLock(ctx, outLogger, fs, localReleaseDirectory, mrsProvider)
+	commandSet["release-sources"] = commands.NewReleaseSources(ctx, outLogger, fs, func(kilnfile cargo.Kilnfile) component.ReleaseSourceList {
+		return component.NewReleaseSourceRepo(kilnfile, outLogger)
+	})
 	commandSet["release-notes"], err = commands.NewReleaseNotesCommand()
 	if err != nil {
 		log.Fatal(err)
@@ -5,6 +5,7 @@ import (
 	"archive/zip"
 	"compress/gzip"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -117,6 +118,7 @@ type BOSHReleaseTarball struct {
 	Manifest BOSHReleaseManifest
 
 	SHA1     string
+	SHA256   string
 	FilePath string
 }
 
@@ -149,16 +151,17 @@ func OpenBOSHReleaseTarball(tarballPath string) (BOSHReleaseTarball, error) {
 }
 
 func ReadBOSHReleaseTarball(tarballPath string, r io.Reader) (BOSHReleaseTarball, error) {
-	sum := sha1.New()
-	r = io.TeeReader(r, sum)
+	sum1, sum256 := sha1.New(), sha256.New()
+	r = io.TeeReader(r, io.MultiWriter(sum1, sum256))
 	m, err := ReadProductTemplatePartFromBOSHReleaseTarball(r)
 	if err != nil {
 		return BOSHReleaseTarball{}, err
 	}
-	_, err = io.ReadAll(r)
+	_, err = io.Copy(io.Discard, r)
 	return BOSHReleaseTarball{
 		Manifest: m,
-		SHA1:     hex.EncodeToString(sum.Sum(nil)),
+		SHA1:     hex.EncodeToString(sum1.Sum(nil)),
+		SHA256:   hex.EncodeToString(sum256.Sum(nil)),
 		FilePath: tarballPath,
 	}, err
 }
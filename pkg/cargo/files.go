@@ -14,10 +14,28 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// InterpolateAndParseKilnfile interpolates and parses the Kilnfile. The Kilnfiles it extends
+// are merged into it (see RenderKilnfile); their paths are relative to the working directory.
 func InterpolateAndParseKilnfile(in io.Reader, templateVariables map[string]any) (Kilnfile, error) {
+	kilnfileYAML, err := InterpolateKilnfile(in, templateVariables)
+	if err != nil {
+		return Kilnfile{}, err
+	}
+	kilnfileYAML, err = renderKilnfileExtends("Kilnfile", kilnfileYAML, os.ReadFile, templateVariables)
+	if err != nil {
+		return Kilnfile{}, err
+	}
+
+	var kilnfile Kilnfile
+	return kilnfile, yaml.Unmarshal(kilnfileYAML, &kilnfile)
+}
+
+// InterpolateKilnfile replaces the $(variable "name") expressions in the Kilnfile and
+// returns the YAML.
+func InterpolateKilnfile(in io.Reader, templateVariables map[string]any) ([]byte, error) {
 	kilnfileYAML, err := io.ReadAll(in)
 	if err != nil {
-		return Kilnfile{}, fmt.Errorf("unable to read Kilnfile: %w", err)
+		return nil, fmt.Errorf("unable to read Kilnfile: %w", err)
 	}
 
 	kilnfileTemplate, err := template.New("Kilnfile").
@@ -28,16 +46,14 @@ func InterpolateAndParseKilnfile(in io.Reader, templateVariables map[string]any)
 		Option("missingkey=error").
 		Parse(string(kilnfileYAML))
 	if err != nil {
-		return Kilnfile{}, err
+		return nil, err
 	}
 
 	var buf bytes.Buffer
 	if err := kilnfileTemplate.Execute(&buf, struct{}{}); err != nil {
-		return Kilnfile{}, err
+		return nil, err
 	}
-
-	var kilnfile Kilnfile
-	return kilnfile, yaml.Unmarshal(buf.Bytes(), &kilnfile)
+	return buf.Bytes(), nil
 }
 
 func variableTemplateFunction(templateVariables map[string]any) func(name string) (string, error) {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -17,6 +18,9 @@ const (
 )
 
 type Kilnfile struct {
+	// Extends lists Kilnfiles this Kilnfile is merged with. See RenderKilnfile.
+	Extends []string `yaml:"extends,omitempty"`
+
 	ReleaseSources     []ReleaseSourceConfig             `yaml:"release_sources,omitempty"`
 	Slug               string                            `yaml:"slug,omitempty"`
 	PreGaUserGroups    []string                          `yaml:"pre_ga_user_groups,omitempty"`
@@ -24,6 +28,7 @@ type Kilnfile struct {
 	TileNames          []string                          `yaml:"tile_names,omitempty"`
 	Stemcell           Stemcell                          `yaml:"stemcell_criteria,omitempty"`
 	BakeConfigurations []BakeConfiguration               `yaml:"bake_configurations"`
+	Validation         ValidationConfiguration           `yaml:"validation,omitempty"`
 }
 
 func (kf *Kilnfile) BOSHReleaseTarballSpecification(name string) (BOSHReleaseTarballSpecification, error) {
@@ -98,6 +103,10 @@ type BOSHReleaseTarballSpecification struct {
 	// See https://github.com/Masterminds/semver for syntax
 	Version string `yaml:"version,omitempty"`
 
+	// Channel limits the versions the release may resolve to: "stable", "rc", or "dev".
+	// See ReleaseChannel.
+	Channel ReleaseChannel `yaml:"channel,omitempty"`
+
 	// StemcellOS may be set when a specifying a component
 	// compiled with a particular stemcell. Usually you should
 	// also set StemcellVersion when setting this field.
@@ -111,6 +120,14 @@ type BOSHReleaseTarballSpecification struct {
 	// GitHubRepository are where the BOSH release source code is
 	GitHubRepository string `yaml:"github_repository,omitempty"`
 
+	// ReleaseSource pins the release to the release source with this ID.
+	// Other release sources are not consulted when finding the release.
+	ReleaseSource string `yaml:"release_source,omitempty"`
+
+	// ReleaseSources pins the release to the release sources with these IDs
+	// and consults them in this order. Set either ReleaseSource or ReleaseSources.
+	ReleaseSources []string `yaml:"release_sources,omitempty"`
+
 	// DeGlazeBehavior changes how version filed changes when de-glaze is run.
 	DeGlazeBehavior DeGlazeBehavior `yaml:"maintenance_version_bump_policy"`
 
@@ -122,6 +139,18 @@ type BOSHReleaseTarballSpecification struct {
 	TeamSlackChannel string `yaml:"slack,omitempty"`
 }
 
+// ReleaseSourceIDs returns the IDs of the release sources the release is pinned to
+// in the order they should be consulted. It returns nil when the release is not pinned.
+func (spec BOSHReleaseTarballSpecification) ReleaseSourceIDs() []string {
+	if len(spec.ReleaseSources) > 0 {
+		return spec.ReleaseSources
+	}
+	if spec.ReleaseSource != "" {
+		return []string{spec.ReleaseSource}
+	}
+	return nil
+}
+
 func (spec BOSHReleaseTarballSpecification) VersionConstraints() (*semver.Constraints, error) {
 	if spec.Version == "" {
 		spec.Version = unconstrainedVersion
@@ -151,22 +180,85 @@ func (spec BOSHReleaseTarballSpecification) ReleaseSlug() boshdir.ReleaseSlug {
 }
 
 type ReleaseSourceConfig struct {
-	Type            string `yaml:"type,omitempty"`
-	ID              string `yaml:"id,omitempty"`
-	Publishable     bool   `yaml:"publishable,omitempty"`
-	Bucket          string `yaml:"bucket,omitempty"`
-	Region          string `yaml:"region,omitempty"`
-	AccessKeyId     string `yaml:"access_key_id,omitempty"`
-	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
-	RoleARN         string `yaml:"role_arn,omitempty"`
-	PathTemplate    string `yaml:"path_template,omitempty"`
-	Endpoint        string `yaml:"endpoint,omitempty"`
-	Org             string `yaml:"org,omitempty"`
-	GithubToken     string `yaml:"github_token,omitempty"`
-	Repo            string `yaml:"repo,omitempty"`
-	ArtifactoryHost string `yaml:"artifactory_host,omitempty"`
-	Username        string `yaml:"username,omitempty"`
-	Password        string `yaml:"password,omitempty"`
+	Type                    string `yaml:"type,omitempty"`
+	ID                      string `yaml:"id,omitempty"`
+	Publishable             bool   `yaml:"publishable,omitempty"`
+	Bucket                  string `yaml:"bucket,omitempty"`
+	Region                  string `yaml:"region,omitempty"`
+	AccessKeyId             string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey         string `yaml:"secret_access_key,omitempty"`
+	RoleARN                 string `yaml:"role_arn,omitempty"`
+	PathTemplate            string `yaml:"path_template,omitempty"`
+	Endpoint                string `yaml:"endpoint,omitempty"`
+	Org                     string `yaml:"org,omitempty"`
+	GithubToken             string `yaml:"github_token,omitempty"`
+	GithubHost              string `yaml:"github_host,omitempty"`
+	GithubAppID             int64  `yaml:"github_app_id,omitempty"`
+	GithubAppInstallationID int64  `yaml:"github_app_installation_id,omitempty"`
+	GithubAppPrivateKey     string `yaml:"github_app_private_key,omitempty"`
+	Repo                    string `yaml:"repo,omitempty"`
+	ArtifactoryHost         string `yaml:"artifactory_host,omitempty"`
+	Username                string `yaml:"username,omitempty"`
+	Password                string `yaml:"password,omitempty"`
+	Registry                string `yaml:"registry,omitempty"`
+	Root                    string `yaml:"root,omitempty"`
+	IndexURL                string `yaml:"index_url,omitempty"`
+	Token                   string `yaml:"token,omitempty"`
+	Catalog                 string `yaml:"catalog,omitempty"`
+	ServiceAccountKey       string `yaml:"service_account_key,omitempty"`
+	Container               string `yaml:"container,omitempty"`
+	ConnectionString        string `yaml:"connection_string,omitempty"`
+	UploadPartSizeMB        int    `yaml:"upload_part_size_mb,omitempty"`
+	UploadConcurrency       int    `yaml:"upload_concurrency,omitempty"`
+
+	Retry RetryConfig `yaml:"retry,omitempty"`
+}
+
+// SecretFields returns pointers to the fields of the configuration that hold secrets,
+// keyed by their YAML names.
+func (c *ReleaseSourceConfig) SecretFields() map[string]*string {
+	return map[string]*string{
+		"access_key_id":          &c.AccessKeyId,
+		"secret_access_key":      &c.SecretAccessKey,
+		"github_token":           &c.GithubToken,
+		"github_app_private_key": &c.GithubAppPrivateKey,
+		"username":               &c.Username,
+		"password":               &c.Password,
+		"token":                  &c.Token,
+		"service_account_key":    &c.ServiceAccountKey,
+		"connection_string":      &c.ConnectionString,
+	}
+}
+
+// Redacted returns a copy of the configuration with the secret fields that are set replaced.
+func (c ReleaseSourceConfig) Redacted() ReleaseSourceConfig {
+	for _, field := range c.SecretFields() {
+		if *field != "" {
+			*field = "REDACTED"
+		}
+	}
+	return c
+}
+
+// String formats the configuration without its secrets so it is safe to log.
+func (c ReleaseSourceConfig) String() string {
+	type withoutMethods ReleaseSourceConfig
+	return fmt.Sprintf("%+v", withoutMethods(c.Redacted()))
+}
+
+// GoString is like String for the %#v verb.
+func (c ReleaseSourceConfig) GoString() string {
+	type withoutMethods ReleaseSourceConfig
+	return fmt.Sprintf("%#v", withoutMethods(c.Redacted()))
+}
+
+// RetryConfig configures how a release source retries downloads that fail with transient
+// network or server errors. Zero values use the defaults; set MaxRetries to -1 to disable
+// retries.
+type RetryConfig struct {
+	MaxRetries     int           `yaml:"max_retries,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
 }
 
 // BOSHReleaseTarballLock represents an exact build of a bosh release
@@ -176,8 +268,10 @@ type ReleaseSourceConfig struct {
 // All fields must be comparable because this struct may be
 // used as a key type in a map. Don't add array or map fields.
 type BOSHReleaseTarballLock struct {
-	Name    string `yaml:"name"`
-	SHA1    string `yaml:"sha1"`
+	Name string `yaml:"name"`
+	SHA1 string `yaml:"sha1"`
+	// SHA256 is optional; locks written before it was added only have SHA1.
+	SHA256  string `yaml:"sha256,omitempty"`
 	Version string `yaml:"version,omitempty"`
 
 	StemcellOS      string `yaml:"-"`
@@ -233,6 +327,11 @@ func (lock BOSHReleaseTarballLock) WithSHA1(sum string) BOSHReleaseTarballLock {
 	return lock
 }
 
+func (lock BOSHReleaseTarballLock) WithSHA256(sum string) BOSHReleaseTarballLock {
+	lock.SHA256 = sum
+	return lock
+}
+
 func (lock BOSHReleaseTarballLock) WithRemote(source, path string) BOSHReleaseTarballLock {
 	lock.RemoteSource = source
 	lock.RemotePath = path
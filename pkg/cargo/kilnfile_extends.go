@@ -0,0 +1,207 @@
+package cargo
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const kilnfileExtendsKey = "extends"
+
+// RenderKilnfile interpolates the Kilnfile at path and merges it with the Kilnfiles it
+// extends. The extended Kilnfiles are read with readFile and interpolated with the same
+// variables. Their paths are relative to the Kilnfile that lists them.
+//
+// The Kilnfiles in extends are merged in order and the Kilnfile at path is merged last,
+// so later files override earlier ones. Releases with the same name and release sources
+// with the same ID are merged field by field, other mappings are merged by key, and
+// lists and scalar values are replaced.
+//
+// When the Kilnfile does not extend other Kilnfiles the interpolated YAML is returned
+// unchanged.
+func RenderKilnfile(path string, readFile func(name string) ([]byte, error), templateVariables map[string]any) ([]byte, error) {
+	buf, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kilnfile: %w", err)
+	}
+	kilnfileYAML, err := InterpolateKilnfile(bytes.NewReader(buf), templateVariables)
+	if err != nil {
+		return nil, err
+	}
+	return renderKilnfileExtends(path, kilnfileYAML, readFile, templateVariables)
+}
+
+func renderKilnfileExtends(path string, kilnfileYAML []byte, readFile func(name string) ([]byte, error), templateVariables map[string]any) ([]byte, error) {
+	var extends struct {
+		Extends []string `yaml:"extends"`
+	}
+	if err := yaml.Unmarshal(kilnfileYAML, &extends); err != nil || len(extends.Extends) == 0 {
+		return kilnfileYAML, nil // parse errors are reported when the Kilnfile is decoded
+	}
+	node, err := extendedKilnfileNode(path, kilnfileYAML, readFile, templateVariables, []string{filepath.Clean(path)})
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	e := yaml.NewEncoder(&out)
+	e.SetIndent(2)
+	if err := e.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// extendedKilnfileNode returns the mapping node of the Kilnfile merged with the Kilnfiles
+// it extends. Stack holds the paths of the Kilnfiles being merged to detect cycles.
+func extendedKilnfileNode(path string, kilnfileYAML []byte, readFile func(name string) ([]byte, error), templateVariables map[string]any, stack []string) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(kilnfileYAML, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(document.Content) > 0 {
+		node = document.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse %s: expected a mapping", path)
+	}
+
+	var extends []string
+	if index := mappingKeyIndex(node, kilnfileExtendsKey); index >= 0 {
+		if err := node.Content[index+1].Decode(&extends); err != nil {
+			return nil, fmt.Errorf("failed to parse extends in %s: %w", path, err)
+		}
+		node.Content = slices.Delete(slices.Clone(node.Content), index, index+2)
+	}
+
+	var merged *yaml.Node
+	for _, name := range extends {
+		extendedPath := name
+		if !filepath.IsAbs(extendedPath) {
+			extendedPath = filepath.Join(filepath.Dir(path), extendedPath)
+		}
+		extendedPath = filepath.Clean(extendedPath)
+		if slices.Contains(stack, extendedPath) {
+			return nil, fmt.Errorf("extends cycle in Kilnfiles: %s", strings.Join(append(stack, extendedPath), " -> "))
+		}
+		buf, err := readFile(extendedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kilnfile %s extended by %s: %w", extendedPath, path, err)
+		}
+		extendedYAML, err := InterpolateKilnfile(bytes.NewReader(buf), templateVariables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpolate Kilnfile %s: %w", extendedPath, err)
+		}
+		extended, err := extendedKilnfileNode(extendedPath, extendedYAML, readFile, templateVariables, append(slices.Clone(stack), extendedPath))
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeKilnfileNodes(merged, extended)
+	}
+	return mergeKilnfileNodes(merged, node), nil
+}
+
+// kilnfileListKeys returns the key used to match the elements of the top level Kilnfile
+// lists merged element by element.
+var kilnfileListKeys = map[string]func(*yaml.Node) string{
+	"releases": func(node *yaml.Node) string {
+		var release struct {
+			Name string `yaml:"name"`
+		}
+		_ = node.Decode(&release)
+		return release.Name
+	},
+	"release_sources": func(node *yaml.Node) string {
+		var config ReleaseSourceConfig
+		if err := node.Decode(&config); err != nil {
+			return ""
+		}
+		return BOSHReleaseTarballSourceID(config)
+	},
+}
+
+func mergeKilnfileNodes(base, override *yaml.Node) *yaml.Node {
+	if base == nil {
+		return override
+	}
+	result := mergeMappingNodes(base, override)
+	for i := 0; i+1 < len(result.Content); i += 2 {
+		key := result.Content[i].Value
+		keyOf, ok := kilnfileListKeys[key]
+		if !ok {
+			continue
+		}
+		baseValue, overrideValue := mappingValue(base, key), mappingValue(override, key)
+		if baseValue != nil && overrideValue != nil && baseValue.Kind == yaml.SequenceNode && overrideValue.Kind == yaml.SequenceNode {
+			result.Content[i+1] = mergeSequenceNodes(baseValue, overrideValue, keyOf)
+		}
+	}
+	return result
+}
+
+// mergeMappingNodes returns a mapping with the keys of base and override. Values of keys
+// in both are merged when they are both mappings and taken from override otherwise.
+func mergeMappingNodes(base, override *yaml.Node) *yaml.Node {
+	result := *base
+	result.Content = slices.Clone(base.Content)
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		index := mappingKeyIndex(&result, key.Value)
+		if index < 0 {
+			result.Content = append(result.Content, key, value)
+			continue
+		}
+		if existing := result.Content[index+1]; existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			result.Content[index+1] = mergeMappingNodes(existing, value)
+		} else {
+			result.Content[index+1] = value
+		}
+	}
+	return &result
+}
+
+// mergeSequenceNodes merges the elements of override into base. Elements with the same
+// key are merged in place and the others are appended.
+func mergeSequenceNodes(base, override *yaml.Node, keyOf func(*yaml.Node) string) *yaml.Node {
+	result := *base
+	result.Content = slices.Clone(base.Content)
+	for _, element := range override.Content {
+		key := keyOf(element)
+		index := -1
+		if key != "" {
+			index = slices.IndexFunc(result.Content, func(node *yaml.Node) bool { return keyOf(node) == key })
+		}
+		if index < 0 {
+			result.Content = append(result.Content, element)
+		} else if existing := result.Content[index]; existing.Kind == yaml.MappingNode && element.Kind == yaml.MappingNode {
+			result.Content[index] = mergeMappingNodes(existing, element)
+		} else {
+			result.Content[index] = element
+		}
+	}
+	return &result
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if index := mappingKeyIndex(node, key); index >= 0 {
+		return node.Content[index+1]
+	}
+	return nil
+}
+
+// mappingKeyIndex returns the index of key in the Content of a mapping node or -1.
+func mappingKeyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,119 @@
+package cargo
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderKilnfile(t *testing.T) {
+	files := map[string]string{
+		filepath.Join("shared", "Kilnfile"): `---
+release_sources:
+  - type: bosh.io
+  - type: github
+    org: cloudfoundry
+    github_token: $(variable "github_token")
+releases:
+  - name: bpm
+    github_repository: https://github.com/cloudfoundry/bpm-release
+    version: ~1
+  - name: capi
+    version: ~2
+stemcell_criteria:
+  os: ubuntu-jammy
+  version: "1.*"
+`,
+		filepath.Join("shared", "Kilnfile.publishing"): `---
+release_sources:
+  - type: s3
+    id: internal-bucket
+    bucket: internal
+    publishable: true
+`,
+		filepath.Join("tile", "Kilnfile"): `---
+extends:
+  - ../shared/Kilnfile
+  - ../shared/Kilnfile.publishing
+slug: my-tile
+release_sources:
+  - type: github
+    org: cloudfoundry
+    publishable: true
+releases:
+  - name: bpm
+    version: ~1.2
+  - name: routing
+stemcell_criteria:
+  version: "1.123"
+`,
+		filepath.Join("cycle", "Kilnfile"):       "extends: [Kilnfile.other]\n",
+		filepath.Join("cycle", "Kilnfile.other"): "extends: [Kilnfile]\n",
+		"Kilnfile":                               "slug: $(variable \"slug\")\n",
+	}
+	readFile := func(name string) ([]byte, error) {
+		buf, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("open %s: %w", name, fs.ErrNotExist)
+		}
+		return []byte(buf), nil
+	}
+	variables := map[string]any{"github_token": "some-token", "slug": "some-slug"}
+
+	t.Run("merges extended Kilnfiles", func(t *testing.T) {
+		please := NewWithT(t)
+
+		buf, err := RenderKilnfile(filepath.Join("tile", "Kilnfile"), readFile, variables)
+		please.Expect(err).NotTo(HaveOccurred())
+
+		var kilnfile Kilnfile
+		please.Expect(yaml.Unmarshal(buf, &kilnfile)).To(Succeed())
+		please.Expect(kilnfile.Extends).To(BeEmpty())
+		please.Expect(kilnfile.Slug).To(Equal("my-tile"))
+		please.Expect(kilnfile.ReleaseSources).To(Equal([]ReleaseSourceConfig{
+			{Type: BOSHReleaseTarballSourceTypeBOSHIO},
+			{Type: BOSHReleaseTarballSourceTypeGithub, Org: "cloudfoundry", GithubToken: "some-token", Publishable: true},
+			{Type: BOSHReleaseTarballSourceTypeS3, ID: "internal-bucket", Bucket: "internal", Publishable: true},
+		}))
+		please.Expect(kilnfile.Releases).To(Equal([]BOSHReleaseTarballSpecification{
+			{Name: "bpm", GitHubRepository: "https://github.com/cloudfoundry/bpm-release", Version: "~1.2"},
+			{Name: "capi", Version: "~2"},
+			{Name: "routing"},
+		}))
+		please.Expect(kilnfile.Stemcell).To(Equal(Stemcell{OS: "ubuntu-jammy", Version: "1.123"}))
+	})
+
+	t.Run("returns the Kilnfile unchanged when it does not extend other Kilnfiles", func(t *testing.T) {
+		please := NewWithT(t)
+
+		buf, err := RenderKilnfile("Kilnfile", readFile, variables)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(buf)).To(Equal("slug: some-slug\n"))
+	})
+
+	t.Run("extends cycle", func(t *testing.T) {
+		please := NewWithT(t)
+
+		_, err := RenderKilnfile(filepath.Join("cycle", "Kilnfile"), readFile, variables)
+		please.Expect(err).To(MatchError(ContainSubstring("extends cycle in Kilnfiles")))
+	})
+
+	t.Run("missing extended Kilnfile", func(t *testing.T) {
+		please := NewWithT(t)
+		files["missing"] = "extends: [shared/Kilnfile.missing]\n"
+
+		_, err := RenderKilnfile("missing", readFile, variables)
+		please.Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	t.Run("variable missing in extended Kilnfile", func(t *testing.T) {
+		please := NewWithT(t)
+
+		_, err := RenderKilnfile(filepath.Join("tile", "Kilnfile"), readFile, map[string]any{})
+		please.Expect(err).To(MatchError(ContainSubstring(`could not find variable with key "github_token"`)))
+	})
+}
@@ -1,7 +1,9 @@
 package cargo
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +32,19 @@ remote_path: fake/path/to/fake-component-name
 	damnit.Expect(string(cl)).To(Equal(validBOSHReleaseTarballLockYaml))
 }
 
+func TestReleaseSourceConfig_retry_yaml(t *testing.T) {
+	t.Run("unmarshal", func(t *testing.T) {
+		var config ReleaseSourceConfig
+		require.NoError(t, yaml.Unmarshal([]byte("type: bosh.io\nretry:\n  max_retries: 5\n  initial_backoff: 2s\n  max_backoff: 1m\n"), &config))
+		assert.Equal(t, RetryConfig{MaxRetries: 5, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute}, config.Retry)
+	})
+	t.Run("omitted when not set", func(t *testing.T) {
+		buf, err := yaml.Marshal(ReleaseSourceConfig{Type: "bosh.io"})
+		require.NoError(t, err)
+		assert.Equal(t, "type: bosh.io\n", string(buf))
+	})
+}
+
 func TestKilnfileLock_UpdateBOSHReleaseTarballLockWithName(t *testing.T) {
 	type args struct {
 		name string
@@ -361,3 +376,33 @@ func Test_deGlazeBOSHReleaseTarballSpecification(t *testing.T) {
 		})
 	}
 }
+
+func TestBOSHReleaseTarballLock_sha256_yaml(t *testing.T) {
+	t.Run("written after sha1", func(t *testing.T) {
+		buf, err := yaml.Marshal(BOSHReleaseTarballLock{Name: "banana", SHA1: "some-sha1", SHA256: "some-sha256", Version: "1.2.3"})
+		require.NoError(t, err)
+		assert.Equal(t, "name: banana\nsha1: some-sha1\nsha256: some-sha256\nversion: 1.2.3\nremote_source: \"\"\nremote_path: \"\"\n", string(buf))
+	})
+	t.Run("omitted when not set", func(t *testing.T) {
+		buf, err := yaml.Marshal(BOSHReleaseTarballLock{Name: "banana", SHA1: "some-sha1", Version: "1.2.3"})
+		require.NoError(t, err)
+		assert.Equal(t, "name: banana\nsha1: some-sha1\nversion: 1.2.3\nremote_source: \"\"\nremote_path: \"\"\n", string(buf))
+	})
+}
+
+func TestReleaseSourceConfig_String(t *testing.T) {
+	config := ReleaseSourceConfig{
+		Type:            BOSHReleaseTarballSourceTypeS3,
+		Bucket:          "some-bucket",
+		AccessKeyId:     "some-access-key-id",
+		SecretAccessKey: "some-secret-access-key",
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		out := fmt.Sprintf(format, config)
+		assert.NotContains(t, out, "some-access-key-id", format)
+		assert.NotContains(t, out, "some-secret-access-key", format)
+		assert.Contains(t, out, "some-bucket", format)
+		assert.Contains(t, out, "REDACTED", format)
+	}
+	assert.Equal(t, "some-secret-access-key", config.SecretAccessKey, "it does not modify the configuration")
+}
@@ -0,0 +1,130 @@
+package cargo
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// The kinds of release changes in a KilnfileLockDiff.
+const (
+	ReleaseAdded      = "added"
+	ReleaseRemoved    = "removed"
+	ReleaseMajor      = "major"
+	ReleaseMinor      = "minor"
+	ReleasePatch      = "patch"
+	ReleasePrerelease = "prerelease"
+	ReleaseDowngrade  = "downgrade"
+	// ReleaseVersionChanged is a version change where one of the versions is not semantic
+	// or only the build metadata changed.
+	ReleaseVersionChanged = "changed"
+	// ReleaseUnchanged is a release with the same version and a different source or checksum.
+	ReleaseUnchanged = "unchanged"
+)
+
+// KilnfileLockDiff is the difference between two Kilnfile.lock files.
+type KilnfileLockDiff struct {
+	Releases []ReleaseDiff `json:"releases"`
+	Stemcell *StemcellDiff `json:"stemcell,omitempty"`
+}
+
+// ReleaseDiff is how a release changed between two Kilnfile.lock files.
+type ReleaseDiff struct {
+	Name string `json:"name"`
+
+	// Change is one of ReleaseAdded, ReleaseRemoved, ReleaseMajor, ReleaseMinor, ReleasePatch,
+	// ReleasePrerelease, ReleaseDowngrade, ReleaseVersionChanged, or ReleaseUnchanged.
+	Change string `json:"change"`
+
+	FromVersion string `json:"from_version,omitempty"`
+	ToVersion   string `json:"to_version,omitempty"`
+
+	// FromSource and ToSource are set when the remote_source of the release changed.
+	FromSource string `json:"from_source,omitempty"`
+	ToSource   string `json:"to_source,omitempty"`
+
+	// ChecksumChanged is true when the release has the same version and a different
+	// SHA1 or SHA256. The tarball was rebuilt or replaced and should be checked.
+	ChecksumChanged bool `json:"checksum_changed,omitempty"`
+}
+
+// SourceChanged returns true when the release comes from a different release source.
+func (diff ReleaseDiff) SourceChanged() bool { return diff.FromSource != diff.ToSource }
+
+// StemcellDiff is how the stemcell criteria changed between two Kilnfile.lock files.
+type StemcellDiff struct {
+	From Stemcell `json:"from"`
+	To   Stemcell `json:"to"`
+}
+
+// Empty returns true when the Kilnfile.lock files have the same releases and stemcell.
+func (diff KilnfileLockDiff) Empty() bool {
+	return len(diff.Releases) == 0 && diff.Stemcell == nil
+}
+
+// DiffKilnfileLocks returns the releases added, removed, and changed from one Kilnfile.lock
+// to another sorted by name, and the stemcell change. Sources and checksums are only
+// compared when they are set in both files.
+func DiffKilnfileLocks(from, to KilnfileLock) KilnfileLockDiff {
+	var result KilnfileLockDiff
+
+	bumps := CalculateBumps(to.Releases, from.Releases)
+	for _, release := range to.Releases {
+		previous, err := from.FindBOSHReleaseWithName(release.Name)
+		if err != nil {
+			result.Releases = append(result.Releases, ReleaseDiff{Name: release.Name, Change: ReleaseAdded, ToVersion: release.Version})
+			continue
+		}
+		diff := ReleaseDiff{Name: release.Name, Change: ReleaseUnchanged, FromVersion: previous.Version, ToVersion: release.Version}
+		if index := slices.IndexFunc(bumps, func(bump Bump) bool { return bump.Name == release.Name }); index >= 0 {
+			diff.Change = classifyBump(bumps[index])
+		} else {
+			diff.ChecksumChanged = checksumChanged(previous.SHA1, release.SHA1) || checksumChanged(previous.SHA256, release.SHA256)
+		}
+		if previous.RemoteSource != "" && release.RemoteSource != "" && previous.RemoteSource != release.RemoteSource {
+			diff.FromSource, diff.ToSource = previous.RemoteSource, release.RemoteSource
+		}
+		if diff.Change == ReleaseUnchanged && !diff.ChecksumChanged && !diff.SourceChanged() {
+			continue
+		}
+		result.Releases = append(result.Releases, diff)
+	}
+	for _, release := range from.Releases {
+		if _, err := to.FindBOSHReleaseWithName(release.Name); err != nil {
+			result.Releases = append(result.Releases, ReleaseDiff{Name: release.Name, Change: ReleaseRemoved, FromVersion: release.Version})
+		}
+	}
+	slices.SortStableFunc(result.Releases, func(a, b ReleaseDiff) int { return strings.Compare(a.Name, b.Name) })
+
+	if from.Stemcell.OS != to.Stemcell.OS || from.Stemcell.Version != to.Stemcell.Version {
+		result.Stemcell = &StemcellDiff{From: from.Stemcell, To: to.Stemcell}
+	}
+
+	return result
+}
+
+func classifyBump(bump Bump) string {
+	from, fromErr := semver.NewVersion(bump.FromVersion)
+	to, toErr := semver.NewVersion(bump.ToVersion)
+	switch {
+	case fromErr != nil || toErr != nil:
+		return ReleaseVersionChanged
+	case to.LessThan(from):
+		return ReleaseDowngrade
+	case to.Major() != from.Major():
+		return ReleaseMajor
+	case to.Minor() != from.Minor():
+		return ReleaseMinor
+	case to.Patch() != from.Patch():
+		return ReleasePatch
+	case to.Prerelease() != from.Prerelease():
+		return ReleasePrerelease
+	default:
+		return ReleaseVersionChanged
+	}
+}
+
+func checksumChanged(from, to string) bool {
+	return from != "" && to != "" && from != to
+}
@@ -0,0 +1,77 @@
+package cargo
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDiffKilnfileLocks(t *testing.T) {
+	from := KilnfileLock{
+		Releases: []BOSHReleaseTarballLock{
+			{Name: "bpm", Version: "1.2.3", SHA1: "bpm-sha1", RemoteSource: "bosh.io"},
+			{Name: "capi", Version: "1.2.3", SHA1: "capi-sha1"},
+			{Name: "diego", Version: "2.0.0", SHA1: "diego-sha1", RemoteSource: "bosh.io"},
+			{Name: "garden", Version: "1.9.0", SHA1: "garden-sha1"},
+			{Name: "routing", Version: "0.200.0", SHA1: "routing-sha1"},
+			{Name: "loggregator", Version: "7.0.0", SHA1: "loggregator-sha1"},
+			{Name: "nats", Version: "2.0.0-rc.1", SHA1: "nats-sha1"},
+			{Name: "uaa", Version: "77", SHA1: "uaa-sha1"},
+			{Name: "removed", Version: "1.0.0"},
+		},
+		Stemcell: Stemcell{OS: "ubuntu-jammy", Version: "1.100"},
+	}
+	to := KilnfileLock{
+		Releases: []BOSHReleaseTarballLock{
+			{Name: "added", Version: "1.0.0"},
+			{Name: "bpm", Version: "1.2.3", SHA1: "bpm-sha1", RemoteSource: "bosh.io"},
+			{Name: "capi", Version: "1.2.4", SHA1: "other-sha1"},
+			{Name: "diego", Version: "2.0.0", SHA1: "other-sha1", RemoteSource: "artifactory"},
+			{Name: "garden", Version: "1.10.0", SHA1: "garden-sha1"},
+			{Name: "routing", Version: "1.0.0", SHA1: "routing-sha1"},
+			{Name: "loggregator", Version: "6.9.0", SHA1: "loggregator-sha1"},
+			{Name: "nats", Version: "2.0.0", SHA1: "nats-sha1"},
+			{Name: "uaa", Version: "78", SHA1: "uaa-sha1"},
+		},
+		Stemcell: Stemcell{OS: "ubuntu-jammy", Version: "1.123"},
+	}
+
+	t.Run("releases and stemcell", func(t *testing.T) {
+		please := NewWithT(t)
+
+		diff := DiffKilnfileLocks(from, to)
+		please.Expect(diff.Empty()).To(BeFalse())
+		please.Expect(diff.Releases).To(Equal([]ReleaseDiff{
+			{Name: "added", Change: ReleaseAdded, ToVersion: "1.0.0"},
+			{Name: "capi", Change: ReleasePatch, FromVersion: "1.2.3", ToVersion: "1.2.4"},
+			{Name: "diego", Change: ReleaseUnchanged, FromVersion: "2.0.0", ToVersion: "2.0.0", FromSource: "bosh.io", ToSource: "artifactory", ChecksumChanged: true},
+			{Name: "garden", Change: ReleaseMinor, FromVersion: "1.9.0", ToVersion: "1.10.0"},
+			{Name: "loggregator", Change: ReleaseDowngrade, FromVersion: "7.0.0", ToVersion: "6.9.0"},
+			{Name: "nats", Change: ReleasePrerelease, FromVersion: "2.0.0-rc.1", ToVersion: "2.0.0"},
+			{Name: "removed", Change: ReleaseRemoved, FromVersion: "1.0.0"},
+			{Name: "routing", Change: ReleaseMajor, FromVersion: "0.200.0", ToVersion: "1.0.0"},
+			{Name: "uaa", Change: ReleaseMajor, FromVersion: "77", ToVersion: "78"},
+		}))
+		please.Expect(diff.Stemcell).To(Equal(&StemcellDiff{
+			From: Stemcell{OS: "ubuntu-jammy", Version: "1.100"},
+			To:   Stemcell{OS: "ubuntu-jammy", Version: "1.123"},
+		}))
+	})
+
+	t.Run("same lock", func(t *testing.T) {
+		please := NewWithT(t)
+
+		diff := DiffKilnfileLocks(from, from)
+		please.Expect(diff.Empty()).To(BeTrue())
+	})
+
+	t.Run("missing checksums and sources", func(t *testing.T) {
+		please := NewWithT(t)
+
+		diff := DiffKilnfileLocks(
+			KilnfileLock{Releases: []BOSHReleaseTarballLock{{Name: "bpm", Version: "1.2.3", SHA1: "bpm-sha1", RemoteSource: "bosh.io"}}},
+			KilnfileLock{Releases: []BOSHReleaseTarballLock{{Name: "bpm", Version: "1.2.3"}}},
+		)
+		please.Expect(diff.Empty()).To(BeTrue())
+	})
+}
@@ -0,0 +1,122 @@
+package cargo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ReleaseChannel limits the versions a release may resolve to. Versions with a
+// prerelease part, like 1.2.0-rc.1 or 1.2.0-dev.3, are prereleases.
+//
+// When a release does not set a channel, prereleases are only considered when
+// the version constraint names one (see https://github.com/Masterminds/semver#working-with-prerelease-versions).
+type ReleaseChannel string
+
+const (
+	// ReleaseChannelStable only allows versions without a prerelease part.
+	ReleaseChannelStable ReleaseChannel = "stable"
+
+	// ReleaseChannelRC allows stable versions and release candidates:
+	// prereleases starting with "rc", like 1.2.0-rc.1.
+	ReleaseChannelRC ReleaseChannel = "rc"
+
+	// ReleaseChannelDev allows every version, including development builds.
+	ReleaseChannelDev ReleaseChannel = "dev"
+)
+
+func (channel ReleaseChannel) Validate() error {
+	switch channel {
+	case "", ReleaseChannelStable, ReleaseChannelRC, ReleaseChannelDev:
+		return nil
+	default:
+		return fmt.Errorf("unknown channel %q (expected %q, %q, or %q)", string(channel), ReleaseChannelStable, ReleaseChannelRC, ReleaseChannelDev)
+	}
+}
+
+func (channel *ReleaseChannel) UnmarshalText(text []byte) error {
+	c := ReleaseChannel(text)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	*channel = c
+	return nil
+}
+
+// Allows reports whether the channel allows version regardless of any version constraint.
+// Every version is allowed when the channel is not set.
+func (channel ReleaseChannel) Allows(version *semver.Version) bool {
+	return channel.allowsPrerelease(version.Prerelease())
+}
+
+// AllowsGitHubRelease reports whether a GitHub release with the draft and prerelease
+// flags may be used. Drafts are only used on the dev channel and releases marked as
+// prereleases are not used on the stable channel. The flags are ignored when the channel
+// is not set.
+func (channel ReleaseChannel) AllowsGitHubRelease(draft, prerelease bool) bool {
+	switch channel {
+	case "", ReleaseChannelDev:
+		return true
+	case ReleaseChannelStable:
+		return !draft && !prerelease
+	default:
+		return !draft
+	}
+}
+
+func (channel ReleaseChannel) allowsPrerelease(prerelease string) bool {
+	if prerelease == "" {
+		return true
+	}
+	switch channel {
+	case "", ReleaseChannelDev:
+		return true
+	case ReleaseChannelRC:
+		return strings.HasPrefix(strings.ToLower(prerelease), "rc")
+	default:
+		return false
+	}
+}
+
+// VersionMatcher checks versions against the version constraint and the channel of a release.
+type VersionMatcher struct {
+	Constraints *semver.Constraints
+	Channel     ReleaseChannel
+}
+
+// VersionMatcher returns a VersionMatcher for the Version and Channel fields.
+// Release sources use it to find versions so every source resolves channels the same way.
+func (spec BOSHReleaseTarballSpecification) VersionMatcher() (VersionMatcher, error) {
+	if err := spec.Channel.Validate(); err != nil {
+		return VersionMatcher{}, err
+	}
+	c, err := spec.VersionConstraints()
+	if err != nil {
+		return VersionMatcher{}, err
+	}
+	return VersionMatcher{Constraints: c, Channel: spec.Channel}, nil
+}
+
+// Check reports whether version is allowed by the channel and satisfies the constraint.
+// When the channel allows a prerelease it is checked as the version it precedes, so on the
+// rc channel 1.3.0-rc.1 satisfies "~1.3" even though the constraint does not name a prerelease.
+func (m VersionMatcher) Check(version *semver.Version) bool {
+	if version == nil {
+		return false
+	}
+	if version.Prerelease() == "" || m.Channel == "" {
+		return m.Constraints.Check(version)
+	}
+	if !m.Channel.Allows(version) {
+		return false
+	}
+	if m.Constraints.Check(version) {
+		return true
+	}
+	release, err := version.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+	return m.Constraints.Check(&release)
+}
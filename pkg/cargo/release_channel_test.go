@@ -0,0 +1,99 @@
+package cargo_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestVersionMatcher_Check(t *testing.T) {
+	for _, tt := range []struct {
+		Name       string
+		Constraint string
+		Channel    cargo.ReleaseChannel
+		Allowed    []string
+		Rejected   []string
+	}{
+		{
+			Name:       "no channel uses semver prerelease rules",
+			Constraint: "~1.2",
+			Allowed:    []string{"1.2.0", "1.2.9"},
+			Rejected:   []string{"1.2.1-rc.1", "1.2.1-dev.2", "1.3.0"},
+		},
+		{
+			Name:       "no channel with a prerelease constraint",
+			Constraint: ">=1.2.0-rc.1",
+			Allowed:    []string{"1.2.0-rc.1", "1.2.0-rc.2", "1.2.0"},
+			Rejected:   []string{"1.2.0-dev.1"},
+		},
+		{
+			Name:       "stable",
+			Constraint: ">=1.2.0-rc.1",
+			Channel:    cargo.ReleaseChannelStable,
+			Allowed:    []string{"1.2.0", "2.0.0"},
+			Rejected:   []string{"1.2.0-rc.1", "1.2.1-rc.2", "1.2.1-dev.1"},
+		},
+		{
+			Name:       "rc",
+			Constraint: "~1.2",
+			Channel:    cargo.ReleaseChannelRC,
+			Allowed:    []string{"1.2.0", "1.2.1-rc.1", "1.2.1-RC2"},
+			Rejected:   []string{"1.2.1-dev.1", "1.2.1-alpha", "1.3.0-rc.1"},
+		},
+		{
+			Name:       "dev",
+			Constraint: "~1.2",
+			Channel:    cargo.ReleaseChannelDev,
+			Allowed:    []string{"1.2.0", "1.2.1-rc.1", "1.2.1-dev.1", "1.2.1-alpha"},
+			Rejected:   []string{"1.3.0-dev.1", "1.1.0"},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			please := NewWithT(t)
+			matcher, err := cargo.BOSHReleaseTarballSpecification{Name: "bpm", Version: tt.Constraint, Channel: tt.Channel}.VersionMatcher()
+			please.Expect(err).NotTo(HaveOccurred())
+			for _, v := range tt.Allowed {
+				please.Expect(matcher.Check(semver.MustParse(v))).To(BeTrue(), v)
+			}
+			for _, v := range tt.Rejected {
+				please.Expect(matcher.Check(semver.MustParse(v))).To(BeFalse(), v)
+			}
+		})
+	}
+}
+
+func TestVersionMatcher_unknown_channel(t *testing.T) {
+	please := NewWithT(t)
+	_, err := cargo.BOSHReleaseTarballSpecification{Name: "bpm", Channel: "nightly"}.VersionMatcher()
+	please.Expect(err).To(MatchError(ContainSubstring(`unknown channel "nightly"`)))
+}
+
+func TestReleaseChannel_AllowsGitHubRelease(t *testing.T) {
+	please := NewWithT(t)
+
+	please.Expect(cargo.ReleaseChannel("").AllowsGitHubRelease(true, true)).To(BeTrue())
+
+	please.Expect(cargo.ReleaseChannelStable.AllowsGitHubRelease(false, false)).To(BeTrue())
+	please.Expect(cargo.ReleaseChannelStable.AllowsGitHubRelease(false, true)).To(BeFalse())
+	please.Expect(cargo.ReleaseChannelStable.AllowsGitHubRelease(true, false)).To(BeFalse())
+
+	please.Expect(cargo.ReleaseChannelRC.AllowsGitHubRelease(false, true)).To(BeTrue())
+	please.Expect(cargo.ReleaseChannelRC.AllowsGitHubRelease(true, false)).To(BeFalse())
+
+	please.Expect(cargo.ReleaseChannelDev.AllowsGitHubRelease(true, true)).To(BeTrue())
+}
+
+func TestReleaseChannel_yaml(t *testing.T) {
+	please := NewWithT(t)
+
+	var spec cargo.BOSHReleaseTarballSpecification
+	please.Expect(yaml.Unmarshal([]byte("name: bpm\nchannel: rc\n"), &spec)).To(Succeed())
+	please.Expect(spec.Channel).To(Equal(cargo.ReleaseChannelRC))
+
+	err := yaml.Unmarshal([]byte("name: bpm\nchannel: nightly\n"), &spec)
+	please.Expect(err).To(MatchError(ContainSubstring(`unknown channel "nightly"`)))
+}
@@ -16,6 +16,30 @@ const (
 	// BOSHReleaseTarballSourceTypeArtifactory is the value for the Type field on cargo.ReleaseSourceConfig
 	// for releases stored on Artifactory.
 	BOSHReleaseTarballSourceTypeArtifactory = "artifactory"
+
+	// BOSHReleaseTarballSourceTypeOCI is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored as OCI artifacts in a container registry.
+	BOSHReleaseTarballSourceTypeOCI = "oci"
+
+	// BOSHReleaseTarballSourceTypeDirectory is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in a directory on the local filesystem or a mounted network share.
+	BOSHReleaseTarballSourceTypeDirectory = "directory"
+
+	// BOSHReleaseTarballSourceTypeHTTP is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases served by a plain HTTP(S) server.
+	BOSHReleaseTarballSourceTypeHTTP = "http"
+
+	// BOSHReleaseTarballSourceTypeCatalog is the value for the Type field on cargo.ReleaseSourceConfig
+	// for a fake release source backed by a local release catalog file.
+	BOSHReleaseTarballSourceTypeCatalog = "catalog"
+
+	// BOSHReleaseTarballSourceTypeGCS is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in a Google Cloud Storage bucket.
+	BOSHReleaseTarballSourceTypeGCS = "gcs"
+
+	// BOSHReleaseTarballSourceTypeAzure is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in an Azure Blob Storage container.
+	BOSHReleaseTarballSourceTypeAzure = "azure"
 )
 
 func BOSHReleaseTarballSourceID(releaseConfig ReleaseSourceConfig) string {
@@ -31,6 +55,18 @@ func BOSHReleaseTarballSourceID(releaseConfig ReleaseSourceConfig) string {
 		return releaseConfig.Org
 	case BOSHReleaseTarballSourceTypeArtifactory:
 		return BOSHReleaseTarballSourceTypeArtifactory
+	case BOSHReleaseTarballSourceTypeOCI:
+		return releaseConfig.Registry
+	case BOSHReleaseTarballSourceTypeDirectory:
+		return releaseConfig.Root
+	case BOSHReleaseTarballSourceTypeHTTP:
+		return BOSHReleaseTarballSourceTypeHTTP
+	case BOSHReleaseTarballSourceTypeCatalog:
+		return releaseConfig.Catalog
+	case BOSHReleaseTarballSourceTypeGCS:
+		return releaseConfig.Bucket
+	case BOSHReleaseTarballSourceTypeAzure:
+		return releaseConfig.Container
 	default:
 		return ""
 	}
@@ -17,12 +17,24 @@ func TestReleaseSourceID(t *testing.T) {
 
 		{Name: BOSHReleaseTarballSourceTypeArtifactory + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeArtifactory}},
 		{Name: BOSHReleaseTarballSourceTypeBOSHIO + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeBOSHIO}},
+		{Name: BOSHReleaseTarballSourceTypeAzure + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeAzure}},
+		{Name: BOSHReleaseTarballSourceTypeCatalog + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeCatalog}},
+		{Name: BOSHReleaseTarballSourceTypeGCS + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeGCS}},
+		{Name: BOSHReleaseTarballSourceTypeDirectory + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeDirectory}},
 		{Name: BOSHReleaseTarballSourceTypeGithub + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeGithub}},
+		{Name: BOSHReleaseTarballSourceTypeHTTP + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeHTTP}},
+		{Name: BOSHReleaseTarballSourceTypeOCI + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeOCI}},
 		{Name: BOSHReleaseTarballSourceTypeS3 + " with ID set", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "identifier", Type: BOSHReleaseTarballSourceTypeS3}},
 
 		{Name: BOSHReleaseTarballSourceTypeArtifactory + " default", ExpectedID: BOSHReleaseTarballSourceTypeArtifactory, Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeArtifactory}},
 		{Name: BOSHReleaseTarballSourceTypeBOSHIO + " default", ExpectedID: BOSHReleaseTarballSourceTypeBOSHIO, Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeBOSHIO}},
+		{Name: BOSHReleaseTarballSourceTypeAzure + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeAzure, Container: "identifier"}},
+		{Name: BOSHReleaseTarballSourceTypeCatalog + " default", ExpectedID: "releases.yml", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeCatalog, Catalog: "releases.yml"}},
+		{Name: BOSHReleaseTarballSourceTypeGCS + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeGCS, Bucket: "identifier"}},
+		{Name: BOSHReleaseTarballSourceTypeDirectory + " default", ExpectedID: "/mnt/releases", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeDirectory, Root: "/mnt/releases"}},
 		{Name: BOSHReleaseTarballSourceTypeGithub + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeGithub, Org: "identifier"}},
+		{Name: BOSHReleaseTarballSourceTypeHTTP + " default", ExpectedID: BOSHReleaseTarballSourceTypeHTTP, Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeHTTP}},
+		{Name: BOSHReleaseTarballSourceTypeOCI + " default", ExpectedID: "registry.example.com", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeOCI, Registry: "registry.example.com"}},
 		{Name: BOSHReleaseTarballSourceTypeS3 + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeS3, Bucket: "identifier"}},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
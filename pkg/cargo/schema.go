@@ -0,0 +1,346 @@
+package cargo
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema describing the YAML encoding of a Kilnfile, a Kilnfile.lock,
+// or part of one. Named struct types are described once in Defs and referenced with Ref.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// SchemaType is the JSON Schema "type" keyword. It is encoded as a string when it has a
+// single type.
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return []byte(strconv.Quote(t[0])), nil
+	}
+	quoted := make([]string, 0, len(t))
+	for _, name := range t {
+		quoted = append(quoted, strconv.Quote(name))
+	}
+	return []byte("[" + strings.Join(quoted, ",") + "]"), nil
+}
+
+// KilnfileSchema returns the JSON Schema of a Kilnfile.
+func KilnfileSchema() *Schema { return NewSchema("Kilnfile", Kilnfile{}) }
+
+// KilnfileLockSchema returns the JSON Schema of a Kilnfile.lock.
+func KilnfileLockSchema() *Schema { return NewSchema("Kilnfile.lock", KilnfileLock{}) }
+
+// ReleaseSourceConfigSchema returns the JSON Schema of an element of release_sources in a Kilnfile.
+func ReleaseSourceConfigSchema() *Schema {
+	return NewSchema("Kilnfile release source", ReleaseSourceConfig{})
+}
+
+// BakeConfigurationSchema returns the JSON Schema of an element of bake_configurations in a Kilnfile.
+func BakeConfigurationSchema() *Schema {
+	return NewSchema("Kilnfile bake configuration", BakeConfiguration{})
+}
+
+// NewSchema returns a JSON Schema for the YAML encoding of v, which must be a struct.
+// Objects do not allow fields that are not in the Go types, so typos in keys are reported.
+func NewSchema(title string, v any) *Schema {
+	g := schemaGenerator{defs: make(map[string]*Schema)}
+	t := reflect.TypeOf(v)
+	g.defs[t.Name()] = nil // the root is not added to $defs
+	root := g.structSchema(t)
+	delete(g.defs, t.Name())
+	root.Schema = jsonSchemaDialect
+	root.Title = title
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return root
+}
+
+// durationPattern matches the strings accepted by time.ParseDuration.
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+// knownSchemas describes types with custom YAML encodings.
+var knownSchemas = map[reflect.Type]func() *Schema{
+	reflect.TypeOf(time.Duration(0)): func() *Schema {
+		return &Schema{Type: SchemaType{"string", "integer"}, Pattern: durationPattern}
+	},
+	reflect.TypeOf(DeGlazeBehavior(0)): func() *Schema {
+		values := make([]string, 0, deGlazeStrings.Len())
+		deGlazeStrings.Range(func(_ DeGlazeBehavior, value string) {
+			values = append(values, value)
+		})
+		slices.Sort(values)
+		return &Schema{Type: SchemaType{"string"}, Enum: values}
+	},
+	reflect.TypeOf(ReleaseChannel("")): func() *Schema {
+		return &Schema{Type: SchemaType{"string"}, Enum: []string{string(ReleaseChannelStable), string(ReleaseChannelRC), string(ReleaseChannelDev)}}
+	},
+}
+
+type schemaGenerator struct {
+	defs map[string]*Schema
+}
+
+func (g schemaGenerator) schema(t reflect.Type) *Schema {
+	if known, ok := knownSchemas[t]; ok {
+		return known()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: SchemaType{"array"}, Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object"}}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // the placeholder stops recursive types from looping
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g schemaGenerator) structSchema(t reflect.Type) *Schema {
+	closed := false
+	s := &Schema{Type: SchemaType{"object"}, Properties: make(map[string]*Schema), AdditionalProperties: &closed}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			for key, value := range g.structSchema(field.Type).Properties {
+				s.Properties[key] = value
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		s.Properties[name] = g.schema(field.Type)
+	}
+	return s
+}
+
+// ValidateYAML checks node, the YAML node tree of a document, against the schema. It returns
+// a *FieldError with the position of each unknown key, value with the wrong type, and value
+// not in an enum. Document is used as the FieldError Document.
+func (s *Schema) ValidateYAML(document string, node *yaml.Node) []error {
+	v := schemaValidator{root: s, document: document}
+	v.validate(s, "", node)
+	return v.errs
+}
+
+type schemaValidator struct {
+	root     *Schema
+	document string
+	errs     []error
+}
+
+func (v *schemaValidator) fail(rule, pointer string, node *yaml.Node, format string, a ...any) {
+	fieldErr := NewFieldError(v.document, rule, fmt.Errorf(format, a...))
+	fieldErr.Pointer = pointer
+	fieldErr.Line, fieldErr.Column = node.Line, node.Column
+	v.errs = append(v.errs, fieldErr)
+}
+
+func (v *schemaValidator) validate(s *Schema, pointer string, node *yaml.Node) {
+	for node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		} else if len(node.Content) > 0 {
+			node = node.Content[0]
+		} else {
+			return
+		}
+	}
+	if s.Ref != "" {
+		s = v.root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if s == nil {
+			return
+		}
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return yamlNodeHasType(node, t) }) {
+		v.fail(RuleInvalidType, pointer, node, "%s must be %s but it is %s", fieldName(pointer), typeDescription(s.Type), nodeDescription(node))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) {
+		v.fail(RuleInvalidValue, pointer, node, "%s must be one of %s but it is %q", fieldName(pointer), strings.Join(s.Enum, ", "), node.Value)
+		return
+	}
+	if s.Pattern != "" && node.Tag == "!!str" && !regexp.MustCompile(s.Pattern).MatchString(node.Value) {
+		v.fail(RuleInvalidValue, pointer, node, "%s has invalid value %q", fieldName(pointer), node.Value)
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateMapping(s, pointer, node)
+	case yaml.SequenceNode:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range node.Content {
+			v.validate(s.Items, pointer+"/"+strconv.Itoa(i), item)
+		}
+	}
+}
+
+func (v *schemaValidator) validateMapping(s *Schema, pointer string, node *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "<<" && key.Tag == "!!merge" {
+			v.validate(s, pointer, value)
+			continue
+		}
+		property, ok := s.Properties[key.Value]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				message := fmt.Sprintf("unknown field %q", key.Value)
+				if suggestion := closestName(key.Value, s.Properties); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				v.fail(RuleUnknownField, pointer+"/"+escapePointerToken(key.Value), key, "%s", message)
+			}
+			continue
+		}
+		v.validate(property, pointer+"/"+escapePointerToken(key.Value), value)
+	}
+}
+
+// yamlNodeHasType reports whether node decodes into a Go value of the JSON Schema type.
+// Like the YAML decoder, strings accept any scalar, so an unquoted version like 1.2 is valid.
+func yamlNodeHasType(node *yaml.Node, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		return node.Kind == yaml.ScalarNode
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	default:
+		return true
+	}
+}
+
+func typeDescription(t SchemaType) string {
+	names := make([]string, 0, len(t))
+	for _, name := range t {
+		switch name {
+		case "object":
+			names = append(names, "a mapping")
+		case "array":
+			names = append(names, "a list")
+		case "integer":
+			names = append(names, "an integer")
+		default:
+			names = append(names, "a "+name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+func nodeDescription(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "an integer"
+	case "!!float":
+		return "a number"
+	case "!!bool":
+		return "a boolean"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
+
+// fieldName returns the last token of pointer, or "the document" for the root.
+func fieldName(pointer string) string {
+	if pointer == "" {
+		return "the document"
+	}
+	return pointer[strings.LastIndex(pointer, "/")+1:]
+}
+
+// closestName returns the property name closest to name when it is likely a typo.
+func closestName(name string, properties map[string]*Schema) string {
+	var (
+		best         string
+		bestDistance = len(name)/3 + 1
+	)
+	for property := range properties {
+		if d := editDistance(name, property); d < bestDistance || (d == bestDistance && best != "" && property < best) {
+			best, bestDistance = property, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,136 @@
+package cargo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKilnfileSchema(t *testing.T) {
+	please := NewWithT(t)
+	schema := KilnfileSchema()
+
+	please.Expect(schema.Schema).To(Equal(jsonSchemaDialect))
+	please.Expect(schema.Title).To(Equal("Kilnfile"))
+	please.Expect(*schema.AdditionalProperties).To(BeFalse())
+	please.Expect(schema.Properties).To(HaveKey("release_sources"))
+	please.Expect(schema.Properties["releases"].Items.Ref).To(Equal("#/$defs/BOSHReleaseTarballSpecification"))
+
+	spec := schema.Defs["BOSHReleaseTarballSpecification"]
+	please.Expect(spec).NotTo(BeNil())
+	please.Expect(spec.Properties["channel"].Enum).To(Equal([]string{"stable", "rc", "dev"}))
+	please.Expect(spec.Properties["float_always"].Type).To(Equal(SchemaType{"boolean"}))
+
+	buf, err := json.Marshal(schema)
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(string(buf)).To(ContainSubstring(`"type":"object"`))
+}
+
+func TestSchema_ValidateYAML(t *testing.T) {
+	for _, tt := range []struct {
+		Name, Document string
+		Errors         []string
+		Positions      [][2]int
+	}{
+		{
+			Name: "valid",
+			Document: `---
+release_sources:
+  - type: bosh.io
+    publishable: true
+releases:
+  - name: bpm
+    version: 1.2
+    channel: rc
+`,
+		},
+		{
+			Name: "unknown field",
+			Document: `---
+releases:
+  - name: bpm
+    verison: 1.2.3
+`,
+			Errors:    []string{`unknown field "verison" (did you mean "version"?)`},
+			Positions: [][2]int{{4, 5}},
+		},
+		{
+			Name: "wrong type",
+			Document: `---
+release_sources:
+  - type: bosh.io
+    publishable: "yes"
+`,
+			Errors:    []string{`publishable must be a boolean but it is "yes"`},
+			Positions: [][2]int{{4, 18}},
+		},
+		{
+			Name: "not in enum",
+			Document: `---
+releases:
+  - name: bpm
+    channel: beta
+`,
+			Errors:    []string{`channel must be one of stable, rc, dev but it is "beta"`},
+			Positions: [][2]int{{4, 14}},
+		},
+		{
+			Name: "merged keys",
+			Document: `---
+defaults: &defaults
+  release_sources: []
+<<: *defaults
+`,
+			Errors:    []string{`unknown field "defaults"`},
+			Positions: [][2]int{{2, 1}},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			please := NewWithT(t)
+			var node yaml.Node
+			please.Expect(yaml.Unmarshal([]byte(tt.Document), &node)).To(Succeed())
+
+			errs := KilnfileSchema().ValidateYAML(KilnfileDocument, &node)
+
+			please.Expect(errs).To(HaveLen(len(tt.Errors)))
+			for i, err := range errs {
+				please.Expect(err).To(MatchError(tt.Errors[i]))
+				fieldErr := err.(*FieldError)
+				please.Expect(fieldErr.Document).To(Equal(KilnfileDocument))
+				please.Expect([2]int{fieldErr.Line, fieldErr.Column}).To(Equal(tt.Positions[i]))
+			}
+		})
+	}
+}
+
+func TestSetFieldErrorPositions(t *testing.T) {
+	please := NewWithT(t)
+	var lockNode yaml.Node
+	please.Expect(yaml.Unmarshal([]byte(`---
+releases:
+  - name: bpm
+    version: 1.2.3
+  - name: capi
+    version: "2.0.0"
+`), &lockNode)).To(Succeed())
+
+	errs := []error{
+		lockFieldError(RuleLockVersionInvalid, errors.New("version"), "releases", 1, "version"),
+		lockFieldError(RuleLockVersionInvalid, errors.New("remote_source"), "releases", 0, "remote_source"),
+		kilnfileFieldError(RuleReleaseNameMissing, errors.New("kilnfile"), "releases", 0),
+	}
+	SetFieldErrorPositions(errs, KilnfileLockDocument, &lockNode)
+
+	positions := make([][2]int, 0, len(errs))
+	for _, err := range errs {
+		positions = append(positions, [2]int{err.(*FieldError).Line, err.(*FieldError).Column})
+	}
+	please.Expect(positions).To(Equal([][2]int{
+		{6, 14}, // the version value
+		{3, 5},  // the closest parent of the missing field
+		{0, 0},  // errors for other documents are not changed
+	}))
+}
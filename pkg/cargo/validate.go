@@ -2,47 +2,195 @@ package cargo
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/gh"
+)
+
+const (
+	KilnfileDocument     = "Kilnfile"
+	KilnfileLockDocument = "Kilnfile.lock"
 )
 
+// FieldError is a validation error for a value in a Kilnfile or Kilnfile.lock.
+type FieldError struct {
+	// Document is KilnfileDocument or KilnfileLockDocument.
+	Document string
+
+	// Pointer is a JSON Pointer (RFC 6901) to the value, like "/releases/2/version".
+	// It points at the closest value that exists when a field is missing.
+	Pointer string
+
+	// Line and Column are the 1-based position of the value in the document.
+	// They are zero until the position is set by ValidateYAML or SetFieldErrorPositions.
+	Line, Column int
+
+	// Rule is the ID of the rule the value breaks, like RuleUnknownField.
+	Rule string
+
+	// Severity is the severity of Rule unless it is changed by a ValidationConfiguration.
+	Severity Severity
+
+	Err error
+}
+
+func (err *FieldError) Error() string { return err.Err.Error() }
+
+func (err *FieldError) Unwrap() error { return err.Err }
+
+// NewFieldError returns a FieldError for document with the default severity of rule.
+func NewFieldError(document, rule string, err error) *FieldError {
+	return &FieldError{Document: document, Rule: rule, Severity: defaultSeverity(rule), Err: err}
+}
+
+func kilnfileFieldError(rule string, err error, tokens ...any) error {
+	fieldErr := NewFieldError(KilnfileDocument, rule, err)
+	fieldErr.Pointer = jsonPointer(tokens...)
+	return fieldErr
+}
+
+func lockFieldError(rule string, err error, tokens ...any) error {
+	fieldErr := NewFieldError(KilnfileLockDocument, rule, err)
+	fieldErr.Pointer = jsonPointer(tokens...)
+	return fieldErr
+}
+
+func jsonPointer(tokens ...any) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(escapePointerToken(fmt.Sprint(token)))
+	}
+	return b.String()
+}
+
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// SetFieldErrorPositions sets the Line and Column of each FieldError in errs for document
+// from root, the YAML node tree the document was parsed into.
+func SetFieldErrorPositions(errs []error, document string, root *yaml.Node) {
+	for _, err := range errs {
+		fieldErr, ok := err.(*FieldError)
+		if !ok || fieldErr.Document != document || fieldErr.Line != 0 {
+			continue
+		}
+		if node := yamlNodeAt(root, fieldErr.Pointer); node != nil {
+			fieldErr.Line, fieldErr.Column = node.Line, node.Column
+		}
+	}
+}
+
+// yamlNodeAt returns the node pointer refers to, or the closest parent that exists.
+func yamlNodeAt(node *yaml.Node, pointer string) *yaml.Node {
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	tokens := strings.Split(pointer, "/")[1:]
+	for {
+		for node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode {
+			if node.Kind == yaml.AliasNode {
+				node = node.Alias
+			} else if len(node.Content) > 0 {
+				node = node.Content[0]
+			} else {
+				return nil
+			}
+		}
+		if len(tokens) == 0 {
+			return node
+		}
+		token := unescape.Replace(tokens[0])
+		tokens = tokens[1:]
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(node.Content) {
+				next = node.Content[i]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+}
+
+// Validate checks the Kilnfile and Kilnfile.lock for mistakes. The errors are *FieldError
+// values pointing at the field with the mistake.
 func Validate(spec Kilnfile, lock KilnfileLock) []error {
 	var result []error
 
 	for index, componentSpec := range spec.Releases {
 		if componentSpec.Name == "" {
-			result = append(result, fmt.Errorf("release at index %d missing name in spec", index))
+			result = append(result, kilnfileFieldError(RuleReleaseNameMissing, fmt.Errorf("release at index %d missing name in spec", index), "releases", index))
 			continue
 		}
 
-		componentLock, err := lock.FindBOSHReleaseWithName(componentSpec.Name)
-		if err != nil {
+		if slices.ContainsFunc(spec.Releases[:index], func(other BOSHReleaseTarballSpecification) bool {
+			return other.Name == componentSpec.Name
+		}) {
 			result = append(result,
-				fmt.Errorf("release %q not found in lock", componentSpec.Name))
+				kilnfileFieldError(RuleDuplicateReleaseName, fmt.Errorf("release %q is in the Kilnfile more than once", componentSpec.Name), "releases", index, "name"))
+		}
+
+		result = append(result, checkReleaseSpecification(componentSpec, index)...)
+
+		lockIndex := slices.IndexFunc(lock.Releases, func(release BOSHReleaseTarballLock) bool {
+			return release.Name == componentSpec.Name
+		})
+		if lockIndex < 0 {
+			result = append(result,
+				kilnfileFieldError(RuleReleaseNotLocked, fmt.Errorf("release %q not found in lock", componentSpec.Name), "releases", index, "name"))
 			continue
 		}
+		componentLock := lock.Releases[lockIndex]
 
-		if err := checkComponentVersionsAndConstraint(componentSpec, componentLock, index); err != nil {
+		if err := checkComponentVersionsAndConstraint(componentSpec, componentLock, index, lockIndex); err != nil {
 			result = append(result, err)
 		}
+
+		result = append(result, checkReleaseSourcePin(spec, componentSpec, componentLock, index, lockIndex)...)
 	}
 
 	for index, componentLock := range lock.Releases {
 		if componentLock.Name == "" {
-			result = append(result, fmt.Errorf("release at index %d missing name in lock", index))
+			result = append(result, lockFieldError(RuleReleaseNameMissing, fmt.Errorf("release at index %d missing name in lock", index), "releases", index))
 			continue
 		}
 
+		if slices.ContainsFunc(lock.Releases[:index], func(other BOSHReleaseTarballLock) bool {
+			return other.Name == componentLock.Name
+		}) {
+			result = append(result,
+				lockFieldError(RuleDuplicateReleaseName, fmt.Errorf("release %q is in the Kilnfile.lock more than once", componentLock.Name), "releases", index, "name"))
+		}
+
 		_, err := spec.BOSHReleaseTarballSpecification(componentLock.Name)
 		if err != nil {
 			result = append(result,
-				fmt.Errorf("release %q not found in spec", componentLock.Name))
+				lockFieldError(RuleReleaseNotInKilnfile, fmt.Errorf("release %q not found in spec", componentLock.Name), "releases", index, "name"))
 			continue
 		}
 	}
 
 	result = append(result, ensureRemoteSourceExistsForEachReleaseLock(spec, lock)...)
+	result = append(result, checkReleaseSources(spec, lock)...)
+	result = append(result, checkStemcellCriteria(spec.Stemcell, lock.Stemcell)...)
+	result = append(result, checkTileNames(spec)...)
 
 	if len(result) > 0 {
 		return result
@@ -53,37 +201,196 @@ func Validate(spec Kilnfile, lock KilnfileLock) []error {
 
 func ensureRemoteSourceExistsForEachReleaseLock(spec Kilnfile, lock KilnfileLock) []error {
 	var result []error
-	for _, release := range lock.Releases {
+	for index, release := range lock.Releases {
 		if releaseSourceIndex := slices.IndexFunc(spec.ReleaseSources, func(config ReleaseSourceConfig) bool {
 			return BOSHReleaseTarballSourceID(config) == release.RemoteSource
 		}); releaseSourceIndex < 0 {
 			result = append(result,
-				fmt.Errorf("release source %q for release lock %q not found in Kilnfile", release.RemoteSource, release.Name))
+				lockFieldError(RuleRemoteSourceNotFound, fmt.Errorf("release source %q for release lock %q not found in Kilnfile", release.RemoteSource, release.Name), "releases", index, "remote_source"))
+		}
+	}
+	return result
+}
+
+func checkReleaseSourcePin(kilnfile Kilnfile, spec BOSHReleaseTarballSpecification, lock BOSHReleaseTarballLock, index, lockIndex int) []error {
+	ids := spec.ReleaseSourceIDs()
+	if len(ids) == 0 {
+		return nil
+	}
+	var result []error
+	if spec.ReleaseSource != "" && len(spec.ReleaseSources) > 0 {
+		result = append(result, kilnfileFieldError(RuleReleaseSourcePinConflict, fmt.Errorf("release %q sets both release_source and release_sources", spec.Name), "releases", index, "release_sources"))
+	}
+	pinField := "release_source"
+	if len(spec.ReleaseSources) > 0 {
+		pinField = "release_sources"
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(kilnfile.ReleaseSources, func(config ReleaseSourceConfig) bool {
+			return BOSHReleaseTarballSourceID(config) == id
+		}) {
+			result = append(result, kilnfileFieldError(RuleReleaseSourcePinNotFound, fmt.Errorf("release %q is pinned to release source %q which is not found in Kilnfile", spec.Name, id), "releases", index, pinField))
 		}
 	}
+	if !slices.Contains(ids, lock.RemoteSource) {
+		result = append(result, lockFieldError(RuleReleaseSourcePinMismatch, fmt.Errorf("release %q has remote_source %q in lock but it is pinned to %q", spec.Name, lock.RemoteSource, ids), "releases", lockIndex, "remote_source"))
+	}
 	return result
 }
 
-func checkComponentVersionsAndConstraint(spec BOSHReleaseTarballSpecification, lock BOSHReleaseTarballLock, index int) error {
+func checkComponentVersionsAndConstraint(spec BOSHReleaseTarballSpecification, lock BOSHReleaseTarballLock, index, lockIndex int) error {
 	v, err := semver.NewVersion(lock.Version)
 	if err != nil {
-		return fmt.Errorf("spec %s (index %d in Kilnfile.lock) has invalid lock version %q: %w",
-			spec.Name, index, lock.Version, err)
+		return lockFieldError(RuleLockVersionInvalid, fmt.Errorf("spec %s (index %d in Kilnfile.lock) has invalid lock version %q: %w",
+			spec.Name, index, lock.Version, err), "releases", lockIndex, "version")
+	}
+
+	if err := spec.Channel.Validate(); err != nil {
+		return kilnfileFieldError(RuleChannelInvalid, fmt.Errorf("spec %s (index %d in Kilnfile) has invalid channel: %w",
+			spec.Name, index, err), "releases", index, "channel")
+	}
+
+	if !spec.Channel.Allows(v) {
+		return lockFieldError(RuleChannelMismatch, fmt.Errorf("spec %s version in lock %q is not allowed on the %s channel",
+			spec.Name, lock.Version, spec.Channel), "releases", lockIndex, "version")
 	}
 
 	if spec.Version != "" {
 		c, err := semver.NewConstraint(spec.Version)
 		if err != nil {
-			return fmt.Errorf("spec %s (index %d in Kilnfile) has invalid version constraint: %w",
-				spec.Name, index, err)
+			return kilnfileFieldError(RuleVersionConstraintInvalid, fmt.Errorf("spec %s (index %d in Kilnfile) has invalid version constraint: %w",
+				spec.Name, index, err), "releases", index, "version")
 		}
 
 		matches, errs := c.Validate(v)
-		if !matches {
-			return fmt.Errorf("spec %s version in lock %q does not match constraint %q: %v",
-				spec.Name, lock.Version, spec.Version, errs)
+		if !matches && !(VersionMatcher{Constraints: c, Channel: spec.Channel}).Check(v) {
+			return lockFieldError(RuleVersionConstraintMismatch, fmt.Errorf("spec %s version in lock %q does not match constraint %q: %v",
+				spec.Name, lock.Version, spec.Version, errs), "releases", lockIndex, "version")
 		}
 	}
 
 	return nil
 }
+
+func checkReleaseSpecification(spec BOSHReleaseTarballSpecification, index int) []error {
+	var result []error
+	if spec.FloatAlways && spec.Version != "" {
+		if _, err := semver.StrictNewVersion(strings.TrimSpace(strings.TrimPrefix(spec.Version, "="))); err == nil {
+			result = append(result, kilnfileFieldError(RuleFloatAlwaysExactVersion,
+				fmt.Errorf("release %q has float_always but its version %q is exact", spec.Name, spec.Version), "releases", index, "version"))
+		}
+	}
+	if spec.GitHubRepository != "" {
+		if _, _, err := gh.RepositoryOwnerAndNameFromPath(spec.GitHubRepository); err != nil {
+			result = append(result, kilnfileFieldError(RuleGitHubRepositoryInvalid,
+				fmt.Errorf("release %q has an invalid github_repository: %w", spec.Name, err), "releases", index, "github_repository"))
+		}
+	}
+	return result
+}
+
+func checkReleaseSources(spec Kilnfile, lock KilnfileLock) []error {
+	var result []error
+	for index, config := range spec.ReleaseSources {
+		id := BOSHReleaseTarballSourceID(config)
+		if slices.ContainsFunc(spec.ReleaseSources[:index], func(other ReleaseSourceConfig) bool {
+			return BOSHReleaseTarballSourceID(other) == id
+		}) {
+			result = append(result, kilnfileFieldError(RuleDuplicateReleaseSourceID,
+				fmt.Errorf("release source ID %q is used by more than one release source", id), "release_sources", index))
+			continue
+		}
+		if !slices.ContainsFunc(lock.Releases, func(release BOSHReleaseTarballLock) bool {
+			return release.RemoteSource == id
+		}) {
+			result = append(result, kilnfileFieldError(RuleUnusedReleaseSource,
+				fmt.Errorf("release source %q is not the remote_source of any release in Kilnfile.lock", id), "release_sources", index))
+		}
+	}
+	return result
+}
+
+func checkStemcellCriteria(spec, lock Stemcell) []error {
+	var result []error
+	if spec.OS != "" && lock.OS != "" && spec.OS != lock.OS {
+		result = append(result, lockFieldError(RuleStemcellCriteriaMismatch,
+			fmt.Errorf("stemcell os %q in lock does not match %q in Kilnfile", lock.OS, spec.OS), "stemcell_criteria", "os"))
+	}
+	if spec.Version == "" || lock.Version == "" {
+		return result
+	}
+	c, err := semver.NewConstraint(spec.Version)
+	if err != nil {
+		return append(result, kilnfileFieldError(RuleVersionConstraintInvalid,
+			fmt.Errorf("stemcell has invalid version constraint: %w", err), "stemcell_criteria", "version"))
+	}
+	v, err := semver.NewVersion(lock.Version)
+	if err != nil {
+		return append(result, lockFieldError(RuleLockVersionInvalid,
+			fmt.Errorf("stemcell has invalid lock version %q: %w", lock.Version, err), "stemcell_criteria", "version"))
+	}
+	if !c.Check(v) {
+		result = append(result, lockFieldError(RuleStemcellCriteriaMismatch,
+			fmt.Errorf("stemcell version in lock %q does not match constraint %q", lock.Version, spec.Version), "stemcell_criteria", "version"))
+	}
+	return result
+}
+
+func checkTileNames(spec Kilnfile) []error {
+	if len(spec.BakeConfigurations) == 0 {
+		return nil
+	}
+	var result []error
+	for index, name := range spec.TileNames {
+		if !slices.ContainsFunc(spec.BakeConfigurations, func(config BakeConfiguration) bool {
+			return config.TileName == name
+		}) {
+			result = append(result, kilnfileFieldError(RuleTileNameWithoutBakeConfiguration,
+				fmt.Errorf("tile name %q does not have a bake configuration", name), "tile_names", index))
+		}
+	}
+	return result
+}
+
+// ValidateBakeConfigurationPaths checks that the files and directories in the Kilnfile bake
+// configurations exist. Relative paths are relative to dir, the directory of the Kilnfile.
+func ValidateBakeConfigurationPaths(spec Kilnfile, dir string, stat func(string) (os.FileInfo, error)) []error {
+	var result []error
+	for index, config := range spec.BakeConfigurations {
+		check := func(p string, field string, fieldIndex ...any) {
+			if p == "" {
+				return
+			}
+			name := p
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			if _, err := stat(p); err != nil {
+				result = append(result, kilnfileFieldError(RuleBakeConfigurationPathMissing,
+					fmt.Errorf("%s %q of bake configuration %q does not exist", field, name, config.TileName),
+					append([]any{"bake_configurations", index, field}, fieldIndex...)...))
+			}
+		}
+		check(config.Metadata, "metadata_filepath")
+		check(config.IconPath, "icon_filepath")
+		for _, list := range []struct {
+			field string
+			paths []string
+		}{
+			{"forms_directories", config.FormDirectories},
+			{"instance_groups_directories", config.InstanceGroupDirectories},
+			{"jobs_directories", config.JobDirectories},
+			{"migrations_directories", config.MigrationDirectories},
+			{"properties_directories", config.PropertyDirectories},
+			{"runtime_configurations_directories", config.RuntimeConfigDirectories},
+			{"bosh_variables_directories", config.BOSHVariableDirectories},
+			{"embed_paths", config.EmbedPaths},
+			{"variable_files", config.VariableFiles},
+		} {
+			for i, p := range list.paths {
+				check(p, list.field, i)
+			}
+		}
+	}
+	return result
+}
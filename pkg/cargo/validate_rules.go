@@ -0,0 +1,220 @@
+package cargo
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how important a validation problem is. Only errors fail kiln validate.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// The IDs of the rules checked by kiln validate. They are stable so they may be used
+// in suppressions.
+const (
+	RuleYAMLSyntax                       = "yaml-syntax"
+	RuleUnknownField                     = "unknown-field"
+	RuleInvalidType                      = "invalid-type"
+	RuleInvalidValue                     = "invalid-value"
+	RuleReleaseNameMissing               = "release-name-missing"
+	RuleReleaseNotLocked                 = "release-not-locked"
+	RuleReleaseNotInKilnfile             = "release-not-in-kilnfile"
+	RuleDuplicateReleaseName             = "duplicate-release-name"
+	RuleLockVersionInvalid               = "lock-version-invalid"
+	RuleVersionConstraintInvalid         = "version-constraint-invalid"
+	RuleVersionConstraintMismatch        = "version-constraint-mismatch"
+	RuleChannelInvalid                   = "channel-invalid"
+	RuleChannelMismatch                  = "channel-mismatch"
+	RuleFloatAlwaysExactVersion          = "float-always-exact-version"
+	RuleDuplicateReleaseSourceID         = "duplicate-release-source-id"
+	RuleRemoteSourceNotFound             = "remote-source-not-found"
+	RuleUnusedReleaseSource              = "unused-release-source"
+	RuleReleaseSourcePinConflict         = "release-source-pin-conflict"
+	RuleReleaseSourcePinNotFound         = "release-source-pin-not-found"
+	RuleReleaseSourcePinMismatch         = "release-source-pin-mismatch"
+	RuleGitHubRepositoryInvalid          = "github-repository-invalid"
+	RuleStemcellCriteriaMismatch         = "stemcell-criteria-mismatch"
+	RuleTileNameWithoutBakeConfiguration = "tile-name-without-bake-configuration"
+	RuleBakeConfigurationPathMissing     = "bake-configuration-path-missing"
+	RuleValidationConfigurationInvalid   = "validation-configuration-invalid"
+)
+
+// ValidationRule describes a check done by kiln validate.
+type ValidationRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+var validationRules = []ValidationRule{
+	{RuleYAMLSyntax, SeverityError, "The document is not valid YAML."},
+	{RuleUnknownField, SeverityError, "A field is not part of the document schema."},
+	{RuleInvalidType, SeverityError, "A value has the wrong type."},
+	{RuleInvalidValue, SeverityError, "A value is not one of the allowed values or does not have the expected format."},
+	{RuleReleaseNameMissing, SeverityError, "A release does not have a name."},
+	{RuleReleaseNotLocked, SeverityError, "A release in the Kilnfile is not in the Kilnfile.lock."},
+	{RuleReleaseNotInKilnfile, SeverityError, "A release in the Kilnfile.lock is not in the Kilnfile."},
+	{RuleDuplicateReleaseName, SeverityError, "Two releases have the same name."},
+	{RuleLockVersionInvalid, SeverityError, "A locked version is not a semantic version."},
+	{RuleVersionConstraintInvalid, SeverityError, "A version constraint can not be parsed."},
+	{RuleVersionConstraintMismatch, SeverityError, "A locked version does not match the Kilnfile version constraint."},
+	{RuleChannelInvalid, SeverityError, "A release channel is not stable, rc, or dev."},
+	{RuleChannelMismatch, SeverityError, "A locked version is not allowed on the release channel."},
+	{RuleFloatAlwaysExactVersion, SeverityWarning, "A release with float_always has an exact version, so it never floats."},
+	{RuleDuplicateReleaseSourceID, SeverityError, "Two release sources have the same ID."},
+	{RuleRemoteSourceNotFound, SeverityError, "The remote_source of a locked release is not a Kilnfile release source."},
+	{RuleUnusedReleaseSource, SeverityWarning, "No locked release uses a release source."},
+	{RuleReleaseSourcePinConflict, SeverityError, "A release sets both release_source and release_sources."},
+	{RuleReleaseSourcePinNotFound, SeverityError, "A release is pinned to a release source that is not in the Kilnfile."},
+	{RuleReleaseSourcePinMismatch, SeverityError, "A locked release comes from a release source it is not pinned to."},
+	{RuleGitHubRepositoryInvalid, SeverityError, "A github_repository is not a GitHub repository URL."},
+	{RuleStemcellCriteriaMismatch, SeverityError, "The locked stemcell does not match the Kilnfile stemcell_criteria."},
+	{RuleTileNameWithoutBakeConfiguration, SeverityError, "A name in tile_names does not have a bake configuration."},
+	{RuleBakeConfigurationPathMissing, SeverityError, "A file or directory in a bake configuration does not exist."},
+	{RuleValidationConfigurationInvalid, SeverityError, "The Kilnfile validation configuration refers to an unknown rule or severity."},
+}
+
+// ValidationRules returns the rules checked by kiln validate sorted by ID.
+func ValidationRules() []ValidationRule {
+	rules := slices.Clone(validationRules)
+	slices.SortFunc(rules, func(a, b ValidationRule) int { return strings.Compare(a.ID, b.ID) })
+	return rules
+}
+
+func validationRule(id string) (ValidationRule, bool) {
+	index := slices.IndexFunc(validationRules, func(rule ValidationRule) bool { return rule.ID == id })
+	if index < 0 {
+		return ValidationRule{}, false
+	}
+	return validationRules[index], true
+}
+
+func defaultSeverity(rule string) Severity {
+	if r, ok := validationRule(rule); ok {
+		return r.Severity
+	}
+	return SeverityError
+}
+
+// ValidationConfiguration is the validation field of a Kilnfile. It turns off rules
+// and changes their severity for the whole Kilnfile and Kilnfile.lock.
+type ValidationConfiguration struct {
+	// Ignore lists the IDs of rules that are not reported.
+	Ignore []string `yaml:"ignore,omitempty"`
+
+	// Severities maps rule IDs to the severity they are reported with.
+	Severities map[string]Severity `yaml:"severities,omitempty"`
+}
+
+// Apply removes the errors of ignored rules from errs and sets the severity of the others.
+// Errors for unknown rule IDs and severities in the configuration are added to the result.
+func (config ValidationConfiguration) Apply(errs []error) []error {
+	result := make([]error, 0, len(errs))
+	for index, id := range config.Ignore {
+		if _, ok := validationRule(id); !ok {
+			result = append(result, kilnfileFieldError(RuleValidationConfigurationInvalid,
+				fmt.Errorf("unknown rule %q in validation ignore", id), "validation", "ignore", index))
+		}
+	}
+	ids := make([]string, 0, len(config.Severities))
+	for id := range config.Severities {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if _, ok := validationRule(id); !ok {
+			result = append(result, kilnfileFieldError(RuleValidationConfigurationInvalid,
+				fmt.Errorf("unknown rule %q in validation severities", id), "validation", "severities", id))
+		} else if severity := config.Severities[id]; severity != SeverityError && severity != SeverityWarning {
+			result = append(result, kilnfileFieldError(RuleValidationConfigurationInvalid,
+				fmt.Errorf("severity of rule %q must be %s or %s but it is %q", id, SeverityError, SeverityWarning, severity), "validation", "severities", id))
+		}
+	}
+
+	for _, err := range errs {
+		fieldErr, ok := err.(*FieldError)
+		if !ok {
+			result = append(result, err)
+			continue
+		}
+		if slices.Contains(config.Ignore, fieldErr.Rule) {
+			continue
+		}
+		if severity, ok := config.Severities[fieldErr.Rule]; ok && (severity == SeverityError || severity == SeverityWarning) {
+			fieldErr.Severity = severity
+		}
+		result = append(result, fieldErr)
+	}
+	return result
+}
+
+var ignoreCommentPattern = regexp.MustCompile(`#\s*kiln:ignore\s+([\w-]+(?:\s*,\s*[\w-]+)*)`)
+
+// RemoveIgnoredFieldErrors removes the errors for document that have a "# kiln:ignore <rule>"
+// comment, where rule is the ID of the error rule. The comment may be at the end of the line
+// with the value or on the lines above it. A comment on a release or release source ignores
+// the rule for all of its fields. Several rule IDs are separated by commas.
+func RemoveIgnoredFieldErrors(errs []error, document string, source []byte, root *yaml.Node) []error {
+	lines := strings.Split(string(source), "\n")
+	ignored := func(line int, rule string) bool {
+		if line < 1 || line > len(lines) {
+			return false
+		}
+		if ignoreCommentHasRule(lines[line-1], rule) {
+			return true
+		}
+		for i := line - 2; i >= 0 && strings.HasPrefix(strings.TrimSpace(lines[i]), "#"); i-- {
+			if ignoreCommentHasRule(lines[i], rule) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]error, 0, len(errs))
+	for _, err := range errs {
+		fieldErr, ok := err.(*FieldError)
+		if !ok || fieldErr.Document != document || fieldErr.Line == 0 {
+			result = append(result, err)
+			continue
+		}
+		suppressed := ignored(fieldErr.Line, fieldErr.Rule)
+		if root != nil && root.Kind != 0 {
+			tokens := strings.Split(fieldErr.Pointer, "/")
+			for n := 2; !suppressed && n < len(tokens); n++ {
+				// only list items are checked because a list starts on the line of its first item
+				if parent := yamlNodeAt(root, strings.Join(tokens[:n-1], "/")); parent == nil || parent.Kind != yaml.SequenceNode {
+					continue
+				}
+				if node := yamlNodeAt(root, strings.Join(tokens[:n], "/")); node != nil {
+					suppressed = ignored(node.Line, fieldErr.Rule)
+				}
+			}
+		}
+		if !suppressed {
+			result = append(result, err)
+		}
+	}
+	return result
+}
+
+func ignoreCommentHasRule(line, rule string) bool {
+	match := ignoreCommentPattern.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+	for _, id := range strings.Split(match[1], ",") {
+		if strings.TrimSpace(id) == rule {
+			return true
+		}
+	}
+	return false
+}
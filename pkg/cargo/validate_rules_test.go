@@ -0,0 +1,101 @@
+package cargo
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidationRules(t *testing.T) {
+	please := NewWithT(t)
+	rules := ValidationRules()
+	please.Expect(rules).To(HaveLen(len(validationRules)))
+	for i, rule := range rules {
+		please.Expect(rule.ID).To(MatchRegexp(`^[a-z]+(-[a-z]+)*$`))
+		please.Expect(rule.Severity).To(BeElementOf(SeverityError, SeverityWarning))
+		please.Expect(rule.Description).NotTo(BeEmpty())
+		if i > 0 {
+			please.Expect(rule.ID > rules[i-1].ID).To(BeTrue(), "rules are sorted and IDs are unique")
+		}
+	}
+}
+
+func TestValidationConfiguration_Apply(t *testing.T) {
+	t.Run("ignore and severities", func(t *testing.T) {
+		please := NewWithT(t)
+		errs := []error{
+			kilnfileFieldError(RuleUnusedReleaseSource, errors.New("unused"), "release_sources", 0),
+			kilnfileFieldError(RuleFloatAlwaysExactVersion, errors.New("float"), "releases", 0, "version"),
+			kilnfileFieldError(RuleDuplicateReleaseName, errors.New("duplicate"), "releases", 1, "name"),
+		}
+
+		result := ValidationConfiguration{
+			Ignore: []string{RuleUnusedReleaseSource},
+			Severities: map[string]Severity{
+				RuleFloatAlwaysExactVersion: SeverityError,
+				RuleDuplicateReleaseName:    SeverityWarning,
+			},
+		}.Apply(errs)
+
+		please.Expect(result).To(HaveLen(2))
+		please.Expect(result[0]).To(MatchError("float"))
+		please.Expect(result[0].(*FieldError).Severity).To(Equal(SeverityError))
+		please.Expect(result[1]).To(MatchError("duplicate"))
+		please.Expect(result[1].(*FieldError).Severity).To(Equal(SeverityWarning))
+	})
+
+	t.Run("unknown rules and severities", func(t *testing.T) {
+		please := NewWithT(t)
+
+		result := ValidationConfiguration{
+			Ignore:     []string{"unused-release-sources"},
+			Severities: map[string]Severity{RuleUnusedReleaseSource: "info"},
+		}.Apply(nil)
+
+		please.Expect(result).To(HaveLen(2))
+		please.Expect(result[0]).To(MatchError(`unknown rule "unused-release-sources" in validation ignore`))
+		please.Expect(result[0].(*FieldError).Pointer).To(Equal("/validation/ignore/0"))
+		please.Expect(result[1]).To(MatchError(ContainSubstring(`severity of rule "unused-release-source" must be error or warning`)))
+		please.Expect(result[1].(*FieldError).Pointer).To(Equal("/validation/severities/unused-release-source"))
+	})
+}
+
+func TestRemoveIgnoredFieldErrors(t *testing.T) {
+	please := NewWithT(t)
+	const source = `---
+release_sources:
+  # kiln:ignore unused-release-source
+  - type: bosh.io
+  - type: github # kiln:ignore duplicate-release-source-id, unused-release-source
+  - type: s3
+releases:
+  # some comment
+  # kiln:ignore float-always-exact-version
+  - name: bpm
+    float_always: true
+    version: 1.2.3
+  - name: capi
+    float_always: true
+    version: 1.2.3 # kiln:ignore unused-release-source
+`
+	var root yaml.Node
+	please.Expect(yaml.Unmarshal([]byte(source), &root)).To(Succeed())
+	errs := []error{
+		kilnfileFieldError(RuleUnusedReleaseSource, errors.New("bosh.io"), "release_sources", 0),
+		kilnfileFieldError(RuleUnusedReleaseSource, errors.New("github"), "release_sources", 1),
+		kilnfileFieldError(RuleUnusedReleaseSource, errors.New("s3"), "release_sources", 2),
+		kilnfileFieldError(RuleFloatAlwaysExactVersion, errors.New("bpm"), "releases", 0, "version"),
+		kilnfileFieldError(RuleFloatAlwaysExactVersion, errors.New("capi"), "releases", 1, "version"),
+		lockFieldError(RuleUnusedReleaseSource, errors.New("lock"), "releases", 0),
+	}
+	SetFieldErrorPositions(errs, KilnfileDocument, &root)
+
+	result := RemoveIgnoredFieldErrors(errs, KilnfileDocument, []byte(source), &root)
+
+	please.Expect(result).To(HaveLen(3))
+	please.Expect(result[0]).To(MatchError("s3"))
+	please.Expect(result[1]).To(MatchError("capi"))
+	please.Expect(result[2]).To(MatchError("lock"))
+}
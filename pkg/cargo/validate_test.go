@@ -1,6 +1,9 @@
 package cargo
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -10,6 +13,18 @@ const (
 	someReleaseSourceID = "some-release-source-id"
 )
 
+// severityErrors removes warnings from the results of Validate.
+func severityErrors(results []error) []error {
+	var errs []error
+	for _, err := range results {
+		if fieldErr, ok := err.(*FieldError); ok && fieldErr.Severity == SeverityWarning {
+			continue
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 func TestValidate_MissingNameInSpec(t *testing.T) {
 	t.Parallel()
 	please := NewWithT(t)
@@ -145,7 +160,7 @@ func TestValidate_PinnedRelease(t *testing.T) {
 func TestValidate_release_sources(t *testing.T) {
 	t.Run("release source is not found", func(t *testing.T) {
 		please := NewWithT(t)
-		results := Validate(Kilnfile{
+		results := severityErrors(Validate(Kilnfile{
 			ReleaseSources: []ReleaseSourceConfig{
 				{ID: "ORANGE_SOURCE"},
 			},
@@ -158,14 +173,14 @@ func TestValidate_release_sources(t *testing.T) {
 				{Name: "lemon", Version: "1.2.3", RemoteSource: "LEMON_SOURCE"},
 				{Name: "orange", Version: "1.2.3", RemoteSource: "ORANGE_SOURCE"},
 			},
-		})
+		}))
 		please.Expect(results).To(HaveLen(1))
 		err := results[0]
 		please.Expect(err).To(MatchError(And(ContainSubstring("lemon"), ContainSubstring("LEMON_SOURCE"))))
 	})
 	t.Run("release source is correctly configured", func(t *testing.T) {
 		please := NewWithT(t)
-		results := Validate(Kilnfile{
+		results := severityErrors(Validate(Kilnfile{
 			ReleaseSources: []ReleaseSourceConfig{
 				{ID: "SOME_TREE"},
 			},
@@ -178,12 +193,12 @@ func TestValidate_release_sources(t *testing.T) {
 				{Name: "lemon", Version: "1.2.3", RemoteSource: "SOME_TREE"},
 				{Name: "orange", Version: "1.2.3", RemoteSource: "SOME_TREE"},
 			},
-		})
+		}))
 		please.Expect(results).To(BeEmpty())
 	})
 	t.Run("match on type", func(t *testing.T) {
 		please := NewWithT(t)
-		results := Validate(Kilnfile{
+		results := severityErrors(Validate(Kilnfile{
 			ReleaseSources: []ReleaseSourceConfig{
 				{Type: BOSHReleaseTarballSourceTypeBOSHIO},
 			},
@@ -194,12 +209,12 @@ func TestValidate_release_sources(t *testing.T) {
 			Releases: []BOSHReleaseTarballLock{
 				{Name: "orange", Version: "1.2.3", RemoteSource: BOSHReleaseTarballSourceTypeBOSHIO},
 			},
-		})
+		}))
 		please.Expect(results).To(BeEmpty())
 	})
 	t.Run("do not match on type when id is set", func(t *testing.T) {
 		please := NewWithT(t)
-		results := Validate(Kilnfile{
+		results := severityErrors(Validate(Kilnfile{
 			ReleaseSources: []ReleaseSourceConfig{
 				{ID: "open source", Type: BOSHReleaseTarballSourceTypeBOSHIO},
 			},
@@ -210,12 +225,12 @@ func TestValidate_release_sources(t *testing.T) {
 			Releases: []BOSHReleaseTarballLock{
 				{Name: "orange", Version: "1.2.3", RemoteSource: BOSHReleaseTarballSourceTypeBOSHIO},
 			},
-		})
+		}))
 		please.Expect(results).To(HaveLen(1))
 	})
 	t.Run("github release source", func(t *testing.T) {
 		please := NewWithT(t)
-		results := Validate(Kilnfile{
+		results := severityErrors(Validate(Kilnfile{
 			ReleaseSources: []ReleaseSourceConfig{
 				{Org: "crhntr", Type: BOSHReleaseTarballSourceTypeGithub},
 			},
@@ -226,11 +241,59 @@ func TestValidate_release_sources(t *testing.T) {
 			Releases: []BOSHReleaseTarballLock{
 				{Name: "hello-tile", Version: "1.2.3", RemoteSource: "crhntr"},
 			},
-		})
+		}))
 		please.Expect(results).To(HaveLen(0))
 	})
 }
 
+func TestValidate_release_source_pin(t *testing.T) {
+	kilnfile := func(releases ...BOSHReleaseTarballSpecification) Kilnfile {
+		return Kilnfile{
+			ReleaseSources: []ReleaseSourceConfig{
+				{ID: "internal-bucket"},
+				{ID: "mirror-bucket"},
+				{ID: "open-source"},
+			},
+			Releases: releases,
+		}
+	}
+	lock := func(remoteSource string) KilnfileLock {
+		return KilnfileLock{
+			Releases: []BOSHReleaseTarballLock{
+				{Name: "lemon", Version: "1.2.3", RemoteSource: remoteSource},
+			},
+		}
+	}
+	t.Run("lock uses the pinned source", func(t *testing.T) {
+		please := NewWithT(t)
+		results := severityErrors(Validate(kilnfile(BOSHReleaseTarballSpecification{Name: "lemon", ReleaseSource: "internal-bucket"}), lock("internal-bucket")))
+		please.Expect(results).To(BeEmpty())
+	})
+	t.Run("lock uses one of the pinned sources", func(t *testing.T) {
+		please := NewWithT(t)
+		results := severityErrors(Validate(kilnfile(BOSHReleaseTarballSpecification{Name: "lemon", ReleaseSources: []string{"internal-bucket", "mirror-bucket"}}), lock("mirror-bucket")))
+		please.Expect(results).To(BeEmpty())
+	})
+	t.Run("lock uses another source", func(t *testing.T) {
+		please := NewWithT(t)
+		results := severityErrors(Validate(kilnfile(BOSHReleaseTarballSpecification{Name: "lemon", ReleaseSource: "internal-bucket"}), lock("open-source")))
+		please.Expect(results).To(HaveLen(1))
+		please.Expect(results[0]).To(MatchError(And(ContainSubstring("lemon"), ContainSubstring("open-source"), ContainSubstring("internal-bucket"))))
+	})
+	t.Run("pinned source is not in the Kilnfile", func(t *testing.T) {
+		please := NewWithT(t)
+		results := severityErrors(Validate(kilnfile(BOSHReleaseTarballSpecification{Name: "lemon", ReleaseSources: []string{"internal-bucket", "missing-bucket"}}), lock("internal-bucket")))
+		please.Expect(results).To(HaveLen(1))
+		please.Expect(results[0]).To(MatchError(ContainSubstring("missing-bucket")))
+	})
+	t.Run("both fields are set", func(t *testing.T) {
+		please := NewWithT(t)
+		results := severityErrors(Validate(kilnfile(BOSHReleaseTarballSpecification{Name: "lemon", ReleaseSource: "open-source", ReleaseSources: []string{"internal-bucket"}}), lock("internal-bucket")))
+		please.Expect(results).To(HaveLen(1))
+		please.Expect(results[0]).To(MatchError(ContainSubstring("both release_source and release_sources")))
+	})
+}
+
 func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 	t.Run("no version", func(t *testing.T) {
 		please := NewWithT(t)
@@ -241,7 +304,7 @@ func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 			Name:    "capi",
 			Version: "2.3.4",
 		}
-		err := checkComponentVersionsAndConstraint(r, l, 0)
+		err := checkComponentVersionsAndConstraint(r, l, 0, 0)
 		please.Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -255,7 +318,7 @@ func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 			Name:    "capi",
 			Version: "2.3.4",
 		}
-		err := checkComponentVersionsAndConstraint(r, l, 0)
+		err := checkComponentVersionsAndConstraint(r, l, 0, 0)
 		please.Expect(err).To(And(
 			HaveOccurred(),
 			MatchError(ContainSubstring("invalid version constraint")),
@@ -272,7 +335,7 @@ func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 			Name:    "capi",
 			Version: "3.0.5",
 		}
-		err := checkComponentVersionsAndConstraint(r, l, 0)
+		err := checkComponentVersionsAndConstraint(r, l, 0, 0)
 		please.Expect(err).To(And(
 			HaveOccurred(),
 			MatchError(ContainSubstring("match constraint")),
@@ -289,10 +352,227 @@ func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 			Name:    "capi",
 			Version: "BAD",
 		}
-		err := checkComponentVersionsAndConstraint(r, l, 0)
+		err := checkComponentVersionsAndConstraint(r, l, 0, 0)
 		please.Expect(err).To(And(
 			HaveOccurred(),
 			MatchError(ContainSubstring("invalid lock version")),
 		))
 	})
 }
+
+func TestValidate_channel(t *testing.T) {
+	t.Run("release candidate on the stable channel", func(t *testing.T) {
+		please := NewWithT(t)
+		err := checkComponentVersionsAndConstraint(BOSHReleaseTarballSpecification{
+			Name:    "capi",
+			Channel: ReleaseChannelStable,
+		}, BOSHReleaseTarballLock{
+			Name:    "capi",
+			Version: "2.3.4-rc.1",
+		}, 0, 0)
+		please.Expect(err).To(MatchError(`spec capi version in lock "2.3.4-rc.1" is not allowed on the stable channel`))
+	})
+
+	t.Run("release candidate on the rc channel", func(t *testing.T) {
+		please := NewWithT(t)
+		err := checkComponentVersionsAndConstraint(BOSHReleaseTarballSpecification{
+			Name:    "capi",
+			Version: "~2.3",
+			Channel: ReleaseChannelRC,
+		}, BOSHReleaseTarballLock{
+			Name:    "capi",
+			Version: "2.3.4-rc.1",
+		}, 0, 0)
+		please.Expect(err).NotTo(HaveOccurred())
+	})
+
+	t.Run("development build on the rc channel", func(t *testing.T) {
+		please := NewWithT(t)
+		err := checkComponentVersionsAndConstraint(BOSHReleaseTarballSpecification{
+			Name:    "capi",
+			Channel: ReleaseChannelRC,
+		}, BOSHReleaseTarballLock{
+			Name:    "capi",
+			Version: "2.3.4-dev.7",
+		}, 0, 0)
+		please.Expect(err).To(MatchError(ContainSubstring("is not allowed on the rc channel")))
+	})
+
+	t.Run("unknown channel", func(t *testing.T) {
+		please := NewWithT(t)
+		err := checkComponentVersionsAndConstraint(BOSHReleaseTarballSpecification{
+			Name:    "capi",
+			Channel: "nightly",
+		}, BOSHReleaseTarballLock{
+			Name:    "capi",
+			Version: "2.3.4",
+		}, 0, 0)
+		please.Expect(err).To(MatchError(ContainSubstring("has invalid channel")))
+	})
+}
+
+func TestValidate_field_error_pointers(t *testing.T) {
+	t.Parallel()
+	please := NewWithT(t)
+	results := Validate(Kilnfile{
+		ReleaseSources: []ReleaseSourceConfig{
+			{ID: someReleaseSourceID},
+		},
+		Releases: []BOSHReleaseTarballSpecification{
+			{Name: "apple", Version: "~1"},
+			{Name: "banana"},
+		},
+	}, KilnfileLock{
+		Releases: []BOSHReleaseTarballLock{
+			{Name: "banana", Version: "1.2.3", RemoteSource: "unknown"},
+			{Name: "apple", Version: "2.0.0", RemoteSource: someReleaseSourceID},
+		},
+	})
+	var pointers []string
+	for _, err := range results {
+		fieldErr, ok := err.(*FieldError)
+		please.Expect(ok).To(BeTrue())
+		pointers = append(pointers, fieldErr.Document+"#"+fieldErr.Pointer)
+	}
+	please.Expect(pointers).To(Equal([]string{
+		"Kilnfile.lock#/releases/1/version",
+		"Kilnfile.lock#/releases/0/remote_source",
+	}))
+}
+
+func TestValidate_rules(t *testing.T) {
+	validLock := KilnfileLock{
+		Releases: []BOSHReleaseTarballLock{
+			{Name: "bpm", Version: "1.2.3", RemoteSource: someReleaseSourceID},
+		},
+		Stemcell: Stemcell{OS: "ubuntu-jammy", Version: "1.123"},
+	}
+	validKilnfile := func() Kilnfile {
+		return Kilnfile{
+			ReleaseSources: []ReleaseSourceConfig{{ID: someReleaseSourceID}},
+			Releases:       []BOSHReleaseTarballSpecification{{Name: "bpm"}},
+			Stemcell:       Stemcell{OS: "ubuntu-jammy", Version: "~1"},
+		}
+	}
+
+	for _, tt := range []struct {
+		Name     string
+		Kilnfile func(kf *Kilnfile)
+		Lock     func(lock *KilnfileLock)
+		Rule     string
+		Severity Severity
+		Pointer  string
+	}{
+		{
+			Name: "duplicate release name in Kilnfile",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.Releases = append(kf.Releases, BOSHReleaseTarballSpecification{Name: "bpm"})
+			},
+			Rule: RuleDuplicateReleaseName, Severity: SeverityError, Pointer: "/releases/1/name",
+		},
+		{
+			Name: "duplicate release name in Kilnfile.lock",
+			Lock: func(lock *KilnfileLock) {
+				lock.Releases = append(lock.Releases, lock.Releases[0])
+			},
+			Rule: RuleDuplicateReleaseName, Severity: SeverityError, Pointer: "/releases/1/name",
+		},
+		{
+			Name: "duplicate release source ID",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.ReleaseSources = append(kf.ReleaseSources, ReleaseSourceConfig{ID: someReleaseSourceID, Type: BOSHReleaseTarballSourceTypeS3})
+			},
+			Rule: RuleDuplicateReleaseSourceID, Severity: SeverityError, Pointer: "/release_sources/1",
+		},
+		{
+			Name: "unused release source",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.ReleaseSources = append(kf.ReleaseSources, ReleaseSourceConfig{Type: BOSHReleaseTarballSourceTypeBOSHIO})
+			},
+			Rule: RuleUnusedReleaseSource, Severity: SeverityWarning, Pointer: "/release_sources/1",
+		},
+		{
+			Name: "float_always with an exact version",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.Releases[0].FloatAlways = true
+				kf.Releases[0].Version = "1.2.3"
+			},
+			Rule: RuleFloatAlwaysExactVersion, Severity: SeverityWarning, Pointer: "/releases/0/version",
+		},
+		{
+			Name: "stemcell os mismatch",
+			Lock: func(lock *KilnfileLock) {
+				lock.Stemcell.OS = "ubuntu-xenial"
+			},
+			Rule: RuleStemcellCriteriaMismatch, Severity: SeverityError, Pointer: "/stemcell_criteria/os",
+		},
+		{
+			Name: "stemcell version mismatch",
+			Lock: func(lock *KilnfileLock) {
+				lock.Stemcell.Version = "2.5"
+			},
+			Rule: RuleStemcellCriteriaMismatch, Severity: SeverityError, Pointer: "/stemcell_criteria/version",
+		},
+		{
+			Name: "invalid github repository",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.Releases[0].GitHubRepository = "https://github.com/cloudfoundry"
+			},
+			Rule: RuleGitHubRepositoryInvalid, Severity: SeverityError, Pointer: "/releases/0/github_repository",
+		},
+		{
+			Name: "tile name without bake configuration",
+			Kilnfile: func(kf *Kilnfile) {
+				kf.TileNames = []string{"ert", "srt"}
+				kf.BakeConfigurations = []BakeConfiguration{{TileName: "ert"}}
+			},
+			Rule: RuleTileNameWithoutBakeConfiguration, Severity: SeverityError, Pointer: "/tile_names/1",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			please := NewWithT(t)
+			kf := validKilnfile()
+			lock := validLock
+			lock.Releases = slices.Clone(validLock.Releases)
+			if tt.Kilnfile != nil {
+				tt.Kilnfile(&kf)
+			}
+			if tt.Lock != nil {
+				tt.Lock(&lock)
+			}
+
+			results := Validate(kf, lock)
+
+			please.Expect(results).To(HaveLen(1))
+			fieldErr := results[0].(*FieldError)
+			please.Expect(fieldErr.Rule).To(Equal(tt.Rule))
+			please.Expect(fieldErr.Severity).To(Equal(tt.Severity))
+			please.Expect(fieldErr.Pointer).To(Equal(tt.Pointer))
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		please := NewWithT(t)
+		please.Expect(Validate(validKilnfile(), validLock)).To(BeEmpty())
+	})
+}
+
+func TestValidateBakeConfigurationPaths(t *testing.T) {
+	please := NewWithT(t)
+	dir := t.TempDir()
+	please.Expect(os.WriteFile(filepath.Join(dir, "base.yml"), nil, 0o644)).To(Succeed())
+	please.Expect(os.Mkdir(filepath.Join(dir, "jobs"), 0o755)).To(Succeed())
+
+	results := ValidateBakeConfigurationPaths(Kilnfile{
+		BakeConfigurations: []BakeConfiguration{
+			{TileName: "ert", Metadata: "base.yml", JobDirectories: []string{"jobs"}},
+			{TileName: "srt", Metadata: "srt.yml", JobDirectories: []string{"jobs", "srt-jobs"}},
+		},
+	}, dir, os.Stat)
+
+	please.Expect(results).To(HaveLen(2))
+	please.Expect(results[0]).To(MatchError(`metadata_filepath "srt.yml" of bake configuration "srt" does not exist`))
+	please.Expect(results[0].(*FieldError).Pointer).To(Equal("/bake_configurations/1/metadata_filepath"))
+	please.Expect(results[1].(*FieldError).Pointer).To(Equal("/bake_configurations/1/jobs_directories/1"))
+	please.Expect(results[1].(*FieldError).Rule).To(Equal(RuleBakeConfigurationPathMissing))
+}